@@ -0,0 +1,133 @@
+package anonymize
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Progress is reported after every copied batch.
+type Progress struct {
+	Table  string
+	Copied int64
+	Total  int64
+}
+
+// CopyOptions configures Copy.
+type CopyOptions struct {
+	BatchSize  int              // rows read and written at once, 500 when not positive
+	OnProgress func(p Progress) // optional
+}
+
+// Copy reads the rows of src matching wheres by batches in primary key order, the
+// unpublished ones included, masks them with policy and creates them in dst. It returns the number of copied rows.
+// Copying related tables keeps foreign keys valid as long as the key columns are
+// not masked, or are masked with the same Hash on both sides.
+func Copy[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](
+	ctx context.Context,
+	src, dst *base.BaseGorm[T, PkType],
+	wheres []base.Where,
+	policy Policy,
+	opts CopyOptions,
+) (int64, error) {
+	var (
+		e        T
+		logEntry = generic_gorm.GetLoggerFromContext(ctx).WithField("table", e.TableName())
+		copied   int64
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	fields, err := maskedFields(src.DB(ctx), &e, policy)
+	if err != nil {
+		return 0, err
+	}
+
+	// every row is copied, the ones outside their publish window too
+	total, err := src.Count(ctx, wheres, base.WithUnpublished())
+	if err != nil {
+		return 0, err
+	}
+
+	batch := make([]*T, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, _, err := dst.CreateMultiple(ctx, batch); err != nil {
+			return err
+		}
+		copied += int64(len(batch))
+		batch = batch[:0]
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(Progress{Table: e.TableName(), Copied: copied, Total: total})
+		}
+		return nil
+	}
+
+	// read by primary key, each batch starting after the last key of the previous one
+	err = src.Each(ctx, wheres, nil, opts.BatchSize, func(row *T) error {
+		masked := *row
+		if err := mask(ctx, &masked, fields, policy); err != nil {
+			return err
+		}
+		batch = append(batch, &masked)
+
+		if len(batch) < opts.BatchSize {
+			return nil
+		}
+		return flush()
+	}, base.WithUnpublished())
+	if err != nil {
+		return copied, err
+	}
+
+	err = flush()
+	return copied, err
+}
+
+// maskedFields resolves the columns of policy to the fields of model.
+func maskedFields(db *gorm.DB, model interface{}, policy Policy) (map[string]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]*schema.Field, len(policy))
+	for column := range policy {
+		field := stmt.Schema.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("masked column %s not found in %s", column, stmt.Schema.Table)
+		}
+		fields[column] = field
+	}
+
+	return fields, nil
+}
+
+func mask[T any](ctx context.Context, row *T, fields map[string]*schema.Field, policy Policy) error {
+	value := reflect.ValueOf(row).Elem()
+	for column, field := range fields {
+		current, _ := field.ValueOf(ctx, value)
+		if err := field.Set(ctx, value, policy[column](current)); err != nil {
+			return fmt.Errorf("mask %s: %w", column, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package anonymize
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Member struct {
+	ID    uint   `gorm:"column:id;primaryKey"`
+	Name  string `gorm:"column:name"`
+	Email string `gorm:"column:email"`
+	Plan  string `gorm:"column:plan"`
+}
+
+func (Member) TableName() string {
+	return "members"
+}
+
+func (Member) PrimaryKey() string {
+	return "id"
+}
+
+func TestMask(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		policy = Policy{"name": Constant("Jane Doe"), "email": Email("salt")}
+		row    = Member{ID: 7, Name: "Alice Smith", Email: "alice@corp.com", Plan: "pro"}
+		same   = Member{ID: 8, Name: "Alice Smith", Email: "alice@corp.com", Plan: "pro"}
+	)
+
+	fields, err := maskedFields(testdb.DryRun(t), &Member{}, policy)
+	if err != nil {
+		t.Fatalf("Failed to resolve masked fields: %v", err)
+	}
+	if err := mask(ctx, &row, fields, policy); err != nil {
+		t.Fatalf("Failed to mask row: %v", err)
+	}
+	if err := mask(ctx, &same, fields, policy); err != nil {
+		t.Fatalf("Failed to mask row: %v", err)
+	}
+
+	if row.Name != "Jane Doe" || row.Plan != "pro" || row.ID != 7 {
+		t.Errorf("Unexpected masked row %+v", row)
+	}
+	if row.Email == "alice@corp.com" || row.Email != same.Email {
+		t.Errorf("Expected the same masked email for equal emails, got %s and %s", row.Email, same.Email)
+	}
+
+	if _, err := maskedFields(testdb.DryRun(t), &Member{}, Policy{"phone": Null()}); err == nil {
+		t.Error("Expected error when masking an unknown column")
+	}
+}
+
+type Article struct {
+	ID        uint       `gorm:"column:id;primaryKey"`
+	Author    string     `gorm:"column:author"`
+	PublishAt *time.Time `gorm:"column:publish_at"`
+	ExpireAt  *time.Time `gorm:"column:expire_at"`
+}
+
+func (Article) TableName() string {
+	return "articles"
+}
+
+func (Article) PrimaryKey() string {
+	return "id"
+}
+
+func TestCopy(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		src       = testdb.DryRun(t)
+		dst       = testdb.DryRun(t)
+		upcoming  = time.Now().Add(time.Hour)
+		reads     []string
+		created   []Article
+		progress  []Progress
+		callbacks = src.Callback()
+	)
+
+	// the dry run returns no rows : the first read returns three articles, one not
+	// published yet, and the next ones none
+	err := callbacks.Query().After("gorm:query").Register("test:stub_rows", func(tx *gorm.DB) {
+		reads = append(reads, tx.Statement.SQL.String())
+		if rows, ok := tx.Statement.Dest.(*[]Article); ok && len(reads) == 2 {
+			*rows = append(*rows,
+				Article{ID: 1, Author: "alice"},
+				Article{ID: 2, Author: "bob", PublishAt: &upcoming},
+				Article{ID: 3, Author: "carol"},
+			)
+			tx.RowsAffected = int64(len(*rows))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+	err = dst.Callback().Create().After("gorm:create").Register("test:record_rows", func(tx *gorm.DB) {
+		for _, row := range tx.Statement.Dest.([]*Article) {
+			created = append(created, *row)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	copied, err := Copy(ctx,
+		base.NewBaseGorm[Article, uint](src),
+		base.NewBaseGorm[Article, uint](dst),
+		nil,
+		Policy{"author": Constant("anonymous")},
+		CopyOptions{BatchSize: 2, OnProgress: func(p Progress) { progress = append(progress, p) }},
+	)
+	if err != nil {
+		t.Fatalf("Failed to copy: %v", err)
+	}
+
+	if copied != 3 || len(created) != 3 {
+		t.Fatalf("Expected 3 copied articles, got %d and %+v", copied, created)
+	}
+	for _, row := range created {
+		if row.Author != "anonymous" {
+			t.Errorf("Expected masked authors, got %+v", row)
+		}
+	}
+	if created[1].PublishAt == nil {
+		t.Error("Expected the unpublished article to be copied")
+	}
+	if len(progress) != 2 || progress[0].Copied != 2 || progress[1].Copied != 3 {
+		t.Errorf("Expected progress after each batch, got %+v", progress)
+	}
+
+	for _, read := range reads {
+		if strings.Contains(read, "publish_at") || strings.Contains(read, "OFFSET") {
+			t.Errorf("Expected keyset reads ignoring the publish window, got %s", read)
+		}
+	}
+}
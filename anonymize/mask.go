@@ -0,0 +1,57 @@
+// Package anonymize copies rows between databases through BaseGorm repositories,
+// masking personal data on the fly, e.g. to fill a staging database from production.
+package anonymize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Masker returns the value stored in place of a column value.
+type Masker func(value interface{}) interface{}
+
+// Policy maps the columns of a table holding personal data to their Masker.
+// Columns not listed are copied unchanged.
+type Policy map[string]Masker
+
+// Constant replaces every value with replacement.
+func Constant(replacement interface{}) Masker {
+	return func(interface{}) interface{} {
+		return replacement
+	}
+}
+
+// Null replaces every value with NULL, the column must be nullable.
+func Null() Masker {
+	return Constant(nil)
+}
+
+// Hash replaces a value with the first 16 hex characters of its salted SHA-256. Equal
+// values get equal hashes, keeping unique indexes and joins on the column working.
+func Hash(salt string) Masker {
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+
+		return hashOf(salt, value)
+	}
+}
+
+// Email replaces a value with an address of the reserved example.invalid domain,
+// whose local part is the Hash of the value.
+func Email(salt string) Masker {
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+
+		return hashOf(salt, value) + "@example.invalid"
+	}
+}
+
+func hashOf(salt string, value interface{}) string {
+	sum := sha256.Sum256([]byte(salt + fmt.Sprint(value)))
+	return hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,102 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SumInt64 returns the sum of the integer column over the rows matching wheres, 0
+// when none matches. See SumDecimal for decimal columns.
+func (o *BaseGorm[T, PkType]) SumInt64(ctx context.Context, column string, wheres []Where) (int64, error) {
+	var sum int64
+
+	err := o.aggregate(ctx, column, wheres, func(db *gorm.DB) error {
+		return db.Select(fmt.Sprintf("COALESCE(SUM(%s), 0)", column)).Scan(&sum).Error
+	})
+
+	return sum, err
+}
+
+// Avg returns the average of column over the rows matching wheres, 0 when none matches.
+func (o *BaseGorm[T, PkType]) Avg(ctx context.Context, column string, wheres []Where) (float64, error) {
+	var avg sql.NullFloat64
+
+	err := o.aggregate(ctx, column, wheres, func(db *gorm.DB) error {
+		return db.Select(fmt.Sprintf("AVG(%s)", column)).Scan(&avg).Error
+	})
+
+	return avg.Float64, err
+}
+
+// Min scans the smallest value of column over the rows matching wheres into dest, a
+// pointer, e.g. a *sql.NullTime as the minimum is NULL when no row matches. See MinBy
+// for the row holding it.
+func (o *BaseGorm[T, PkType]) Min(ctx context.Context, column string, wheres []Where, dest interface{}) error {
+	return o.aggregate(ctx, column, wheres, func(db *gorm.DB) error {
+		return db.Select(fmt.Sprintf("MIN(%s)", column)).Scan(dest).Error
+	})
+}
+
+// Max scans the greatest value of column over the rows matching wheres into dest,
+// like Min. See MaxBy for the row holding it.
+func (o *BaseGorm[T, PkType]) Max(ctx context.Context, column string, wheres []Where, dest interface{}) error {
+	return o.aggregate(ctx, column, wheres, func(db *gorm.DB) error {
+		return db.Select(fmt.Sprintf("MAX(%s)", column)).Scan(dest).Error
+	})
+}
+
+// GroupCount counts the rows matching wheres by value of groupColumn, the NULL
+// values being counted under "".
+func (o *BaseGorm[T, PkType]) GroupCount(ctx context.Context, groupColumn string, wheres []Where) (map[string]int64, error) {
+	var groups []struct {
+		Value sql.NullString `gorm:"column:group_value"`
+		Count int64          `gorm:"column:group_count"`
+	}
+
+	err := o.aggregate(ctx, groupColumn, wheres, func(db *gorm.DB) error {
+		return db.
+			Select(fmt.Sprintf("%s AS group_value, COUNT(*) AS group_count", groupColumn)).
+			Group(groupColumn).
+			Scan(&groups).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(groups))
+	for _, group := range groups {
+		counts[group.Value.String] += group.Count
+	}
+
+	return counts, nil
+}
+
+// aggregate runs query on the rows of T matching wheres, once column and wheres are
+// checked against WithColumnAllowList.
+func (o *BaseGorm[T, PkType]) aggregate(ctx context.Context, column string, wheres []Where, query func(db *gorm.DB) error) error {
+	var (
+		e   T
+		db  = o.conn(ctx).Model(&e).Table(o.table)
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	if err = o.checkColumns(append([]Where{{Name: column}}, wheres...), nil); err != nil {
+		return err
+	}
+
+	for _, v := range wheres {
+		db.Where(v.StringFor(db), v.Args()...)
+	}
+
+	err = query(db)
+	return err
+}
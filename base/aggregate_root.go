@@ -0,0 +1,357 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// AggregateRepo is a repository of aggregate roots T owning the children of has one
+// and has many associations : Load reads a root with its children, and Save persists
+// what changed in the whole graph since. The loaded state is kept in the tracking
+// context of ctx, see ContextWithTracking, so each unit of work saves against its
+// own loads. The other methods are the ones of the wrapped repository.
+type AggregateRepo[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] struct {
+	*BaseGorm[T, PkType]
+
+	owned []string
+}
+
+// aggregateSnapshot is the loaded state of an aggregate : a copy of the root and of
+// its children, keyed by association then by primary key.
+type aggregateSnapshot[T any] struct {
+	root     T
+	children map[string]map[interface{}]reflect.Value
+}
+
+// NewAggregateRepo returns the AggregateRepo of the roots of repo owning the children
+// of the associations owned, the names of fields of T.
+func NewAggregateRepo[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](repo *BaseGorm[T, PkType], owned ...string) *AggregateRepo[T, PkType] {
+	return &AggregateRepo[T, PkType]{BaseGorm: repo, owned: owned}
+}
+
+// snapshotsTable is the table the snapshots of the aggregates are kept under in the
+// tracking context, apart from the rows tracked by WithTracking.
+func (r *AggregateRepo[T, PkType]) snapshotsTable() string {
+	return "aggregate:" + r.table
+}
+
+// relationships returns the owned associations of T, which must be has one or has
+// many ones with a single primary key.
+func (r *AggregateRepo[T, PkType]) relationships() (*schema.Schema, []*schema.Relationship, error) {
+	sch, err := r.schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relationships := make([]*schema.Relationship, 0, len(r.owned))
+	for _, name := range r.owned {
+		relationship, ok := sch.Relationships.Relations[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("aggregate %s: unknown association %s", sch.Table, name)
+		}
+		if relationship.Type != schema.HasOne && relationship.Type != schema.HasMany {
+			return nil, nil, fmt.Errorf("aggregate %s: %s is a %s relation, only has one and has many children are owned", sch.Table, name, relationship.Type)
+		}
+		if relationship.FieldSchema.PrioritizedPrimaryField == nil {
+			return nil, nil, fmt.Errorf("aggregate %s: %s of %s have no single primary key", sch.Table, relationship.FieldSchema.Table, name)
+		}
+		relationships = append(relationships, relationship)
+	}
+
+	return sch, relationships, nil
+}
+
+// Load returns the root of primary key id with its owned children, nil when it does
+// not exist, and remembers their state for Save in the tracking context of ctx.
+func (r *AggregateRepo[T, PkType]) Load(ctx context.Context, id PkType, opts ...QueryOption) (*T, error) {
+	var err error
+
+	defer func() {
+		if err != nil {
+			r.logError(ctx, err)
+		}
+	}()
+
+	sch, relationships, err := r.relationships()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range r.owned {
+		opts = append(opts, WithPreload(name))
+	}
+	root, err := r.Detail(ctx, id, opts...)
+	if err != nil || root == nil {
+		return root, err
+	}
+
+	r.remember(ctx, sch, relationships, root)
+
+	return root, nil
+}
+
+// remember stores the snapshot of root in the tracking context of ctx, if any,
+// copying its children so the changes made to them afterwards are seen by Save.
+func (r *AggregateRepo[T, PkType]) remember(ctx context.Context, sch *schema.Schema, relationships []*schema.Relationship, root *T) {
+	t := trackerOf(ctx)
+	if t == nil {
+		return
+	}
+
+	pk, err := r.primaryKeyOf(ctx, sch, root)
+	if err != nil {
+		r.logError(ctx, err)
+		return
+	}
+
+	snapshot := aggregateSnapshot[T]{root: *root, children: make(map[string]map[interface{}]reflect.Value, len(relationships))}
+	for _, relationship := range relationships {
+		var (
+			children     = ownedChildren(ctx, relationship, reflect.ValueOf(root).Elem())
+			primaryField = relationship.FieldSchema.PrioritizedPrimaryField
+			byKey        = make(map[interface{}]reflect.Value, children.Len())
+		)
+		for i := 0; i < children.Len(); i++ {
+			child := children.Index(i)
+			copied := reflect.New(child.Type().Elem())
+			copied.Elem().Set(child.Elem())
+
+			key, _ := primaryField.ValueOf(ctx, copied.Elem())
+			byKey[key] = copied
+		}
+		snapshot.children[relationship.Name] = byKey
+	}
+
+	t.store(r.snapshotsTable(), pk, snapshot)
+}
+
+// ownedChildren returns the children of root in relationship as a slice of pointers
+// into root, so the keys given by the inserts land in it.
+func ownedChildren(ctx context.Context, relationship *schema.Relationship, root reflect.Value) reflect.Value {
+	var (
+		field    = relationship.Field.ReflectValueOf(ctx, root)
+		children = reflect.MakeSlice(reflect.SliceOf(reflect.PointerTo(relationship.FieldSchema.ModelType)), 0, 1)
+	)
+
+	switch field.Kind() {
+	case reflect.Slice:
+		for i := 0; i < field.Len(); i++ {
+			child := field.Index(i)
+			if child.Kind() != reflect.Pointer {
+				child = child.Addr()
+			} else if child.IsNil() {
+				continue
+			}
+			children = reflect.Append(children, child)
+		}
+	case reflect.Pointer:
+		if !field.IsNil() {
+			children = reflect.Append(children, field)
+		}
+	default:
+		// a zero has one child is no child
+		if !field.IsZero() {
+			children = reflect.Append(children, field.Addr())
+		}
+	}
+
+	return children
+}
+
+// Save persists root in one transaction : a new root is created with its children,
+// and a loaded one, see Load, has its changed columns updated, its new children
+// created, its changed children updated and its removed children deleted. The counts
+// of SyncResult are the ones of the children. Saving a root not loaded in the
+// tracking context of ctx writes all its columns and compares its children with the
+// stored ones.
+func (r *AggregateRepo[T, PkType]) Save(ctx context.Context, root *T) (SyncResult, error) {
+	var (
+		result SyncResult
+		err    error
+	)
+
+	defer func() {
+		if err != nil {
+			r.logError(ctx, err)
+		}
+	}()
+
+	sch, relationships, err := r.relationships()
+	if err != nil {
+		return result, err
+	}
+
+	pk, err := r.primaryKeyOf(ctx, sch, root)
+	if err != nil {
+		return result, err
+	}
+
+	var (
+		rootValue = reflect.ValueOf(root).Elem()
+		zero      PkType
+	)
+
+	err = r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := generic_gorm.ContextWithDB(ctx, tx)
+
+		// gorm creates the children of a new root along with it
+		if pk == zero {
+			if _, err := r.Create(txCtx, root); err != nil {
+				return err
+			}
+			for _, relationship := range relationships {
+				result.Created += int64(ownedChildren(ctx, relationship, rootValue).Len())
+			}
+			return nil
+		}
+
+		// the columns of the root, its children being saved below
+		var (
+			snapshot aggregateSnapshot[T]
+			loaded   bool
+			columns  = rootColumns(sch)
+		)
+		if t := trackerOf(ctx); t != nil {
+			var value interface{}
+			if value, loaded = t.load(r.snapshotsTable(), pk); loaded {
+				snapshot = value.(aggregateSnapshot[T])
+			}
+		}
+		if loaded {
+			changes, err := r.Diff(&snapshot.root, root)
+			if err != nil {
+				return err
+			}
+			columns = changedColumns(changes)
+		}
+		if len(columns) > 0 {
+			if _, err := r.Update(txCtx, root, columns); err != nil {
+				return err
+			}
+		}
+
+		for _, relationship := range relationships {
+			var current map[interface{}]reflect.Value
+			if loaded {
+				current = snapshot.children[relationship.Name]
+			} else {
+				stored, err := storedChildren(ctx, tx, root, relationship)
+				if err != nil {
+					return err
+				}
+				current = stored
+			}
+
+			if err := syncChildren(ctx, tx, rootValue, relationship, ownedChildren(ctx, relationship, rootValue), current, &result); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	r.remember(ctx, sch, relationships, root)
+
+	return result, nil
+}
+
+// rootColumns returns the columns of the root a Save of a root not loaded writes.
+func rootColumns(sch *schema.Schema) []string {
+	var columns []string
+	for _, field := range sch.Fields {
+		if field.DBName != "" && !field.PrimaryKey && field.AutoCreateTime == 0 {
+			columns = append(columns, field.DBName)
+		}
+	}
+
+	return columns
+}
+
+// storedChildren reads the children of root in relationship, keyed by primary key.
+func storedChildren(ctx context.Context, tx *gorm.DB, root interface{}, relationship *schema.Relationship) (map[interface{}]reflect.Value, error) {
+	var (
+		primaryField = relationship.FieldSchema.PrioritizedPrimaryField
+		stored       = reflect.New(reflect.SliceOf(reflect.PointerTo(relationship.FieldSchema.ModelType)))
+	)
+
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(root).Association(relationship.Name).Find(stored.Interface()); err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[interface{}]reflect.Value, stored.Elem().Len())
+	for i := 0; i < stored.Elem().Len(); i++ {
+		child := stored.Elem().Index(i)
+		key, _ := primaryField.ValueOf(ctx, child.Elem())
+		byKey[key] = child
+	}
+
+	return byKey, nil
+}
+
+// Delete deletes the root of primary key id and its owned children in one
+// transaction, and forgets its loaded state. The children are deleted like the
+// root : softly when it has a gorm.DeletedAt field, and they have one too,
+// permanently otherwise.
+func (r *AggregateRepo[T, PkType]) Delete(ctx context.Context, id PkType) (int64, error) {
+	var (
+		rowsAffected int64
+		err          error
+	)
+
+	defer func() {
+		if err != nil {
+			r.logError(ctx, err)
+		}
+	}()
+
+	sch, relationships, err := r.relationships()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		e    T
+		root = reflect.New(sch.ModelType)
+	)
+	if err = sch.LookUpField(e.PrimaryKey()).Set(ctx, root.Elem(), id); err != nil {
+		return 0, err
+	}
+
+	err = r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, relationship := range relationships {
+			if err := deleteChildren(ctx, tx, root.Elem(), relationship, !hasDeletedAt(sch)); err != nil {
+				return fmt.Errorf("delete %s of %s %v: %w", relationship.Name, sch.Table, id, err)
+			}
+		}
+
+		var err error
+		rowsAffected, err = r.BaseGorm.Delete(generic_gorm.ContextWithDB(ctx, tx), id)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if t := trackerOf(ctx); t != nil {
+		t.delete(r.snapshotsTable(), id)
+	}
+
+	return rowsAffected, nil
+}
+
+// deleteChildren deletes the children of root in relationship, permanently when
+// unscoped is set.
+func deleteChildren(ctx context.Context, tx *gorm.DB, root reflect.Value, relationship *schema.Relationship, unscoped bool) error {
+	return scoped(tx.Session(&gorm.Session{NewDB: true}), unscoped).
+		Clauses(clause.Where{Exprs: relationship.ToQueryConditions(ctx, root)}).
+		Delete(reflect.New(relationship.FieldSchema.ModelType).Interface()).Error
+}
@@ -0,0 +1,133 @@
+package base
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func TestAggregateRepoRelationships(t *testing.T) {
+	db := setupDryRunDB(t)
+
+	if _, _, err := NewAggregateRepo(NewBaseGorm[User, uint](db), "Posts", "Profile").relationships(); err != nil {
+		t.Fatalf("Expected has one and has many children to be owned, got %v", err)
+	}
+	if _, _, err := NewAggregateRepo(NewBaseGorm[User, uint](db), "Comments").relationships(); err == nil {
+		t.Error("Expected an error for an unknown association")
+	}
+}
+
+func TestAggregateRepoSnapshot(t *testing.T) {
+	var (
+		ctx  = ContextWithTracking(context.Background())
+		repo = NewAggregateRepo(NewBaseGorm[User, uint](setupDryRunDB(t)), "Posts", "Profile")
+		user = &User{ID: 1, Posts: []Post{{ID: 10, Title: "Draft"}, {ID: 11, Title: "Kept"}}}
+	)
+
+	sch, relationships, err := repo.relationships()
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	repo.remember(ctx, sch, relationships, user)
+	user.Posts[0].Title = "Published"
+
+	value, ok := trackerOf(ctx).load("aggregate:dummy_users", uint(1))
+	if !ok {
+		t.Fatal("Expected the aggregate to be remembered")
+	}
+	snapshot := value.(aggregateSnapshot[User])
+
+	posts := snapshot.children["Posts"]
+	if len(posts) != 2 || posts[uint(10)].Interface().(*Post).Title != "Draft" {
+		t.Errorf("Expected a copy of the posts as loaded, got %v", posts)
+	}
+	if len(snapshot.children["Profile"]) != 0 {
+		t.Errorf("Expected a zero profile not to be a child, got %v", snapshot.children["Profile"])
+	}
+}
+
+func TestAggregateRepoSnapshotScopedToContext(t *testing.T) {
+	var (
+		ctx  = ContextWithTracking(context.Background())
+		repo = NewAggregateRepo(NewBaseGorm[User, uint](setupDryRunDB(t), WithTracking()), "Posts")
+		user = &User{ID: 1, Posts: []Post{{ID: 10}}}
+	)
+
+	sch, relationships, err := repo.relationships()
+	if err != nil {
+		t.Fatalf("Failed to read relationships: %v", err)
+	}
+	repo.remember(ctx, sch, relationships, user)
+
+	if _, ok := trackerOf(ctx).load("dummy_users", uint(1)); ok {
+		t.Error("Expected the aggregate apart from the rows tracked by WithTracking")
+	}
+	if _, ok := trackerOf(ContextWithTracking(context.Background())).load("aggregate:dummy_users", uint(1)); ok {
+		t.Error("Expected the aggregate not remembered in another context")
+	}
+
+	// without a tracking context nothing is remembered
+	repo.remember(context.Background(), sch, relationships, user)
+}
+
+// Forum is a soft deleted root of replies.
+type Forum struct {
+	ID        uint           `gorm:"column:id;primaryKey"`
+	Replies   []Reply        `gorm:"foreignKey:ThreadID"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index"`
+}
+
+func (Forum) TableName() string {
+	return "forums"
+}
+
+func (Forum) PrimaryKey() string {
+	return "id"
+}
+
+func TestAggregateRepoDeleteChildrenLikeRoot(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		children func(db *gorm.DB) (*schema.Schema, []*schema.Relationship, error)
+		want     string
+	}{
+		{
+			name: "soft deleted root",
+			children: func(db *gorm.DB) (*schema.Schema, []*schema.Relationship, error) {
+				return NewAggregateRepo(NewBaseGorm[Forum, uint](db), "Replies").relationships()
+			},
+			want: "UPDATE `replies` SET `deleted_at`=? WHERE `replies`.`thread_id` = ? AND `replies`.`deleted_at` IS NULL",
+		},
+		{
+			name: "hard deleted root",
+			children: func(db *gorm.DB) (*schema.Schema, []*schema.Relationship, error) {
+				return NewAggregateRepo(NewBaseGorm[Thread, uint](db), "Replies").relationships()
+			},
+			want: "DELETE FROM `replies` WHERE `replies`.`thread_id` = ?",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				db  = setupDryRunDB(t)
+				sql = captureSQL(t, db)
+			)
+
+			sch, relationships, err := tc.children(db)
+			if err != nil {
+				t.Fatalf("Failed to read relationships: %v", err)
+			}
+			root := reflect.New(sch.ModelType).Elem()
+			root.Field(0).SetUint(7)
+
+			if err := deleteChildren(context.Background(), db, root, relationships[0], !hasDeletedAt(sch)); err != nil {
+				t.Fatalf("Failed to delete children: %v", err)
+			}
+			if *sql != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, *sql)
+			}
+		})
+	}
+}
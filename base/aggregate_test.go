@@ -0,0 +1,62 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestAggregates(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		db     = setupDryRunDB(t)
+		query  string
+		repo   = NewBaseGorm[Document, uint](db)
+		wheres = []Where{{Name: "user_id", Value: 7}}
+		latest sql.NullTime
+	)
+	if err := db.Callback().Row().After("gorm:row").Register("test:capture_sql", func(tx *gorm.DB) {
+		query = tx.Statement.SQL.String()
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"SumInt64", func() error {
+			_, err := repo.SumInt64(ctx, "views", wheres)
+			return err
+		}, "SELECT COALESCE(SUM(views), 0) FROM `documents` WHERE user_id = ? AND `documents`.`deleted_at` IS NULL"},
+		{"SumDecimal", func() error {
+			_, err := repo.SumDecimal(ctx, "views", wheres)
+			return err
+		}, "SELECT COALESCE(SUM(views), 0) FROM `documents` WHERE user_id = ? AND `documents`.`deleted_at` IS NULL"},
+		{"Avg", func() error {
+			_, err := repo.Avg(ctx, "views", wheres)
+			return err
+		}, "SELECT AVG(views) FROM `documents` WHERE user_id = ? AND `documents`.`deleted_at` IS NULL"},
+		{"Max", func() error {
+			return repo.Max(ctx, "created_at", wheres, &latest)
+		}, "SELECT MAX(created_at) FROM `documents` WHERE user_id = ? AND `documents`.`deleted_at` IS NULL"},
+		{"GroupCount", func() error {
+			_, err := repo.GroupCount(ctx, "status", nil)
+			return err
+		}, "SELECT status AS group_value, COUNT(*) AS group_count FROM `documents` WHERE `documents`.`deleted_at` IS NULL GROUP BY `status`"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// the dry run builds the statement without scanning its result
+			if err := tt.run(); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+				t.Fatalf("Failed to run %s: %v", tt.name, err)
+			}
+			if query != tt.want {
+				t.Errorf("Expected SQL\n%s\ngot\n%s", tt.want, query)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// AssociationOption adjusts the query used by association reads.
+type AssociationOption = func(*gorm.DB) *gorm.DB
+
+// WithTrashed includes soft deleted children, which association reads exclude by default.
+func WithTrashed() AssociationOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+}
+
+// ListAssociation finds one page of the children of model in field into dest.
+func (o *BaseGorm[T, PkType]) ListAssociation(ctx context.Context, model *T, field string, dest interface{}, page int, pageSize int, opts ...AssociationOption) (*Paginator, error) {
+	var (
+		err       error
+		paginator = &Paginator{
+			Page:    page,
+			PerPage: pageSize,
+			Total:   0,
+		}
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	association := o.Association(ctx, model, field, opts...)
+	count := association.Count()
+	if err = association.Error; err != nil {
+		return nil, err
+	}
+
+	paginator.SetTotal(int(count))
+	if count == 0 {
+		return paginator, nil
+	}
+
+	pageOpts := append(append(make([]AssociationOption, 0, len(opts)+1), opts...), func(db *gorm.DB) *gorm.DB {
+		return db.Offset((page - 1) * pageSize).Limit(pageSize)
+	})
+	if err = o.Association(ctx, model, field, pageOpts...).Find(dest); err != nil {
+		return paginator, err
+	}
+
+	return paginator, nil
+}
+
+// IterateAssociation walks the children of model in field by batches of batchSize,
+// ordered by their primary key, and calls fn after loading each batch into dest, a
+// pointer to a slice. Each batch starts after the last key of the previous one, so
+// the cost of a batch does not grow with the number of children already read.
+// Iteration stops at the first error returned by fn. batchSize defaults to 1000.
+func (o *BaseGorm[T, PkType]) IterateAssociation(ctx context.Context, model *T, field string, dest interface{}, batchSize int, fn func() error, opts ...AssociationOption) error {
+	var (
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	association := o.Association(ctx, model, field, opts...)
+	if err = association.Error; err != nil {
+		return err
+	}
+
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	childSchema := association.Relationship.FieldSchema
+	primaryField := childSchema.PrioritizedPrimaryField
+	if primaryField == nil {
+		err = fmt.Errorf("association %s of %s has no single primary key to iterate on", field, childSchema.Table)
+		return err
+	}
+
+	var (
+		rows   = reflect.ValueOf(dest).Elem()
+		column = clause.Column{Table: childSchema.Table, Name: primaryField.DBName}
+		after  interface{}
+	)
+
+	for {
+		batchOpts := append(append(make([]AssociationOption, 0, len(opts)+1), opts...), func(db *gorm.DB) *gorm.DB {
+			if after != nil {
+				db = db.Where(clause.Gt{Column: column, Value: after})
+			}
+			return db.Order(clause.OrderByColumn{Column: column}).Limit(batchSize)
+		})
+
+		rows.SetLen(0)
+		if err = o.Association(ctx, model, field, batchOpts...).Find(dest); err != nil {
+			return err
+		}
+		if rows.Len() == 0 {
+			return nil
+		}
+
+		if err = fn(); err != nil {
+			return err
+		}
+		if rows.Len() < batchSize {
+			return nil
+		}
+
+		after, _ = primaryField.ValueOf(ctx, reflect.Indirect(rows.Index(rows.Len()-1)))
+	}
+}
+
+// softClearAssociation soft deletes the has one/has many children of model in field,
+// and falls back to gorm's Clear for other relations or children without DeletedAt.
+func (o *BaseGorm[T, PkType]) softClearAssociation(ctx context.Context, model *T, field string) error {
+	association := o.Association(ctx, model, field)
+	if association.Error != nil {
+		return association.Error
+	}
+
+	relationship := association.Relationship
+	if (relationship.Type != schema.HasOne && relationship.Type != schema.HasMany) || !hasDeletedAt(relationship.FieldSchema) {
+		return association.Clear()
+	}
+
+	children := reflect.New(reflect.SliceOf(reflect.PointerTo(relationship.FieldSchema.ModelType)))
+	if err := association.Find(children.Interface()); err != nil {
+		return err
+	}
+	if children.Elem().Len() == 0 {
+		return nil
+	}
+
+	return o.conn(ctx).Delete(children.Interface()).Error
+}
+
+// hasDeletedAt reports whether sch has a gorm.DeletedAt soft delete field.
+func hasDeletedAt(sch *schema.Schema) bool {
+	return deletedAtField(sch) != nil
+}
+
+// deletedAtField returns the gorm.DeletedAt field of sch, if any.
+func deletedAtField(sch *schema.Schema) *schema.Field {
+	deletedAtType := reflect.TypeOf(gorm.DeletedAt{})
+	for _, field := range sch.Fields {
+		if field.FieldType == deletedAtType {
+			return field
+		}
+	}
+
+	return nil
+}
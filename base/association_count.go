@@ -0,0 +1,111 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// CountAssociations counts the children in field of every model with a single
+// GROUP BY query, e.g. for a page of List results. Every model is a key of the
+// result, with 0 when it has no children. Many to many associations count the
+// links of the join table.
+func (o *BaseGorm[T, PkType]) CountAssociations(ctx context.Context, models []*T, field string, opts ...AssociationOption) (map[PkType]int64, error) {
+	var (
+		counts = make(map[PkType]int64, len(models))
+		err    error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	if len(models) == 0 {
+		return counts, nil
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	relationship, ok := sch.Relationships.Relations[field]
+	if !ok {
+		err = fmt.Errorf("association %s not found in %s", field, sch.Table)
+		return nil, err
+	}
+
+	parentKeys := make([]interface{}, 0, len(models))
+	for _, model := range models {
+		var pk PkType
+		if pk, err = o.primaryKeyOf(ctx, sch, model); err != nil {
+			return nil, err
+		}
+		counts[pk] = 0
+		parentKeys = append(parentKeys, pk)
+	}
+
+	db := o.conn(ctx)
+	for _, opt := range opts {
+		db = opt(db)
+	}
+
+	var (
+		foreignKey *schema.Field
+		conditions []clause.Expression
+	)
+	switch relationship.Type {
+	case schema.HasOne, schema.HasMany:
+		db = db.Model(reflect.New(relationship.FieldSchema.ModelType).Interface())
+	case schema.Many2Many:
+		db = db.Table(relationship.JoinTable.Table)
+	default:
+		err = fmt.Errorf("count associations %s: %s relations are not supported", field, relationship.Type)
+		return nil, err
+	}
+	for _, reference := range relationship.References {
+		switch {
+		case reference.OwnPrimaryKey:
+			foreignKey = reference.ForeignKey
+		case reference.PrimaryValue != "":
+			conditions = append(conditions, clause.Eq{Column: clause.Column{Table: reference.ForeignKey.Schema.Table, Name: reference.ForeignKey.DBName}, Value: reference.PrimaryValue})
+		}
+	}
+	if foreignKey == nil {
+		err = fmt.Errorf("count associations %s: no foreign key referencing %s", field, sch.Table)
+		return nil, err
+	}
+
+	if len(conditions) > 0 {
+		db = db.Where(clause.And(conditions...))
+	}
+
+	var (
+		column  = clause.Column{Table: foreignKey.Schema.Table, Name: foreignKey.DBName}
+		results []associationCount[PkType]
+	)
+	err = db.
+		Select("? AS association_key, COUNT(*) AS association_count", column).
+		Where(clause.IN{Column: column, Values: parentKeys}).
+		Group("association_key").
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		counts[result.Key] = result.Count
+	}
+
+	return counts, nil
+}
+
+type associationCount[PkType string | int64 | int32 | int | uint] struct {
+	Key   PkType `gorm:"column:association_key"`
+	Count int64  `gorm:"column:association_count"`
+}
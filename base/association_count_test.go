@@ -0,0 +1,46 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCountAssociations(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	counts, err := repo.CountAssociations(context.Background(), []*User{{ID: 1}, {ID: 2}}, "Posts")
+	if err != nil {
+		t.Fatalf("Failed to count posts: %v", err)
+	}
+
+	want := "SELECT `dummy_posts`.`user_id` AS association_key, COUNT(*) AS association_count FROM `dummy_posts` WHERE `dummy_posts`.`user_id` IN (?,?) GROUP BY `association_key`"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+	if len(counts) != 2 || counts[1] != 0 || counts[2] != 0 {
+		t.Errorf("Expected a zero count for every user, got %v", counts)
+	}
+
+	if _, err := repo.CountAssociations(context.Background(), []*User{{ID: 1}}, "Unknown"); err == nil {
+		t.Error("Expected error for an unknown association")
+	}
+}
+
+func TestCountAssociationWithError(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+
+	if _, err := repo.CountAssociationWithError(context.Background(), &User{ID: 1}, "Posts"); err != nil {
+		t.Errorf("Unexpected error counting posts: %v", err)
+	}
+
+	if _, err := repo.CountAssociationWithError(context.Background(), &User{ID: 1}, "Unknown"); err == nil {
+		t.Error("Expected error for an unknown association")
+	}
+	if count := repo.CountAssociation(context.Background(), &User{ID: 1}, "Unknown"); count != 0 {
+		t.Errorf("Expected 0 from the deprecated CountAssociation, got %d", count)
+	}
+}
@@ -0,0 +1,206 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// SyncResult counts the children written by SyncAssociation. For many to many
+// associations, Created and Deleted count links.
+type SyncResult struct {
+	Created int64
+	Updated int64
+	Deleted int64
+}
+
+// SyncAssociation makes the children of model in field match desired, a slice of
+// children or of pointers to children, matched by primary key : children with a zero
+// key are created, changed ones update only their changed columns, and missing ones
+// are deleted (unlinked for many to many). Unlike ReplaceAssociation, kept children
+// keep their ids and timestamps. Everything runs in one transaction.
+func (o *BaseGorm[T, PkType]) SyncAssociation(ctx context.Context, model *T, field string, desired interface{}) (SyncResult, error) {
+	var (
+		result SyncResult
+		err    error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	desiredRows := reflect.Indirect(reflect.ValueOf(desired))
+	if desiredRows.Kind() != reflect.Slice {
+		err = fmt.Errorf("sync association %s: desired must be a slice, got %T", field, desired)
+		return result, err
+	}
+
+	err = o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		association := tx.Model(model).Association(field)
+		if association.Error != nil {
+			return association.Error
+		}
+
+		var (
+			relationship = association.Relationship
+			childSchema  = relationship.FieldSchema
+			primaryField = childSchema.PrioritizedPrimaryField
+			current      = reflect.New(reflect.SliceOf(reflect.PointerTo(childSchema.ModelType)))
+		)
+		if primaryField == nil {
+			return fmt.Errorf("association %s of %s has no single primary key to sync on", field, childSchema.Table)
+		}
+
+		if err := association.Find(current.Interface()); err != nil {
+			return err
+		}
+		currentByKey := make(map[interface{}]reflect.Value, current.Elem().Len())
+		for i := 0; i < current.Elem().Len(); i++ {
+			child := current.Elem().Index(i)
+			key, _ := primaryField.ValueOf(ctx, child.Elem())
+			currentByKey[key] = child
+		}
+
+		switch relationship.Type {
+		case schema.HasOne, schema.HasMany:
+			return syncChildren(ctx, tx, reflect.ValueOf(model).Elem(), relationship, desiredRows, currentByKey, &result)
+		case schema.Many2Many:
+			links := func() *gorm.Association { return tx.Model(model).Association(field) }
+			return syncLinks(ctx, links, primaryField, desiredRows, currentByKey, &result)
+		default:
+			return fmt.Errorf("sync association %s: %s relations are not supported", field, relationship.Type)
+		}
+	})
+
+	return result, err
+}
+
+// syncChildren creates, updates and deletes the has one/has many children of parent.
+func syncChildren(ctx context.Context, tx *gorm.DB, parent reflect.Value, relationship *schema.Relationship, desired reflect.Value, current map[interface{}]reflect.Value, result *SyncResult) error {
+	var (
+		childSchema  = relationship.FieldSchema
+		primaryField = childSchema.PrioritizedPrimaryField
+		seen         = make(map[interface{}]bool, desired.Len())
+		session      = func() *gorm.DB { return tx.Session(&gorm.Session{NewDB: true}) }
+	)
+
+	for i := 0; i < desired.Len(); i++ {
+		child := desired.Index(i)
+		if child.Kind() != reflect.Pointer {
+			child = child.Addr()
+		}
+
+		for _, reference := range relationship.References {
+			var value interface{} = reference.PrimaryValue // polymorphic type
+			if reference.OwnPrimaryKey {
+				value, _ = reference.PrimaryKey.ValueOf(ctx, parent)
+			}
+			if err := reference.ForeignKey.Set(ctx, child.Elem(), value); err != nil {
+				return err
+			}
+		}
+
+		key, isZero := primaryField.ValueOf(ctx, child.Elem())
+		if isZero {
+			if err := session().Omit(clause.Associations).Create(child.Interface()).Error; err != nil {
+				return err
+			}
+			result.Created++
+			continue
+		}
+
+		stored, ok := current[key]
+		if !ok {
+			return fmt.Errorf("sync association: %s %v is not a child of this %s", childSchema.Table, key, relationship.Schema.Table)
+		}
+		seen[key] = true
+
+		var columns []string
+		for _, field := range childSchema.Fields {
+			if field.DBName == "" || field.PrimaryKey || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+				continue
+			}
+			before, _ := field.ValueOf(ctx, stored.Elem())
+			after, _ := field.ValueOf(ctx, child.Elem())
+			if !valuesEqual(before, after) {
+				columns = append(columns, field.DBName)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		if err := session().Model(child.Interface()).Select(columns).Updates(child.Interface()).Error; err != nil {
+			return err
+		}
+		result.Updated++
+	}
+
+	for key, stored := range current {
+		if seen[key] {
+			continue
+		}
+		if err := session().Delete(stored.Interface()).Error; err != nil {
+			return err
+		}
+		result.Deleted++
+	}
+
+	return nil
+}
+
+// syncLinks links the missing many to many children, creating the new ones, and
+// unlinks the ones not desired anymore.
+func syncLinks(ctx context.Context, links func() *gorm.Association, primaryField *schema.Field, desired reflect.Value, current map[interface{}]reflect.Value, result *SyncResult) error {
+	var (
+		seen   = make(map[interface{}]bool, desired.Len())
+		linked []interface{}
+		gone   []interface{}
+	)
+
+	for i := 0; i < desired.Len(); i++ {
+		child := desired.Index(i)
+		if child.Kind() != reflect.Pointer {
+			child = child.Addr()
+		}
+
+		key, isZero := primaryField.ValueOf(ctx, child.Elem())
+		if !isZero {
+			if _, ok := current[key]; ok {
+				seen[key] = true
+				continue
+			}
+		}
+		linked = append(linked, child.Interface())
+	}
+
+	for key, stored := range current {
+		if !seen[key] {
+			gone = append(gone, stored.Interface())
+		}
+	}
+
+	if len(linked) > 0 {
+		if err := links().Append(linked...); err != nil {
+			return err
+		}
+		result.Created = int64(len(linked))
+	}
+	if len(gone) > 0 {
+		if err := links().Delete(gone...); err != nil {
+			return err
+		}
+		result.Deleted = int64(len(gone))
+	}
+
+	return nil
+}
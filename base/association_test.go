@@ -0,0 +1,210 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+)
+
+// txPool stands for the *sql.Tx of a transaction carried by a context.
+type txPool struct {
+	*sql.DB
+}
+
+func TestAssociationUsesContextTransaction(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		repo = NewBaseGorm[User, uint](db)
+		tx   = db.WithContext(context.Background())
+		pool = txPool{}
+	)
+	tx.Statement.ConnPool = pool
+
+	ctx := generic_gorm.ContextWithDB(context.Background(), tx)
+
+	if got := repo.Association(ctx, &User{ID: 1}, "Posts").DB.Statement.ConnPool; got != pool {
+		t.Errorf("Expected the association to run on the context transaction, got %T", got)
+	}
+	if got := repo.Association(context.Background(), &User{ID: 1}, "Posts").DB.Statement.ConnPool; got == pool {
+		t.Error("Expected the association to run on the repository database without transaction")
+	}
+}
+
+type Thread struct {
+	ID      uint    `gorm:"column:id;primaryKey"`
+	Replies []Reply // Has Many, soft deleted
+}
+
+func (Thread) TableName() string {
+	return "threads"
+}
+
+func (Thread) PrimaryKey() string {
+	return "id"
+}
+
+type Reply struct {
+	ID        uint           `gorm:"column:id;primaryKey"`
+	ThreadID  uint           `gorm:"column:thread_id"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index"`
+}
+
+func (Reply) TableName() string {
+	return "replies"
+}
+
+// recordStatements records the SQL of every statement of the dry run db, with its
+// vars, and makes the counts return count and the reads of replies return two of
+// them, the dry run returning no rows.
+func recordStatements(t *testing.T, db *gorm.DB, count int64) (*[]string, *[][]interface{}) {
+	t.Helper()
+
+	var (
+		statements []string
+		vars       [][]interface{}
+		record     = func(tx *gorm.DB) {
+			statements = append(statements, tx.Statement.SQL.String())
+			vars = append(vars, tx.Statement.Vars)
+		}
+		stub = func(tx *gorm.DB) {
+			switch dest := tx.Statement.Dest.(type) {
+			case *int64:
+				*dest, tx.RowsAffected = count, 1
+			case *[]*Reply:
+				*dest = append(*dest, &Reply{ID: 1, ThreadID: 1}, &Reply{ID: 2, ThreadID: 1})
+			}
+			record(tx)
+		}
+		callbacks = db.Callback()
+		errs      = []error{
+			callbacks.Query().After("gorm:query").Register("test:record_sql", stub),
+			callbacks.Update().After("gorm:update").Register("test:record_sql", record),
+			callbacks.Delete().After("gorm:delete").Register("test:record_sql", record),
+		}
+	)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to register callback: %v", err)
+		}
+	}
+
+	return &statements, &vars
+}
+
+func TestListAssociation(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []AssociationOption
+		wantCount string
+		wantPage  string
+	}{
+		{
+			name:      "skips trashed children",
+			wantCount: "SELECT count(*) FROM `replies` WHERE `replies`.`thread_id` = ? AND `replies`.`deleted_at` IS NULL",
+			wantPage:  "SELECT * FROM `replies` WHERE `replies`.`thread_id` = ? AND `replies`.`deleted_at` IS NULL LIMIT ? OFFSET ?",
+		},
+		{
+			name:      "with trashed",
+			opts:      []AssociationOption{WithTrashed()},
+			wantCount: "SELECT count(*) FROM `replies` WHERE `replies`.`thread_id` = ?",
+			wantPage:  "SELECT * FROM `replies` WHERE `replies`.`thread_id` = ? LIMIT ? OFFSET ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				db               = setupDryRunDB(t)
+				statements, vars = recordStatements(t, db, 25)
+				repo             = NewBaseGorm[Thread, uint](db)
+				replies          []*Reply
+			)
+
+			paginator, err := repo.ListAssociation(context.Background(), &Thread{ID: 1}, "Replies", &replies, 3, 10, tt.opts...)
+			if err != nil {
+				t.Fatalf("Failed to list the replies: %v", err)
+			}
+			if paginator.Total != 25 || paginator.Page != 3 || paginator.PerPage != 10 {
+				t.Errorf("Unexpected paginator %+v", paginator)
+			}
+
+			want := []string{tt.wantCount, tt.wantPage}
+			if len(*statements) != len(want) {
+				t.Fatalf("Expected %d statements, got %q", len(want), *statements)
+			}
+			for i := range want {
+				if (*statements)[i] != want[i] {
+					t.Errorf("Statement %d: expected\n%s\ngot\n%s", i, want[i], (*statements)[i])
+				}
+			}
+			if page := (*vars)[1]; len(page) != 3 || page[1] != 10 || page[2] != 20 {
+				t.Errorf("Expected the page to be limited to 10 rows from offset 20, got vars %v", page)
+			}
+		})
+	}
+}
+
+func TestListAssociationWithoutChildren(t *testing.T) {
+	var (
+		db            = setupDryRunDB(t)
+		statements, _ = recordStatements(t, db, 0)
+		repo          = NewBaseGorm[Thread, uint](db)
+		replies       []*Reply
+	)
+
+	if _, err := repo.ListAssociation(context.Background(), &Thread{ID: 1}, "Replies", &replies, 1, 10); err != nil {
+		t.Fatalf("Failed to list the replies: %v", err)
+	}
+	if len(*statements) != 1 {
+		t.Errorf("Expected only the count without children, got %q", *statements)
+	}
+}
+
+func TestClearAssociation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []RepoOption
+		want []string
+	}{
+		{
+			name: "unlinks the children by default",
+			want: []string{
+				"UPDATE `replies` SET `thread_id`=? WHERE `replies`.`thread_id` = ? AND `replies`.`deleted_at` IS NULL",
+			},
+		},
+		{
+			name: "soft deletes the children with WithSoftDeleteAssociations",
+			opts: []RepoOption{WithSoftDeleteAssociations()},
+			want: []string{
+				"SELECT * FROM `replies` WHERE `replies`.`thread_id` = ? AND `replies`.`deleted_at` IS NULL",
+				"UPDATE `replies` SET `deleted_at`=? WHERE `replies`.`id` IN (?,?) AND `replies`.`deleted_at` IS NULL",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				db            = setupDryRunDB(t)
+				statements, _ = recordStatements(t, db, 0)
+				repo          = NewBaseGorm[Thread, uint](db, tt.opts...)
+			)
+
+			if err := repo.ClearAssociation(context.Background(), &Thread{ID: 1}, "Replies"); err != nil {
+				t.Fatalf("Failed to clear the replies: %v", err)
+			}
+
+			if len(*statements) != len(tt.want) {
+				t.Fatalf("Expected %d statements, got %q", len(tt.want), *statements)
+			}
+			for i := range tt.want {
+				if (*statements)[i] != tt.want[i] {
+					t.Errorf("Statement %d: expected\n%s\ngot\n%s", i, tt.want[i], (*statements)[i])
+				}
+			}
+		})
+	}
+}
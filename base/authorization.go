@@ -0,0 +1,166 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrForbidden is returned when the Policy of a repository denies the actor of the
+// context access to a row.
+var ErrForbidden = errors.New("forbidden")
+
+// Actor is the user or service the repositories act for, see ContextWithActor.
+type Actor struct {
+	ID    string
+	Roles []string
+}
+
+// HasRole reports whether the actor has role.
+func (a Actor) HasRole(role string) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+type actorCtxKey struct{}
+
+// ContextWithActor sets the actor the policies of the repositories check.
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor.
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorCtxKey{}).(Actor)
+	return actor, ok
+}
+
+// Action is what an actor is about to do with a row.
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Policy decides whether actor, nil without actor in the context, may perform action
+// on row, a pointer to the stored row. A non nil error, usually ErrForbidden, denies it.
+type Policy func(ctx context.Context, actor *Actor, action Action, row interface{}) error
+
+// WithPolicy checks policy before Detail returns a row and before Update,
+// UpdateWhere, Increment, DeleteWhere, SoftDelete, ForceDelete and Restore change
+// rows, every matching row for the bulk ones. Wheres, WheresList and the List
+// methods are not checked, filter them by owner instead.
+func WithPolicy(policy Policy) RepoOption {
+	return func(o *repoOptions) {
+		o.policy = policy
+	}
+}
+
+// ownerSchemas caches the schemas OwnerPolicy parses.
+var ownerSchemas sync.Map
+
+// OwnerPolicy allows the actors whose ID is the value of column in the row, and the
+// actors having one of bypassRoles for every row. Rows are denied without actor.
+func OwnerPolicy(column string, bypassRoles ...string) Policy {
+	return func(ctx context.Context, actor *Actor, action Action, row interface{}) error {
+		if actor == nil {
+			return fmt.Errorf("%w: %s without actor", ErrForbidden, action)
+		}
+		for _, role := range bypassRoles {
+			if actor.HasRole(role) {
+				return nil
+			}
+		}
+
+		sch, err := schema.Parse(row, &ownerSchemas, schema.NamingStrategy{})
+		if err != nil {
+			return err
+		}
+		field := sch.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("owner column %s not found in %s", column, sch.Table)
+		}
+
+		owner, _ := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(row)))
+		if fmt.Sprint(owner) != actor.ID {
+			return fmt.Errorf("%w: %s %s of %v", ErrForbidden, action, sch.Table, owner)
+		}
+
+		return nil
+	}
+}
+
+// authorize checks the policy of the repository for row.
+func (o *BaseGorm[T, PkType]) authorize(ctx context.Context, action Action, row *T) error {
+	if o.opts.policy == nil {
+		return nil
+	}
+
+	var actor *Actor
+	if a, ok := ActorFromContext(ctx); ok {
+		actor = &a
+	}
+
+	return o.opts.policy(ctx, actor, action, row)
+}
+
+// authorizeID checks the policy of the repository for the stored row id. A missing
+// row is allowed, the operation then changing nothing.
+func (o *BaseGorm[T, PkType]) authorizeID(ctx context.Context, action Action, id PkType, unscoped bool) error {
+	var e T
+
+	return o.authorizeWheres(ctx, action, scoped(o.conn(ctx), unscoped), []Where{{Name: e.PrimaryKey(), Value: id}})
+}
+
+// authorizeStored checks the policy of the repository for the stored version of row.
+func (o *BaseGorm[T, PkType]) authorizeStored(ctx context.Context, action Action, row *T) error {
+	if o.opts.policy == nil {
+		return nil
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return err
+	}
+	pk, err := o.primaryKeyOf(ctx, sch, row)
+	if err != nil {
+		return err
+	}
+
+	return o.authorizeID(ctx, action, pk, false)
+}
+
+// authorizeWheres checks the policy of the repository for every row of db matching wheres.
+func (o *BaseGorm[T, PkType]) authorizeWheres(ctx context.Context, action Action, db *gorm.DB, wheres []Where) error {
+	var (
+		e    T
+		rows []T
+	)
+
+	if o.opts.policy == nil {
+		return nil
+	}
+
+	if err := bulkWheres(db.Table(e.TableName()), wheres).Find(&rows).Error; err != nil {
+		return err
+	}
+	for i := range rows {
+		if err := o.authorize(ctx, action, &rows[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
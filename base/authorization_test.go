@@ -0,0 +1,95 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestOwnerPolicy(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		policy = OwnerPolicy("user_id", "admin")
+		post   = &Post{ID: 1, UserID: 7}
+	)
+
+	tests := []struct {
+		name    string
+		actor   *Actor
+		allowed bool
+	}{
+		{name: "owner", actor: &Actor{ID: "7"}, allowed: true},
+		{name: "other actor", actor: &Actor{ID: "8"}, allowed: false},
+		{name: "bypass role", actor: &Actor{ID: "8", Roles: []string{"admin"}}, allowed: true},
+		{name: "no actor", actor: nil, allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy(ctx, tt.actor, ActionUpdate, post)
+			if tt.allowed && err != nil {
+				t.Errorf("Expected access to be allowed, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrForbidden) {
+				t.Errorf("Expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDetailPolicy(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[Post, uint](setupDryRunDB(t), WithPolicy(OwnerPolicy("user_id", "admin")))
+	)
+
+	// dry run finds a zero row, owned by user 0
+	if _, err := repo.Detail(ContextWithActor(ctx, Actor{ID: "0"}), 1); err != nil {
+		t.Errorf("Expected the owner to read the row, got %v", err)
+	}
+
+	row, err := repo.Detail(ContextWithActor(ctx, Actor{ID: "7"}), 1)
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, got %v", err)
+	}
+	if row != nil {
+		t.Errorf("Expected no row to be returned, got %+v", row)
+	}
+
+	if _, err := repo.Detail(ContextWithActor(ctx, Actor{ID: "7", Roles: []string{"admin"}}), 1); err != nil {
+		t.Errorf("Expected an admin to read the row, got %v", err)
+	}
+}
+
+func TestIncrementPolicy(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Post, uint](db, WithPolicy(OwnerPolicy("user_id")))
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:stored_post", func(tx *gorm.DB) {
+		if rows, ok := tx.Statement.Dest.(*[]Post); ok {
+			*rows = []Post{{ID: 1, UserID: 7}}
+		}
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	if _, err := repo.Increment(ContextWithActor(ctx, Actor{ID: "8"}), 1, "user_id", 1); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected ErrForbidden, got %v", err)
+	}
+	if strings.HasPrefix(*sql, "UPDATE") {
+		t.Errorf("Expected no UPDATE, got %s", *sql)
+	}
+
+	if _, err := repo.Increment(ContextWithActor(ctx, Actor{ID: "7"}), 1, "user_id", 1); err != nil {
+		t.Errorf("Expected the owner to increment the row, got %v", err)
+	}
+	if !strings.HasPrefix(*sql, "UPDATE") {
+		t.Errorf("Expected an UPDATE, got %s", *sql)
+	}
+}
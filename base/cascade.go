@@ -0,0 +1,170 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// CascadeAction is what happens to the children of a deleted row.
+type CascadeAction int
+
+const (
+	CascadeDelete   CascadeAction = iota + 1 // delete the children, applying their own cascade rules first
+	CascadeNullify                           // set the foreign key of the children to NULL
+	CascadeRestrict                          // refuse to delete while children exist
+)
+
+// CascadeRule declares the action applied to the has one/has many association
+// Association (the struct field name, e.g. "Posts") when its owner is deleted.
+type CascadeRule struct {
+	Association string
+	Action      CascadeAction
+}
+
+// CascadeDeleter is implemented by models declaring cascade rules, which Delete
+// enforces inside one transaction, deepest children first.
+type CascadeDeleter interface {
+	CascadeRules() []CascadeRule
+}
+
+// ErrDeleteRestricted is returned when a CascadeRestrict rule finds children.
+var ErrDeleteRestricted = errors.New("delete restricted by dependent rows")
+
+// Delete deletes the row with the given primary key, a soft delete when T has a
+// gorm.DeletedAt field. Cascade rules declared by T are applied in the same transaction.
+func (o *BaseGorm[T, PkType]) Delete(ctx context.Context, id PkType) (int64, error) {
+	return o.deleteByID(ctx, id, false)
+}
+
+// deleteByID deletes the row with the given primary key and applies the cascade
+// rules, permanently when unscoped is set.
+func (o *BaseGorm[T, PkType]) deleteByID(ctx context.Context, id PkType, unscoped bool) (int64, error) {
+	var (
+		e            T
+		rowsAffected int64
+		err          error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	o.forgetIdentities(ctx)
+
+	if err = o.authorizeID(ctx, ActionDelete, id, unscoped); err != nil {
+		return 0, err
+	}
+
+	// like cascadeDelete, so CascadeRules may have a pointer receiver
+	if _, ok := interface{}(&e).(CascadeDeleter); !ok {
+		result := scoped(o.conn(ctx), unscoped).
+			Where(fmt.Sprintf("%s = ?", e.PrimaryKey()), id).
+			Delete(&e)
+		err = result.Error
+
+		return result.RowsAffected, err
+	}
+
+	err = o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []*T
+		if err := scoped(tx, unscoped).Where(fmt.Sprintf("%s = ?", e.PrimaryKey()), id).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		result := cascadeDelete(ctx, tx, reflect.ValueOf(rows), unscoped)
+		rowsAffected = result.RowsAffected
+
+		return result.Error
+	})
+
+	return rowsAffected, err
+}
+
+// scoped returns db, including soft deleted rows and deleting permanently when unscoped is set.
+func scoped(db *gorm.DB, unscoped bool) *gorm.DB {
+	if unscoped {
+		return db.Unscoped()
+	}
+
+	return db
+}
+
+// cascadeDelete applies the cascade rules of the model held by rows, a slice of
+// pointers to models, then deletes rows, permanently when unscoped is set.
+func cascadeDelete(ctx context.Context, tx *gorm.DB, rows reflect.Value, unscoped bool) *gorm.DB {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(rows.Interface()); err != nil {
+		tx.AddError(err)
+		return tx
+	}
+
+	if deleter, ok := reflect.New(stmt.Schema.ModelType).Interface().(CascadeDeleter); ok {
+		for _, rule := range deleter.CascadeRules() {
+			if err := applyCascadeRule(ctx, tx, stmt.Schema, rows, rule, unscoped); err != nil {
+				tx.AddError(err)
+				return tx
+			}
+		}
+	}
+
+	return scoped(tx.Session(&gorm.Session{NewDB: true}), unscoped).Delete(rows.Interface())
+}
+
+func applyCascadeRule(ctx context.Context, tx *gorm.DB, sch *schema.Schema, rows reflect.Value, rule CascadeRule, unscoped bool) error {
+	relationship, ok := sch.Relationships.Relations[rule.Association]
+	if !ok || (relationship.Type != schema.HasOne && relationship.Type != schema.HasMany) {
+		return fmt.Errorf("cascade rule of %s: %s is not a has one/has many association", sch.Table, rule.Association)
+	}
+
+	var (
+		// through the child model, so its soft deleted rows are left out
+		childDB = scoped(tx.Session(&gorm.Session{NewDB: true}), unscoped).
+			Model(reflect.New(relationship.FieldSchema.ModelType).Interface()).
+			Clauses(clause.Where{Exprs: relationship.ToQueryConditions(ctx, rows)})
+		children = reflect.New(reflect.SliceOf(reflect.PointerTo(relationship.FieldSchema.ModelType)))
+	)
+
+	switch rule.Action {
+	case CascadeRestrict:
+		var count int64
+		if err := childDB.Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %d %s of %s", ErrDeleteRestricted, count, relationship.FieldSchema.Table, sch.Table)
+		}
+	case CascadeNullify:
+		values := map[string]interface{}{}
+		for _, reference := range relationship.References {
+			if reference.OwnPrimaryKey {
+				values[reference.ForeignKey.DBName] = nil
+			}
+		}
+		return childDB.Updates(values).Error
+	case CascadeDelete:
+		if err := childDB.Find(children.Interface()).Error; err != nil {
+			return err
+		}
+		if children.Elem().Len() > 0 {
+			return cascadeDelete(ctx, tx, children.Elem(), unscoped).Error
+		}
+	default:
+		return fmt.Errorf("cascade rule of %s: unknown action %d for %s", sch.Table, rule.Action, rule.Association)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type Shelf struct {
+	ID    uint `gorm:"column:id;primaryKey"`
+	Books []Book
+}
+
+func (Shelf) TableName() string {
+	return "shelves"
+}
+
+func (Shelf) PrimaryKey() string {
+	return "id"
+}
+
+func (*Shelf) CascadeRules() []CascadeRule {
+	return []CascadeRule{{Association: "Books", Action: CascadeRestrict}}
+}
+
+type Book struct {
+	ID        uint `gorm:"column:id;primaryKey"`
+	ShelfID   uint `gorm:"column:shelf_id"`
+	DeletedAt gorm.DeletedAt
+}
+
+func (Book) TableName() string {
+	return "books"
+}
+
+func (Book) PrimaryKey() string {
+	return "id"
+}
+
+func openRecordingDB(t *testing.T) (*gorm.DB, *recordingConnector) {
+	t.Helper()
+
+	connector := &recordingConnector{}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(connector),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{SkipDefaultTransaction: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	return db, connector
+}
+
+func TestDeleteCascadeRulesPointerReceiver(t *testing.T) {
+	db, connector := openRecordingDB(t)
+	repo := NewBaseGorm[Shelf, uint](db)
+
+	// the shelf is not found, so only the find runs in the transaction
+	if _, err := repo.Delete(context.Background(), 1); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	want := []string{"BEGIN", "SELECT * FROM `shelves` WHERE id = ?", "COMMIT"}
+	if !reflect.DeepEqual(connector.statements, want) {
+		t.Errorf("Expected statements\n%q\ngot\n%q", want, connector.statements)
+	}
+}
+
+func TestCascadeRestrictSkipsSoftDeletedChildren(t *testing.T) {
+	var (
+		ctx           = context.Background()
+		db, connector = openRecordingDB(t)
+		shelves       = []*Shelf{{ID: 1}}
+		stmt          = &gorm.Statement{DB: db}
+	)
+	if err := stmt.Parse(&Shelf{}); err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	err := applyCascadeRule(ctx, db, stmt.Schema, reflect.ValueOf(shelves), CascadeRule{Association: "Books", Action: CascadeRestrict}, false)
+	if err != nil {
+		t.Fatalf("Expected no restriction without children, got %v", err)
+	}
+	if len(connector.statements) != 1 || !strings.Contains(connector.statements[0], "`books`.`deleted_at` IS NULL") {
+		t.Errorf("Expected the count to skip the soft deleted books, got %q", connector.statements)
+	}
+
+	connector.statements = nil
+	if err := applyCascadeRule(ctx, db, stmt.Schema, reflect.ValueOf(shelves), CascadeRule{Association: "Books", Action: CascadeRestrict}, true); err != nil {
+		t.Fatalf("Expected no restriction without children, got %v", err)
+	}
+	if len(connector.statements) != 1 || strings.Contains(connector.statements[0], "deleted_at") {
+		t.Errorf("Expected the permanent delete to count every book, got %q", connector.statements)
+	}
+}
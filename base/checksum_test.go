@@ -0,0 +1,41 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	var (
+		alice = []sql.NullString{{String: "1", Valid: true}, {String: "Alice", Valid: true}}
+		bob   = []sql.NullString{{String: "2", Valid: true}, {String: "Bob", Valid: true}}
+		empty = []sql.NullString{{String: "3", Valid: true}, {String: "", Valid: true}}
+		null  = []sql.NullString{{String: "3", Valid: true}, {}}
+		sum   = func(rows ...[]sql.NullString) string {
+			var c checksum
+			for _, row := range rows {
+				c.add(row)
+			}
+			return c.String()
+		}
+	)
+
+	if sum(alice, bob) != sum(bob, alice) {
+		t.Error("Expected the checksum not to depend on the row order")
+	}
+	if sum(alice, alice) == sum() {
+		t.Error("Expected a row stored twice not to cancel out")
+	}
+	if sum(empty) == sum(null) {
+		t.Error("Expected NULL to differ from an empty string")
+	}
+}
+
+func TestTableChecksumUnknownColumn(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+
+	if _, err := repo.TableChecksum(context.Background(), nil, []string{"missing"}); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
@@ -0,0 +1,135 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// CleanupScope restricts the rows CleanupTables deletes from table, e.g. to one tenant.
+type CleanupScope = func(db *gorm.DB, table *schema.Schema) *gorm.DB
+
+// DependencyOrder returns the schemas of models, plus the join tables of their many
+// to many associations, ordered so that every table comes before the tables it
+// references through a foreign key. Deleting in this order never violates a foreign key.
+// The associations with a table not among them are ignored.
+func DependencyOrder(db *gorm.DB, models ...interface{}) ([]*schema.Schema, error) {
+	var (
+		tables     []*schema.Schema
+		byTable    = map[string]*schema.Schema{}
+		references = map[string]map[string]bool{} // table => tables it references
+	)
+
+	addTable := func(sch *schema.Schema) {
+		if _, ok := byTable[sch.Table]; !ok {
+			byTable[sch.Table] = sch
+			references[sch.Table] = map[string]bool{}
+			tables = append(tables, sch)
+		}
+	}
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		addTable(stmt.Schema)
+	}
+
+	for _, sch := range append([]*schema.Schema(nil), tables...) {
+		for _, relationship := range sch.Relationships.Relations {
+			switch {
+			case relationship.JoinTable != nil:
+				addTable(relationship.JoinTable)
+				references[relationship.JoinTable.Table][sch.Table] = true
+				references[relationship.JoinTable.Table][relationship.FieldSchema.Table] = true
+			case relationship.Type == schema.BelongsTo:
+				references[sch.Table][relationship.FieldSchema.Table] = true
+			case relationship.Type == schema.HasOne || relationship.Type == schema.HasMany:
+				// a child table not among models is not ordered
+				if childReferences, ok := references[relationship.FieldSchema.Table]; ok {
+					childReferences[sch.Table] = true
+				}
+			}
+		}
+	}
+
+	// Kahn's algorithm on "is referenced by" edges, keeping the input order for ties
+	var (
+		ordered      = make([]*schema.Schema, 0, len(tables))
+		done         = map[string]bool{}
+		referencedBy = func(table string) bool {
+			for _, other := range tables {
+				if !done[other.Table] && other.Table != table && references[other.Table][table] {
+					return true
+				}
+			}
+			return false
+		}
+	)
+
+	for len(ordered) < len(tables) {
+		progressed := false
+		for _, sch := range tables {
+			if !done[sch.Table] && !referencedBy(sch.Table) {
+				done[sch.Table] = true
+				ordered = append(ordered, sch)
+				progressed = true
+			}
+		}
+
+		if !progressed {
+			var cycle []string
+			for _, sch := range tables {
+				if !done[sch.Table] {
+					cycle = append(cycle, sch.Table)
+				}
+			}
+			return nil, fmt.Errorf("foreign key cycle between tables %v", cycle)
+		}
+	}
+
+	return ordered, nil
+}
+
+// CleanupTables deletes the rows of models and of their join tables in one transaction,
+// children first, so foreign key checks stay enabled. Soft deleted rows are removed too.
+// A nil scope deletes every row, otherwise scope selects the rows to delete per table.
+func CleanupTables(ctx context.Context, db *gorm.DB, scope CleanupScope, models ...interface{}) error {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx)
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	tables, err := DependencyOrder(db, models...)
+	if err != nil {
+		return err
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			tableDB := tx.Session(&gorm.Session{NewDB: true, AllowGlobalUpdate: true}).Unscoped().Table(table.Table)
+			if scope != nil {
+				tableDB = scope(tableDB, table)
+			}
+
+			if err := tableDB.Delete(reflect.New(table.ModelType).Interface()).Error; err != nil {
+				return fmt.Errorf("cleanup %s: %w", table.Table, err)
+			}
+		}
+
+		return nil
+	})
+
+	return err
+}
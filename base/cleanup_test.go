@@ -0,0 +1,35 @@
+package base
+
+import (
+	"testing"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	tables, err := DependencyOrder(setupDryRunDB(t), &User{}, &Profile{}, &Post{})
+	if err != nil {
+		t.Fatalf("Failed to compute dependency order: %v", err)
+	}
+
+	position := map[string]int{}
+	for i, table := range tables {
+		position[table.Table] = i
+	}
+
+	if len(tables) != 3 {
+		t.Fatalf("Expected 3 tables, got %d", len(tables))
+	}
+	if position["dummy_users"] < position["dummy_profiles"] || position["dummy_users"] < position["dummy_posts"] {
+		t.Errorf("Expected dummy_users to be deleted after its children, got %v", position)
+	}
+}
+
+func TestDependencyOrderIgnoresTablesNotListed(t *testing.T) {
+	tables, err := DependencyOrder(setupDryRunDB(t), &User{})
+	if err != nil {
+		t.Fatalf("Failed to compute dependency order: %v", err)
+	}
+
+	if len(tables) != 1 || tables[0].Table != "dummy_users" {
+		t.Errorf("Expected only dummy_users, got %v", tables)
+	}
+}
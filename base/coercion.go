@@ -0,0 +1,142 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidValue is wrapped by the *CoercionError of CoerceWheres.
+var ErrInvalidValue = errors.New("invalid value")
+
+// CoercionError is returned by CoerceWheres for a string value which is not one of
+// the type of its column.
+type CoercionError struct {
+	Column string
+	Value  string
+	Type   schema.DataType
+}
+
+func (e *CoercionError) Error() string {
+	return fmt.Sprintf("%s %q for %s column %s", ErrInvalidValue, e.Value, e.Type, e.Column)
+}
+
+func (e *CoercionError) Unwrap() error {
+	return ErrInvalidValue
+}
+
+// CoerceWheres converts the string values of wheres, e.g. decoded from a query
+// string or JSON, to the type of their column in T : "123" to an int64, "true" to a
+// bool, an RFC 3339 timestamp or a 2006-01-02 date to a time.Time, so they compare
+// as numbers, booleans and times rather than as strings. The slices of IN and
+// BETWEEN are converted element by element. LIKE and full text values, the columns
+// of other tables and the other values are kept. The given slice is not modified.
+func (o *BaseGorm[T, PkType]) CoerceWheres(wheres []Where) ([]Where, error) {
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	return coerceWheres(sch, wheres)
+}
+
+func coerceWheres(sch *schema.Schema, wheres []Where) ([]Where, error) {
+	coerced := make([]Where, len(wheres))
+	for i, v := range wheres {
+		if v.Group != nil {
+			nested, err := coerceWheres(sch, v.Group.Wheres)
+			if err != nil {
+				return nil, err
+			}
+			v.Group = &WhereGroup{Or: v.Group.Or, Wheres: nested}
+			coerced[i] = v
+			continue
+		}
+
+		coerced[i] = v
+		if v.IsLike || v.IsFullTextSearch || v.Operator == OpIsNull || v.Operator == OpIsNotNull {
+			continue
+		}
+
+		column, ok := columnOf(sch.Table, v.Name)
+		if !ok {
+			continue
+		}
+		field := sch.LookUpField(column)
+		if field == nil {
+			continue
+		}
+
+		value, err := coerceValue(field, v.Value)
+		if err != nil {
+			return nil, err
+		}
+		coerced[i].Value = value
+	}
+
+	return coerced, nil
+}
+
+// coerceValue converts value, a string or a slice of strings, to the type of field.
+func coerceValue(field *schema.Field, value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return coerceString(field, s)
+	}
+
+	values := reflect.ValueOf(value)
+	if values.Kind() != reflect.Slice || values.Type().Elem().Kind() == reflect.Uint8 {
+		return value, nil
+	}
+
+	coerced := make([]interface{}, values.Len())
+	for i := range coerced {
+		element := values.Index(i).Interface()
+		s, ok := element.(string)
+		if !ok {
+			coerced[i] = element
+			continue
+		}
+
+		var err error
+		if coerced[i], err = coerceString(field, s); err != nil {
+			return nil, err
+		}
+	}
+
+	return coerced, nil
+}
+
+func coerceString(field *schema.Field, s string) (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+		text  = strings.TrimSpace(s)
+	)
+
+	switch field.DataType {
+	case schema.Bool:
+		value, err = strconv.ParseBool(text)
+	case schema.Int:
+		value, err = strconv.ParseInt(text, 10, 64)
+	case schema.Uint:
+		value, err = strconv.ParseUint(text, 10, 64)
+	case schema.Float:
+		value, err = strconv.ParseFloat(text, 64)
+	case schema.Time:
+		if value, err = time.Parse(time.RFC3339Nano, text); err != nil {
+			value, err = time.Parse(time.DateOnly, text)
+		}
+	default:
+		return s, nil
+	}
+	if err != nil {
+		return nil, &CoercionError{Column: field.DBName, Value: s, Type: field.DataType}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,95 @@
+package base
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Subscription struct {
+	ID        uint      `gorm:"column:id;primaryKey" json:"id"`
+	Plan      string    `gorm:"column:plan" json:"plan"`
+	Seats     int       `gorm:"column:seats" json:"seats"`
+	Price     float64   `gorm:"column:price" json:"price"`
+	Active    bool      `gorm:"column:active" json:"active"`
+	RenewedAt time.Time `gorm:"column:renewed_at" json:"renewedAt"`
+}
+
+func (Subscription) TableName() string {
+	return "subscriptions"
+}
+
+func (Subscription) PrimaryKey() string {
+	return "id"
+}
+
+func TestCoerceWheres(t *testing.T) {
+	repo := NewBaseGorm[Subscription, uint](setupDryRunDB(t))
+
+	wheres := []Where{
+		{Name: "seats", Operator: OpGte, Value: "10"},
+		{Name: "subscriptions.active", Value: "true"},
+		{Name: "plan", Value: "42"},
+		{Name: "plan", IsLike: true, Value: "7"},
+		{Group: &WhereGroup{Or: true, Wheres: []Where{
+			{Name: "id", Operator: OpIn, Value: []string{"1", "2"}},
+			{Name: "price", Operator: OpBetween, Value: []interface{}{"9.5", 20}},
+		}}},
+		{Name: "renewed_at", Operator: OpLt, Value: "2024-03-01T10:00:00Z"},
+		{Name: "renewed_at", Operator: OpGte, Value: "2024-01-01"},
+		{Name: "owners.seats", Value: "x"},
+	}
+
+	coerced, err := repo.CoerceWheres(wheres)
+	if err != nil {
+		t.Fatalf("Failed to coerce wheres: %v", err)
+	}
+
+	want := map[int]interface{}{
+		0: int64(10),
+		1: true,
+		2: "42",
+		3: "7",
+		5: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		6: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		7: "x",
+	}
+	for i, value := range want {
+		if !reflect.DeepEqual(coerced[i].Value, value) {
+			t.Errorf("Where %d: expected %#v, got %#v", i, value, coerced[i].Value)
+		}
+	}
+	group := coerced[4].Group.Wheres
+	if !reflect.DeepEqual(group[0].Value, []interface{}{uint64(1), uint64(2)}) {
+		t.Errorf("Expected the IN values to be coerced, got %#v", group[0].Value)
+	}
+	if !reflect.DeepEqual(group[1].Value, []interface{}{9.5, 20}) {
+		t.Errorf("Expected the BETWEEN bounds to be coerced, got %#v", group[1].Value)
+	}
+	if wheres[0].Value != "10" || !reflect.DeepEqual(wheres[4].Group.Wheres[0].Value, []string{"1", "2"}) {
+		t.Error("Expected the given wheres to be left untouched")
+	}
+
+	_, err = repo.CoerceWheres([]Where{{Name: "seats", Value: "ten"}})
+	var coercionError *CoercionError
+	if !errors.Is(err, ErrInvalidValue) || !errors.As(err, &coercionError) || coercionError.Column != "seats" {
+		t.Errorf("Expected a CoercionError on seats, got %v", err)
+	}
+}
+
+func TestMapJSONNamesCoercesValues(t *testing.T) {
+	repo := NewBaseGorm[Subscription, uint](setupDryRunDB(t))
+
+	wheres, _, err := repo.MapJSONNames([]Where{{Name: "renewedAt", Operator: OpGt, Value: "2024-01-01"}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to map JSON names: %v", err)
+	}
+	if wheres[0].Name != "renewed_at" || !reflect.DeepEqual(wheres[0].Value, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected a time on renewed_at, got %+v", wheres[0])
+	}
+
+	if _, _, err := repo.MapJSONNames([]Where{{Name: "active", Value: "maybe"}}, nil); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrUnknownColumn is returned with WithColumnAllowList for a Where or an OrderBy
+// naming a column which is not allowed.
+var ErrUnknownColumn = errors.New("unknown column")
+
+// WithColumnAllowList makes the reads, UpdateWhere and DeleteWhere fail with
+// ErrUnknownColumn when a Where name, an OrderBy field or a WithSelect or
+// WithDistinct column, which are written in the SQL as is, is not a column of T,
+// qualified by its table or not, nor one of extra, e.g. the columns of the tables
+// joined by a ListCustom callback.
+// Unknown filters are rejected rather than dropped, which would widen the query.
+func WithColumnAllowList(extra ...string) RepoOption {
+	return func(o *repoOptions) {
+		if o.allowedColumns == nil {
+			o.allowedColumns = map[string]bool{}
+		}
+		for _, column := range extra {
+			o.allowedColumns[column] = true
+		}
+	}
+}
+
+// checkColumns checks the columns of wheres and orders, and the WithSelect and
+// WithDistinct columns of opts, against WithColumnAllowList.
+func (o *BaseGorm[T, PkType]) checkColumns(wheres []Where, orders []OrderBy, opts ...QueryOption) error {
+	if o.opts.allowedColumns == nil {
+		return nil
+	}
+
+	for _, v := range wheres {
+		if err := o.checkWhere(v); err != nil {
+			return err
+		}
+	}
+	for _, order := range orders {
+		if order.String() == "" {
+			continue
+		}
+		if err := o.checkColumn(order.Field); err != nil {
+			return err
+		}
+	}
+
+	options := newQueryOptions(opts)
+	for _, column := range append(append([]string(nil), options.selects...), options.distinctOn...) {
+		if column == "*" || strings.HasSuffix(column, ".*") {
+			continue
+		}
+		if err := o.checkColumn(column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *BaseGorm[T, PkType]) checkWhere(v Where) error {
+	if v.Group != nil {
+		for _, nested := range v.Group.Wheres {
+			if err := o.checkWhere(nested); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if v.IsFullTextSearch {
+		// MATCH(title, body)
+		for _, column := range strings.Split(v.Name, ",") {
+			if err := o.checkColumn(strings.TrimSpace(column)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return o.checkColumn(v.Name)
+}
+
+func (o *BaseGorm[T, PkType]) checkColumn(column string) error {
+	var e T
+
+	if o.opts.allowedColumns[column] {
+		return nil
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return err
+	}
+
+	name := strings.Trim(column, "`")
+	if table, unqualified, ok := strings.Cut(name, "."); ok {
+		if strings.Trim(table, "`") != e.TableName() {
+			return fmt.Errorf("%w %q in %s", ErrUnknownColumn, column, e.TableName())
+		}
+		name = strings.Trim(unqualified, "`")
+	}
+	if _, ok := sch.FieldsByDBName[name]; !ok {
+		return fmt.Errorf("%w %q in %s", ErrUnknownColumn, column, e.TableName())
+	}
+
+	return nil
+}
+
+// resolveColumn returns the column backed field of T named column, by its column or
+// Go field name, qualified by the table or not, backquoted or not, and whatever its
+// case as MySQL ignores it. gorm accepts all these forms, so the checks must see them
+// as the column they write.
+func (o *BaseGorm[T, PkType]) resolveColumn(column string) (*schema.Field, error) {
+	var e T
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.Trim(column, "`")
+	if table, unqualified, ok := strings.Cut(name, "."); ok {
+		if !strings.EqualFold(strings.Trim(table, "`"), e.TableName()) {
+			return nil, fmt.Errorf("%w %q in %s", ErrUnknownColumn, column, e.TableName())
+		}
+		name = strings.Trim(unqualified, "`")
+	}
+
+	if field := sch.LookUpField(name); field != nil && field.DBName != "" {
+		return field, nil
+	}
+	for _, field := range sch.Fields {
+		if field.DBName != "" && (strings.EqualFold(field.DBName, name) || strings.EqualFold(field.Name, name)) {
+			return field, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %q in %s", ErrUnknownColumn, column, e.TableName())
+}
+
+// ErrUnknownField is returned by MapJSONNames for a name which is not the JSON name
+// of a column backed field of T.
+var ErrUnknownField = errors.New("unknown field")
+
+// MapJSONNames translates the Where names and OrderBy fields of an API request, the
+// JSON names of T's fields (e.g. "userName"), to their columns (e.g. "user_name"),
+// failing with ErrUnknownField on any other name. The Where values are converted to
+// the type of their column by CoerceWheres. The given slices are not modified.
+func (o *BaseGorm[T, PkType]) MapJSONNames(wheres []Where, orders []OrderBy) ([]Where, []OrderBy, error) {
+	sch, err := o.schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := jsonFields(sch)
+
+	mappedWheres, err := mapWhereNames(fields, sch.Table, wheres)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mappedWheres, err = coerceWheres(sch, mappedWheres); err != nil {
+		return nil, nil, err
+	}
+
+	mappedOrders := make([]OrderBy, len(orders))
+	for i, order := range orders {
+		field, ok := fields[order.Field]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w %q in %s", ErrUnknownField, order.Field, sch.Table)
+		}
+		mappedOrders[i] = OrderBy{Field: field.DBName, Direction: order.Direction}
+	}
+
+	return mappedWheres, mappedOrders, nil
+}
+
+func mapWhereNames(fields map[string]*schema.Field, table string, wheres []Where) ([]Where, error) {
+	mapped := make([]Where, len(wheres))
+	for i, v := range wheres {
+		if v.Group != nil {
+			nested, err := mapWhereNames(fields, table, v.Group.Wheres)
+			if err != nil {
+				return nil, err
+			}
+			v.Group = &WhereGroup{Or: v.Group.Or, Wheres: nested}
+			mapped[i] = v
+			continue
+		}
+
+		names := []string{v.Name}
+		if v.IsFullTextSearch {
+			names = strings.Split(v.Name, ",")
+		}
+		for j, name := range names {
+			field, ok := fields[strings.TrimSpace(name)]
+			if !ok {
+				return nil, fmt.Errorf("%w %q in %s", ErrUnknownField, strings.TrimSpace(name), table)
+			}
+			names[j] = field.DBName
+		}
+		v.Name = strings.Join(names, ",")
+		mapped[i] = v
+	}
+
+	return mapped, nil
+}
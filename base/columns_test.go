@@ -0,0 +1,160 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColumnAllowList(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](setupDryRunDB(t), WithColumnAllowList("dummy_profiles.bio"))
+	)
+
+	tests := []struct {
+		name   string
+		wheres []Where
+		orders []OrderBy
+		valid  bool
+	}{
+		{name: "column", wheres: []Where{{Name: "email", Value: "alice@example.com"}}, orders: []OrderBy{{Field: "created_at", Direction: "desc"}}, valid: true},
+		{name: "qualified column", wheres: []Where{{Name: "dummy_users.name", Value: "Alice"}}, valid: true},
+		{name: "extra column", wheres: []Where{{Name: "dummy_profiles.bio", IsLike: true, Value: "go"}}, valid: true},
+		{name: "nested group", wheres: []Where{{Group: &WhereGroup{Or: true, Wheres: []Where{{Name: "name", Value: "Alice"}, {Name: "email", Value: "alice@example.com"}}}}}, valid: true},
+		{name: "unknown column", wheres: []Where{{Name: "password", Value: "secret"}}},
+		{name: "injection", wheres: []Where{{Name: "1 = 1 OR name", Value: "Alice"}}},
+		{name: "other table", wheres: []Where{{Name: "dummy_posts.title", Value: "Hello"}}},
+		{name: "unknown nested column", wheres: []Where{{Group: &WhereGroup{Wheres: []Where{{Name: "name", Value: "Alice"}, {Name: "role", Value: "admin"}}}}}},
+		{name: "unknown order", orders: []OrderBy{{Field: "(SELECT 1)", Direction: "asc"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := repo.List(ctx, 1, 10, tt.orders, tt.wheres)
+			if tt.valid && err != nil {
+				t.Errorf("Expected the columns to be allowed, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrUnknownColumn) {
+				t.Errorf("Expected ErrUnknownColumn, got %v", err)
+			}
+		})
+	}
+
+	if _, err := repo.DeleteWhere(ctx, []Where{{Name: "1 = 1 OR id", Value: 1}}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected DeleteWhere to reject an unknown column, got %v", err)
+	}
+}
+
+func TestPurgeExpiredChecksColumn(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+
+	statements, err := repo.SQLOf(context.Background(), func(repo *BaseGorm[User, uint]) error {
+		_, err := repo.PurgeExpired(context.Background(), "CreatedAt", time.Hour, 10)
+		return err
+	})
+	if err != nil || len(statements) != 1 || !strings.Contains(statements[0], "WHERE created_at < ") {
+		t.Errorf("Expected the field name resolved to its column, got %q, %v", statements, err)
+	}
+
+	if _, err := repo.PurgeExpired(context.Background(), "1 = 1 OR created_at", time.Hour, 10); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn, got %v", err)
+	}
+}
+
+func TestColumnAllowListReads(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		repo    = NewBaseGorm[User, uint](setupDryRunDB(t), WithColumnAllowList())
+		unknown = []Where{{Name: "1 = 1 OR id", Value: 1}}
+	)
+
+	tests := []struct {
+		name string
+		read func() error
+	}{
+		{name: "Exists", read: func() error { _, err := repo.Exists(ctx, unknown); return err }},
+		{name: "Count", read: func() error { _, err := repo.Count(ctx, unknown); return err }},
+		{name: "PreviewWhere", read: func() error { _, _, err := repo.PreviewWhere(ctx, unknown); return err }},
+		{name: "MaxBy", read: func() error { _, err := repo.MaxBy(ctx, "created_at", unknown); return err }},
+		{name: "MaxBy column", read: func() error { _, err := repo.MaxBy(ctx, "(SELECT 1)", nil); return err }},
+		{name: "MinBy", read: func() error { _, err := repo.MinBy(ctx, "created_at", unknown); return err }},
+		{name: "MinBy column", read: func() error { _, err := repo.MinBy(ctx, "(SELECT 1)", nil); return err }},
+		{name: "TableChecksum", read: func() error { _, err := repo.TableChecksum(ctx, unknown, nil); return err }},
+		{name: "SumDecimal", read: func() error { _, err := repo.SumDecimal(ctx, "id", unknown); return err }},
+		{name: "SumDecimal column", read: func() error { _, err := repo.SumDecimal(ctx, "(SELECT 1)", nil); return err }},
+		{name: "WithSelect", read: func() error {
+			_, err := repo.WheresList(ctx, nil, nil, WithSelect("id", "(SELECT password FROM admins)"))
+			return err
+		}},
+		{name: "WithDistinct", read: func() error {
+			_, _, err := repo.List(ctx, 1, 10, nil, nil, WithDistinct("(SELECT 1)"))
+			return err
+		}},
+		{name: "Count WithSelect", read: func() error { _, err := repo.Count(ctx, nil, WithSelect("(SELECT 1)")); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.read(); !errors.Is(err, ErrUnknownColumn) {
+				t.Errorf("Expected ErrUnknownColumn, got %v", err)
+			}
+		})
+	}
+
+	if _, err := repo.WheresList(ctx, nil, nil, WithSelect("id", "name"), WithDistinct("name")); err != nil {
+		t.Errorf("Expected the selected columns to be allowed, got %v", err)
+	}
+}
+
+type Member struct {
+	ID        uint   `gorm:"column:id;primaryKey" json:"id"`
+	UserName  string `gorm:"column:user_name" json:"userName"`
+	Biography string `gorm:"column:bio" json:"biography"`
+	Password  string `gorm:"column:password" json:"-"`
+}
+
+func (Member) TableName() string {
+	return "members"
+}
+
+func (Member) PrimaryKey() string {
+	return "id"
+}
+
+func TestMapJSONNames(t *testing.T) {
+	repo := NewBaseGorm[Member, uint](setupDryRunDB(t))
+
+	wheres := []Where{
+		{Name: "userName", Value: "alice"},
+		{Group: &WhereGroup{Or: true, Wheres: []Where{{Name: "id", Operator: OpGt, Value: 10}, {Name: "userName,biography", IsFullTextSearch: true, Value: "go"}}}},
+	}
+	mappedWheres, mappedOrders, err := repo.MapJSONNames(wheres, []OrderBy{{Field: "userName", Direction: "asc"}})
+	if err != nil {
+		t.Fatalf("Failed to map JSON names: %v", err)
+	}
+
+	if mappedWheres[0].Name != "user_name" {
+		t.Errorf("Expected userName to map to user_name, got %s", mappedWheres[0].Name)
+	}
+	if got := mappedWheres[1].Group.Wheres[1].Name; got != "user_name,bio" {
+		t.Errorf("Expected the full text columns to be mapped, got %s", got)
+	}
+	if mappedOrders[0].Field != "user_name" || mappedOrders[0].Direction != "asc" {
+		t.Errorf("Expected the order to be mapped, got %+v", mappedOrders[0])
+	}
+	if wheres[0].Name != "userName" || wheres[1].Group.Wheres[1].Name != "userName,biography" {
+		t.Error("Expected the given wheres to be left untouched")
+	}
+
+	for _, name := range []string{"user_name", "Password", "password", "1 = 1 OR id"} {
+		if _, _, err := repo.MapJSONNames([]Where{{Name: name, Value: "x"}}, nil); !errors.Is(err, ErrUnknownField) {
+			t.Errorf("Expected %q to fail with ErrUnknownField, got %v", name, err)
+		}
+	}
+	if _, _, err := repo.MapJSONNames(nil, []OrderBy{{Field: "perPage", Direction: "asc"}}); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("Expected an unknown sort key to fail with ErrUnknownField, got %v", err)
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 
 	generic_gorm "github.com/harryosmar/generic-gorm"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type TablerWithPrimaryKey interface {
@@ -15,10 +18,50 @@ type TablerWithPrimaryKey interface {
 	PrimaryKey() string
 }
 
+// Paginator describes the page returned by the List methods. The JSON names are
+// part of the API responses built from it and must not change.
 type Paginator struct {
-	Page    int
-	PerPage int
-	Total   int
+	Page       int  `json:"Page"`
+	PerPage    int  `json:"PerPage"`
+	Total      int  `json:"Total"`
+	TotalPages int  `json:"TotalPages"`
+	LastPage   int  `json:"LastPage"` // TotalPages, but 1 when there is no row
+	HasNext    bool `json:"HasNext"`
+	HasPrev    bool `json:"HasPrev"`
+	FirstItem  int  `json:"FirstItem"` // 1 based index of the first row of the page, 0 when the page is empty
+	LastItem   int  `json:"LastItem"`  // 1 based index of the last row of the page, 0 when the page is empty
+}
+
+// setPageWithoutTotal fills the navigation fields of a page of rows listed
+// WithoutTotal, leaving the fields depending on the total at -1.
+func (p *Paginator) setPageWithoutTotal(rows int, hasNext bool) {
+	p.Total, p.TotalPages, p.LastPage = -1, -1, -1
+	p.HasNext = hasNext
+	p.HasPrev = p.Page > 1
+	p.FirstItem, p.LastItem = 0, 0
+
+	if rows > 0 {
+		p.FirstItem = (p.Page-1)*p.PerPage + 1
+		p.LastItem = p.FirstItem + rows - 1
+	}
+}
+
+// SetTotal sets the total number of rows and computes the navigation fields from it.
+func (p *Paginator) SetTotal(total int) {
+	p.Total = total
+	p.TotalPages, p.FirstItem, p.LastItem = 0, 0, 0
+
+	if p.PerPage > 0 {
+		p.TotalPages = (total + p.PerPage - 1) / p.PerPage
+	}
+	p.LastPage = max(p.TotalPages, 1)
+	p.HasNext = p.Page < p.TotalPages
+	p.HasPrev = p.Page > 1
+
+	if p.Page >= 1 && p.Page <= p.TotalPages {
+		p.FirstItem = (p.Page-1)*p.PerPage + 1
+		p.LastItem = min(p.Page*p.PerPage, total)
+	}
 }
 
 type OrderBy struct {
@@ -35,132 +78,379 @@ func (o OrderBy) String() string {
 }
 
 type BaseGorm[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] struct {
-	db *gorm.DB
-}
+	db    *gorm.DB
+	opts  repoOptions
+	bound bool // db is a transaction set by WithTx, used whatever the context holds
+
+	// computed once for the point lookups
+	table       string              // T's table
+	pkCondition string              // "<primary key> = ?"
+	templates   *statementTemplates // see WithStatementTemplates
+	statements  *statementOptions   // see statementContext
 
-func NewBaseGorm[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](db *gorm.DB) *BaseGorm[T, PkType] {
-	return &BaseGorm[T, PkType]{db: db}
+	timeZoneCheck sync.Once
 }
 
-func (o *BaseGorm[T, PkType]) Detail(ctx context.Context, id PkType) (*T, error) {
-	var (
-		db       = o.db.WithContext(ctx)
-		row      T
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		err      error
-	)
+func NewBaseGorm[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](db *gorm.DB, opts ...RepoOption) *BaseGorm[T, PkType] {
+	var e T
 
-	defer func() {
-		if err != nil {
-			logEntry.Error(err)
+	repo := &BaseGorm[T, PkType]{
+		db:          db,
+		opts:        newRepoOptions(opts),
+		table:       e.TableName(),
+		pkCondition: e.PrimaryKey() + " = ?",
+	}
+	repo.statements = &statementOptions{
+		tracer:      repo.opts.tracer,
+		slowQueries: repo.opts.slowQueries,
+		debug:       repo.opts.debugLogging,
+		values:      repo.opts.loggedValues,
+	}
+	if repo.opts.timestamps != nil {
+		repo.db = db.Session(&gorm.Session{NowFunc: repo.opts.timestamps.now})
+	}
+	if repo.opts.statementTemplates {
+		repo.templates = repo.buildTemplates()
+	}
+	for _, db := range append([]*gorm.DB{db}, repo.opts.hedgedReads.databases()...) {
+		if err := checkStatementTimeouts(db, repo.opts.statementTimeouts); err != nil {
+			panic(err)
 		}
-	}()
+		if repo.opts.statementTimeouts != (StatementTimeouts{}) && db.Dialector.Name() == DialectPostgres {
+			if err := registerStatementTimeouts(db); err != nil {
+				panic(err)
+			}
+		}
+		if repo.opts.tracer != nil {
+			if err := registerTracing(db); err != nil {
+				panic(err)
+			}
+		}
+		if err := registerStatementCallbacks(db); err != nil {
+			panic(err)
+		}
+	}
+	if repo.opts.verifyTypes {
+		if err := repo.VerifyFieldTypes(); err != nil {
+			panic(err)
+		}
+	}
+
+	return repo
+}
 
-	db = db.
-		Table(row.TableName()).
-		Where(
-			fmt.Sprintf(
-				"%s = ?",
-				row.PrimaryKey(),
-			),
-			id,
-		)
-
-	if err = db.First(&row).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+// Detail finds the row with the given primary key, nil when there is none, see
+// WithNotFoundError and ContextWithIdentityMap. As the most frequent read, it logs
+// its errors where it returns them rather than in a deferred closure.
+func (o *BaseGorm[T, PkType]) Detail(ctx context.Context, id PkType, opts ...QueryOption) (*T, error) {
+	if len(opts) == 0 {
+		if loaded := o.identityOf(ctx, id); loaded != nil {
+			if err := o.authorize(ctx, ActionRead, loaded); err != nil {
+				o.logError(ctx, err)
+				return nil, err
+			}
+			return loaded, nil
+		}
+	}
+
+	row, err := hedgedRead(ctx, o, func(db *gorm.DB) (*T, error) {
+		var row T
+		return &row, o.findByID(db, id, &row, opts)
+	})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			o.logError(ctx, err)
+			return nil, err
+		}
+		if !o.opts.notFoundError {
 			return nil, nil
 		}
+		var e T
+		return nil, o.notFound(fmt.Sprintf("%s = %v", e.PrimaryKey(), id))
+	}
+	if err := o.authorize(ctx, ActionRead, row); err != nil {
+		o.logError(ctx, err)
 		return nil, err
 	}
 
-	return &row, nil
+	o.afterFindRow(ctx, row)
+	if len(opts) == 0 {
+		o.remember(ctx, id, row)
+	}
+
+	return row, nil
+}
+
+// findByID loads the row with the given primary key in row, failing with
+// gorm.ErrRecordNotFound when there is none.
+func (o *BaseGorm[T, PkType]) findByID(db *gorm.DB, id PkType, row *T, opts []QueryOption) error {
+	db = db.Table(o.table)
+	if len(opts) > 0 {
+		db = newQueryOptions(opts).apply(db)
+	} else if o.templates != nil && o.templates.detail != nil {
+		tx := o.templates.detail.bind(db, id).Find(row)
+		if tx.Error == nil && tx.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Error
+	}
+
+	return db.Where(o.pkCondition, id).First(row).Error
+}
+
+// Operator compares a column with the Value of a Where.
+type Operator string
+
+const (
+	OpEq        Operator = "="
+	OpNe        Operator = "!="
+	OpGt        Operator = ">"
+	OpGte       Operator = ">="
+	OpLt        Operator = "<"
+	OpLte       Operator = "<="
+	OpIn        Operator = "IN"          // Value is a slice : WHERE status IN ('new','paid')
+	OpNotIn     Operator = "NOT IN"      // Value is a slice
+	OpBetween   Operator = "BETWEEN"     // Value is a slice of 2 bounds : WHERE created_at BETWEEN ? AND ?
+	OpIsNull    Operator = "IS NULL"     // Value is ignored : WHERE deleted_at IS NULL
+	OpIsNotNull Operator = "IS NOT NULL" // Value is ignored
+)
+
+// IsValid reports whether op is one of the Op constants, or empty for equality.
+func (op Operator) IsValid() bool {
+	switch op {
+	case "", OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNotIn, OpBetween, OpIsNull, OpIsNotNull:
+		return true
+	}
+
+	return false
 }
 
 type Where struct {
 	Name             string
-	IsLike           bool // use "%keyword%" : WHERE name LIKE '%ware%'
-	IsFullTextSearch bool // use "*keyword*" : WHERE MATCH(name) AGAINST ('*ware*' IN BOOLEAN MODE) : To fully optimize this, create index "FULLTEXT KEY `idx_fulltext_columName` (`columName`)"
+	IsLike           bool     // use "%keyword%" : WHERE name LIKE '%ware%'
+	IsFullTextSearch bool     // use "*keyword*" : WHERE MATCH(name) AGAINST ('*ware*' IN BOOLEAN MODE) : To fully optimize this, create index "FULLTEXT KEY `idx_fulltext_columName` (`columName`)"
+	Operator         Operator // used when neither IsLike nor IsFullTextSearch is set, equality when empty or invalid
 	Value            interface{}
+	Group            *WhereGroup // when set, the other fields are ignored and the group is rendered in parentheses
 }
 
+// String renders the condition for MySQL, see StringFor.
 func (c *Where) String() string {
+	return c.render(DialectMySQL)
+}
+
+// StringFor renders the condition for the dialect of db : on Postgres, IsLike is a
+// case insensitive ILIKE like MySQL's LIKE with its default collations, and
+// IsFullTextSearch matches to_tsvector(name) against plainto_tsquery(value).
+func (c *Where) StringFor(db *gorm.DB) string {
+	return c.render(db.Dialector.Name())
+}
+
+func (c *Where) render(dialect string) string {
 	whereSql := fmt.Sprintf("%s = ?", c.Name)
-	if c.IsFullTextSearch {
-		whereSql = fmt.Sprintf("MATCH(%s) AGAINST (? IN BOOLEAN MODE)", c.Name)
+	if c.Group != nil {
+		whereSql = c.Group.render(dialect)
+	} else if c.IsFullTextSearch {
+		whereSql = fullTextSearch(dialect, c.Name)
 	} else if c.IsLike {
-		whereSql = fmt.Sprintf("%s LIKE ?", c.Name)
+		whereSql = fmt.Sprintf("%s %s ?", c.Name, like(dialect))
+	} else if c.Operator == OpBetween {
+		whereSql = fmt.Sprintf("%s BETWEEN ? AND ?", c.Name)
+	} else if c.Operator == OpIsNull || c.Operator == OpIsNotNull {
+		whereSql = fmt.Sprintf("%s %s", c.Name, c.Operator)
+	} else if c.Operator != "" && c.Operator.IsValid() {
+		whereSql = fmt.Sprintf("%s %s ?", c.Name, c.Operator)
 	}
 
 	return whereSql
 }
 
-func (o *BaseGorm[T, PkType]) Wheres(ctx context.Context, wheres []Where) (*T, error) {
-	var (
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		row      T
-		db       = o.db.WithContext(ctx).Table(row.TableName())
-		err      error
-	)
+// Args returns the values bound to the placeholders of String.
+func (c *Where) Args() []interface{} {
+	if c.Group != nil {
+		return c.Group.Args()
+	}
+	if c.IsLike || c.IsFullTextSearch {
+		return []interface{}{c.Value}
+	}
 
-	defer func() {
-		if err != nil {
-			logEntry.Error(err)
+	switch c.Operator {
+	case OpIsNull, OpIsNotNull:
+		return nil
+	case OpBetween:
+		bounds := reflect.ValueOf(c.Value)
+		if (bounds.Kind() == reflect.Slice || bounds.Kind() == reflect.Array) && bounds.Len() == 2 {
+			return []interface{}{bounds.Index(0).Interface(), bounds.Index(1).Interface()}
 		}
-	}()
+		return []interface{}{c.Value, c.Value}
+	}
 
-	for _, v := range wheres {
-		db.Where(v.String(), v.Value)
+	return []interface{}{c.Value}
+}
+
+// WhereGroup joins conditions with AND, or with OR when Or is set. A condition may be
+// a group itself, see Where.Group, so filters decoded from JSON can express trees
+// like (status = 'new' OR status = 'paid') AND total > 100.
+type WhereGroup struct {
+	Or     bool
+	Wheres []Where
+}
+
+func (g *WhereGroup) String() string {
+	return g.render(DialectMySQL)
+}
+
+func (g *WhereGroup) render(dialect string) string {
+	if len(g.Wheres) == 0 {
+		return "1 = 1"
+	}
+
+	separator := " AND "
+	if g.Or {
+		separator = " OR "
+	}
+
+	conditions := make([]string, len(g.Wheres))
+	for i := range g.Wheres {
+		conditions[i] = g.Wheres[i].render(dialect)
 	}
 
-	if err = db.First(&row).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	return "(" + strings.Join(conditions, separator) + ")"
+}
+
+// Args returns the values bound to the placeholders of String, in order.
+func (g *WhereGroup) Args() []interface{} {
+	var args []interface{}
+	for i := range g.Wheres {
+		args = append(args, g.Wheres[i].Args()...)
+	}
+
+	return args
+}
+
+// Wheres finds the first row matching wheres, nil when there is none, see
+// WithNotFoundError. Like Detail, it logs its errors where it returns them.
+func (o *BaseGorm[T, PkType]) Wheres(ctx context.Context, wheres []Where, opts ...QueryOption) (*T, error) {
+	if err := o.checkColumns(wheres, nil, opts...); err != nil {
+		o.logError(ctx, err)
+		return nil, err
+	}
+	o.adviseIndex(wheres, nil)
+
+	row, err := hedgedRead(ctx, o, func(db *gorm.DB) (*T, error) {
+		var row T
+
+		db = db.Table(o.table)
+		if len(opts) > 0 {
+			db = newQueryOptions(opts).apply(db)
+		}
+		for _, v := range wheres {
+			db.Where(v.StringFor(db), v.Args()...)
+		}
+
+		return &row, db.First(&row).Error
+	})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			o.logError(ctx, err)
+			return nil, err
+		}
+		if !o.opts.notFoundError {
 			return nil, nil
 		}
-		return nil, err
+		return nil, o.notFound(whereKey(wheres))
 	}
 
-	return &row, nil
+	o.afterFindRow(ctx, row)
+
+	return row, nil
 }
 
-func (o *BaseGorm[T, PkType]) WheresList(ctx context.Context, orders []OrderBy, wheres []Where) ([]T, error) {
+func (o *BaseGorm[T, PkType]) WheresList(ctx context.Context, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, error) {
 	var (
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		e        T
-		db       = o.db.WithContext(ctx).Table(e.TableName())
-		rows     []T
-		err      error
+		options = newQueryOptions(opts)
+		buffer  []T
+		rows    []T
+		err     error
 	)
 
 	defer func() {
 		if err != nil {
-			logEntry.Error(err)
+			o.logError(ctx, err)
 		}
 	}()
 
-	for _, v := range wheres {
-		db.Where(v.String(), v.Value)
+	if err = o.checkColumns(wheres, orders, opts...); err != nil {
+		return nil, err
+	}
+	o.adviseIndex(wheres, orders)
+	if buffer, err = rowsOf[T](options); err != nil {
+		return nil, err
+	}
+	if o.hedges(ctx) {
+		// both reads cannot scan into the same slice
+		buffer = nil
 	}
 
-	for _, order := range orders {
-		orderByStr := order.String()
-		if orderByStr != "" {
-			db.Order(orderByStr)
+	rows, err = hedgedRead(ctx, o, func(db *gorm.DB) ([]T, error) {
+		rows := buffer
+		if len(wheres) == 0 && len(orders) == 0 && len(opts) == 0 && o.templates != nil && o.templates.list != nil {
+			return rows, o.templates.list.bind(db).Find(&rows).Error
+		}
+
+		db = options.apply(db.Table(o.table))
+		for _, v := range wheres {
+			db.Where(v.StringFor(db), v.Args()...)
+		}
+
+		for _, order := range orders {
+			orderByStr := order.String()
+			if orderByStr != "" {
+				db.Order(orderByStr)
+			}
 		}
-	}
 
-	if err = db.Find(&rows).Error; err != nil {
+		return rows, db.Find(&rows).Error
+	})
+	if err != nil {
 		return rows, err
 	}
 
+	o.afterFind(ctx, rows)
+
 	return rows, nil
 }
 
-func (o *BaseGorm[T, PkType]) List(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where) ([]T, *Paginator, error) {
+// List finds one page of the rows matching wheres. Rows outside their publish
+// window, see Publishable, are skipped unless WithUnpublished is given.
+func (o *BaseGorm[T, PkType]) List(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error) {
+	var e T
+
+	// Model lets the count skip soft deleted rows like the find does
+	db := o.conn(ctx).Model(&e).Table(e.TableName())
+	if !newQueryOptions(opts).unpublished {
+		db = o.visible(db)
+	}
+
+	return o.paginate(ctx, db, page, pageSize, orders, wheres, opts)
+}
+
+// paginate finds one page of the rows of db matching wheres, with their total count
+// unless WithoutTotal is given.
+func (o *BaseGorm[T, PkType]) paginate(ctx context.Context, db *gorm.DB, page int, pageSize int, orders []OrderBy, wheres []Where, opts []QueryOption) ([]T, *Paginator, error) {
+	rows, paginator, err := paginateInto[T](ctx, o, db, page, pageSize, orders, wheres, opts)
+	if err == nil {
+		o.afterFind(ctx, rows)
+	}
+
+	return rows, paginator, err
+}
+
+// paginateInto is paginate scanning the rows into D.
+func paginateInto[D any, T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](ctx context.Context, o *BaseGorm[T, PkType], db *gorm.DB, page int, pageSize int, orders []OrderBy, wheres []Where, opts []QueryOption) ([]D, *Paginator, error) {
 	var (
-		logEntry  = generic_gorm.GetLoggerFromContext(ctx)
-		e         T
-		db        = o.db.WithContext(ctx).Table(e.TableName())
-		rows      []T
+		options   = newQueryOptions(opts)
+		rows      []D
 		count     int64
 		err       error
 		paginator = &Paginator{
@@ -172,12 +462,28 @@ func (o *BaseGorm[T, PkType]) List(ctx context.Context, page int, pageSize int,
 
 	defer func() {
 		if err != nil {
-			logEntry.Error(err)
+			o.logError(ctx, err)
 		}
 	}()
 
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+	if o.opts.tracer != nil {
+		// the count and the find are nested in the span of the method
+		db = db.WithContext(o.statementContext(ctx))
+	}
+
+	if err = o.checkColumns(wheres, orders, opts...); err != nil {
+		return nil, nil, err
+	}
+	o.adviseIndex(wheres, orders)
+	if rows, err = rowsOf[D](options); err != nil {
+		return nil, nil, err
+	}
+
+	db = options.scope(withTimeout(db, o.opts.statementTimeouts.List))
 	for _, v := range wheres {
-		db.Where(v.String(), v.Value)
+		db.Where(v.StringFor(db), v.Args()...)
 	}
 
 	for _, order := range orders {
@@ -187,16 +493,45 @@ func (o *BaseGorm[T, PkType]) List(ctx context.Context, page int, pageSize int,
 		}
 	}
 
-	if err = db.Count(&count).Error; err != nil {
+	if options.withoutTotal {
+		// one more row tells whether a next page exists
+		if err = options.load(db).Offset((page - 1) * pageSize).Limit(pageSize + 1).Find(&rows).Error; err != nil {
+			return rows, paginator, err
+		}
+
+		hasNext := len(rows) > pageSize
+		if hasNext {
+			rows = rows[:pageSize]
+		}
+		paginator.setPageWithoutTotal(len(rows), hasNext)
+
+		return rows, paginator, nil
+	}
+
+	// a dry run keeps the SQL of the count on its statement, SQLOf counts on a copy
+	_, sqlOf := db.Logger.(*sqlRecorder)
+	countDB := db
+	switch {
+	case sqlOf && options.countDB == nil && !options.distinct:
+		countDB = db.Session(&gorm.Session{})
+	case options.countDB != nil:
+		countDB = withTimeout(options.countDB.WithContext(ctx), o.opts.statementTimeouts.List)
+	case options.distinct:
+		var e T
+		countDB = db.Session(&gorm.Session{}).Select(options.countDistinct(e.TableName() + "." + e.PrimaryKey()))
+	}
+	if err = countDB.Count(&count).Error; err != nil {
 		return rows, nil, err
 	}
 
-	paginator.Total = int(count)
-	if count == 0 {
+	paginator.SetTotal(int(count))
+	// the count of SQLOf is always 0, its find is built all the same
+	if count == 0 && !sqlOf {
 		return rows, paginator, nil
 	}
 
-	if err = db.Offset((page - 1) * pageSize).Limit(pageSize).Find(&rows).Error; err != nil {
+	// preloads are only applied to the find, not the count
+	if err = options.load(db).Offset((page - 1) * pageSize).Limit(pageSize).Find(&rows).Error; err != nil {
 		return rows, paginator, err
 	}
 
@@ -205,18 +540,22 @@ func (o *BaseGorm[T, PkType]) List(ctx context.Context, page int, pageSize int,
 
 func (o *BaseGorm[T, PkType]) Create(ctx context.Context, row *T) (*T, error) {
 	var (
-		e        T
-		db       = o.db.WithContext(ctx).Table(e.TableName())
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		err      error
+		e   T
+		db  = o.conn(ctx).Table(e.TableName())
+		err error
 	)
 
 	defer func() {
 		if err != nil {
-			logEntry.Error(err)
+			o.logError(ctx, err)
 		}
 	}()
 
+	o.normalizeTimes(ctx, row)
+	if err = o.validate(ctx, row, nil); err != nil {
+		return nil, err
+	}
+
 	// cannot handle upsert will get err Duplicate entry
 	if err = db.Create(row).Error; err != nil {
 		return nil, err
@@ -226,9 +565,28 @@ func (o *BaseGorm[T, PkType]) Create(ctx context.Context, row *T) (*T, error) {
 }
 
 func (o *BaseGorm[T, PkType]) DB(ctx context.Context) *gorm.DB {
-	return o.db.WithContext(ctx)
+	return o.conn(ctx)
+}
+
+// conn returns the *gorm.DB an operation runs on : the one stored in ctx by
+// generic_gorm.ContextWithDB, e.g. a transaction or a pinned connection, when it
+// belongs to the database of the repository. Every method, association ones
+// included, must resolve its handle here so it joins the caller's transaction.
+func (o *BaseGorm[T, PkType]) conn(ctx context.Context) *gorm.DB {
+	db, ok := generic_gorm.DBFromContext(ctx)
+	if o.bound || !ok || !generic_gorm.SameDatabase(db, o.db) {
+		return withTimeout(o.db.WithContext(o.statementContext(ctx)), o.opts.statementTimeouts.Read)
+	}
+
+	if o.opts.timestamps != nil {
+		db = db.Session(&gorm.Session{NowFunc: o.opts.timestamps.now})
+	}
+
+	return withTimeout(db.WithContext(o.statementContext(ctx)), o.opts.statementTimeouts.Read)
 }
 
+// CreateMultiple inserts rows by batches, see WithCreateBatchSize, and returns
+// the total number of rows affected.
 func (o *BaseGorm[T, PkType]) CreateMultiple(ctx context.Context, rows []*T) ([]*T, int64, error) {
 	var (
 		rowsAffected int64
@@ -239,19 +597,35 @@ func (o *BaseGorm[T, PkType]) CreateMultiple(ctx context.Context, rows []*T) ([]
 	}
 
 	var (
-		e        T
-		db       = o.db.WithContext(ctx).Table(e.TableName())
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		err      error
+		e   T
+		err error
 	)
 
 	defer func() {
 		if err != nil {
-			logEntry.Error(err)
+			o.logError(ctx, err)
 		}
 	}()
 
-	result := db.Create(rows)
+	// the batches are nested in the span of the method
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+	db := o.conn(ctx).Table(e.TableName())
+
+	for i, row := range rows {
+		o.normalizeTimes(ctx, row)
+		if err = o.validate(ctx, row, nil); err != nil {
+			err = fmt.Errorf("row %d: %w", i, err)
+			return rows, rowsAffected, err
+		}
+	}
+
+	batchSize := o.opts.createBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCreateBatchSize
+	}
+
+	result := db.CreateInBatches(rows, batchSize)
 	err = result.Error
 	rowsAffected = result.RowsAffected
 
@@ -260,18 +634,61 @@ func (o *BaseGorm[T, PkType]) CreateMultiple(ctx context.Context, rows []*T) ([]
 
 func (o *BaseGorm[T, PkType]) Update(ctx context.Context, row *T, updatedColumns []string) (int64, error) {
 	var (
-		e        T
-		db       = o.db.WithContext(ctx).Table(e.TableName())
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		err      error
+		e   T
+		db  = o.conn(ctx).Table(e.TableName())
+		err error
 	)
 
 	defer func() {
 		if err != nil {
-			logEntry.Error(err)
+			o.logError(ctx, err)
 		}
 	}()
 
+	o.forgetIdentities(ctx)
+
+	if err = o.authorizeStored(ctx, ActionUpdate, row); err != nil {
+		return 0, err
+	}
+
+	var changes map[string]Change
+	if o.opts.diffUpdate && len(updatedColumns) == 0 {
+		if changes, err = o.changesOf(ctx, row); err != nil {
+			return 0, err
+		}
+		if len(changes) == 0 {
+			return 0, nil
+		}
+		updatedColumns = changedColumns(changes)
+	}
+
+	if o.opts.writePermissions != nil {
+		var written, skipped []string
+		if written, err = o.writtenColumns(ctx, row, updatedColumns); err != nil {
+			return 0, err
+		}
+		if skipped, err = o.checkWrite(ctx, written); err != nil {
+			return 0, err
+		}
+		if len(skipped) > 0 {
+			for _, column := range skipped {
+				delete(changes, column)
+			}
+			if len(updatedColumns) > 0 && !slices.Contains(updatedColumns, "*") {
+				if updatedColumns = without(updatedColumns, skipped); len(updatedColumns) == 0 {
+					return 0, nil
+				}
+			} else {
+				db = db.Omit(skipped...)
+			}
+		}
+	}
+
+	o.normalizeTimes(ctx, row)
+	if err = o.validate(ctx, row, updatedColumns); err != nil {
+		return 0, err
+	}
+
 	if len(updatedColumns) > 0 {
 		db = db.Select(updatedColumns)
 	}
@@ -280,116 +697,151 @@ func (o *BaseGorm[T, PkType]) Update(ctx context.Context, row *T, updatedColumns
 	result := db.Model(row).Updates(row)
 	err = result.Error
 
+	if err == nil && changes != nil && o.opts.changeListener != nil {
+		o.opts.changeListener(ctx, e.TableName(), changes)
+	}
+
 	return result.RowsAffected, err
 }
 
 func (o *BaseGorm[T, PkType]) UpdateWhere(ctx context.Context, wheres []Where, values map[string]interface{}) (int64, error) {
 	var (
-		e        T
-		db       = o.db.WithContext(ctx).Table(e.TableName())
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		err      error
+		e   T
+		db  = o.conn(ctx).Table(e.TableName())
+		err error
 	)
 
 	defer func() {
 		if err != nil {
-			logEntry.Error(err)
+			o.logError(ctx, err)
 		}
 	}()
 
-	// Build where clauses
-	for _, v := range wheres {
-		if v.IsLike {
-			db = db.Where(fmt.Sprintf("%s LIKE ?", v.Name), fmt.Sprintf("%%%v%%", v.Value))
-		} else if v.IsFullTextSearch {
-			db = db.Where(fmt.Sprintf("MATCH(%s) AGAINST(? IN BOOLEAN MODE)", v.Name), v.Value)
-		} else {
-			db = db.Where(fmt.Sprintf("%s = ?", v.Name), v.Value)
+	o.forgetIdentities(ctx)
+
+	if err = o.checkColumns(wheres, nil); err != nil {
+		return 0, err
+	}
+
+	if err = o.authorizeWheres(ctx, ActionUpdate, o.conn(ctx), wheres); err != nil {
+		return 0, err
+	}
+
+	if o.opts.writePermissions != nil {
+		if values, err = o.permittedValues(ctx, values); err != nil || len(values) == 0 {
+			return 0, err
 		}
 	}
 
 	// Execute update
-	result := db.Updates(values)
+	o.normalizeTimeValues(values)
+	update := func(db *gorm.DB) *gorm.DB {
+		return bulkWheres(db, wheres).Updates(values)
+	}
+
+	if o.opts.undoJournal != nil {
+		var rowsAffected int64
+		rowsAffected, err = o.journaled(ctx, journalUpdate, wheres, update)
+		return rowsAffected, err
+	}
+
+	result := update(db)
 	err = result.Error
 
 	return result.RowsAffected, err
 }
 
-func (o *BaseGorm[T, PkType]) Upsert(ctx context.Context, row *T, onConflictUpdatedColumns []string) (int64, error) {
+// DeleteWhere deletes the rows matching wheres, a soft delete when T has a gorm.DeletedAt field.
+func (o *BaseGorm[T, PkType]) DeleteWhere(ctx context.Context, wheres []Where) (int64, error) {
 	var (
-		e        T
-		db       = o.db.WithContext(ctx).Table(e.TableName())
-		logEntry = generic_gorm.GetLoggerFromContext(ctx)
-		err      error
+		e   T
+		db  = o.conn(ctx).Table(e.TableName())
+		err error
 	)
 
 	defer func() {
 		if err != nil {
-			logEntry.Error(err)
+			o.logError(ctx, err)
 		}
 	}()
 
-	result := db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{},
-		DoUpdates: clause.AssignmentColumns(onConflictUpdatedColumns),
-	}).Create(&row)
+	o.forgetIdentities(ctx)
 
-	return result.RowsAffected, result.Error
-}
+	if err = o.checkColumns(wheres, nil); err != nil {
+		return 0, err
+	}
 
-type ListCustomCallback = func(*gorm.DB) *gorm.DB
+	if err = o.authorizeWheres(ctx, ActionDelete, o.conn(ctx), wheres); err != nil {
+		return 0, err
+	}
 
-func (o *BaseGorm[T, PkType]) ListCustom(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, customCallback ListCustomCallback) ([]T, *Paginator, error) {
-	var (
-		logEntry  = generic_gorm.GetLoggerFromContext(ctx)
-		db        = o.db.WithContext(ctx)
-		rows      []T
-		count     int64
-		err       error
-		paginator = &Paginator{
-			Page:    page,
-			PerPage: pageSize,
-			Total:   0,
-		}
-	)
+	remove := func(db *gorm.DB) *gorm.DB {
+		return bulkWheres(db, wheres).Delete(&e)
+	}
 
-	defer func() {
-		if err != nil {
-			logEntry.Error(err)
-		}
-	}()
+	if o.opts.undoJournal != nil {
+		var rowsAffected int64
+		rowsAffected, err = o.journaled(ctx, journalDelete, wheres, remove)
+		return rowsAffected, err
+	}
 
-	db = customCallback(db)
+	result := remove(db)
+	err = result.Error
 
-	for _, v := range wheres {
-		db.Where(v.String(), v.Value)
-	}
+	return result.RowsAffected, err
+}
 
-	for _, order := range orders {
-		orderByStr := order.String()
-		if orderByStr != "" {
-			db.Order(orderByStr)
+// bulkWheres adds the where clauses of the bulk operations UpdateWhere and DeleteWhere.
+func bulkWheres(db *gorm.DB, wheres []Where) *gorm.DB {
+	for _, v := range wheres {
+		if v.IsLike && v.Group == nil && !v.IsFullTextSearch {
+			db = db.Where(v.StringFor(db), fmt.Sprintf("%%%v%%", v.Value))
+		} else {
+			db = db.Where(v.StringFor(db), v.Args()...)
 		}
 	}
 
-	if err = db.Count(&count).Error; err != nil {
-		return rows, nil, err
-	}
+	return db
+}
 
-	paginator.Total = int(count)
-	if count == 0 {
-		return rows, paginator, nil
-	}
+type ListCustomCallback = func(*gorm.DB) *gorm.DB
 
-	if err = db.Offset((page - 1) * pageSize).Limit(pageSize).Find(&rows).Error; err != nil {
-		return rows, paginator, err
+func (o *BaseGorm[T, PkType]) ListCustom(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, customCallback ListCustomCallback, opts ...QueryOption) ([]T, *Paginator, error) {
+	return o.paginate(ctx, customCallback(o.conn(ctx)), page, pageSize, orders, wheres, opts)
+}
+
+// ListCustomWithCount is ListCustom counting the total on the query built by
+// countCallback instead of on the one of selectCallback, for selects a COUNT cannot
+// wrap, e.g. with a GROUP BY. countCallback is given a new query filtered by wheres,
+// and returns the query to count, setting its table like selectCallback does :
+//
+//	func(db *gorm.DB) *gorm.DB {
+//		grouped := db.Table("orders").Select("customer_id").Group("customer_id")
+//		return db.Session(&gorm.Session{NewDB: true}).Table("(?) AS grouped", grouped)
+//	}
+func (o *BaseGorm[T, PkType]) ListCustomWithCount(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, selectCallback ListCustomCallback, countCallback ListCustomCallback, opts ...QueryOption) ([]T, *Paginator, error) {
+	countDB := o.conn(ctx)
+	for _, v := range wheres {
+		countDB = countDB.Where(v.StringFor(countDB), v.Args()...)
 	}
+	countDB = countCallback(countDB)
 
-	return rows, paginator, nil
+	opts = append(opts[:len(opts):len(opts)], func(o *queryOptions) {
+		o.countDB = countDB
+	})
+
+	return o.paginate(ctx, selectCallback(o.conn(ctx)), page, pageSize, orders, wheres, opts)
 }
 
-func (o *BaseGorm[T, PkType]) Association(ctx context.Context, model *T, field string) *gorm.Association {
-	return o.db.WithContext(ctx).Model(model).Association(field)
+// Association returns gorm's association of model in field, running on the
+// transaction carried by ctx if any, like the other methods.
+func (o *BaseGorm[T, PkType]) Association(ctx context.Context, model *T, field string, opts ...AssociationOption) *gorm.Association {
+	db := o.conn(ctx)
+	for _, opt := range opts {
+		db = opt(db)
+	}
+
+	return db.Model(model).Association(field)
 }
 
 func (o *BaseGorm[T, PkType]) AppendAssociation(ctx context.Context, model *T, field string, values interface{}) error {
@@ -405,13 +857,40 @@ func (o *BaseGorm[T, PkType]) DeleteAssociation(ctx context.Context, model *T, f
 }
 
 func (o *BaseGorm[T, PkType]) ClearAssociation(ctx context.Context, model *T, field string) error {
+	if o.opts.softDeleteAssociations {
+		return o.softClearAssociation(ctx, model, field)
+	}
+
 	return o.Association(ctx, model, field).Clear()
 }
 
-func (o *BaseGorm[T, PkType]) CountAssociation(ctx context.Context, model *T, field string) int64 {
-	return o.Association(ctx, model, field).Count()
+// CountAssociation returns 0 when counting fails.
+//
+// Deprecated: use CountAssociationWithError, which tells failures from associations without children.
+func (o *BaseGorm[T, PkType]) CountAssociation(ctx context.Context, model *T, field string, opts ...AssociationOption) int64 {
+	count, _ := o.CountAssociationWithError(ctx, model, field, opts...)
+	return count
+}
+
+// CountAssociationWithError counts the children of model in field.
+func (o *BaseGorm[T, PkType]) CountAssociationWithError(ctx context.Context, model *T, field string, opts ...AssociationOption) (int64, error) {
+	var (
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	association := o.Association(ctx, model, field, opts...)
+	count := association.Count()
+	err = association.Error
+
+	return count, err
 }
 
-func (o *BaseGorm[T, PkType]) FindAssociation(ctx context.Context, model *T, field string, dest interface{}) error {
-	return o.Association(ctx, model, field).Find(dest)
+func (o *BaseGorm[T, PkType]) FindAssociation(ctx context.Context, model *T, field string, dest interface{}, opts ...AssociationOption) error {
+	return o.Association(ctx, model, field, opts...).Find(dest)
 }
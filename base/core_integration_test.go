@@ -2,12 +2,14 @@ package base
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"testing"
 	"time"
 
+	generic_gorm "github.com/harryosmar/generic-gorm"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -32,6 +34,13 @@ func (User) PrimaryKey() string {
 	return "id"
 }
 
+func (User) CascadeRules() []CascadeRule {
+	return []CascadeRule{
+		{Association: "Profile", Action: CascadeDelete},
+		{Association: "Posts", Action: CascadeDelete},
+	}
+}
+
 type Profile struct {
 	ID        uint `gorm:"primaryKey"`
 	UserID    uint
@@ -116,12 +125,10 @@ func setupTestDB(t *testing.T) *gorm.DB {
 func cleanupDB(t *testing.T, db *gorm.DB) {
 	t.Helper()
 
-	// Clean up existing data
-	db.Exec("SET FOREIGN_KEY_CHECKS = 0")
-	db.Exec("TRUNCATE TABLE dummy_users")
-	db.Exec("TRUNCATE TABLE dummy_profiles")
-	db.Exec("TRUNCATE TABLE dummy_posts")
-	db.Exec("SET FOREIGN_KEY_CHECKS = 1")
+	// Clean up existing data, children first
+	if err := CleanupTables(context.Background(), db, nil, &User{}, &Profile{}, &Post{}); err != nil {
+		t.Fatalf("Failed to clean up database: %v", err)
+	}
 }
 
 func TestCRUDOperations(t *testing.T) {
@@ -462,20 +469,10 @@ func TestTransactionWithAssociations(t *testing.T) {
 			t.Errorf("Transaction failed: %v", err)
 		}
 
-		// Verify data persisted after transaction
-		savedUser, err := baseRepo.Detail(ctx, user.ID)
-		if err != nil {
-			t.Errorf("Failed to get user after transaction: %v", err)
-		}
-
-		// Load associations
-		err = db.Model(&savedUser).Association("Profile").Find(&savedUser.Profile)
-		if err != nil {
-			t.Errorf("Failed to load profile: %v", err)
-		}
-		err = db.Model(&savedUser).Association("Posts").Find(&savedUser.Posts)
+		// Verify data persisted after transaction, with its associations
+		savedUser, err := baseRepo.Detail(ctx, user.ID, WithPreload("Profile"), WithPreload("Posts"))
 		if err != nil {
-			t.Errorf("Failed to load posts: %v", err)
+			t.Fatalf("Failed to get user after transaction: %v", err)
 		}
 
 		// Verify all data
@@ -530,3 +527,1014 @@ func TestTransactionWithAssociations(t *testing.T) {
 		}
 	})
 }
+
+func TestSaveChanges(t *testing.T) {
+	db := setupTestDB(t)
+
+	t.Cleanup(func() {
+		cleanupDB(t, db)
+	})
+
+	cleanupDB(t, db)
+
+	baseRepo := NewBaseGorm[User, uint](db, WithTracking())
+	ctx := ContextWithTracking(context.Background())
+
+	user, err := baseRepo.Create(ctx, &User{Name: "Tracked User", Email: "tracked@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	loadedUser, err := baseRepo.Detail(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get user detail: %v", err)
+	}
+
+	loadedUser.Name = "Tracked User Renamed"
+	rowsAffected, err := baseRepo.SaveChanges(ctx, loadedUser)
+	if err != nil {
+		t.Fatalf("Failed to save changes: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Expected 1 row affected, got %d", rowsAffected)
+	}
+
+	// Nothing changed since the last save
+	rowsAffected, err = baseRepo.SaveChanges(ctx, loadedUser)
+	if err != nil {
+		t.Fatalf("Failed to save unchanged user: %v", err)
+	}
+	if rowsAffected != 0 {
+		t.Errorf("Expected 0 rows affected, got %d", rowsAffected)
+	}
+}
+
+func TestCascadeDelete(t *testing.T) {
+	db := setupTestDB(t)
+
+	t.Cleanup(func() {
+		cleanupDB(t, db)
+	})
+
+	cleanupDB(t, db)
+
+	baseRepo := NewBaseGorm[User, uint](db)
+	ctx := context.Background()
+
+	user, err := baseRepo.Create(ctx, &User{
+		Name:    "Cascade User",
+		Email:   "cascade@example.com",
+		Profile: Profile{Bio: "Cascade bio"},
+		Posts:   []Post{{Title: "Cascade Post"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	rowsAffected, err := baseRepo.Delete(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if rowsAffected != 1 {
+		t.Errorf("Expected 1 row affected, got %d", rowsAffected)
+	}
+
+	var profiles, posts int64
+	db.Model(&Profile{}).Where("user_id = ?", user.ID).Count(&profiles)
+	db.Model(&Post{}).Where("user_id = ?", user.ID).Count(&posts)
+	if profiles != 0 || posts != 0 {
+		t.Errorf("Expected children to be deleted, got %d profiles and %d posts", profiles, posts)
+	}
+}
+
+func TestUndoOperation(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&JournalEntry{}); err != nil {
+		t.Fatalf("Failed to migrate journal: %v", err)
+	}
+
+	t.Cleanup(func() {
+		cleanupDB(t, db)
+		db.Where("table_name = ?", User{}.TableName()).Delete(&JournalEntry{})
+	})
+
+	cleanupDB(t, db)
+
+	baseRepo := NewBaseGorm[User, uint](db, WithUndoJournal(time.Hour))
+	ctx := ContextWithOperationID(context.Background(), fmt.Sprintf("undo-test-%d", time.Now().UnixNano()))
+
+	user, err := baseRepo.Create(ctx, &User{Name: "Before Bulk Edit", Email: "undo@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	where := []Where{{Name: "email", Value: "undo@example.com"}}
+	if _, err = baseRepo.UpdateWhere(ctx, where, map[string]interface{}{"name": "Fat Fingered"}); err != nil {
+		t.Fatalf("Failed to update users: %v", err)
+	}
+
+	operationID := ctx.Value(operationIDCtxKey{}).(string)
+	restored, err := baseRepo.UndoOperation(ctx, operationID)
+	if err != nil {
+		t.Fatalf("Failed to undo operation: %v", err)
+	}
+	if restored != 1 {
+		t.Errorf("Expected 1 restored row, got %d", restored)
+	}
+
+	restoredUser, err := baseRepo.Detail(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if restoredUser.Name != "Before Bulk Edit" {
+		t.Errorf("Expected name 'Before Bulk Edit', got '%s'", restoredUser.Name)
+	}
+
+	if _, err = baseRepo.UndoOperation(ctx, operationID); !errors.Is(err, ErrAlreadyUndone) {
+		t.Errorf("Expected ErrAlreadyUndone, got %v", err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	for i := 0; i < 5; i++ {
+		if _, err := baseRepo.Create(ctx, &User{Name: "Old User", Email: fmt.Sprintf("old%d@example.com", i)}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	if err := db.Model(&User{}).Where("1 = 1").Update("created_at", time.Now().AddDate(0, 0, -40)).Error; err != nil {
+		t.Fatalf("Failed to age users: %v", err)
+	}
+	if _, err := baseRepo.Create(ctx, &User{Name: "New User", Email: "new@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	purged, err := baseRepo.PurgeExpired(ctx, "created_at", 30*24*time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Failed to purge users: %v", err)
+	}
+	if purged != 5 {
+		t.Errorf("Expected 5 purged users, got %d", purged)
+	}
+
+	remaining, err := baseRepo.WheresList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].Email != "new@example.com" {
+		t.Errorf("Expected only the new user to remain, got %+v", remaining)
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	db := setupTestDB(t)
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	variables := func(ctx context.Context) []generic_gorm.SessionVar {
+		return []generic_gorm.SessionVar{generic_gorm.UserVariable("current_user_id", 42)}
+	}
+
+	err := generic_gorm.WithSession(context.Background(), db, variables, func(ctx context.Context) error {
+		var userID int
+		if err := baseRepo.DB(ctx).Raw("SELECT @current_user_id").Scan(&userID).Error; err != nil {
+			return err
+		}
+		if userID != 42 {
+			t.Errorf("Expected @current_user_id 42 on the session connection, got %d", userID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to run with session: %v", err)
+	}
+}
+
+func TestIterateAssociation(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	user := &User{Name: "Prolific", Email: "prolific@example.com"}
+	for i := 0; i < 5; i++ {
+		user.Posts = append(user.Posts, Post{Title: fmt.Sprintf("Post %d", i)})
+	}
+	if _, err := baseRepo.Create(ctx, user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	var (
+		posts   []Post
+		batches int
+		titles  []string
+	)
+	err := baseRepo.IterateAssociation(ctx, user, "Posts", &posts, 2, func() error {
+		batches++
+		for _, post := range posts {
+			titles = append(titles, post.Title)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to iterate posts: %v", err)
+	}
+
+	if batches != 3 {
+		t.Errorf("Expected 3 batches, got %d", batches)
+	}
+	if len(titles) != 5 || titles[0] != "Post 0" || titles[4] != "Post 4" {
+		t.Errorf("Expected the 5 posts in key order, got %v", titles)
+	}
+}
+
+func TestSyncAssociation(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	user := &User{Name: "Syncer", Email: "syncer@example.com", Posts: []Post{{Title: "Keep"}, {Title: "Edit"}, {Title: "Drop"}}}
+	if _, err := baseRepo.Create(ctx, user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	kept, edited := user.Posts[0], user.Posts[1]
+
+	edited.Title = "Edited"
+	result, err := baseRepo.SyncAssociation(ctx, user, "Posts", []Post{kept, edited, {Title: "New"}})
+	if err != nil {
+		t.Fatalf("Failed to sync posts: %v", err)
+	}
+	if result != (SyncResult{Created: 1, Updated: 1, Deleted: 1}) {
+		t.Errorf("Expected 1 created, 1 updated and 1 deleted post, got %+v", result)
+	}
+
+	var posts []Post
+	if err := baseRepo.FindAssociation(ctx, user, "Posts", &posts); err != nil {
+		t.Fatalf("Failed to find posts: %v", err)
+	}
+	titles := map[uint]string{}
+	for _, post := range posts {
+		titles[post.ID] = post.Title
+	}
+	if len(posts) != 3 || titles[kept.ID] != "Keep" || titles[edited.ID] != "Edited" {
+		t.Errorf("Expected kept and edited posts to keep their ids, got %v", titles)
+	}
+}
+
+func TestAggregateRepo(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = ContextWithTracking(context.Background())
+		repo = NewAggregateRepo(NewBaseGorm[User, uint](db), "Profile", "Posts")
+		user = &User{Name: "Root", Email: "root@example.com", Profile: Profile{Bio: "Bio"}, Posts: []Post{{Title: "Keep"}, {Title: "Edit"}, {Title: "Drop"}}}
+	)
+
+	if result, err := repo.Save(ctx, user); err != nil || result.Created != 4 {
+		t.Fatalf("Expected the root created with 4 children, got %+v, %v", result, err)
+	}
+
+	loaded, err := repo.Load(ctx, user.ID)
+	if err != nil || loaded == nil || len(loaded.Posts) != 3 || loaded.Profile.Bio != "Bio" {
+		t.Fatalf("Expected the root loaded with its children, got %+v, %v", loaded, err)
+	}
+
+	loaded.Name = "Renamed"
+	loaded.Profile.Bio = "New bio"
+	loaded.Posts[1].Title = "Edited"
+	loaded.Posts = append(loaded.Posts[:2], Post{Title: "New"})
+	result, err := repo.Save(ctx, loaded)
+	if err != nil {
+		t.Fatalf("Failed to save aggregate: %v", err)
+	}
+	if result != (SyncResult{Created: 1, Updated: 2, Deleted: 1}) {
+		t.Errorf("Expected 1 created, 2 updated and 1 deleted children, got %+v", result)
+	}
+
+	reloaded, err := repo.Load(ctx, user.ID)
+	if err != nil || reloaded.Name != "Renamed" || reloaded.Profile.Bio != "New bio" || len(reloaded.Posts) != 3 {
+		t.Fatalf("Expected the changes of the graph persisted, got %+v, %v", reloaded, err)
+	}
+	if result, err := repo.Save(ctx, reloaded); err != nil || result != (SyncResult{}) {
+		t.Errorf("Expected nothing written for an unchanged aggregate, got %+v, %v", result, err)
+	}
+
+	if _, err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Failed to delete aggregate: %v", err)
+	}
+	var children int64
+	db.Model(&Post{}).Where("user_id = ?", user.ID).Count(&children)
+	if children != 0 {
+		t.Errorf("Expected the posts deleted with the root, got %d", children)
+	}
+}
+
+func TestAssociationInContextTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	user, err := baseRepo.Create(ctx, &User{Name: "Rolled Back", Email: "rollback@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	rollback := errors.New("rollback")
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txCtx := generic_gorm.ContextWithDB(ctx, tx)
+		if err := baseRepo.AppendAssociation(txCtx, user, "Posts", []Post{{Title: "Never Committed"}}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+
+	count, err := baseRepo.CountAssociationWithError(ctx, user, "Posts")
+	if err != nil {
+		t.Fatalf("Failed to count posts: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the appended post to be rolled back, got %d posts", count)
+	}
+}
+
+func TestCreateMultiplePartial(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	existing, err := baseRepo.Create(ctx, &User{Name: "Existing", Email: "existing@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	rows := []*User{
+		{Name: "First", Email: "first@example.com"},
+		{ID: existing.ID, Name: "Duplicate", Email: "duplicate@example.com"},
+		{Name: "Last", Email: "last@example.com"},
+	}
+	report, err := baseRepo.CreateMultiplePartial(ctx, rows)
+	if err != nil {
+		t.Fatalf("Failed to import users: %v", err)
+	}
+
+	if len(report.Inserted) != 2 || len(report.Failed) != 1 || report.Failed[0].Index != 1 {
+		t.Fatalf("Expected row 1 to fail and the 2 others to be inserted, got %+v", report)
+	}
+
+	users, err := baseRepo.WheresList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(users) != 3 {
+		t.Errorf("Expected 3 stored users, got %d", len(users))
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	rollback := errors.New("rollback")
+	err := baseRepo.Transaction(ctx, func(repo *BaseGorm[User, uint]) error {
+		if _, err := repo.Create(ctx, &User{Name: "Rolled Back", Email: "rollback@example.com"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+
+	err = baseRepo.Transaction(ctx, func(repo *BaseGorm[User, uint]) error {
+		_, err := repo.Create(ctx, &User{Name: "Committed", Email: "commit@example.com"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to run transaction: %v", err)
+	}
+
+	users, err := baseRepo.WheresList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "Committed" {
+		t.Errorf("Expected only the committed user, got %+v", users)
+	}
+}
+
+func TestTableChecksum(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+	columns := []string{"name", "email"}
+
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := baseRepo.Create(ctx, &User{Name: name, Email: name + "@example.com"}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	before, err := baseRepo.TableChecksum(ctx, nil, columns)
+	if err != nil {
+		t.Fatalf("Failed to checksum users: %v", err)
+	}
+
+	if _, err := baseRepo.UpdateWhere(ctx, []Where{{Name: "name", Value: "Bob"}}, map[string]interface{}{"email": "bob@example.org"}); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+
+	after, err := baseRepo.TableChecksum(ctx, nil, columns)
+	if err != nil {
+		t.Fatalf("Failed to checksum users: %v", err)
+	}
+	if before == after {
+		t.Error("Expected the checksum to change with the data")
+	}
+
+	alice, err := baseRepo.TableChecksum(ctx, []Where{{Name: "name", Value: "Alice"}}, columns)
+	if err != nil {
+		t.Fatalf("Failed to checksum users: %v", err)
+	}
+	if alice == after {
+		t.Error("Expected the wheres to restrict the checksummed rows")
+	}
+}
+
+func TestTxManager(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx      = context.Background()
+		manager  = generic_gorm.NewTxManager(db)
+		userRepo = NewBaseGorm[User, uint](db)
+		postRepo = NewBaseGorm[Post, uint](db)
+		rollback = errors.New("rollback")
+	)
+
+	err := manager.RunInTransaction(ctx, func(ctx context.Context) error {
+		user, err := userRepo.Create(ctx, &User{Name: "Rolled Back", Email: "rollback@example.com"})
+		if err != nil {
+			return err
+		}
+		if _, err := postRepo.Create(ctx, &Post{UserID: user.ID, Title: "Never Committed"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+
+	users, err := userRepo.WheresList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	posts, err := postRepo.WheresList(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to list posts: %v", err)
+	}
+	if len(users) != 0 || len(posts) != 0 {
+		t.Errorf("Expected both repositories to be rolled back, got %d users and %d posts", len(users), len(posts))
+	}
+}
+
+func TestListWithPreload(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	if _, err := baseRepo.Create(ctx, &User{
+		Name:  "Preloaded",
+		Email: "preloaded@example.com",
+		Posts: []Post{{Title: "Draft"}, {Title: "Published"}},
+	}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	users, _, err := baseRepo.List(ctx, 1, 10, nil, nil, WithPreload("Posts", "title = ?", "Published"))
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(users) != 1 || len(users[0].Posts) != 1 || users[0].Posts[0].Title != "Published" {
+		t.Errorf("Expected the user with its published post, got %+v", users)
+	}
+
+	rows, err := baseRepo.WheresList(ctx, nil, []Where{{Name: "name", Value: "Preloaded"}}, WithPreload("Posts"))
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(rows) != 1 || len(rows[0].Posts) != 2 {
+		t.Errorf("Expected the user with its 2 posts, got %+v", rows)
+	}
+}
+
+func TestFirstOrCreate(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+	wheres := []Where{{Name: "email", Value: "provisioned@example.com"}}
+
+	user, created, err := baseRepo.FirstOrCreate(ctx, wheres, &User{Name: "Provisioned"})
+	if err != nil {
+		t.Fatalf("Failed to first or create user: %v", err)
+	}
+	if !created || user.ID == 0 || user.Email != "provisioned@example.com" || user.Name != "Provisioned" {
+		t.Fatalf("Expected the user to be created from the defaults and wheres, got %+v, %v", user, created)
+	}
+
+	found, created, err := baseRepo.FirstOrCreateAssign(ctx, wheres, &User{Name: "Ignored"}, map[string]interface{}{"name": "Renamed"})
+	if err != nil {
+		t.Fatalf("Failed to first or create user: %v", err)
+	}
+	if created || found.ID != user.ID || found.Name != "Renamed" {
+		t.Fatalf("Expected the existing user with its assigned name, got %+v, %v", found, created)
+	}
+
+	stored, err := baseRepo.Detail(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if stored.Name != "Renamed" {
+		t.Errorf("Expected the assigned name to be stored, got %s", stored.Name)
+	}
+}
+
+func TestUpsertWithOptions(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[User, uint](db)
+
+	user, err := baseRepo.Create(ctx, &User{Name: "Stored", Email: "stored@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	persisted, err := baseRepo.UpsertWithOptions(ctx, &User{ID: user.ID, Name: "Ignored", Email: "ignored@example.com"}, UpsertOptions{
+		ConflictColumns: []string{"id"},
+		DoNothing:       true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to upsert user: %v", err)
+	}
+	if persisted.Name != "Stored" || persisted.Email != "stored@example.com" {
+		t.Errorf("Expected the stored user to be kept and returned, got %+v", persisted)
+	}
+
+	persisted, err = baseRepo.UpsertWithOptions(ctx, &User{ID: user.ID, Name: "Renamed", Email: "ignored@example.com"}, UpsertOptions{
+		UpdateColumns: []string{"name"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to upsert user: %v", err)
+	}
+	if persisted.Name != "Renamed" || persisted.Email != "stored@example.com" {
+		t.Errorf("Expected only the name to be updated, got %+v", persisted)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx      = context.Background()
+		owner    = ContextWithActor(ctx, Actor{ID: "1"})
+		stranger = ContextWithActor(ctx, Actor{ID: "2"})
+		repo     = NewBaseGorm[Post, uint](db, WithPolicy(OwnerPolicy("user_id")))
+	)
+
+	post, err := NewBaseGorm[Post, uint](db).Create(ctx, &Post{UserID: 1, Title: "Owned"})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	post.Title = "Stolen"
+	if _, err := repo.Update(stranger, post, []string{"title"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected update by another actor to be forbidden, got %v", err)
+	}
+	if _, err := repo.UpdateWhere(stranger, []Where{{Name: "title", Value: "Owned"}}, map[string]interface{}{"title": "Stolen"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected bulk update by another actor to be forbidden, got %v", err)
+	}
+	if _, err := repo.Delete(stranger, post.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Expected delete by another actor to be forbidden, got %v", err)
+	}
+
+	post.Title = "Renamed"
+	if _, err := repo.Update(owner, post, []string{"title"}); err != nil {
+		t.Errorf("Expected update by the owner to be allowed, got %v", err)
+	}
+	if rowsAffected, err := repo.Delete(owner, post.ID); err != nil || rowsAffected != 1 {
+		t.Errorf("Expected delete by the owner to delete 1 row, got %d, %v", rowsAffected, err)
+	}
+}
+
+func TestPreviewWhereSample(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	ctx := context.Background()
+	baseRepo := NewBaseGorm[Post, uint](db)
+
+	for i := 0; i < 12; i++ {
+		if _, err := baseRepo.Create(ctx, &Post{UserID: 1, Title: fmt.Sprintf("Draft %d", i)}); err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	count, sample, err := baseRepo.PreviewWhere(ctx, []Where{{Name: "user_id", Value: 1}})
+	if err != nil {
+		t.Fatalf("Failed to preview: %v", err)
+	}
+	if count != 12 {
+		t.Errorf("Expected 12 rows to be matched, got %d", count)
+	}
+	if len(sample) != 10 || sample[0].Title != "Draft 0" {
+		t.Errorf("Expected the first 10 posts as sample, got %+v", sample)
+	}
+}
+
+func TestWithNotFoundError(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db, WithNotFoundError())
+	)
+
+	if _, err := repo.Detail(ctx, 404); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected Detail to fail with ErrNotFound, got %v", err)
+	}
+	if _, err := repo.Wheres(ctx, []Where{{Name: "email", Value: "missing@example.com"}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected Wheres to fail with ErrNotFound, got %v", err)
+	}
+
+	user, created, err := repo.FirstOrCreate(ctx, []Where{{Name: "email", Value: "new@example.com"}}, &User{Name: "New"})
+	if err != nil || !created {
+		t.Fatalf("Expected FirstOrCreate to create the missing user, got %v, %v", created, err)
+	}
+	if _, err := repo.Detail(ctx, user.ID); err != nil {
+		t.Errorf("Expected the created user to be found, got %v", err)
+	}
+}
+
+func TestStatementTemplatesQueries(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db, WithStatementTemplates(), WithNotFoundError())
+	)
+
+	if exists, err := repo.Exists(ctx, nil); err != nil || exists {
+		t.Errorf("Expected no user to exist, got %v, %v", exists, err)
+	}
+	if _, err := repo.Detail(ctx, 404); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected Detail to fail with ErrNotFound, got %v", err)
+	}
+
+	user, err := repo.Create(ctx, &User{Name: "Templated", Email: "templated@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	found, err := repo.Detail(ctx, user.ID)
+	if err != nil || found.Email != "templated@example.com" {
+		t.Errorf("Expected the created user, got %+v, %v", found, err)
+	}
+	if exists, err := repo.Exists(ctx, nil); err != nil || !exists {
+		t.Errorf("Expected a user to exist, got %v, %v", exists, err)
+	}
+	if users, err := repo.WheresList(ctx, nil, nil); err != nil || len(users) != 1 {
+		t.Errorf("Expected 1 user, got %d, %v", len(users), err)
+	}
+}
+
+func TestWithRows(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db)
+		pool = NewRowPool[User](10)
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Create(ctx, &User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	buffer := pool.Get()
+	rows, _, err := repo.List(ctx, 1, 10, nil, nil, WithRows(buffer))
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(rows) != 3 || rows[0].Name != "User 0" {
+		t.Errorf("Expected the 3 users, got %+v", rows)
+	}
+	if &rows[:1][0] != &buffer[:1][0] {
+		t.Error("Expected the users to be scanned into the given slice")
+	}
+	pool.Put(rows)
+}
+
+func TestEachAndIterate(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	for i := 0; i < 5; i++ {
+		if _, err := repo.Create(ctx, &User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	var names []string
+	if err := repo.Each(ctx, nil, nil, 2, func(user *User) error {
+		names = append(names, user.Name)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to run Each: %v", err)
+	}
+	if len(names) != 5 || names[0] != "User 0" || names[4] != "User 4" {
+		t.Errorf("Expected the 5 users by primary key, got %v", names)
+	}
+
+	names = nil
+	for user, err := range repo.Iterate(ctx, nil, []OrderBy{{Field: "name", Direction: "desc"}}, 2) {
+		if err != nil {
+			t.Fatalf("Failed to iterate: %v", err)
+		}
+		if names = append(names, user.Name); len(names) == 3 {
+			break
+		}
+	}
+	if len(names) != 3 || names[0] != "User 4" || names[2] != "User 2" {
+		t.Errorf("Expected the 3 last users by name, got %v", names)
+	}
+}
+
+func TestDetailMultiple(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx   = context.Background()
+		repo  = NewBaseGorm[User, uint](db)
+		users []*User
+	)
+
+	for i := 0; i < 3; i++ {
+		user, err := repo.Create(ctx, &User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)})
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		users = append(users, user)
+	}
+
+	rows, err := repo.DetailMultiple(ctx, []uint{users[2].ID, 404, users[0].ID})
+	if err != nil {
+		t.Fatalf("Failed to run DetailMultiple: %v", err)
+	}
+	if len(rows) != 3 || rows[0].Name != "User 2" || rows[1] != nil || rows[2].Name != "User 0" {
+		t.Errorf("Expected users 2, none and 0, got %v", rows)
+	}
+}
+
+func TestListIntoReadModel(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	type userName struct {
+		ID   uint
+		Name string
+	}
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Create(ctx, &User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	names, paginator, err := ListInto[userName](ctx, repo, 1, 2, []OrderBy{{Field: "name", Direction: "asc"}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to run ListInto: %v", err)
+	}
+	if paginator.Total != 3 || len(names) != 2 || names[0].Name != "User 0" || names[0].ID == 0 {
+		t.Errorf("Expected the first 2 of 3 user names, got %+v, %+v", names, paginator)
+	}
+}
+
+func TestAggregateHelpers(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db)
+		ids  int64
+	)
+
+	for i, name := range []string{"Alice", "Alice", "Bob"} {
+		user, err := repo.Create(ctx, &User{Name: name, Email: fmt.Sprintf("user%d@example.com", i)})
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		ids += int64(user.ID)
+	}
+
+	if sum, err := repo.SumInt64(ctx, "id", nil); err != nil || sum != ids {
+		t.Errorf("Expected the sum of the ids %d, got %d, %v", ids, sum, err)
+	}
+	if avg, err := repo.Avg(ctx, "id", []Where{{Name: "name", Value: "Nobody"}}); err != nil || avg != 0 {
+		t.Errorf("Expected 0 without rows, got %v, %v", avg, err)
+	}
+
+	var last string
+	if err := repo.Max(ctx, "name", nil, &last); err != nil || last != "Bob" {
+		t.Errorf("Expected Bob as greatest name, got %q, %v", last, err)
+	}
+
+	counts, err := repo.GroupCount(ctx, "name", nil)
+	if err != nil || counts["Alice"] != 2 || counts["Bob"] != 1 {
+		t.Errorf("Expected 2 Alice and 1 Bob, got %v, %v", counts, err)
+	}
+
+	facets, err := repo.Facets(ctx, []Where{{Name: "name", Operator: OpNe, Value: "Nobody"}}, []string{"name", "email"})
+	if err != nil {
+		t.Fatalf("Failed to count the facets: %v", err)
+	}
+	if names := facets["name"]; len(names) != 2 || names[0] != (FacetValue{Value: "Alice", Count: 2}) || names[1] != (FacetValue{Value: "Bob", Count: 1}) {
+		t.Errorf("Expected 2 Alice then 1 Bob, got %+v", names)
+	}
+	if _, ok := facets["email"]; !ok {
+		t.Error("Expected the email facet")
+	}
+}
+
+func TestWithDistinctJoin(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db)
+		user = &User{Name: "Author", Email: "author@example.com"}
+	)
+
+	if _, err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.Create(&Post{UserID: user.ID, Title: fmt.Sprintf("Post %d", i)}).Error; err != nil {
+			t.Fatalf("Failed to create post: %v", err)
+		}
+	}
+
+	join := func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id")
+	}
+
+	users, paginator, err := repo.ListCustom(ctx, 1, 10, nil, nil, join, WithDistinct())
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(users) != 1 || paginator.Total != 1 {
+		t.Errorf("Expected the author once, got %d users of %d", len(users), paginator.Total)
+	}
+}
+
+func TestListCustomWithCountGrouped(t *testing.T) {
+	db := setupTestDB(t)
+	t.Cleanup(func() { cleanupDB(t, db) })
+	cleanupDB(t, db)
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	for i := 0; i < 3; i++ {
+		user := &User{Name: fmt.Sprintf("Author %d", i), Email: fmt.Sprintf("author%d@example.com", i)}
+		if _, err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		for j := 0; j <= i; j++ {
+			if err := db.Create(&Post{UserID: user.ID, Title: fmt.Sprintf("Post %d", j)}).Error; err != nil {
+				t.Fatalf("Failed to create post: %v", err)
+			}
+		}
+	}
+
+	var (
+		grouped = func(db *gorm.DB) *gorm.DB {
+			return db.Model(&User{}).Select("dummy_users.*").
+				Joins("JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id").
+				Group("dummy_users.id")
+		}
+		count = func(db *gorm.DB) *gorm.DB {
+			authors := db.Table("dummy_users").Select("dummy_users.id").
+				Joins("JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id").
+				Group("dummy_users.id")
+			return db.Session(&gorm.Session{NewDB: true}).Table("(?) AS authors", authors)
+		}
+	)
+
+	users, paginator, err := repo.ListCustomWithCount(ctx, 1, 2, []OrderBy{{Field: "dummy_users.id"}}, nil, grouped, count)
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if len(users) != 2 || paginator.Total != 3 {
+		t.Errorf("Expected 2 of the 3 authors, got %d users of %d", len(users), paginator.Total)
+	}
+}
+
+func TestIndexAdvisorSuggestions(t *testing.T) {
+	db := setupTestDB(t)
+
+	var (
+		ctx     = context.Background()
+		advisor = NewIndexAdvisor(db)
+		repo    = NewBaseGorm[User, uint](db, WithIndexAdvisor(advisor))
+	)
+
+	if _, err := repo.Detail(ctx, 1); err != nil {
+		t.Fatalf("Failed to find user: %v", err)
+	}
+	if _, err := repo.WheresList(ctx, []OrderBy{{Field: "id"}}, nil); err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if _, err := repo.Count(ctx, []Where{{Name: "name", Value: "Alice"}}); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+
+	suggestions, err := advisor.Suggestions(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get suggestions: %v", err)
+	}
+	want := "CREATE INDEX idx_dummy_users_name ON dummy_users (name);"
+	if len(suggestions) != 1 || suggestions[0].DDL != want || suggestions[0].Reads != 1 {
+		t.Errorf("Expected the index on name only, got %+v", suggestions)
+	}
+}
@@ -0,0 +1,148 @@
+package base
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// setupDryRunDB returns a *gorm.DB that never opens a connection, for tests
+// that only need schema metadata or generated SQL.
+func setupDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/dry_run",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	return db
+}
+
+func TestDiff(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+	now := time.Now()
+
+	before := &User{ID: 1, Name: "Before", Email: "same@example.com", CreatedAt: now}
+	after := &User{ID: 1, Name: "After", Email: "same@example.com", CreatedAt: now.UTC()}
+
+	changes, err := repo.Diff(before, after)
+	if err != nil {
+		t.Fatalf("Failed to diff users: %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 changed column, got %d: %+v", len(changes), changes)
+	}
+	if change := changes["name"]; change.Old != "Before" || change.New != "After" {
+		t.Errorf("Expected name change Before -> After, got %+v", change)
+	}
+
+	if _, err := repo.Diff(nil, after); err == nil {
+		t.Error("Expected error when diffing a nil row")
+	}
+}
+
+func TestCreateMultipleInBatches(t *testing.T) {
+	var (
+		db      = setupDryRunDB(t)
+		inserts int
+	)
+	if err := db.Callback().Create().After("gorm:create").Register("test:count_inserts", func(tx *gorm.DB) {
+		inserts++
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	rows := make([]*Document, 5)
+	for i := range rows {
+		rows[i] = &Document{Title: "Imported"}
+	}
+
+	repo := NewBaseGorm[Document, uint](db, WithCreateBatchSize(2))
+	if _, _, err := repo.CreateMultiple(context.Background(), rows); err != nil {
+		t.Fatalf("Failed to create rows: %v", err)
+	}
+	if inserts != 3 {
+		t.Errorf("Expected 5 rows to be inserted in 3 batches, got %d INSERTs", inserts)
+	}
+}
+
+func BenchmarkDetail(b *testing.B) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/dry_run",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, SkipDefaultTransaction: true, DisableAutomaticPing: true})
+	if err != nil {
+		b.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	b.Run("Detail", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = repo.Detail(ctx, 1)
+		}
+	})
+	b.Run("DetailTemplate", func(b *testing.B) {
+		templated := NewBaseGorm[User, uint](db, WithStatementTemplates())
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = templated.Detail(ctx, 1)
+		}
+	})
+	b.Run("Exists", func(b *testing.B) {
+		b.ReportAllocs()
+		wheres := []Where{{Name: "email", Value: "alice@example.com"}}
+		for i := 0; i < b.N; i++ {
+			_, _ = repo.Exists(ctx, wheres)
+		}
+	})
+}
+
+func TestListCustomWithCount(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		db      = setupDryRunDB(t)
+		repo    = NewBaseGorm[User, uint](db)
+		queries []string
+		grouped = func(db *gorm.DB) *gorm.DB {
+			return db.Model(&User{}).Select("dummy_users.*, COUNT(dummy_posts.id) AS posts").
+				Joins("JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id").
+				Group("dummy_users.id")
+		}
+		count = func(db *gorm.DB) *gorm.DB {
+			authors := db.Table("dummy_users").Select("dummy_users.id").
+				Joins("JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id").
+				Group("dummy_users.id")
+			return db.Session(&gorm.Session{NewDB: true}).Table("(?) AS authors", authors)
+		}
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:capture_queries", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	// the dry run counts 0 rows, skipping the find, the subquery is run by the query callbacks first
+	if _, _, err := repo.ListCustomWithCount(ctx, 2, 10, nil, []Where{{Name: "name", Value: "Alice"}}, grouped, count); err != nil {
+		t.Fatalf("Failed to run ListCustomWithCount: %v", err)
+	}
+	want := "SELECT count(*) FROM (SELECT dummy_users.id FROM `dummy_users` JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id WHERE name = ? GROUP BY `dummy_users`.`id`) AS authors"
+	if len(queries) != 2 || queries[1] != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%v", want, queries)
+	}
+}
@@ -0,0 +1,109 @@
+package base
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Exists reports whether a row matches wheres, with a SELECT 1 ... LIMIT 1 which
+// stops at the first match.
+func (o *BaseGorm[T, PkType]) Exists(ctx context.Context, wheres []Where, opts ...QueryOption) (bool, error) {
+	if err := o.checkColumns(wheres, nil, opts...); err != nil {
+		o.logError(ctx, err)
+		return false, err
+	}
+	o.adviseIndex(wheres, nil)
+
+	found, err := hedgedRead(ctx, o, func(db *gorm.DB) (bool, error) {
+		var (
+			e     T
+			found []int
+		)
+
+		if len(wheres) == 0 && len(opts) == 0 && o.templates != nil && o.templates.exists != nil {
+			db = o.templates.exists.bind(db)
+		} else {
+			db = newQueryOptions(opts).scope(db.Model(&e).Table(o.table)).Select("1").Limit(1)
+			for _, v := range wheres {
+				db.Where(v.StringFor(db), v.Args()...)
+			}
+		}
+
+		err := db.Find(&found).Error
+		return len(found) > 0, err
+	})
+
+	// logged here rather than in a deferred closure, Exists being a hot path
+	if err != nil {
+		o.logError(ctx, err)
+		return false, err
+	}
+
+	return found, nil
+}
+
+// Count returns the number of rows matching wheres.
+func (o *BaseGorm[T, PkType]) Count(ctx context.Context, wheres []Where, opts ...QueryOption) (int64, error) {
+	var (
+		count int64
+		err   error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	if err = o.checkColumns(wheres, nil, opts...); err != nil {
+		return 0, err
+	}
+	o.adviseIndex(wheres, nil)
+	count, err = hedgedRead(ctx, o, func(db *gorm.DB) (int64, error) {
+		var (
+			e     T
+			count int64
+		)
+
+		db = newQueryOptions(opts).scope(db.Model(&e).Table(e.TableName()))
+		for _, v := range wheres {
+			db.Where(v.StringFor(db), v.Args()...)
+		}
+
+		err := db.Count(&count).Error
+		return count, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Pluck scans the values of column of the rows matching wheres into dest, a pointer
+// to a slice, e.g. a *[]string for the emails of users, instead of loading the rows.
+func (o *BaseGorm[T, PkType]) Pluck(ctx context.Context, column string, wheres []Where, dest interface{}, opts ...QueryOption) error {
+	var (
+		e   T
+		db  = newQueryOptions(opts).scope(o.conn(ctx).Model(&e).Table(o.table))
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	if err = o.checkColumns(append([]Where{{Name: column}}, wheres...), nil, opts...); err != nil {
+		return err
+	}
+
+	for _, v := range wheres {
+		db.Where(v.StringFor(db), v.Args()...)
+	}
+
+	err = db.Pluck(column, dest).Error
+	return err
+}
@@ -0,0 +1,55 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExistsAndCount(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		db     = setupDryRunDB(t)
+		sql    = captureSQL(t, db)
+		repo   = NewBaseGorm[Document, uint](db)
+		wheres = []Where{{Name: "title", Value: "Draft"}}
+	)
+
+	if _, err := repo.Exists(ctx, wheres); err != nil {
+		t.Fatalf("Failed to run Exists: %v", err)
+	}
+	want := "SELECT 1 FROM `documents` WHERE title = ? AND `documents`.`deleted_at` IS NULL LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, err := repo.Count(ctx, wheres); err != nil {
+		t.Fatalf("Failed to run Count: %v", err)
+	}
+	want = "SELECT count(*) FROM `documents` WHERE title = ? AND `documents`.`deleted_at` IS NULL"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
+
+func TestPluck(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		db     = setupDryRunDB(t)
+		sql    = captureSQL(t, db)
+		repo   = NewBaseGorm[Document, uint](db, WithColumnAllowList())
+		titles []string
+	)
+
+	if err := repo.Pluck(ctx, "title", []Where{{Name: "id", Operator: OpGt, Value: 10}}, &titles); err != nil {
+		t.Fatalf("Failed to run Pluck: %v", err)
+	}
+	want := "SELECT `title` FROM `documents` WHERE id > ? AND `documents`.`deleted_at` IS NULL"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if err := repo.Pluck(ctx, "password", nil, &titles); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn for a column not in the allow list, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package base
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Decimal is an exact fixed point number for DECIMAL columns such as money amounts.
+// It scans DECIMAL values without going through float64, and is written as a string,
+// so declare the column type on the field : `gorm:"type:decimal(20,4)"`.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// NewDecimal returns unscaled * 10^-scale, e.g. NewDecimal(1999, 2) is 19.99.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal parses a decimal string such as "-1234.5600".
+func ParseDecimal(s string) (Decimal, error) {
+	var (
+		digits = strings.TrimSpace(s)
+		scale  int32
+	)
+
+	intPart, fr, hasFraction := strings.Cut(digits, ".")
+	if hasFraction {
+		scale = int32(len(fr))
+		digits = intPart + fr
+	}
+
+	unscaled, ok := new(big.Int).SetString(digits, 10)
+	if !ok || (hasFraction && (fr == "" || strings.ContainsAny(fr, "+-"))) {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	return Decimal{unscaled: unscaled, scale: scale}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+
+	return d.unscaled
+}
+
+// rescale returns the unscaled value of d expressed with the given larger scale.
+func (d Decimal) rescale(scale int32) *big.Int {
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-d.scale)), nil)
+	return factor.Mul(factor, d.int())
+}
+
+// Add returns d + other with the larger of both scales.
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return Decimal{unscaled: new(big.Int).Add(d.rescale(scale), other.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - other with the larger of both scales.
+func (d Decimal) Sub(other Decimal) Decimal {
+	return d.Add(other.Neg())
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Cmp compares d and other and returns -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// IsZero reports whether d equals 0.
+func (d Decimal) IsZero() bool {
+	return d.int().Sign() == 0
+}
+
+// String formats d with its scale, e.g. "-12.50".
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+	if d.int().Sign() < 0 {
+		digits = "-" + digits
+	}
+
+	return digits
+}
+
+// Value implements driver.Valuer, sending the exact string representation.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (d *Decimal) Scan(value interface{}) (err error) {
+	switch v := value.(type) {
+	case nil:
+		*d = Decimal{}
+	case []byte:
+		*d, err = ParseDecimal(string(v))
+	case string:
+		*d, err = ParseDecimal(v)
+	case int64:
+		*d = NewDecimal(v, 0)
+	case float64:
+		// only reached when the driver already converted the column to float
+		*d, err = ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		err = fmt.Errorf("cannot scan %T into Decimal", value)
+	}
+
+	return err
+}
+
+// MarshalJSON encodes d as a JSON string to keep its precision.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts both JSON strings and numbers, and leaves d unchanged for
+// null, like the decoding of the other types.
+func (d *Decimal) UnmarshalJSON(data []byte) (err error) {
+	if string(data) == "null" {
+		return nil
+	}
+
+	*d, err = ParseDecimal(strings.Trim(string(data), `"`))
+	return err
+}
+
+// Increment atomically adds delta to column of the row with the given primary key,
+// computing the new value in the database instead of read-modify-write in Go.
+// delta may be a Decimal or any numeric value. column must be a column of T.
+func (o *BaseGorm[T, PkType]) Increment(ctx context.Context, id PkType, column string, delta interface{}) (int64, error) {
+	var (
+		e       T
+		db      = o.conn(ctx).Table(e.TableName())
+		skipped []string
+		err     error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	o.forgetIdentities(ctx)
+
+	// column is written in the SQL as is
+	field, err := o.resolveColumn(column)
+	if err != nil {
+		return 0, err
+	}
+	if err = o.authorizeID(ctx, ActionUpdate, id, false); err != nil {
+		return 0, err
+	}
+	// a stripped column leaves nothing to update
+	if skipped, err = o.checkWrite(ctx, []string{column}); err != nil || len(skipped) > 0 {
+		return 0, err
+	}
+
+	result := db.
+		Where(fmt.Sprintf("%s = ?", e.PrimaryKey()), id).
+		Update(field.DBName, gorm.Expr(fmt.Sprintf("%s + ?", field.DBName), delta))
+	err = result.Error
+
+	return result.RowsAffected, err
+}
+
+// SumDecimal returns the exact SUM of a DECIMAL column, 0 when no row matches.
+func (o *BaseGorm[T, PkType]) SumDecimal(ctx context.Context, column string, wheres []Where) (Decimal, error) {
+	var sum Decimal
+
+	err := o.aggregate(ctx, column, wheres, func(db *gorm.DB) error {
+		return db.Select(fmt.Sprintf("COALESCE(SUM(%s), 0)", column)).Scan(&sum).Error
+	})
+
+	return sum, err
+}
@@ -0,0 +1,79 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+	cases := []struct {
+		a, b string
+		sum  string
+		diff string
+	}{
+		{"0.1", "0.2", "0.3", "-0.1"},
+		{"19.99", "0.01", "20.00", "19.98"},
+		{"-1.5", "1.25", "-0.25", "-2.75"},
+		{"1000000000000000000.0001", "1", "1000000000000000001.0001", "999999999999999999.0001"},
+	}
+
+	for _, c := range cases {
+		a, b := MustParseDecimal(c.a), MustParseDecimal(c.b)
+		if got := a.Add(b).String(); got != c.sum {
+			t.Errorf("%s + %s = %s, want %s", c.a, c.b, got, c.sum)
+		}
+		if got := a.Sub(b).String(); got != c.diff {
+			t.Errorf("%s - %s = %s, want %s", c.a, c.b, got, c.diff)
+		}
+	}
+
+	var scanned Decimal
+	if err := scanned.Scan([]byte("12.3400")); err != nil || scanned.String() != "12.3400" {
+		t.Errorf("Expected to scan 12.3400, got %s (%v)", scanned, err)
+	}
+	if scanned.Cmp(NewDecimal(1234, 2)) != 0 {
+		t.Errorf("Expected 12.3400 to equal 12.34")
+	}
+
+	if _, err := ParseDecimal("1.2.3"); err == nil {
+		t.Error("Expected error parsing 1.2.3")
+	}
+
+	var decoded struct{ Amount Decimal }
+	if err := json.Unmarshal([]byte(`{"Amount": 0.07}`), &decoded); err != nil || decoded.Amount.String() != "0.07" {
+		t.Errorf("Expected to decode 0.07, got %s (%v)", decoded.Amount, err)
+	}
+}
+
+func TestDecimalNullJSON(t *testing.T) {
+	decoded := struct {
+		Amount   Decimal
+		Discount *Decimal
+	}{Amount: NewDecimal(5, 0)}
+
+	if err := json.Unmarshal([]byte(`{"Amount": null, "Discount": null}`), &decoded); err != nil {
+		t.Fatalf("Expected null to decode, got %v", err)
+	}
+	if decoded.Amount.String() != "5" || decoded.Discount != nil {
+		t.Errorf("Expected null to leave the decimals unchanged, got %s and %v", decoded.Amount, decoded.Discount)
+	}
+}
+
+func TestIncrementChecksColumn(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+
+	statements, err := repo.SQLOf(context.Background(), func(repo *BaseGorm[User, uint]) error {
+		_, err := repo.Increment(context.Background(), 1, "Name", 1)
+		return err
+	})
+	if err != nil || len(statements) != 1 || !strings.Contains(statements[0], "`name`=name + 1") {
+		t.Errorf("Expected the field name resolved to its column, got %q, %v", statements, err)
+	}
+
+	if _, err := repo.Increment(context.Background(), 1, "name = 0, email", 1); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn, got %v", err)
+	}
+}
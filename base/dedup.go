@@ -0,0 +1,142 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// CreateMultipleDedup inserts the rows whose natural key, the values of keyColumns,
+// is not stored yet, e.g. for idempotent sync jobs. Existing keys are looked up with
+// one query, and rows repeating a key of an earlier row are skipped too. It returns
+// the inserted and the skipped rows. A unique index on keyColumns makes concurrent
+// imports fail instead of inserting duplicates.
+func (o *BaseGorm[T, PkType]) CreateMultipleDedup(ctx context.Context, rows []*T, keyColumns []string) (inserted []*T, skipped []*T, err error) {
+	var (
+		e T
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
+	if len(keyColumns) == 0 {
+		err = fmt.Errorf("dedup of %s requires key columns", e.TableName())
+		return nil, nil, err
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := make([]*schema.Field, len(keyColumns))
+	for i, column := range keyColumns {
+		if fields[i] = sch.LookUpField(column); fields[i] == nil || fields[i].DBName == "" {
+			err = fmt.Errorf("key column %s not found in %s", column, sch.Table)
+			return nil, nil, err
+		}
+	}
+
+	keys := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		keys[i] = make([]interface{}, len(fields))
+		for j, field := range fields {
+			keys[i][j], _ = field.ValueOf(ctx, reflect.ValueOf(row).Elem())
+		}
+	}
+
+	existing, err := o.existingKeys(ctx, fields, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, row := range rows {
+		key := naturalKey(keys[i])
+		if existing[key] {
+			skipped = append(skipped, row)
+			continue
+		}
+		existing[key] = true
+		inserted = append(inserted, row)
+	}
+
+	if inserted, _, err = o.CreateMultiple(ctx, inserted); err != nil {
+		return nil, skipped, err
+	}
+
+	return inserted, skipped, nil
+}
+
+// existingKeys returns the natural keys among keys already stored.
+func (o *BaseGorm[T, PkType]) existingKeys(ctx context.Context, fields []*schema.Field, keys [][]interface{}) (map[string]bool, error) {
+	var (
+		e       T
+		columns = make([]string, len(fields))
+		stored  []map[string]interface{}
+	)
+	for i, field := range fields {
+		columns[i] = field.DBName
+	}
+
+	tuple := strings.Join(columns, ", ")
+	if len(columns) > 1 {
+		tuple = "(" + tuple + ")"
+	}
+
+	var values interface{} = keys
+	if len(columns) == 1 {
+		single := make([]interface{}, len(keys))
+		for i, key := range keys {
+			single[i] = key[0]
+		}
+		values = single
+	}
+
+	if err := o.conn(ctx).
+		Table(e.TableName()).
+		Select(columns).
+		Where(fmt.Sprintf("%s IN ?", tuple), values).
+		Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(stored))
+	for _, row := range stored {
+		key := make([]interface{}, len(columns))
+		for i, column := range columns {
+			key[i] = row[column]
+		}
+		existing[naturalKey(key)] = true
+	}
+
+	return existing, nil
+}
+
+// naturalKey formats key values so that equal keys read from rows and from the
+// database give the same string.
+func naturalKey(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		if v := reflect.Indirect(reflect.ValueOf(value)); v.IsValid() {
+			value = v.Interface()
+		}
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		parts[i] = fmt.Sprint(value)
+	}
+
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,46 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCreateMultipleDedup(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[User, uint](db)
+		rows = []*User{
+			{Name: "Alice", Email: "alice@example.com"},
+			{Name: "Bob", Email: "bob@example.com"},
+			{Name: "Alice again", Email: "alice@example.com"},
+		}
+	)
+
+	inserted, skipped, err := repo.CreateMultipleDedup(context.Background(), rows, []string{"email"})
+	if err != nil {
+		t.Fatalf("Failed to create users: %v", err)
+	}
+
+	want := "SELECT email FROM `dummy_users` WHERE email IN (?,?,?)"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+	if len(inserted) != 2 || len(skipped) != 1 || skipped[0] != rows[2] {
+		t.Errorf("Expected the repeated email to be skipped, got %d inserted and %d skipped", len(inserted), len(skipped))
+	}
+
+	if _, _, err := repo.CreateMultipleDedup(context.Background(), rows, []string{"unknown"}); err == nil {
+		t.Error("Expected error for an unknown key column")
+	}
+}
+
+func TestNaturalKey(t *testing.T) {
+	name := "alice"
+	if naturalKey([]interface{}{&name, 1}) != naturalKey([]interface{}{[]byte("alice"), int64(1)}) {
+		t.Error("Expected equal keys for equal values of different types")
+	}
+	if naturalKey([]interface{}{nil, "a"}) == naturalKey([]interface{}{"a", nil}) {
+		t.Error("Expected key order to matter")
+	}
+}
@@ -0,0 +1,80 @@
+package base
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DetailMultiple finds the rows with the given primary keys in one IN query, in the
+// order of ids, a nil row standing for a missing one. The rows already loaded in the
+// identity map of ctx, see ContextWithIdentityMap, are not queried again, and the
+// queried ones are added to it, unless query options are given.
+func (o *BaseGorm[T, PkType]) DetailMultiple(ctx context.Context, ids []PkType, opts ...QueryOption) ([]*T, error) {
+	var (
+		e       T
+		found   = make(map[PkType]*T, len(ids))
+		missing = make([]PkType, 0, len(ids))
+		err     error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	for _, id := range ids {
+		if _, ok := found[id]; ok {
+			continue
+		}
+		if row := o.identityOf(ctx, id); row != nil && len(opts) == 0 {
+			found[id] = row
+			continue
+		}
+		found[id] = nil
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		var rows []T
+		rows, err = hedgedRead(ctx, o, func(db *gorm.DB) ([]T, error) {
+			var rows []T
+			err := newQueryOptions(opts).apply(db.Table(o.table)).Where(fmt.Sprintf("%s IN ?", e.PrimaryKey()), missing).Find(&rows).Error
+			return rows, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		o.afterFind(ctx, rows)
+		var sch *schema.Schema
+		if sch, err = o.schema(); err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			var id PkType
+			if id, err = o.primaryKeyOf(ctx, sch, &rows[i]); err != nil {
+				return nil, err
+			}
+			found[id] = &rows[i]
+			if len(opts) == 0 {
+				o.remember(ctx, id, &rows[i])
+			}
+		}
+	}
+
+	result := make([]*T, len(ids))
+	for i, id := range ids {
+		if row := found[id]; row != nil {
+			if err = o.authorize(ctx, ActionRead, row); err != nil {
+				return nil, err
+			}
+			result[i] = row
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetailMultipleQueriesMissingIDs(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[User, uint](db)
+		ctx  = ContextWithIdentityMap(context.Background())
+	)
+
+	loaded, err := repo.Detail(ctx, 2)
+	if err != nil {
+		t.Fatalf("Failed to run Detail: %v", err)
+	}
+
+	rows, err := repo.DetailMultiple(ctx, []uint{3, 2, 1, 3})
+	if err != nil {
+		t.Fatalf("Failed to run DetailMultiple: %v", err)
+	}
+	want := "SELECT * FROM `dummy_users` WHERE id IN (?,?)"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+	// the dry run finds no row
+	if len(rows) != 4 || rows[0] != nil || rows[1] != loaded || rows[2] != nil || rows[3] != nil {
+		t.Errorf("Expected the loaded row only, in the order of the ids, got %v", rows)
+	}
+}
@@ -0,0 +1,40 @@
+package base
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The names of the dialects Where renders differently, as returned by the Name of
+// gorm's dialectors.
+const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+)
+
+// like returns the LIKE operator of dialect, case insensitive on Postgres.
+func like(dialect string) string {
+	if dialect == DialectPostgres {
+		return "ILIKE"
+	}
+
+	return "LIKE"
+}
+
+// fullTextSearch returns the full text condition on the comma separated columns
+// of dialect.
+func fullTextSearch(dialect string, columns string) string {
+	if dialect != DialectPostgres {
+		return fmt.Sprintf("MATCH(%s) AGAINST (? IN BOOLEAN MODE)", columns)
+	}
+
+	document := columns
+	if names := strings.Split(columns, ","); len(names) > 1 {
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		document = fmt.Sprintf("concat_ws(' ', %s)", strings.Join(names, ", "))
+	}
+
+	return fmt.Sprintf("to_tsvector(%s) @@ plainto_tsquery(?)", document)
+}
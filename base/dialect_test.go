@@ -0,0 +1,62 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// postgresDialector reports the postgres dialect while building MySQL SQL, enough
+// to check the dialect specific conditions without a Postgres driver.
+type postgresDialector struct {
+	gorm.Dialector
+}
+
+func (postgresDialector) Name() string {
+	return DialectPostgres
+}
+
+func TestWhereStringFor(t *testing.T) {
+	db, err := gorm.Open(postgresDialector{mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/dry_run",
+		SkipInitializeWithVersion: true,
+	})}, &gorm.Config{DryRun: true, SkipDefaultTransaction: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	tests := []struct {
+		where    Where
+		mysql    string
+		postgres string
+	}{
+		{Where{Name: "name", IsLike: true, Value: "ware"}, "name LIKE ?", "name ILIKE ?"},
+		{Where{Name: "body", IsFullTextSearch: true, Value: "gorm"}, "MATCH(body) AGAINST (? IN BOOLEAN MODE)", "to_tsvector(body) @@ plainto_tsquery(?)"},
+		{Where{Name: "title,body", IsFullTextSearch: true, Value: "gorm"}, "MATCH(title,body) AGAINST (? IN BOOLEAN MODE)", "to_tsvector(concat_ws(' ', title, body)) @@ plainto_tsquery(?)"},
+		{
+			Where{Group: &WhereGroup{Or: true, Wheres: []Where{{Name: "name", IsLike: true, Value: "ware"}, {Name: "id", Value: 1}}}},
+			"(name LIKE ? OR id = ?)",
+			"(name ILIKE ? OR id = ?)",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.where.String(); got != tt.mysql {
+			t.Errorf("Expected MySQL condition %q, got %q", tt.mysql, got)
+		}
+		if got := tt.where.StringFor(db); got != tt.postgres {
+			t.Errorf("Expected Postgres condition %q, got %q", tt.postgres, got)
+		}
+	}
+
+	sql := captureSQL(t, db)
+	repo := NewBaseGorm[User, uint](db)
+	if _, err := repo.WheresList(context.Background(), nil, []Where{{Name: "name", IsLike: true, Value: "ali"}}); err != nil {
+		t.Fatalf("Failed to run WheresList: %v", err)
+	}
+	if want := "SELECT * FROM `dummy_users` WHERE name ILIKE ?"; *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
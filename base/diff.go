@@ -0,0 +1,100 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Change holds the stored and the new value of a single column.
+type Change struct {
+	Old interface{}
+	New interface{}
+}
+
+// ChangeListener receives the changed columns of a row after it was updated.
+type ChangeListener = func(ctx context.Context, table string, changes map[string]Change)
+
+// Diff compares oldRow and newRow field by field using gorm's schema metadata of T,
+// and returns the changed columns keyed by column name. Associations are ignored.
+func (o *BaseGorm[T, PkType]) Diff(oldRow, newRow *T) (map[string]Change, error) {
+	if oldRow == nil || newRow == nil {
+		return nil, errors.New("diff requires two non nil rows")
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ctx      = context.Background()
+		oldValue = reflect.ValueOf(oldRow).Elem()
+		newValue = reflect.ValueOf(newRow).Elem()
+		changes  = map[string]Change{}
+	)
+
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.PrimaryKey {
+			continue
+		}
+
+		before, _ := field.ValueOf(ctx, oldValue)
+		after, _ := field.ValueOf(ctx, newValue)
+		if !valuesEqual(before, after) {
+			changes[field.DBName] = Change{Old: before, New: after}
+		}
+	}
+
+	return changes, nil
+}
+
+// valuesEqual compares two field values, treating equal instants in different
+// locations as the same time.
+func valuesEqual(a, b interface{}) bool {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Equal(tb)
+		}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// changedColumns returns the columns of changes in a stable order.
+func changedColumns(changes map[string]Change) []string {
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	return columns
+}
+
+// changesOf diffs row against its currently stored version.
+func (o *BaseGorm[T, PkType]) changesOf(ctx context.Context, row *T) (map[string]Change, error) {
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := o.primaryKeyOf(ctx, sch, row)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := o.Detail(ctx, pk)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return o.Diff(stored, row)
+}
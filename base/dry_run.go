@@ -0,0 +1,59 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLOf runs fn with a repository bound to a dry run session of the database and
+// returns the SQL of every statement it built, values inlined, in order. Nothing
+// is sent to the database, e.g. to log or assert the queries of List, Wheres or
+// UpdateWhere in a test. A dry run count is 0, so List reports no total but still
+// builds its find. Reads scanning raw rows, e.g. aggregates, stop at their first
+// statement : gorm.ErrDryRunModeUnsupported is not returned, the other errors of
+// fn are, with the statements built until then.
+func (o *BaseGorm[T, PkType]) SQLOf(ctx context.Context, fn func(repo *BaseGorm[T, PkType]) error) ([]string, error) {
+	var (
+		recorder = &sqlRecorder{Interface: o.conn(ctx).Logger}
+		dryRun   = o.conn(ctx).Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true, Logger: recorder})
+	)
+
+	err := fn(o.WithTx(dryRun))
+	if errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		err = nil
+	}
+
+	return recorder.statements(), err
+}
+
+// sqlRecorder is the logger of a SQLOf session, recording the SQL gorm traces
+// after each statement.
+type sqlRecorder struct {
+	logger.Interface
+	mu  sync.Mutex
+	sql []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface {
+	return r
+}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sql = append(r.sql, sql)
+}
+
+func (r *sqlRecorder) statements() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.sql...)
+}
@@ -0,0 +1,60 @@
+package base
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestSQLOf(t *testing.T) {
+	// not a dry run database : nothing listens on port 1, any statement sent fails
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/sql_of",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	var (
+		ctx    = context.Background()
+		repo   = NewBaseGorm[User, uint](db)
+		wheres = []Where{{Name: "name", Value: "alice"}}
+	)
+
+	statements, err := repo.SQLOf(ctx, func(repo *BaseGorm[User, uint]) error {
+		if _, _, err := repo.List(ctx, 2, 10, []OrderBy{{Field: "id", Direction: "desc"}}, wheres); err != nil {
+			return err
+		}
+		_, err := repo.UpdateWhere(ctx, wheres, map[string]interface{}{"email": "alice@example.com"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to build the SQL: %v", err)
+	}
+
+	want := []string{
+		"SELECT count(*) FROM `dummy_users` WHERE name = 'alice'",
+		"SELECT * FROM `dummy_users` WHERE name = 'alice' ORDER BY id desc LIMIT 10 OFFSET 10",
+		"UPDATE `dummy_users` SET `email`='alice@example.com'",
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("Expected %d statements, got %q", len(want), statements)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(statements[i], prefix) {
+			t.Errorf("Statement %d: expected %q, got %q", i, prefix, statements[i])
+		}
+	}
+
+	statements, err = repo.SQLOf(ctx, func(repo *BaseGorm[User, uint]) error {
+		_, err := repo.SumInt64(ctx, "id", wheres)
+		return err
+	})
+	if err != nil || len(statements) != 1 || !strings.Contains(statements[0], "SUM") {
+		t.Errorf("Expected the SUM statement without error, got %q, %v", statements, err)
+	}
+}
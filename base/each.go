@@ -0,0 +1,110 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errStopIteration stops Each when the loop over Iterate breaks.
+var errStopIteration = errors.New("iteration stopped")
+
+// Each calls fn with every row matching wheres, loading batchSize rows at a time,
+// 1000 by default, so large results are streamed instead of held in memory like
+// WheresList does. Without orders the rows are read by primary key, each batch
+// starting after the last key of the previous one; with orders the batches are
+// pages of growing offset, ordered by the primary key last. The row given to fn is
+// overwritten by the next batch, copy it to keep it. Iteration stops at the first
+// error returned by fn.
+func (o *BaseGorm[T, PkType]) Each(ctx context.Context, wheres []Where, orders []OrderBy, batchSize int, fn func(row *T) error, opts ...QueryOption) error {
+	err := o.each(ctx, wheres, orders, batchSize, fn, opts)
+	if err != nil {
+		o.logError(ctx, err)
+	}
+
+	return err
+}
+
+// Iterate returns the rows matching wheres as a range-over-func iterator, read by
+// batches like Each. A failing batch ends the iteration with a nil row and its error.
+func (o *BaseGorm[T, PkType]) Iterate(ctx context.Context, wheres []Where, orders []OrderBy, batchSize int, opts ...QueryOption) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := o.each(ctx, wheres, orders, batchSize, func(row *T) error {
+			if !yield(row, nil) {
+				return errStopIteration
+			}
+			return nil
+		}, opts)
+		if err != nil && !errors.Is(err, errStopIteration) {
+			o.logError(ctx, err)
+			yield(nil, err)
+		}
+	}
+}
+
+func (o *BaseGorm[T, PkType]) each(ctx context.Context, wheres []Where, orders []OrderBy, batchSize int, fn func(row *T) error, opts []QueryOption) (err error) {
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	var (
+		e    T
+		db   = newQueryOptions(opts).apply(o.conn(ctx).Model(&e).Table(o.table))
+		rows []T
+	)
+
+	if err := o.checkColumns(wheres, orders, opts...); err != nil {
+		return err
+	}
+	o.adviseIndex(wheres, orders)
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	rows = make([]T, 0, batchSize)
+
+	for _, v := range wheres {
+		db.Where(v.StringFor(db), v.Args()...)
+	}
+
+	if len(orders) == 0 {
+		return db.FindInBatches(&rows, batchSize, func(tx *gorm.DB, batch int) error {
+			return o.eachRow(ctx, rows, fn)
+		}).Error
+	}
+
+	for _, order := range orders {
+		orderByStr := order.String()
+		if orderByStr != "" {
+			db.Order(orderByStr)
+		}
+	}
+	// the primary key breaks the ties, so no row moves between two pages
+	db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}}).
+		Session(&gorm.Session{})
+
+	for offset := 0; ; offset += batchSize {
+		if err := db.Offset(offset).Limit(batchSize).Find(&rows).Error; err != nil {
+			return err
+		}
+		if err := o.eachRow(ctx, rows, fn); err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+	}
+}
+
+// eachRow calls fn with every row of a batch.
+func (o *BaseGorm[T, PkType]) eachRow(ctx context.Context, rows []T, fn func(row *T) error) error {
+	o.afterFind(ctx, rows)
+	for i := range rows {
+		if err := fn(&rows[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
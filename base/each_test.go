@@ -0,0 +1,32 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEachBatches(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Document, uint](db)
+		none = func(*Document) error { return nil }
+	)
+
+	if err := repo.Each(ctx, []Where{{Name: "title", Value: "Draft"}}, nil, 100, none); err != nil {
+		t.Fatalf("Failed to run Each: %v", err)
+	}
+	want := "SELECT * FROM `documents` WHERE title = ? AND `documents`.`deleted_at` IS NULL ORDER BY `documents`.`id` LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if err := repo.Each(ctx, nil, []OrderBy{{Field: "title", Direction: "desc"}}, 100, none); err != nil {
+		t.Fatalf("Failed to run Each: %v", err)
+	}
+	want = "SELECT * FROM `documents` WHERE `documents`.`deleted_at` IS NULL ORDER BY title desc,`documents`.`id` LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
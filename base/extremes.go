@@ -0,0 +1,61 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// MaxBy returns the row matching wheres having the greatest value of column, nil
+// when none matches. Ties are broken by the greatest primary key. The query is an
+// ORDER BY column DESC LIMIT 1, which an index on the where columns followed by
+// column resolves without sorting.
+func (o *BaseGorm[T, PkType]) MaxBy(ctx context.Context, column string, wheres []Where) (*T, error) {
+	return o.extremeBy(ctx, column, wheres, "DESC")
+}
+
+// MinBy returns the row matching wheres having the smallest non NULL value of column,
+// nil when none matches. Ties are broken by the smallest primary key.
+func (o *BaseGorm[T, PkType]) MinBy(ctx context.Context, column string, wheres []Where) (*T, error) {
+	return o.extremeBy(ctx, column, wheres, "ASC")
+}
+
+func (o *BaseGorm[T, PkType]) extremeBy(ctx context.Context, column string, wheres []Where, direction string) (*T, error) {
+	var (
+		row T
+		db  = o.conn(ctx).Table(row.TableName())
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	// column is the order column too
+	if err = o.checkColumns(append([]Where{{Name: column}}, wheres...), nil); err != nil {
+		return nil, err
+	}
+	for _, v := range wheres {
+		db = db.Where(v.StringFor(db), v.Args()...)
+	}
+
+	// NULL sorts first in ascending order
+	err = db.
+		Where(fmt.Sprintf("%s IS NOT NULL", column)).
+		Order(fmt.Sprintf("%s %s, %s %s", column, direction, row.PrimaryKey(), direction)).
+		Take(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	o.afterFindRow(ctx, &row)
+
+	return &row, nil
+}
@@ -0,0 +1,48 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// captureSQL records the SQL of the last statement run on db.
+func captureSQL(t *testing.T, db *gorm.DB) *string {
+	t.Helper()
+
+	var (
+		sql     string
+		capture = func(tx *gorm.DB) {
+			sql = tx.Statement.SQL.String()
+		}
+		callbacks = db.Callback()
+		errs      = []error{
+			callbacks.Query().After("gorm:query").Register("test:capture_sql", capture),
+			callbacks.Update().After("gorm:update").Register("test:capture_sql", capture),
+			callbacks.Delete().After("gorm:delete").Register("test:capture_sql", capture),
+		}
+	)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to register callback: %v", err)
+		}
+	}
+
+	return &sql
+}
+
+func TestMaxBy(t *testing.T) {
+	db := setupDryRunDB(t)
+	sql := captureSQL(t, db)
+	repo := NewBaseGorm[User, uint](db)
+
+	if _, err := repo.MaxBy(context.Background(), "created_at", []Where{{Name: "name", Value: "Alice"}}); err != nil {
+		t.Fatalf("Failed to run MaxBy: %v", err)
+	}
+
+	want := "SELECT * FROM `dummy_users` WHERE name = ? AND created_at IS NOT NULL ORDER BY created_at DESC, id DESC LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
@@ -0,0 +1,73 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FacetValue is one value of a facet column with the number of rows holding it.
+type FacetValue struct {
+	Value  string `json:"value"`
+	IsNull bool   `json:"is_null"`
+	Count  int64  `json:"count"`
+}
+
+// Facets counts the rows matching wheres by value of each of facetColumns, in a
+// single UNION ALL query, e.g. for the sidebar of a list screen. The values are
+// read as strings, the most frequent first, and every column has an entry, empty
+// when no row matches.
+func (o *BaseGorm[T, PkType]) Facets(ctx context.Context, wheres []Where, facetColumns []string) (map[string][]FacetValue, error) {
+	var (
+		e       T
+		db      = o.conn(ctx)
+		facets  = make(map[string][]FacetValue, len(facetColumns))
+		queries []interface{}
+		rows    []struct {
+			Column string         `gorm:"column:facet_column"`
+			Value  sql.NullString `gorm:"column:facet_value"`
+			Count  int64          `gorm:"column:facet_count"`
+		}
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	for _, column := range facetColumns {
+		if _, ok := facets[column]; ok {
+			continue
+		}
+		if err = o.checkColumns(append([]Where{{Name: column}}, wheres...), nil); err != nil {
+			return nil, err
+		}
+		facets[column] = []FacetValue{}
+
+		query := db.Session(&gorm.Session{NewDB: true}).Model(&e).Table(o.table).
+			Select("? AS facet_column, CAST("+column+" AS CHAR) AS facet_value, COUNT(*) AS facet_count", column).
+			Group(column)
+		for _, v := range wheres {
+			query.Where(v.StringFor(query), v.Args()...)
+		}
+		queries = append(queries, query)
+	}
+	if len(queries) == 0 {
+		return facets, nil
+	}
+
+	union := strings.TrimSuffix(strings.Repeat("(?) UNION ALL ", len(queries)), " UNION ALL ")
+	if err = db.Raw(union+" ORDER BY facet_column, facet_count DESC, facet_value", queries...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		facets[row.Column] = append(facets[row.Column], FacetValue{Value: row.Value.String, IsNull: !row.Value.Valid, Count: row.Count})
+	}
+
+	return facets, nil
+}
@@ -0,0 +1,34 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFacetsSQL(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		repo = NewBaseGorm[Document, uint](setupDryRunDB(t))
+	)
+
+	statements, err := repo.SQLOf(ctx, func(repo *BaseGorm[Document, uint]) error {
+		_, err := repo.Facets(ctx, []Where{{Name: "id", Operator: OpGt, Value: 3}}, []string{"title", "user_id", "title"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to build the facets: %v", err)
+	}
+
+	want := "(SELECT 'title' AS facet_column, CAST(title AS CHAR) AS facet_value, COUNT(*) AS facet_count FROM `documents` WHERE id > 3 AND `documents`.`deleted_at` IS NULL GROUP BY `title`)" +
+		" UNION ALL " +
+		"(SELECT 'user_id' AS facet_column, CAST(user_id AS CHAR) AS facet_value, COUNT(*) AS facet_count FROM `documents` WHERE id > 3 AND `documents`.`deleted_at` IS NULL GROUP BY `user_id`)" +
+		" ORDER BY facet_column, facet_count DESC, facet_value"
+	if len(statements) == 0 || statements[len(statements)-1] != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%q", want, statements)
+	}
+
+	facets, err := repo.Facets(ctx, nil, nil)
+	if err != nil || len(facets) != 0 {
+		t.Errorf("Expected no facet without columns, got %v, %v", facets, err)
+	}
+}
@@ -0,0 +1,103 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// UpdateWithFieldMask updates the columns of row selected by a protobuf FieldMask,
+// following AIP-134 : paths use the JSON (snake_case) field names, nested embedded
+// structs are addressed with dots ("address.city" or "address" for all of its fields),
+// "*" replaces every updatable field and an empty mask updates the populated fields.
+func (o *BaseGorm[T, PkType]) UpdateWithFieldMask(ctx context.Context, row *T, mask []string) (int64, error) {
+	var (
+		e       T
+		columns []string
+		err     error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	sch, err := o.schema()
+	if err != nil {
+		return 0, err
+	}
+
+	paths := fieldMaskPaths(sch)
+	seen := map[string]bool{}
+	for _, path := range mask {
+		if path == "*" {
+			columns = []string{"*"}
+			break
+		}
+
+		pathColumns, ok := paths[path]
+		if !ok {
+			err = fmt.Errorf("invalid field mask path %q for %s", path, e.TableName())
+			return 0, err
+		}
+		for _, column := range pathColumns {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	return o.Update(ctx, row, columns)
+}
+
+// fieldMaskPaths maps every field mask path of sch, including the paths of embedded
+// structs, to the columns it selects.
+func fieldMaskPaths(sch *schema.Schema) map[string][]string {
+	paths := map[string][]string{}
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.PrimaryKey || !field.Updatable {
+			continue
+		}
+
+		var (
+			segments   []string
+			structType = sch.ModelType
+		)
+		for _, bindName := range field.BindNames {
+			for structType.Kind() == reflect.Ptr {
+				structType = structType.Elem()
+			}
+
+			structField, ok := structType.FieldByName(bindName)
+			if !ok {
+				break
+			}
+			structType = structField.Type
+
+			// fields of anonymous embedded structs are promoted to the parent
+			if structField.Anonymous {
+				continue
+			}
+			segments = append(segments, fieldMaskName(structField))
+			paths[strings.Join(segments, ".")] = append(paths[strings.Join(segments, ".")], field.DBName)
+		}
+	}
+
+	return paths
+}
+
+// fieldMaskName returns the JSON name of a struct field, or its snake_case name.
+func fieldMaskName(structField reflect.StructField) string {
+	if tag, ok := structField.Tag.Lookup("json"); ok {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return schema.NamingStrategy{}.ColumnName("", structField.Name)
+}
@@ -0,0 +1,49 @@
+package base
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type Address struct {
+	City    string `json:"city"`
+	ZipCode string `json:"zip_code"`
+}
+
+type Customer struct {
+	ID       uint    `gorm:"primaryKey" json:"id"`
+	FullName string  `json:"full_name"`
+	Address  Address `gorm:"embedded;embeddedPrefix:address_" json:"address"`
+}
+
+func (Customer) TableName() string {
+	return "dummy_customers"
+}
+
+func (Customer) PrimaryKey() string {
+	return "id"
+}
+
+func TestFieldMaskPaths(t *testing.T) {
+	repo := NewBaseGorm[Customer, uint](setupDryRunDB(t))
+	sch, err := repo.schema()
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	paths := fieldMaskPaths(sch)
+	for _, columns := range paths {
+		sort.Strings(columns)
+	}
+
+	want := map[string][]string{
+		"full_name":        {"full_name"},
+		"address":          {"address_city", "address_zip_code"},
+		"address.city":     {"address_city"},
+		"address.zip_code": {"address_zip_code"},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected paths %v, got %v", want, paths)
+	}
+}
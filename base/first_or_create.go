@@ -0,0 +1,125 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// FirstOrCreate returns the first row matching wheres, or creates it, reporting
+// whether it was created, e.g. for idempotent provisioning. A created row is
+// defaults, like gorm's Attrs, with the columns compared for equality in wheres set
+// to their value. When a concurrent call creates the row first, making the insert
+// fail on a unique index, the row it created is returned.
+func (o *BaseGorm[T, PkType]) FirstOrCreate(ctx context.Context, wheres []Where, defaults *T) (*T, bool, error) {
+	return o.firstOrCreate(ctx, wheres, defaults, nil)
+}
+
+// FirstOrCreateAssign is FirstOrCreate also setting the assign columns, like gorm's
+// Assign : they are updated on a found row and set on a created one.
+func (o *BaseGorm[T, PkType]) FirstOrCreateAssign(ctx context.Context, wheres []Where, defaults *T, assign map[string]interface{}) (*T, bool, error) {
+	return o.firstOrCreate(ctx, wheres, defaults, assign)
+}
+
+func (o *BaseGorm[T, PkType]) firstOrCreate(ctx context.Context, wheres []Where, defaults *T, assign map[string]interface{}) (row *T, created bool, err error) {
+	var (
+		e T
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, false, err
+	}
+	if o.opts.writePermissions != nil {
+		if assign, err = o.permittedValues(ctx, assign); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if row, err = found(o.Wheres(ctx, wheres)); err != nil {
+		return nil, false, err
+	}
+	if row != nil {
+		return row, false, o.assign(ctx, sch, row, assign)
+	}
+
+	row = new(T)
+	if defaults != nil {
+		*row = *defaults
+	}
+	for _, v := range wheres {
+		if v.Group == nil && !v.IsLike && !v.IsFullTextSearch && (v.Operator == "" || v.Operator == OpEq) {
+			if err = setColumn(ctx, sch, row, v.Name, v.Value); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	for column, value := range assign {
+		if err = setColumn(ctx, sch, row, column, value); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if _, createErr := o.Create(ctx, row); createErr != nil {
+		// a concurrent call may have created the row first
+		existing, findErr := found(o.Wheres(ctx, wheres))
+		if findErr != nil || existing == nil {
+			err = fmt.Errorf("first or create %s: %w", e.TableName(), createErr)
+			return nil, false, err
+		}
+		return existing, false, o.assign(ctx, sch, existing, assign)
+	}
+
+	return row, true, nil
+}
+
+// assign updates the assign columns of the stored row and sets them on row.
+func (o *BaseGorm[T, PkType]) assign(ctx context.Context, sch *schema.Schema, row *T, assign map[string]interface{}) error {
+	var e T
+
+	if len(assign) == 0 {
+		return nil
+	}
+
+	pk, err := o.primaryKeyOf(ctx, sch, row)
+	if err != nil {
+		return err
+	}
+	if _, err = o.UpdateWhere(ctx, []Where{{Name: e.PrimaryKey(), Value: pk}}, assign); err != nil {
+		return err
+	}
+
+	for column, value := range assign {
+		if err = setColumn(ctx, sch, row, column, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setColumn sets the field of column in row to value. Columns which are not fields
+// of the model, e.g. qualified with their table, are left alone.
+func setColumn[T any](ctx context.Context, sch *schema.Schema, row *T, column string, value interface{}) error {
+	field := sch.LookUpField(column)
+	if field == nil {
+		return nil
+	}
+
+	if err := field.Set(ctx, reflect.ValueOf(row).Elem(), value); err != nil {
+		return fmt.Errorf("set %s of %s: %w", column, sch.Table, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetColumn(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+	sch, err := repo.schema()
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	user := &User{}
+	if err := setColumn(context.Background(), sch, user, "email", "alice@example.com"); err != nil {
+		t.Fatalf("Failed to set column: %v", err)
+	}
+	if err := setColumn(context.Background(), sch, user, "dummy_users.name", "Alice"); err != nil {
+		t.Fatalf("Failed to skip unknown column: %v", err)
+	}
+	if user.Email != "alice@example.com" || user.Name != "" {
+		t.Errorf("Expected only the email to be set, got %+v", user)
+	}
+}
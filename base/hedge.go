@@ -0,0 +1,123 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+)
+
+// hedgedReads are the replicas of WithHedgedReads.
+type hedgedReads struct {
+	delay    time.Duration
+	replicas []*gorm.DB
+	next     atomic.Uint32
+}
+
+// WithHedgedReads runs Detail, Wheres, WheresList, Exists and Count on replicas, in
+// turn, and runs the same read on the next replica, or on the primary with a single
+// replica, when the first one has not answered within delay or failed. The first
+// result is returned and the other read cancelled, so one slow replica does not make
+// the p99 latency. Without replicas both reads run on the primary. The reads of a
+// transaction, or of a connection set by generic_gorm.ContextWithDB, are not hedged.
+func WithHedgedReads(delay time.Duration, replicas ...*gorm.DB) RepoOption {
+	return func(o *repoOptions) {
+		o.hedgedReads = &hedgedReads{delay: delay, replicas: replicas}
+	}
+}
+
+// targets returns the database a read runs on first and the one it is hedged on.
+func (h *hedgedReads) targets(primary *gorm.DB) (*gorm.DB, *gorm.DB) {
+	n := uint32(len(h.replicas))
+	switch n {
+	case 0:
+		return primary, primary
+	case 1:
+		return h.replicas[0], primary
+	}
+
+	i := h.next.Add(1)
+	return h.replicas[i%n], h.replicas[(i+1)%n]
+}
+
+// databases returns the replicas of h, none when h is nil.
+func (h *hedgedReads) databases() []*gorm.DB {
+	if h == nil {
+		return nil
+	}
+
+	return h.replicas
+}
+
+// hedges reports whether the reads of ctx are hedged, i.e. run on the databases of
+// the repository rather than on a transaction.
+func (o *BaseGorm[T, PkType]) hedges(ctx context.Context) bool {
+	if o.opts.hedgedReads == nil || o.bound {
+		return false
+	}
+
+	db, ok := generic_gorm.DBFromContext(ctx)
+	return !ok || !generic_gorm.SameDatabase(db, o.db)
+}
+
+// hedgedRead runs read on the connection of ctx, hedged when WithHedgedReads is
+// given. gorm.ErrRecordNotFound is a result, not a failure to hedge.
+func hedgedRead[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint, R any](ctx context.Context, o *BaseGorm[T, PkType], read func(db *gorm.DB) (R, error)) (R, error) {
+	if !o.hedges(ctx) {
+		return read(o.conn(ctx))
+	}
+
+	type result struct {
+		value R
+		err   error
+	}
+
+	var (
+		first, second = o.opts.hedgedReads.targets(o.db)
+		results       = make(chan result, 2)
+		timer         = time.NewTimer(o.opts.hedgedReads.delay)
+		fired         = 0
+		received      = 0
+		zero          R
+		err           error
+	)
+	defer timer.Stop()
+
+	// the read left running is cancelled once one returned
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the method is resolved here, the stack of the goroutines has none
+	statementCtx := o.statementContext(ctx)
+	attempt := func(db *gorm.DB) {
+		fired++
+		go func() {
+			value, err := read(withTimeout(db.WithContext(statementCtx), o.opts.statementTimeouts.Read))
+			results <- result{value: value, err: err}
+		}()
+	}
+
+	attempt(first)
+	for {
+		select {
+		case <-timer.C:
+			if fired == 1 {
+				attempt(second)
+			}
+		case r := <-results:
+			received++
+			if r.err == nil || errors.Is(r.err, gorm.ErrRecordNotFound) {
+				return r.value, r.err
+			}
+			err = r.err
+			if fired == 1 {
+				attempt(second)
+			} else if received == fired {
+				return zero, err
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+)
+
+// hedgeTarget is a dry run database whose queries take latency, or fail with err.
+type hedgeTarget struct {
+	db        *gorm.DB
+	queries   atomic.Int32
+	cancelled atomic.Int32
+}
+
+func newHedgeTarget(t *testing.T, latency time.Duration, err error) *hedgeTarget {
+	t.Helper()
+
+	target := &hedgeTarget{db: setupDryRunDB(t)}
+	if cbErr := target.db.Callback().Query().After("gorm:query").Register("test:latency", func(tx *gorm.DB) {
+		target.queries.Add(1)
+		select {
+		case <-time.After(latency):
+		case <-tx.Statement.Context.Done():
+			target.cancelled.Add(1)
+		}
+		if err != nil {
+			_ = tx.AddError(err)
+		}
+	}); cbErr != nil {
+		t.Fatalf("Failed to register callback: %v", cbErr)
+	}
+
+	return target
+}
+
+func TestWithHedgedReads(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("slow replica", func(t *testing.T) {
+		var (
+			primary = newHedgeTarget(t, 0, nil)
+			replica = newHedgeTarget(t, time.Second, nil)
+			repo    = NewBaseGorm[User, uint](primary.db, WithHedgedReads(10*time.Millisecond, replica.db))
+			started = time.Now()
+		)
+
+		if _, err := repo.Detail(ctx, 1); err != nil {
+			t.Fatalf("Failed to run Detail: %v", err)
+		}
+		if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+			t.Errorf("Expected the primary to answer for the slow replica, took %s", elapsed)
+		}
+		if primary.queries.Load() != 1 {
+			t.Errorf("Expected the read to be hedged on the primary, got %d queries", primary.queries.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+		if replica.cancelled.Load() != 1 {
+			t.Error("Expected the read of the slow replica to be cancelled")
+		}
+	})
+
+	t.Run("fast replica", func(t *testing.T) {
+		var (
+			primary = newHedgeTarget(t, 0, nil)
+			replica = newHedgeTarget(t, 0, nil)
+			repo    = NewBaseGorm[User, uint](primary.db, WithHedgedReads(time.Second, replica.db))
+		)
+
+		if _, err := repo.WheresList(ctx, nil, []Where{{Name: "name", Value: "Alice"}}); err != nil {
+			t.Fatalf("Failed to run WheresList: %v", err)
+		}
+		if replica.queries.Load() != 1 || primary.queries.Load() != 0 {
+			t.Errorf("Expected the replica only to be queried, got %d and %d queries", replica.queries.Load(), primary.queries.Load())
+		}
+	})
+
+	t.Run("failing replica", func(t *testing.T) {
+		var (
+			primary = newHedgeTarget(t, 0, nil)
+			replica = newHedgeTarget(t, 0, errors.New("replica down"))
+			repo    = NewBaseGorm[User, uint](primary.db, WithHedgedReads(time.Second, replica.db))
+		)
+
+		if _, err := repo.Exists(ctx, nil); err != nil {
+			t.Errorf("Expected the primary to answer for the failing replica, got %v", err)
+		}
+		if primary.queries.Load() != 1 {
+			t.Errorf("Expected the read to be hedged at once on the primary, got %d queries", primary.queries.Load())
+		}
+	})
+
+	t.Run("context connection", func(t *testing.T) {
+		var (
+			primary = newHedgeTarget(t, 0, nil)
+			replica = newHedgeTarget(t, 0, nil)
+			repo    = NewBaseGorm[User, uint](primary.db, WithHedgedReads(time.Second, replica.db))
+		)
+
+		if _, err := repo.Count(generic_gorm.ContextWithDB(ctx, primary.db), nil); err != nil {
+			t.Fatalf("Failed to run Count: %v", err)
+		}
+		if replica.queries.Load() != 0 || primary.queries.Load() != 1 {
+			t.Errorf("Expected the connection of the context only to be queried, got %d and %d queries", replica.queries.Load(), primary.queries.Load())
+		}
+	})
+}
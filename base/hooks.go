@@ -0,0 +1,44 @@
+package base
+
+import "context"
+
+// afterFindRow runs the optional post-processing of a row loaded from the database.
+func (o *BaseGorm[T, PkType]) afterFindRow(ctx context.Context, row *T) {
+	o.localizeTimes(ctx, row)
+	o.track(ctx, row)
+}
+
+// afterFind runs afterFindRow on every loaded row, in place.
+func (o *BaseGorm[T, PkType]) afterFind(ctx context.Context, rows []T) {
+	for i := range rows {
+		o.afterFindRow(ctx, &rows[i])
+	}
+}
+
+// Loaded runs on row, read by Wheres and kept outside of the repository, e.g. by a
+// cache, what Wheres runs on the rows it reads : the time zone of WithTimeZone and
+// the tracking of WithTracking.
+func (o *BaseGorm[T, PkType]) Loaded(ctx context.Context, row *T) {
+	o.afterFindRow(ctx, row)
+}
+
+// LoadedByID runs on row, read by Detail with the primary key id and kept outside of
+// the repository, e.g. by a cache, what Detail runs on the rows it reads : it returns
+// the row of the identity map of ctx when there is one, checks the policy of
+// WithPolicy, then localizes and tracks the row and remembers it in the identity map.
+func (o *BaseGorm[T, PkType]) LoadedByID(ctx context.Context, id PkType, row *T) (*T, error) {
+	loaded := o.identityOf(ctx, id)
+	if loaded != nil {
+		row = loaded
+	}
+	if err := o.authorize(ctx, ActionRead, row); err != nil {
+		o.logError(ctx, err)
+		return nil, err
+	}
+	if loaded == nil {
+		o.afterFindRow(ctx, row)
+		o.remember(ctx, id, row)
+	}
+
+	return row, nil
+}
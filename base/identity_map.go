@@ -0,0 +1,69 @@
+package base
+
+import (
+	"context"
+	"sync"
+)
+
+type identityMapCtxKey struct{}
+
+// identityMap holds the rows loaded by Detail within a context, by table and primary key.
+type identityMap struct {
+	mu   sync.Mutex
+	rows map[string]map[interface{}]interface{}
+}
+
+// ContextWithIdentityMap returns a context in which Detail loads every row once : the
+// next calls with the same primary key return the same *T without querying the
+// database, until a write of the repository with that context, which forgets the
+// rows of its table. Create it per request, e.g. in a middleware, so the rows are
+// dropped with the request. Detail given query options always queries.
+func ContextWithIdentityMap(ctx context.Context) context.Context {
+	return context.WithValue(ctx, identityMapCtxKey{}, &identityMap{rows: map[string]map[interface{}]interface{}{}})
+}
+
+// identityOf returns the row with the given primary key loaded in ctx, nil when there is none.
+func (o *BaseGorm[T, PkType]) identityOf(ctx context.Context, id PkType) *T {
+	m, ok := ctx.Value(identityMapCtxKey{}).(*identityMap)
+	if !ok {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row, _ := m.rows[o.table][id].(*T)
+	return row
+}
+
+// remember keeps row, loaded with the given primary key, in the identity map of ctx.
+func (o *BaseGorm[T, PkType]) remember(ctx context.Context, id PkType, row *T) {
+	m, ok := ctx.Value(identityMapCtxKey{}).(*identityMap)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows, ok := m.rows[o.table]
+	if !ok {
+		rows = map[interface{}]interface{}{}
+		m.rows[o.table] = rows
+	}
+	rows[id] = row
+}
+
+// forgetIdentities drops the rows of the table from the identity map of ctx, before
+// a write changes them.
+func (o *BaseGorm[T, PkType]) forgetIdentities(ctx context.Context) {
+	m, ok := ctx.Value(identityMapCtxKey{}).(*identityMap)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.rows, o.table)
+}
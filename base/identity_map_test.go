@@ -0,0 +1,56 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestContextWithIdentityMap(t *testing.T) {
+	var (
+		db      = setupDryRunDB(t)
+		repo    = NewBaseGorm[User, uint](db)
+		queries int
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		queries++
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	detail := func(ctx context.Context, id uint, opts ...QueryOption) *User {
+		t.Helper()
+		row, err := repo.Detail(ctx, id, opts...)
+		if err != nil {
+			t.Fatalf("Failed to run Detail: %v", err)
+		}
+		return row
+	}
+
+	ctx := ContextWithIdentityMap(context.Background())
+	first := detail(ctx, 1)
+	if again := detail(ctx, 1); again != first || queries != 1 {
+		t.Errorf("Expected the same row from 1 query, got %p and %p from %d queries", first, again, queries)
+	}
+
+	detail(ctx, 2)
+	detail(ctx, 1, WithLock(clause.Locking{Strength: clause.LockingStrengthUpdate}))
+	if queries != 3 {
+		t.Errorf("Expected another primary key and query options to query, got %d queries", queries)
+	}
+
+	if _, err := repo.UpdateWhere(ctx, []Where{{Name: "id", Value: 1}}, map[string]interface{}{"name": "Renamed"}); err != nil {
+		t.Fatalf("Failed to run UpdateWhere: %v", err)
+	}
+	if detail(ctx, 1) == first || queries != 4 {
+		t.Errorf("Expected UpdateWhere to forget the loaded rows, got %d queries", queries)
+	}
+
+	detail(context.Background(), 1)
+	detail(context.Background(), 1)
+	if queries != 6 {
+		t.Errorf("Expected every Detail to query without identity map, got %d queries", queries)
+	}
+}
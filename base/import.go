@@ -0,0 +1,87 @@
+package base
+
+import (
+	"context"
+	"fmt"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+)
+
+// RowError is a row CreateMultiplePartial could not insert.
+type RowError[T any] struct {
+	Index int // index of the row in the input
+	Row   *T
+	Err   error
+}
+
+func (e RowError[T]) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index, e.Err)
+}
+
+func (e RowError[T]) Unwrap() error {
+	return e.Err
+}
+
+// ImportReport lists the rows inserted and the rows rejected by CreateMultiplePartial.
+type ImportReport[T any] struct {
+	Inserted []*T
+	Failed   []RowError[T]
+}
+
+// CreateMultiplePartial inserts rows with one batch insert like CreateMultiple. When
+// the batch fails, e.g. on one duplicate key among thousands of rows, every row is
+// inserted on its own inside a savepoint, and the rows that fail are reported instead
+// of aborting the import. The returned error is only set when the transaction fails.
+func (o *BaseGorm[T, PkType]) CreateMultiplePartial(ctx context.Context, rows []*T) (*ImportReport[T], error) {
+	var (
+		report = &ImportReport[T]{}
+		err    error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	if len(rows) == 0 {
+		return report, nil
+	}
+
+	err = o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		// a nested transaction is a savepoint
+		batchErr := tx.Transaction(func(savepoint *gorm.DB) error {
+			_, _, err := o.CreateMultiple(generic_gorm.ContextWithDB(ctx, savepoint), rows)
+			return err
+		})
+		if batchErr == nil {
+			report.Inserted = rows
+			return nil
+		}
+
+		generic_gorm.GetLoggerFromContext(ctx).WithField("rows", len(rows)).Warn("batch insert failed, inserting rows one by one")
+
+		for i, row := range rows {
+			rowErr := tx.Transaction(func(savepoint *gorm.DB) error {
+				_, err := o.Create(generic_gorm.ContextWithDB(ctx, savepoint), row)
+				return err
+			})
+			if rowErr != nil {
+				report.Failed = append(report.Failed, RowError[T]{Index: i, Row: row, Err: rowErr})
+				continue
+			}
+			report.Inserted = append(report.Inserted, row)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,252 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+)
+
+// IndexAdvisor collects the Where and OrderBy columns of the reads of the
+// repositories given WithIndexAdvisor, and reports the ones no index of their table
+// serves. It is meant for development and staging, where the query shapes of the
+// service are exercised, not to tune production.
+type IndexAdvisor struct {
+	db *gorm.DB
+
+	mu     sync.Mutex
+	shapes map[string]*queryShape // by table and columns
+}
+
+// queryShape is the index a read would use : its equality columns, in any order,
+// then its range or order columns.
+type queryShape struct {
+	table    string
+	equality []string
+	ordered  []string
+	reads    int64
+}
+
+// IndexSuggestion is an index missing for the reads of a table.
+type IndexSuggestion struct {
+	Table   string
+	Columns []string
+	Reads   int64  // reads it would serve
+	DDL     string // CREATE INDEX statement
+}
+
+// NewIndexAdvisor returns an IndexAdvisor reading the indexes of the tables in db.
+func NewIndexAdvisor(db *gorm.DB) *IndexAdvisor {
+	return &IndexAdvisor{db: db, shapes: map[string]*queryShape{}}
+}
+
+// WithIndexAdvisor records the Where and OrderBy columns of Wheres, WheresList, the
+// List methods, Exists, Count and Each in advisor.
+func WithIndexAdvisor(advisor *IndexAdvisor) RepoOption {
+	return func(o *repoOptions) {
+		o.indexAdvisor = advisor
+	}
+}
+
+// adviseIndex records the shape of a read of the repository, when WithIndexAdvisor is given.
+func (o *BaseGorm[T, PkType]) adviseIndex(wheres []Where, orders []OrderBy) {
+	if o.opts.indexAdvisor != nil {
+		o.opts.indexAdvisor.record(o.table, wheres, orders)
+	}
+}
+
+// record adds a read of table. Equality, IN and IS NULL filters are the leading
+// columns of its index, followed by the first range filter, or else the orders.
+// Groups, LIKE and full text filters and the columns of other tables are ignored.
+func (a *IndexAdvisor) record(table string, wheres []Where, orders []OrderBy) {
+	var (
+		equality []string
+		ranged   string
+		ordered  []string
+	)
+
+	for _, v := range wheres {
+		column, ok := columnOf(table, v.Name)
+		if !ok || v.Group != nil || v.IsLike || v.IsFullTextSearch {
+			continue
+		}
+
+		switch v.Operator {
+		case OpGt, OpGte, OpLt, OpLte, OpBetween:
+			if ranged == "" {
+				ranged = column
+			}
+		case OpNe, OpNotIn, OpIsNotNull:
+		default:
+			equality = append(equality, column)
+		}
+	}
+	sort.Strings(equality)
+	equality = compactColumns(equality)
+
+	if ranged != "" {
+		ordered = []string{ranged}
+	} else {
+		for _, order := range orders {
+			column, ok := columnOf(table, order.Field)
+			if !ok || order.String() == "" {
+				break
+			}
+			ordered = append(ordered, column)
+		}
+	}
+
+	if len(equality) == 0 && len(ordered) == 0 {
+		return
+	}
+
+	key := fmt.Sprintf("%s(%s|%s)", table, strings.Join(equality, ","), strings.Join(ordered, ","))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	shape, ok := a.shapes[key]
+	if !ok {
+		shape = &queryShape{table: table, equality: equality, ordered: ordered}
+		a.shapes[key] = shape
+	}
+	shape.reads++
+}
+
+// columnOf returns the column named name, unqualified, unless it belongs to another table.
+func columnOf(table string, name string) (string, bool) {
+	name = strings.Trim(strings.TrimSpace(name), "`")
+	if qualifier, column, ok := strings.Cut(name, "."); ok {
+		if strings.Trim(qualifier, "`") != table {
+			return "", false
+		}
+		name = strings.Trim(column, "`")
+	}
+
+	return name, name != ""
+}
+
+func compactColumns(columns []string) []string {
+	compacted := columns[:0]
+	for i, column := range columns {
+		if i == 0 || column != columns[i-1] {
+			compacted = append(compacted, column)
+		}
+	}
+
+	return compacted
+}
+
+// Suggestions returns the indexes missing for the recorded reads, the ones serving
+// the most reads first. An existing index serves a read when its leading columns
+// are the equality columns of the read, in any order, followed by its range or
+// order columns.
+func (a *IndexAdvisor) Suggestions(ctx context.Context) ([]IndexSuggestion, error) {
+	a.mu.Lock()
+	shapes := make([]queryShape, 0, len(a.shapes))
+	for _, shape := range a.shapes {
+		shapes = append(shapes, *shape)
+	}
+	a.mu.Unlock()
+
+	var (
+		indexes     = map[string][][]string{}
+		suggestions = map[string]*IndexSuggestion{}
+	)
+	for _, shape := range shapes {
+		existing, ok := indexes[shape.table]
+		if !ok {
+			tableIndexes, err := a.db.WithContext(ctx).Migrator().GetIndexes(shape.table)
+			if err != nil {
+				return nil, fmt.Errorf("indexes of %s: %w", shape.table, err)
+			}
+			for _, index := range tableIndexes {
+				existing = append(existing, index.Columns())
+			}
+			indexes[shape.table] = existing
+		}
+
+		if shape.servedBy(existing) {
+			continue
+		}
+
+		columns := append(append([]string(nil), shape.equality...), shape.ordered...)
+		key := shape.table + "(" + strings.Join(columns, ",") + ")"
+		suggestion, ok := suggestions[key]
+		if !ok {
+			name := "idx_" + shape.table + "_" + strings.Join(columns, "_")
+			suggestion = &IndexSuggestion{
+				Table:   shape.table,
+				Columns: columns,
+				DDL:     fmt.Sprintf("CREATE INDEX %s ON %s (%s);", name, shape.table, strings.Join(columns, ", ")),
+			}
+			suggestions[key] = suggestion
+		}
+		suggestion.Reads += shape.reads
+	}
+
+	sorted := make([]IndexSuggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		sorted = append(sorted, *suggestion)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Reads != sorted[j].Reads {
+			return sorted[i].Reads > sorted[j].Reads
+		}
+		return sorted[i].DDL < sorted[j].DDL
+	})
+
+	return sorted, nil
+}
+
+// servedBy reports whether one of indexes, given by their columns, serves the shape.
+func (s queryShape) servedBy(indexes [][]string) bool {
+	width := len(s.equality) + len(s.ordered)
+
+	for _, columns := range indexes {
+		if len(columns) < width {
+			continue
+		}
+
+		leading := append([]string(nil), columns[:len(s.equality)]...)
+		sort.Strings(leading)
+		if strings.Join(leading, ",") != strings.Join(s.equality, ",") {
+			continue
+		}
+		if strings.Join(columns[len(s.equality):width], ",") == strings.Join(s.ordered, ",") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Start logs the Suggestions every interval as warnings, until ctx is done, and
+// returns ctx.Err().
+func (a *IndexAdvisor) Start(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			logEntry := generic_gorm.GetLoggerFromContext(ctx)
+
+			suggestions, err := a.Suggestions(ctx)
+			if err != nil {
+				logEntry.Error(err)
+				continue
+			}
+			for _, suggestion := range suggestions {
+				logEntry.WithField("reads", suggestion.Reads).Warnf("missing index on %s: %s", suggestion.Table, suggestion.DDL)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package base
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIndexAdvisorRecordsShapes(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		db      = setupDryRunDB(t)
+		advisor = NewIndexAdvisor(db)
+		repo    = NewBaseGorm[User, uint](db, WithIndexAdvisor(advisor))
+		wheres  = []Where{
+			{Name: "name", Value: "Alice"},
+			{Name: "dummy_users.email", Value: "alice@example.com"},
+			{Name: "created_at", Operator: OpGte, Value: "2024-01-01"},
+			{Name: "dummy_posts.title", Value: "joined"},
+		}
+	)
+
+	if _, err := repo.Count(ctx, wheres); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	if _, err := repo.WheresList(ctx, []OrderBy{{Field: "created_at", Direction: "desc"}}, wheres[:2]); err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	if _, err := repo.Count(ctx, wheres[1:3]); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+
+	want := map[string]queryShape{
+		"dummy_users(email,name|created_at)": {table: "dummy_users", equality: []string{"email", "name"}, ordered: []string{"created_at"}, reads: 2},
+		"dummy_users(email|created_at)":      {table: "dummy_users", equality: []string{"email"}, ordered: []string{"created_at"}, reads: 1},
+	}
+	got := map[string]queryShape{}
+	for key, shape := range advisor.shapes {
+		got[key] = *shape
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected shapes %+v, got %+v", want, got)
+	}
+}
+
+func TestQueryShapeServedBy(t *testing.T) {
+	shape := queryShape{table: "orders", equality: []string{"customer_id", "status"}, ordered: []string{"created_at"}}
+
+	tests := []struct {
+		indexes [][]string
+		want    bool
+	}{
+		{indexes: [][]string{{"id"}}, want: false},
+		{indexes: [][]string{{"status", "customer_id", "created_at"}}, want: true},
+		{indexes: [][]string{{"customer_id", "status", "created_at", "id"}}, want: true},
+		{indexes: [][]string{{"customer_id", "created_at", "status"}}, want: false},
+		{indexes: [][]string{{"customer_id", "status"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := shape.servedBy(tt.indexes); got != tt.want {
+			t.Errorf("servedBy(%v): expected %v, got %v", tt.indexes, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package base
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	journalUpdate = "update"
+	journalDelete = "delete"
+)
+
+var (
+	// ErrUnknownOperation is returned by UndoOperation for an operation without journal entries.
+	ErrUnknownOperation = errors.New("unknown journaled operation")
+	// ErrUndoWindowExpired is returned by UndoOperation once the undo window is over.
+	ErrUndoWindowExpired = errors.New("undo window expired")
+	// ErrAlreadyUndone is returned by UndoOperation for an operation undone before.
+	ErrAlreadyUndone = errors.New("operation already undone")
+)
+
+type operationIDCtxKey struct{}
+
+// ContextWithOperationID sets the id under which the next journaled bulk operations
+// are recorded. Without it, an id is generated and logged.
+func ContextWithOperationID(ctx context.Context, operationID string) context.Context {
+	return context.WithValue(ctx, operationIDCtxKey{}, operationID)
+}
+
+// JournalEntry stores the prior values of one row changed by a journaled bulk operation.
+type JournalEntry struct {
+	ID          uint64     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	OperationID string     `json:"operation_id" gorm:"column:operation_id;size:64;index"`
+	Table       string     `json:"table_name" gorm:"column:table_name;size:191"`
+	Operation   string     `json:"operation" gorm:"column:operation;size:16"`
+	PriorValues string     `json:"prior_values" gorm:"column:prior_values;type:longtext"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UndoneAt    *time.Time `json:"undone_at" gorm:"column:undone_at"`
+}
+
+func (JournalEntry) TableName() string {
+	return "operation_journal"
+}
+
+func (JournalEntry) PrimaryKey() string {
+	return "id"
+}
+
+type undoJournalOptions struct {
+	window time.Duration
+}
+
+// WithUndoJournal makes UpdateWhere and DeleteWhere record the prior values of the
+// affected rows in the operation_journal table (see JournalEntry) in the same
+// transaction, so UndoOperation can revert them during window.
+func WithUndoJournal(window time.Duration) RepoOption {
+	return func(o *repoOptions) {
+		o.undoJournal = &undoJournalOptions{window: window}
+	}
+}
+
+// journaled records the rows matching wheres, then runs operation on them, in one transaction.
+func (o *BaseGorm[T, PkType]) journaled(ctx context.Context, operation string, wheres []Where, run func(db *gorm.DB) *gorm.DB) (int64, error) {
+	var (
+		e            T
+		sch          *schema.Schema
+		rowsAffected int64
+		err          error
+	)
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	if sch, err = o.schema(); err != nil {
+		return 0, err
+	}
+
+	operationID, _ := ctx.Value(operationIDCtxKey{}).(string)
+	if operationID == "" {
+		b := make([]byte, 16)
+		if _, err = rand.Read(b); err != nil {
+			return 0, err
+		}
+		operationID = hex.EncodeToString(b)
+	}
+
+	err = o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []T
+		if err := bulkWheres(tx.Table(e.TableName()), wheres).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		entries := make([]JournalEntry, 0, len(rows))
+		for i := range rows {
+			priorValues, err := columnValues(ctx, sch, reflect.ValueOf(&rows[i]).Elem())
+			if err != nil {
+				return err
+			}
+			entries = append(entries, JournalEntry{
+				OperationID: operationID,
+				Table:       e.TableName(),
+				Operation:   operation,
+				PriorValues: string(priorValues),
+			})
+		}
+
+		if err := tx.Session(&gorm.Session{NewDB: true}).CreateInBatches(entries, 500).Error; err != nil {
+			return err
+		}
+
+		result := run(tx.Table(e.TableName()))
+		rowsAffected = result.RowsAffected
+
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	generic_gorm.GetLoggerFromContext(ctx).
+		WithField("operation_id", operationID).
+		Infof("journaled %s of %d %s rows", operation, rowsAffected, e.TableName())
+
+	return rowsAffected, nil
+}
+
+// UndoOperation restores the rows changed by the journaled operation operationID
+// and returns how many rows were restored.
+func (o *BaseGorm[T, PkType]) UndoOperation(ctx context.Context, operationID string) (int64, error) {
+	var (
+		e        T
+		entries  []JournalEntry
+		restored int64
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	o.forgetIdentities(ctx)
+
+	if o.opts.undoJournal == nil {
+		err = fmt.Errorf("undo journal is not enabled for %s", e.TableName())
+		return 0, err
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return 0, err
+	}
+
+	err = o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("operation_id = ? AND table_name = ?", operationID, e.TableName()).Find(&entries).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case len(entries) == 0:
+			return fmt.Errorf("%w: %s", ErrUnknownOperation, operationID)
+		case entries[0].UndoneAt != nil:
+			return fmt.Errorf("%w: %s", ErrAlreadyUndone, operationID)
+		case time.Since(entries[0].CreatedAt) > o.opts.undoJournal.window:
+			return fmt.Errorf("%w: %s", ErrUndoWindowExpired, operationID)
+		}
+
+		for _, entry := range entries {
+			values, err := decodeColumnValues(sch, []byte(entry.PriorValues))
+			if err != nil {
+				return err
+			}
+
+			rowDB := tx.Session(&gorm.Session{NewDB: true}).Table(e.TableName())
+			result := rowDB.Where(fmt.Sprintf("%s = ?", e.PrimaryKey()), values[e.PrimaryKey()]).Updates(values)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 && entry.Operation == journalDelete {
+				// hard deleted row
+				result = tx.Session(&gorm.Session{NewDB: true}).Table(e.TableName()).Create(values)
+				if result.Error != nil {
+					return result.Error
+				}
+			}
+			restored += result.RowsAffected
+		}
+
+		return tx.Model(&JournalEntry{}).
+			Where("operation_id = ? AND table_name = ?", operationID, e.TableName()).
+			Update("undone_at", time.Now()).
+			Error
+	})
+
+	return restored, err
+}
+
+// columnValues encodes the column values of row as a JSON object keyed by column name.
+func columnValues(ctx context.Context, sch *schema.Schema, row reflect.Value) ([]byte, error) {
+	values := make(map[string]interface{}, len(sch.DBNames))
+	for _, field := range sch.Fields {
+		if field.DBName != "" {
+			values[field.DBName], _ = field.ValueOf(ctx, row)
+		}
+	}
+
+	return json.Marshal(values)
+}
+
+// decodeColumnValues decodes columnValues output back into values of the field types.
+func decodeColumnValues(sch *schema.Schema, data []byte) (map[string]interface{}, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{}, len(raw))
+	for column, message := range raw {
+		field := sch.LookUpField(column)
+		if field == nil {
+			continue
+		}
+
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(message, value.Interface()); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", column, err)
+		}
+		values[column] = value.Elem().Interface()
+	}
+
+	return values, nil
+}
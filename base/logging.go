@@ -0,0 +1,272 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/internal/callbacks"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSampledErrors bounds the distinct error messages a logSampler counts.
+const maxSampledErrors = 1000
+
+const startedAtKey = "base:started_at"
+
+// statementsKey is the context key of the statementOptions of the statements run
+// by a repository, see statementContext.
+type statementsKey struct{}
+
+// statementOptions are how the statements of a repository are traced, logged and
+// reported, see WithTracer, WithDebugLogging, WithLoggedValues and
+// WithSlowQueryThreshold.
+type statementOptions struct {
+	tracer      Tracer
+	slowQueries *slowQueries
+	debug       bool
+	values      bool
+	method      string // the repository method running them, when needed
+}
+
+// needsMethod reports whether the statements are traced, logged or reported with
+// the method running them.
+func (s *statementOptions) needsMethod() bool {
+	return s.tracer != nil || s.slowQueries != nil || s.debug
+}
+
+// statementOptionsOf returns the statementOptions of the statement of db, false
+// when it is not run by a repository.
+func statementOptionsOf(db *gorm.DB) (*statementOptions, bool) {
+	options, ok := db.Statement.Context.Value(statementsKey{}).(*statementOptions)
+	return options, ok
+}
+
+// statementError is the error of a failed statement of a repository, with what
+// logError adds to its entry. Its message is the one of err.
+type statementError struct {
+	err          error
+	duration     time.Duration
+	rowsAffected int64
+	wheres       string
+}
+
+func (e *statementError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statementError) Unwrap() error {
+	return e.err
+}
+
+// WithLogSampling logs only the first of every n identical errors of the repository,
+// with the number of the suppressed ones, to keep logging cheap at high QPS.
+func WithLogSampling(n int) RepoOption {
+	return func(o *repoOptions) {
+		if n > 1 {
+			o.logSampler = &logSampler{every: n, seen: map[string]int{}}
+		}
+	}
+}
+
+// logSampler counts the occurrences of each error message.
+type logSampler struct {
+	every int
+
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+// sample reports whether the error message should be logged, and how many
+// occurrences were suppressed since it last was.
+func (s *logSampler) sample(message string) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.seen[message]
+	if !ok && len(s.seen) >= maxSampledErrors {
+		s.seen = map[string]int{}
+	}
+	s.seen[message] = n + 1
+
+	if n%s.every != 0 {
+		return false, 0
+	}
+	if n == 0 {
+		return true, 0
+	}
+
+	return true, s.every - 1
+}
+
+// logError logs err with the logger of ctx, resolved only then, subject to
+// WithLogSampling. The entry carries the table of the repository and the method
+// failing, and when a statement failed its duration, its rows affected and its
+// WHERE clause, see WithLoggedValues.
+func (o *BaseGorm[T, PkType]) logError(ctx context.Context, err error) {
+	suppressed := 0
+	if o.opts.logSampler != nil {
+		var ok bool
+		if ok, suppressed = o.opts.logSampler.sample(err.Error()); !ok {
+			return
+		}
+	}
+
+	logEntry := o.logEntry(ctx)
+	if suppressed > 0 {
+		logEntry = logEntry.WithField("suppressed", suppressed)
+	}
+	var statementErr *statementError
+	if errors.As(err, &statementErr) {
+		logEntry = logEntry.WithFields(logrus.Fields{
+			"duration":      statementErr.duration,
+			"rows_affected": statementErr.rowsAffected,
+		})
+		if statementErr.wheres != "" {
+			logEntry = logEntry.WithField("wheres", statementErr.wheres)
+		}
+	}
+	logEntry.Error(err)
+}
+
+// logEntry returns the logger of ctx with the table of the repository and the
+// method being run.
+func (o *BaseGorm[T, PkType]) logEntry(ctx context.Context) *logrus.Entry {
+	logEntry := generic_gorm.GetLoggerFromContext(ctx).WithField("table", o.table)
+	if method := callerMethod(); method != "" {
+		logEntry = logEntry.WithField("method", method)
+	}
+
+	return logEntry
+}
+
+// callerMethod returns the innermost exported BaseGorm method of the stack, e.g.
+// List, or "" when there is none. It is only called to log an error and once per
+// method when its statements need it, as walking the stack is not free.
+func callerMethod() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if _, method, ok := strings.Cut(frame.Function, "/base.(*BaseGorm[...])."); ok {
+			method, _, _ = strings.Cut(method, ".")
+			if method != "" && unicode.IsUpper(rune(method[0])) {
+				return method
+			}
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// WithDebugLogging logs every statement of the repository at the debug level, with
+// the table, the method, the operation, the duration, the rows affected and the SQL,
+// with placeholders unless WithLoggedValues is given. The failures are logged at the
+// error level by the methods, the statement entry coming first.
+func WithDebugLogging() RepoOption {
+	return func(o *repoOptions) {
+		o.debugLogging = true
+	}
+}
+
+// WithLoggedValues inlines the bound values in the SQL logged by WithDebugLogging
+// and in the WHERE clause of the error entries, which only carry placeholders
+// otherwise. The values may be personal data, e.g. an email searched for, so the
+// logs must be protected accordingly.
+func WithLoggedValues() RepoOption {
+	return func(o *repoOptions) {
+		o.loggedValues = true
+	}
+}
+
+// registerStatementCallbacks registers the callbacks timing the statements on db,
+// for the error entries, WithDebugLogging and WithSlowQueryThreshold, once for all
+// the repositories of the database.
+func registerStatementCallbacks(db *gorm.DB) error {
+	return callbacks.Register(db, "base:statements", func(string) func(*gorm.DB) { return timeStatement }, logStatement)
+}
+
+func timeStatement(db *gorm.DB) {
+	if _, ok := statementOptionsOf(db); ok {
+		db.InstanceSet(startedAtKey, time.Now())
+	}
+}
+
+func logStatement(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startedAtKey)
+		if !ok || db.Statement.SQL.Len() == 0 {
+			return
+		}
+		var (
+			elapsed    = time.Since(value.(time.Time))
+			options, _ = statementOptionsOf(db)
+		)
+
+		if options.slowQueries != nil {
+			options.slowQueries.report(db, options.method, operation, elapsed)
+		}
+
+		var statementErr *statementError
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) && !errors.As(db.Error, &statementErr) {
+			db.Error = &statementError{
+				err:          db.Error,
+				duration:     elapsed,
+				rowsAffected: db.RowsAffected,
+				wheres:       renderedWheres(db, options.values),
+			}
+		}
+
+		if !options.debug {
+			return
+		}
+		logEntry := generic_gorm.GetLoggerFromContext(db.Statement.Context)
+		if !logEntry.Logger.IsLevelEnabled(logrus.DebugLevel) {
+			return
+		}
+
+		sql := db.Statement.SQL.String()
+		if options.values {
+			sql = db.Dialector.Explain(sql, db.Statement.Vars...)
+		}
+		fields := logrus.Fields{
+			"table":         db.Statement.Table,
+			"operation":     operation,
+			"duration":      elapsed,
+			"rows_affected": db.RowsAffected,
+			"sql":           sql,
+		}
+		if options.method != "" {
+			fields["method"] = options.method
+		}
+		if db.Error != nil {
+			fields[logrus.ErrorKey] = db.Error
+		}
+		logEntry.WithFields(fields).Debug("statement")
+	}
+}
+
+// renderedWheres returns the WHERE clause of the statement of db, with placeholders
+// unless values is set, or "" when it has none.
+func renderedWheres(db *gorm.DB, values bool) string {
+	where, ok := db.Statement.Clauses["WHERE"]
+	if !ok || where.Expression == nil {
+		return ""
+	}
+
+	statement := &gorm.Statement{DB: db, Table: db.Statement.Table, Clauses: map[string]clause.Clause{}}
+	where.Expression.Build(statement)
+	if values {
+		return db.Dialector.Explain(statement.SQL.String(), statement.Vars...)
+	}
+
+	return statement.SQL.String()
+}
@@ -0,0 +1,154 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/gorm"
+)
+
+func TestLogSampling(t *testing.T) {
+	var (
+		logger, hook = test.NewNullLogger()
+		ctx          = generic_gorm.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+		repo         = NewBaseGorm[User, uint](setupDryRunDB(t), WithLogSampling(3))
+	)
+
+	for i := 0; i < 7; i++ {
+		repo.logError(ctx, errors.New("deadlock found"))
+	}
+	repo.logError(ctx, errors.New("connection refused"))
+
+	entries := hook.AllEntries()
+	if len(entries) != 4 {
+		t.Fatalf("Expected 3 sampled deadlocks and 1 connection error to be logged, got %d entries", len(entries))
+	}
+
+	for i, want := range []interface{}{nil, 2, 2} {
+		if got := entries[i].Data["suppressed"]; got != want {
+			t.Errorf("Expected entry %d to report %v suppressed errors, got %v", i, want, got)
+		}
+	}
+	if entries[3].Message != "connection refused" {
+		t.Errorf("Expected a distinct error to be logged at once, got %q", entries[3].Message)
+	}
+}
+
+func TestLogErrorFields(t *testing.T) {
+	var (
+		logger, hook = test.NewNullLogger()
+		ctx          = generic_gorm.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+		repo         = NewBaseGorm[User, uint](setupDryRunDB(t), WithColumnAllowList())
+	)
+
+	if _, err := repo.Wheres(ctx, []Where{{Name: "unknown", Value: 1}}); err == nil {
+		t.Fatal("Expected an error for an unknown column")
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("Expected the error to be logged")
+	}
+	if entry.Data["table"] != "dummy_users" || entry.Data["method"] != "Wheres" {
+		t.Errorf("Expected the table and method fields, got %v", entry.Data)
+	}
+}
+
+func TestWithDebugLogging(t *testing.T) {
+	var (
+		logger, hook = test.NewNullLogger()
+		ctx          = generic_gorm.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+		repo         = NewBaseGorm[User, uint](setupDryRunDB(t), WithDebugLogging())
+	)
+
+	if _, err := repo.Wheres(ctx, []Where{{Name: "email", Value: "a@b.c"}}); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if len(hook.AllEntries()) != 0 {
+		t.Fatalf("Expected no statement logged above the debug level, got %d entries", len(hook.AllEntries()))
+	}
+
+	logger.SetLevel(logrus.DebugLevel)
+	if _, err := repo.Wheres(ctx, []Where{{Name: "email", Value: "a@b.c"}}); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.DebugLevel {
+		t.Fatalf("Expected the statement logged at the debug level, got %v", entry)
+	}
+	for field, want := range map[string]interface{}{"table": "dummy_users", "method": "Wheres", "operation": "query", "rows_affected": int64(0)} {
+		if got := entry.Data[field]; got != want {
+			t.Errorf("Expected %s %v, got %v", field, want, got)
+		}
+	}
+	if sql, _ := entry.Data["sql"].(string); !strings.Contains(sql, "email = ?") || strings.Contains(sql, "a@b.c") {
+		t.Errorf("Expected the SQL with placeholders, got %q", sql)
+	}
+	if _, ok := entry.Data["duration"].(time.Duration); !ok {
+		t.Errorf("Expected the duration, got %v", entry.Data["duration"])
+	}
+
+	// the values are only inlined on demand
+	repo = NewBaseGorm[User, uint](setupDryRunDB(t), WithDebugLogging(), WithLoggedValues())
+	if _, err := repo.Wheres(ctx, []Where{{Name: "email", Value: "a@b.c"}}); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if sql, _ := hook.LastEntry().Data["sql"].(string); !strings.Contains(sql, "email = 'a@b.c'") {
+		t.Errorf("Expected the SQL with its values inlined, got %q", sql)
+	}
+}
+
+func TestLogErrorStatementFields(t *testing.T) {
+	var (
+		logger, hook = test.NewNullLogger()
+		ctx          = generic_gorm.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+		db           = setupDryRunDB(t)
+		failure      = errors.New("lock wait timeout exceeded")
+	)
+
+	for _, tc := range []struct {
+		name   string
+		opts   []RepoOption
+		wheres string
+	}{
+		{"placeholders", nil, "email = ?"},
+		{"values", []RepoOption{WithLoggedValues()}, "email = 'a@b.c'"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewBaseGorm[User, uint](db, tc.opts...)
+			if db.Callback().Query().Get("test:fail") == nil {
+				if err := db.Callback().Query().After("gorm:query").Before("base:statements:after_query").Register("test:fail", func(tx *gorm.DB) {
+					tx.AddError(failure)
+				}); err != nil {
+					t.Fatalf("Failed to register the callback: %v", err)
+				}
+			}
+
+			_, err := repo.Wheres(ctx, []Where{{Name: "email", Value: "a@b.c"}})
+			if !errors.Is(err, failure) || err.Error() != failure.Error() {
+				t.Fatalf("Expected the statement error, got %v", err)
+			}
+
+			entry := hook.LastEntry()
+			if entry == nil || entry.Level != logrus.ErrorLevel {
+				t.Fatalf("Expected the error to be logged, got %v", entry)
+			}
+			if _, ok := entry.Data["duration"].(time.Duration); !ok {
+				t.Errorf("Expected the duration, got %v", entry.Data["duration"])
+			}
+			if got := entry.Data["rows_affected"]; got != int64(0) {
+				t.Errorf("Expected the rows affected, got %v", got)
+			}
+			if wheres, _ := entry.Data["wheres"].(string); !strings.Contains(wheres, tc.wheres) {
+				t.Errorf("Expected the wheres %q, got %q", tc.wheres, wheres)
+			}
+		})
+	}
+}
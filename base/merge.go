@@ -0,0 +1,202 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+)
+
+// MergeReference is a foreign key column of another table pointing at T.
+type MergeReference struct {
+	Table  string
+	Column string
+}
+
+// MergeStrategy configures Merge.
+type MergeStrategy[T any] struct {
+	// References are repointed from the merged rows to the kept row.
+	References []MergeReference
+	// Combine, when set, folds the merged rows into keep and returns the columns
+	// to update, all of them, zero values included, when empty.
+	Combine func(keep *T, merged []T) []string
+}
+
+// FindDuplicates groups the rows sharing the same values of columns, e.g. the
+// contacts stored twice with one email. Rows without a duplicate are left out,
+// and each group is ordered by primary key.
+func (o *BaseGorm[T, PkType]) FindDuplicates(ctx context.Context, columns []string) ([][]T, error) {
+	var (
+		e    T
+		rows []T
+		err  error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	if len(columns) == 0 {
+		err = fmt.Errorf("duplicates of %s require key columns", e.TableName())
+		return nil, err
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		field := sch.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			err = fmt.Errorf("key column %s not found in %s", column, sch.Table)
+			return nil, err
+		}
+		names[i] = field.DBName
+	}
+
+	var (
+		grouped = strings.Join(names, ", ")
+		keys    = o.conn(ctx).Model(&e).Table(e.TableName()).
+			Select(grouped).
+			Group(grouped).
+			Having("COUNT(*) > 1")
+	)
+	if err = o.conn(ctx).Model(&e).Table(e.TableName()).
+		Where(fmt.Sprintf("(%s) IN (?)", grouped), keys).
+		Order(grouped + ", " + e.PrimaryKey()).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	o.afterFind(ctx, rows)
+
+	var (
+		groups [][]T
+		last   string
+	)
+	for _, row := range rows {
+		key := make([]interface{}, len(names))
+		for i, name := range names {
+			key[i], _ = sch.FieldsByDBName[name].ValueOf(ctx, reflect.ValueOf(&row).Elem())
+		}
+
+		if k := naturalKey(key); len(groups) == 0 || k != last {
+			groups = append(groups, nil)
+			last = k
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], row)
+	}
+
+	return groups, nil
+}
+
+// Merge folds the rows of mergeIDs into the row of keepID in one transaction: the
+// references of strategy are repointed to keepID, Combine updates the kept row,
+// then the merged rows are soft deleted. Cascade rules are not applied to the
+// merged rows. mergeIDs may hold duplicates but not keepID. It returns the number
+// of merged rows, and ErrSoftDeleteUnsupported when T has no gorm.DeletedAt field.
+func (o *BaseGorm[T, PkType]) Merge(ctx context.Context, keepID PkType, mergeIDs []PkType, strategy MergeStrategy[T]) (int64, error) {
+	var (
+		e            T
+		rowsAffected int64
+		err          error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	o.forgetIdentities(ctx)
+
+	if _, err = o.deletedAtColumn(); err != nil {
+		return 0, err
+	}
+
+	ids, ok := distinctMergeIDs(keepID, mergeIDs)
+	if !ok {
+		err = fmt.Errorf("merge into %s %v: the kept row is among the merged rows", e.TableName(), keepID)
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	byID := fmt.Sprintf("%s = ?", e.PrimaryKey())
+	byIDs := fmt.Sprintf("%s IN ?", e.PrimaryKey())
+
+	err = o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var (
+			txCtx  = generic_gorm.ContextWithDB(ctx, tx)
+			keep   T
+			merged []T
+		)
+
+		if err := tx.Table(e.TableName()).Where(byID, keepID).Take(&keep).Error; err != nil {
+			return err
+		}
+		if err := tx.Table(e.TableName()).Where(byIDs, ids).Find(&merged).Error; err != nil {
+			return err
+		}
+		if len(merged) != len(ids) {
+			return fmt.Errorf("merge into %s %v: %d of %d merged rows not found", e.TableName(), keepID, len(ids)-len(merged), len(ids))
+		}
+
+		for _, reference := range strategy.References {
+			if err := tx.Table(reference.Table).
+				Where(fmt.Sprintf("%s IN ?", reference.Column), ids).
+				Update(reference.Column, keepID).Error; err != nil {
+				return err
+			}
+		}
+
+		if strategy.Combine != nil {
+			columns := strategy.Combine(&keep, merged)
+			if len(columns) == 0 {
+				// zero values included, Update skipping them without columns
+				columns = []string{"*"}
+			}
+			if _, err := o.Update(txCtx, &keep, columns); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where(byIDs, ids).Delete(&e)
+		rowsAffected = result.RowsAffected
+
+		return result.Error
+	})
+
+	return rowsAffected, err
+}
+
+// distinctMergeIDs returns mergeIDs without their duplicates, and false when keepID
+// is one of them.
+func distinctMergeIDs[PkType comparable](keepID PkType, mergeIDs []PkType) ([]PkType, bool) {
+	var (
+		ids  = make([]PkType, 0, len(mergeIDs))
+		seen = make(map[PkType]bool, len(mergeIDs))
+	)
+	for _, id := range mergeIDs {
+		if id == keepID {
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, true
+}
@@ -0,0 +1,50 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Document, uint](db)
+	)
+
+	if _, err := repo.FindDuplicates(context.Background(), []string{"title"}); err != nil {
+		t.Fatalf("Failed to find duplicates: %v", err)
+	}
+
+	want := "SELECT * FROM `documents` WHERE (title) IN (SELECT `title` FROM `documents` WHERE `documents`.`deleted_at` IS NULL GROUP BY `title` HAVING COUNT(*) > 1) AND `documents`.`deleted_at` IS NULL ORDER BY title, id"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, err := repo.FindDuplicates(context.Background(), []string{"missing"}); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestMergeRequiresSoftDelete(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+
+	if _, err := repo.Merge(context.Background(), 1, []uint{2}, MergeStrategy[User]{}); !errors.Is(err, ErrSoftDeleteUnsupported) {
+		t.Errorf("Expected ErrSoftDeleteUnsupported, got %v", err)
+	}
+}
+
+func TestMergeIDs(t *testing.T) {
+	repo := NewBaseGorm[Document, uint](setupDryRunDB(t))
+
+	if _, err := repo.Merge(context.Background(), 1, []uint{2, 1}, MergeStrategy[Document]{}); err == nil || !strings.Contains(err.Error(), "the kept row is among the merged rows") {
+		t.Errorf("Expected the kept row to be rejected, got %v", err)
+	}
+
+	if ids, ok := distinctMergeIDs(1, []uint{3, 2, 3, 2}); !ok || !reflect.DeepEqual(ids, []uint{3, 2}) {
+		t.Errorf("Expected [3 2], got %v, %v", ids, ok)
+	}
+}
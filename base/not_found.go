@@ -0,0 +1,51 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned by Detail and Wheres when no row matches, with
+// WithNotFoundError. The returned error wraps it with the table and the key searched.
+var ErrNotFound = errors.New("record not found")
+
+// WithNotFoundError makes Detail and Wheres fail with an error wrapping ErrNotFound
+// instead of returning a nil row when no row matches.
+func WithNotFoundError() RepoOption {
+	return func(o *repoOptions) {
+		o.notFoundError = true
+	}
+}
+
+// notFound returns the error of Detail and Wheres when no row matches, nil without
+// WithNotFoundError.
+func (o *BaseGorm[T, PkType]) notFound(key string) error {
+	var e T
+
+	if !o.opts.notFoundError {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s %s", ErrNotFound, e.TableName(), key)
+}
+
+// whereKey describes wheres in the error of notFound.
+func whereKey(wheres []Where) string {
+	conditions := make([]string, len(wheres))
+	for i, v := range wheres {
+		conditions[i] = fmt.Sprintf("%s %v", v.String(), v.Args())
+	}
+
+	return "where " + strings.Join(conditions, " AND ")
+}
+
+// found turns the ErrNotFound of Detail and Wheres back into a nil row, for the
+// methods built on them.
+func found[T any](row *T, err error) (*T, error) {
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+
+	return row, err
+}
@@ -0,0 +1,27 @@
+package base
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	db := setupDryRunDB(t)
+
+	if err := NewBaseGorm[User, uint](db).notFound("id = 1"); err != nil {
+		t.Errorf("Expected no error without WithNotFoundError, got %v", err)
+	}
+
+	err := NewBaseGorm[User, uint](db, WithNotFoundError()).notFound(whereKey([]Where{{Name: "email", Value: "alice@example.com"}}))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	want := "record not found: dummy_users where email = ? [alice@example.com]"
+	if err.Error() != want {
+		t.Errorf("Expected error %q, got %q", want, err.Error())
+	}
+
+	if row, err := found(&User{}, err); row != nil || err != nil {
+		t.Errorf("Expected found to drop ErrNotFound, got %v, %v", row, err)
+	}
+}
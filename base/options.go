@@ -0,0 +1,118 @@
+package base
+
+import "github.com/go-playground/validator/v10"
+
+// RepoOption configures optional behaviour of a BaseGorm repository.
+type RepoOption func(*repoOptions)
+
+type repoOptions struct {
+	diffUpdate      bool
+	changeListener  ChangeListener
+	tracking        bool
+	patchableFields map[string]bool
+	validator       *validator.Validate
+	verifyTypes     bool
+	timestamps      *timestampOptions
+	createBatchSize int
+	policy          Policy
+
+	writePermissions   *writePermissions
+	statementTimeouts  StatementTimeouts
+	notFoundError      bool
+	logSampler         *logSampler
+	allowedColumns     map[string]bool
+	statementTemplates bool
+	hedgedReads        *hedgedReads
+	indexAdvisor       *IndexAdvisor
+	tracer             Tracer
+	debugLogging       bool
+	loggedValues       bool
+	slowQueries        *slowQueries
+
+	softDeleteAssociations bool
+	undoJournal            *undoJournalOptions
+}
+
+func newRepoOptions(opts []RepoOption) repoOptions {
+	var o repoOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	return o
+}
+
+// WithDiffUpdate makes Update compare the row against the stored one when no
+// updatedColumns are given, and only write the columns that actually changed.
+func WithDiffUpdate() RepoOption {
+	return func(o *repoOptions) {
+		o.diffUpdate = true
+	}
+}
+
+// WithChangeListener registers a callback receiving the before/after values of
+// every column changed by a diff based Update, e.g. to emit events or audit rows.
+func WithChangeListener(listener ChangeListener) RepoOption {
+	return func(o *repoOptions) {
+		o.changeListener = listener
+	}
+}
+
+// WithTracking makes the repository remember rows loaded via Detail, Wheres,
+// WheresList and List in the context created by ContextWithTracking, so SaveChanges
+// can persist only their modified fields.
+func WithTracking() RepoOption {
+	return func(o *repoOptions) {
+		o.tracking = true
+	}
+}
+
+// WithPatchableFields whitelists the JSON field names Patch is allowed to write.
+func WithPatchableFields(jsonNames ...string) RepoOption {
+	return func(o *repoOptions) {
+		if o.patchableFields == nil {
+			o.patchableFields = make(map[string]bool, len(jsonNames))
+		}
+		for _, name := range jsonNames {
+			o.patchableFields[name] = true
+		}
+	}
+}
+
+// WithValidator validates rows with go-playground/validator before Create,
+// CreateMultiple, Update and Upsert write them, failing with ValidationErrors.
+func WithValidator(v *validator.Validate) RepoOption {
+	return func(o *repoOptions) {
+		o.validator = v
+	}
+}
+
+// WithSerializableFields makes NewBaseGorm panic when a column of T has a type that
+// cannot be stored without a serializer, see VerifyFieldTypes.
+func WithSerializableFields() RepoOption {
+	return func(o *repoOptions) {
+		o.verifyTypes = true
+	}
+}
+
+// WithSoftDeleteAssociations makes ClearAssociation soft delete has one/has many
+// children having a gorm.DeletedAt field, instead of unlinking them.
+func WithSoftDeleteAssociations() RepoOption {
+	return func(o *repoOptions) {
+		o.softDeleteAssociations = true
+	}
+}
+
+// defaultCreateBatchSize keeps the INSERTs of CreateMultiple under MySQL's default
+// max_allowed_packet for rows of a few KB.
+const defaultCreateBatchSize = 1000
+
+// WithCreateBatchSize sets how many rows CreateMultiple inserts per INSERT, 1000 by
+// default. The batches of one call are written in one transaction.
+func WithCreateBatchSize(size int) RepoOption {
+	return func(o *repoOptions) {
+		o.createBatchSize = size
+	}
+}
@@ -0,0 +1,74 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestPaginatorSetTotal(t *testing.T) {
+	tests := []struct {
+		page, perPage, total int
+		want                 Paginator
+	}{
+		{1, 10, 0, Paginator{Page: 1, PerPage: 10, LastPage: 1}},
+		{1, 10, 25, Paginator{Page: 1, PerPage: 10, Total: 25, TotalPages: 3, LastPage: 3, HasNext: true, FirstItem: 1, LastItem: 10}},
+		{3, 10, 25, Paginator{Page: 3, PerPage: 10, Total: 25, TotalPages: 3, LastPage: 3, HasPrev: true, FirstItem: 21, LastItem: 25}},
+		{5, 10, 25, Paginator{Page: 5, PerPage: 10, Total: 25, TotalPages: 3, LastPage: 3, HasPrev: true}},
+	}
+
+	for _, tt := range tests {
+		p := Paginator{Page: tt.page, PerPage: tt.perPage}
+		p.SetTotal(tt.total)
+		if p != tt.want {
+			t.Errorf("page %d of %d rows by %d : expected %+v, got %+v", tt.page, tt.total, tt.perPage, tt.want, p)
+		}
+	}
+}
+
+func TestPaginatorJSON(t *testing.T) {
+	p := Paginator{Page: 2, PerPage: 10}
+	p.SetTotal(15)
+
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Failed to encode paginator: %v", err)
+	}
+
+	want := `{"Page":2,"PerPage":10,"Total":15,"TotalPages":2,"LastPage":2,"HasNext":false,"HasPrev":true,"FirstItem":11,"LastItem":15}`
+	if string(encoded) != want {
+		t.Errorf("Expected %s, got %s", want, encoded)
+	}
+}
+
+func TestPaginatorSetPageWithoutTotal(t *testing.T) {
+	p := Paginator{Page: 3, PerPage: 10}
+	p.setPageWithoutTotal(10, true)
+
+	want := Paginator{Page: 3, PerPage: 10, Total: -1, TotalPages: -1, LastPage: -1, HasNext: true, HasPrev: true, FirstItem: 21, LastItem: 30}
+	if p != want {
+		t.Errorf("Expected %+v, got %+v", want, p)
+	}
+}
+
+func TestListWithoutTotal(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	_, paginator, err := repo.List(context.Background(), 2, 10, nil, []Where{{Name: "name", Value: "Alice"}}, WithoutTotal())
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	// without the count, the page is fetched even though the dry run counts nothing
+	want := "SELECT * FROM `dummy_users` WHERE name = ? LIMIT ? OFFSET ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+	if paginator.Total != -1 || paginator.HasNext || !paginator.HasPrev {
+		t.Errorf("Unexpected paginator %+v", paginator)
+	}
+}
@@ -0,0 +1,173 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Patch applies a JSON Merge Patch (RFC 7396) to the row with the given primary key
+// and persists the patched columns. Keys are the JSON names of T's fields and must be
+// listed with WithPatchableFields, a null value resets the field to its zero value.
+func (o *BaseGorm[T, PkType]) Patch(ctx context.Context, id PkType, patch json.RawMessage) (*T, error) {
+	var (
+		e       T
+		members map[string]json.RawMessage
+		err     error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	if len(o.opts.patchableFields) == 0 {
+		err = fmt.Errorf("no patchable fields configured for %s", e.TableName())
+		return nil, err
+	}
+
+	if err = json.Unmarshal(patch, &members); err != nil || members == nil {
+		err = fmt.Errorf("merge patch for %s must be a JSON object: %v", e.TableName(), err)
+		return nil, err
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := o.Detail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		err = gorm.ErrRecordNotFound
+		return nil, err
+	}
+
+	var (
+		fields   = jsonFields(sch)
+		rowValue = reflect.ValueOf(row).Elem()
+		columns  = make([]string, 0, len(members))
+	)
+
+	for name, value := range members {
+		field, ok := fields[name]
+		if !ok || !o.opts.patchableFields[name] {
+			err = fmt.Errorf("field %s of %s is not patchable", name, e.TableName())
+			return nil, err
+		}
+
+		if err = applyMergePatch(ctx, field, rowValue, value); err != nil {
+			err = fmt.Errorf("cannot patch field %s of %s: %w", name, e.TableName(), err)
+			return nil, err
+		}
+		columns = append(columns, field.DBName)
+	}
+
+	// an empty Update would write every non zero field
+	if len(columns) == 0 {
+		return row, nil
+	}
+
+	if _, err = o.Update(ctx, row, columns); err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
+
+// jsonFields indexes the column backed fields of sch by their JSON name.
+func jsonFields(sch *schema.Schema) map[string]*schema.Field {
+	fields := make(map[string]*schema.Field, len(sch.Fields))
+	for _, field := range sch.Fields {
+		if field.DBName == "" {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.StructField.Tag.Lookup("json"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields[name] = field
+	}
+
+	return fields
+}
+
+// applyMergePatch merges patch into the value of field held by rowValue.
+func applyMergePatch(ctx context.Context, field *schema.Field, rowValue reflect.Value, patch json.RawMessage) error {
+	target := field.ReflectValueOf(ctx, rowValue)
+
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return err
+	}
+
+	if patchDoc == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	if _, isObject := patchDoc.(map[string]interface{}); isObject {
+		current, err := json.Marshal(target.Interface())
+		if err != nil {
+			return err
+		}
+
+		var targetDoc interface{}
+		if err = json.Unmarshal(current, &targetDoc); err != nil {
+			return err
+		}
+
+		if patch, err = json.Marshal(mergePatch(targetDoc, patchDoc)); err != nil {
+			return err
+		}
+	}
+
+	value := reflect.New(target.Type())
+	if err := json.Unmarshal(patch, value.Interface()); err != nil {
+		return err
+	}
+	target.Set(value.Elem())
+
+	return nil
+}
+
+// mergePatch implements the MergePatch function of RFC 7396 on decoded JSON documents.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+
+	return targetObject
+}
@@ -0,0 +1,58 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7396 appendix A
+	cases := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, c := range cases {
+		var target, patch, want interface{}
+		_ = json.Unmarshal([]byte(c.target), &target)
+		_ = json.Unmarshal([]byte(c.patch), &patch)
+		_ = json.Unmarshal([]byte(c.want), &want)
+
+		if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", c.target, c.patch, got, c.want)
+		}
+	}
+}
+
+func TestPatchWithoutMembersWritesNothing(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t), WithPatchableFields("Name"))
+
+	statements, err := repo.SQLOf(context.Background(), func(repo *BaseGorm[User, uint]) error {
+		_, err := repo.Patch(context.Background(), 1, json.RawMessage(`{}`))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to patch: %v", err)
+	}
+	for _, statement := range statements {
+		if strings.HasPrefix(statement, "UPDATE") {
+			t.Errorf("Expected an empty patch to write nothing, got %q", statement)
+		}
+	}
+	if len(statements) != 1 {
+		t.Errorf("Expected only the SELECT of the row, got %q", statements)
+	}
+}
@@ -0,0 +1,220 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ColumnPermissionError is returned when a write sets columns the actor of the
+// context may not write, see WithWriteRoles. It matches ErrForbidden.
+type ColumnPermissionError struct {
+	Table   string
+	Columns []string
+}
+
+func (e *ColumnPermissionError) Error() string {
+	return fmt.Sprintf("forbidden to write %s of %s", strings.Join(e.Columns, ", "), e.Table)
+}
+
+func (e *ColumnPermissionError) Unwrap() error {
+	return ErrForbidden
+}
+
+type writePermissions struct {
+	roles map[string][]string // column => roles allowed to write it
+	strip bool
+}
+
+// WithWriteRoles restricts writing column with Update, UpdateWhere, Patch, Increment,
+// FirstOrCreateAssign and the conflict updates of Upsert to the actors of the
+// context having one of roles. Writing it otherwise fails with a
+// ColumnPermissionError, see WithStripForbiddenColumns.
+func WithWriteRoles(column string, roles ...string) RepoOption {
+	return func(o *repoOptions) {
+		if o.writePermissions == nil {
+			o.writePermissions = &writePermissions{roles: map[string][]string{}}
+		}
+		o.writePermissions.roles[column] = roles
+	}
+}
+
+// WithStripForbiddenColumns makes the writes of WithWriteRoles silently skip the
+// columns the actor may not write instead of failing.
+func WithStripForbiddenColumns() RepoOption {
+	return func(o *repoOptions) {
+		if o.writePermissions == nil {
+			o.writePermissions = &writePermissions{roles: map[string][]string{}}
+		}
+		o.writePermissions.strip = true
+	}
+}
+
+// forbiddenColumns returns the columns among columns the actor of ctx may not write,
+// sorted. The columns are resolved through the schema, so a field name or another
+// case cannot bypass the restriction, and the ones not found fail with ErrUnknownColumn.
+func (o *BaseGorm[T, PkType]) forbiddenColumns(ctx context.Context, columns []string) ([]string, error) {
+	if o.opts.writePermissions == nil {
+		return nil, nil
+	}
+
+	restricted, err := o.restrictedColumns()
+	if err != nil {
+		return nil, err
+	}
+
+	actor, _ := ActorFromContext(ctx)
+
+	var forbidden []string
+	for _, column := range columns {
+		field, err := o.resolveColumn(column)
+		if err != nil {
+			return nil, err
+		}
+
+		roles, ok := restricted[field.DBName]
+		if !ok {
+			continue
+		}
+
+		allowed := false
+		for _, role := range roles {
+			if actor.HasRole(role) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			forbidden = append(forbidden, column)
+		}
+	}
+	sort.Strings(forbidden)
+
+	return forbidden, nil
+}
+
+// restrictedColumns returns the roles of WithWriteRoles by resolved column name.
+func (o *BaseGorm[T, PkType]) restrictedColumns() (map[string][]string, error) {
+	restricted := make(map[string][]string, len(o.opts.writePermissions.roles))
+	for column, roles := range o.opts.writePermissions.roles {
+		field, err := o.resolveColumn(column)
+		if err != nil {
+			return nil, err
+		}
+		restricted[field.DBName] = roles
+	}
+
+	return restricted, nil
+}
+
+// writtenColumns returns the columns Update writes from row given updatedColumns :
+// every column for "*", and the columns holding a non zero value when empty.
+func (o *BaseGorm[T, PkType]) writtenColumns(ctx context.Context, row *T, updatedColumns []string) ([]string, error) {
+	all := slices.Contains(updatedColumns, "*")
+	if o.opts.writePermissions == nil || (len(updatedColumns) > 0 && !all) {
+		return updatedColumns, nil
+	}
+
+	var columns []string
+	for column := range o.opts.writePermissions.roles {
+		field, err := o.resolveColumn(column)
+		if err != nil {
+			return nil, err
+		}
+		if _, zero := field.ValueOf(ctx, reflect.ValueOf(row).Elem()); all || !zero {
+			columns = append(columns, field.DBName)
+		}
+	}
+
+	return columns, nil
+}
+
+// checkWrite returns the columns to skip, or a ColumnPermissionError, when the actor
+// of ctx may not write some of columns.
+func (o *BaseGorm[T, PkType]) checkWrite(ctx context.Context, columns []string) ([]string, error) {
+	var e T
+
+	forbidden, err := o.forbiddenColumns(ctx, columns)
+	if err != nil || len(forbidden) == 0 || o.opts.writePermissions.strip {
+		return forbidden, err
+	}
+
+	return nil, &ColumnPermissionError{Table: e.TableName(), Columns: forbidden}
+}
+
+// permittedValues returns the values of UpdateWhere without the columns to skip.
+func (o *BaseGorm[T, PkType]) permittedValues(ctx context.Context, values map[string]interface{}) (map[string]interface{}, error) {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+
+	skipped, err := o.checkWrite(ctx, columns)
+	if err != nil || len(skipped) == 0 {
+		return values, err
+	}
+
+	permitted := make(map[string]interface{}, len(values))
+	for column, value := range values {
+		if !slices.Contains(skipped, column) {
+			permitted[column] = value
+		}
+	}
+
+	return permitted, nil
+}
+
+// permittedUpsert returns opts without the columns to skip among the ones updated on
+// conflict, UpdateAll becoming the other updatable columns, and DoNothing when none
+// is left.
+func (o *BaseGorm[T, PkType]) permittedUpsert(ctx context.Context, row *T, opts UpsertOptions) (UpsertOptions, error) {
+	if o.opts.writePermissions == nil || opts.DoNothing {
+		return opts, nil
+	}
+
+	updated := opts.UpdateColumns
+	if opts.UpdateAll {
+		updated = []string{"*"}
+	}
+	written, err := o.writtenColumns(ctx, row, updated)
+	if err != nil {
+		return opts, err
+	}
+	skipped, err := o.checkWrite(ctx, written)
+	if err != nil || len(skipped) == 0 {
+		return opts, err
+	}
+
+	if opts.UpdateAll {
+		sch, err := o.schema()
+		if err != nil {
+			return opts, err
+		}
+		opts.UpdateAll, opts.UpdateColumns = false, nil
+		for _, field := range sch.Fields {
+			if field.DBName != "" && !field.PrimaryKey && field.Updatable && field.AutoCreateTime == 0 && !slices.Contains(skipped, field.DBName) {
+				opts.UpdateColumns = append(opts.UpdateColumns, field.DBName)
+			}
+		}
+	} else {
+		opts.UpdateColumns = without(opts.UpdateColumns, skipped)
+	}
+	opts.DoNothing = len(opts.UpdateColumns) == 0
+
+	return opts, nil
+}
+
+// without returns columns without the skipped ones.
+func without(columns []string, skipped []string) []string {
+	kept := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !slices.Contains(skipped, column) {
+			kept = append(kept, column)
+		}
+	}
+
+	return kept
+}
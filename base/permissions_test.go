@@ -0,0 +1,214 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteRoles(t *testing.T) {
+	var (
+		admin  = ContextWithActor(context.Background(), Actor{ID: "1", Roles: []string{"admin"}})
+		member = ContextWithActor(context.Background(), Actor{ID: "2", Roles: []string{"member"}})
+	)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		opts      []RepoOption
+		update    func(repo *BaseGorm[User, uint], ctx context.Context) error
+		forbidden bool
+		written   bool
+	}{
+		{
+			name: "update rejects a forbidden non zero field",
+			ctx:  member,
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.Update(ctx, &User{ID: 1, Name: "Renamed", Email: "taken@example.com"}, nil)
+				return err
+			},
+			forbidden: true,
+		},
+		{
+			name: "update rejects a forbidden updated column",
+			ctx:  member,
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.Update(ctx, &User{ID: 1}, []string{"name", "email"})
+				return err
+			},
+			forbidden: true,
+		},
+		{
+			name: "update lets the role write the column",
+			ctx:  admin,
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.Update(ctx, &User{ID: 1, Name: "Renamed", Email: "taken@example.com"}, nil)
+				return err
+			},
+			written: true,
+		},
+		{
+			name: "update strips the forbidden column",
+			ctx:  member,
+			opts: []RepoOption{WithStripForbiddenColumns()},
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.Update(ctx, &User{ID: 1}, []string{"name", "email"})
+				return err
+			},
+		},
+		{
+			name: "update where rejects a forbidden value",
+			ctx:  member,
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.UpdateWhere(ctx, []Where{{Name: "id", Value: 1}}, map[string]interface{}{"name": "Renamed", "email": "taken@example.com"})
+				return err
+			},
+			forbidden: true,
+		},
+		{
+			name: "update rejects the field name of a forbidden column",
+			ctx:  member,
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.Update(ctx, &User{ID: 1, Name: "Renamed"}, []string{"name", "Email"})
+				return err
+			},
+			forbidden: true,
+		},
+		{
+			name: "update rejects another case of a forbidden column",
+			ctx:  member,
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.Update(ctx, &User{ID: 1, Name: "Renamed"}, []string{"name", "EMAIL"})
+				return err
+			},
+			forbidden: true,
+		},
+		{
+			name: "update where rejects the field name of a forbidden column",
+			ctx:  member,
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.UpdateWhere(ctx, []Where{{Name: "id", Value: 1}}, map[string]interface{}{"name": "Renamed", "Email": "taken@example.com"})
+				return err
+			},
+			forbidden: true,
+		},
+		{
+			name: "update where strips the forbidden value",
+			ctx:  member,
+			opts: []RepoOption{WithStripForbiddenColumns()},
+			update: func(repo *BaseGorm[User, uint], ctx context.Context) error {
+				_, err := repo.UpdateWhere(ctx, []Where{{Name: "id", Value: 1}}, map[string]interface{}{"name": "Renamed", "email": "taken@example.com"})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				db   = setupDryRunDB(t)
+				sql  = captureSQL(t, db)
+				repo = NewBaseGorm[User, uint](db, append([]RepoOption{WithWriteRoles("email", "admin")}, tt.opts...)...)
+			)
+
+			err := tt.update(repo, tt.ctx)
+			if tt.forbidden {
+				var permissionErr *ColumnPermissionError
+				if !errors.As(err, &permissionErr) || !errors.Is(err, ErrForbidden) {
+					t.Fatalf("Expected a ColumnPermissionError, got %v", err)
+				}
+				if len(permissionErr.Columns) != 1 || !strings.EqualFold(permissionErr.Columns[0], "email") {
+					t.Errorf("Expected email to be forbidden, got %v", permissionErr.Columns)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to update: %v", err)
+			}
+			if !strings.Contains(*sql, "`name`=") {
+				t.Errorf("Expected name to be written, got: %s", *sql)
+			}
+			if strings.Contains(*sql, "`email`=") != tt.written {
+				t.Errorf("Expected email written to be %v, got: %s", tt.written, *sql)
+			}
+		})
+	}
+}
+
+func TestWriteRolesRejectUnknownColumns(t *testing.T) {
+	var (
+		ctx  = ContextWithActor(context.Background(), Actor{ID: "2", Roles: []string{"member"}})
+		repo = NewBaseGorm[User, uint](setupDryRunDB(t), WithWriteRoles("email", "admin"))
+	)
+
+	if _, err := repo.Update(ctx, &User{ID: 1}, []string{"name", "e_mail"}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn from Update, got %v", err)
+	}
+	if _, err := repo.UpdateWhere(ctx, []Where{{Name: "id", Value: 1}}, map[string]interface{}{"e_mail": "taken@example.com"}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Expected ErrUnknownColumn from UpdateWhere, got %v", err)
+	}
+}
+
+func TestWriteRolesOtherWrites(t *testing.T) {
+	member := ContextWithActor(context.Background(), Actor{ID: "2", Roles: []string{"member"}})
+
+	tests := []struct {
+		name  string
+		write func(repo *BaseGorm[User, uint]) error
+		want  string // the conflict update once the forbidden column is stripped
+	}{
+		{
+			name: "upsert update columns",
+			write: func(repo *BaseGorm[User, uint]) error {
+				_, err := repo.Upsert(member, &User{Name: "Alice", Email: "alice@example.com"}, []string{"name", "email"})
+				return err
+			},
+			want: "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)",
+		},
+		{
+			name: "upsert update all",
+			write: func(repo *BaseGorm[User, uint]) error {
+				_, err := repo.UpsertWithOptions(member, &User{Name: "Alice", Email: "alice@example.com"}, UpsertOptions{UpdateAll: true})
+				return err
+			},
+			want: "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`updated_at`=VALUES(`updated_at`)",
+		},
+		{
+			name: "increment",
+			write: func(repo *BaseGorm[User, uint]) error {
+				_, err := repo.Increment(member, 1, "email", 1)
+				return err
+			},
+		},
+		{
+			name: "first or create assign",
+			write: func(repo *BaseGorm[User, uint]) error {
+				_, _, err := repo.FirstOrCreateAssign(member, []Where{{Name: "name", Value: "Alice"}}, nil, map[string]interface{}{"email": "alice@example.com"})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewBaseGorm[User, uint](setupDryRunDB(t), WithWriteRoles("email", "admin"))
+			if err := tt.write(repo); !errors.Is(err, ErrForbidden) {
+				t.Errorf("Expected ErrForbidden, got %v", err)
+			}
+
+			repo = NewBaseGorm[User, uint](setupDryRunDB(t), WithWriteRoles("email", "admin"), WithStripForbiddenColumns())
+			statements, err := repo.SQLOf(member, tt.write)
+			if err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			sql := strings.Join(statements, "\n")
+			if strings.Contains(sql, "`email`=") {
+				t.Errorf("Expected email to be skipped, got: %s", sql)
+			}
+			if !strings.Contains(sql, tt.want) {
+				t.Errorf("Expected %s, got: %s", tt.want, sql)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package base
+
+import "context"
+
+// previewSampleSize is the number of rows PreviewWhere returns.
+const previewSampleSize = 10
+
+// PreviewWhere reports how many rows UpdateWhere or DeleteWhere would change with
+// wheres, and returns the first of them by primary key, e.g. for a confirmation
+// screen before a bulk operation. Nothing is changed.
+func (o *BaseGorm[T, PkType]) PreviewWhere(ctx context.Context, wheres []Where) (int64, []T, error) {
+	var (
+		e      T
+		count  int64
+		sample []T
+		err    error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	if err = o.checkColumns(wheres, nil); err != nil {
+		return 0, nil, err
+	}
+	if err = bulkWheres(o.conn(ctx).Model(&e).Table(e.TableName()), wheres).Count(&count).Error; err != nil {
+		return 0, nil, err
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+
+	if err = bulkWheres(o.conn(ctx).Table(e.TableName()), wheres).Order(e.PrimaryKey()).Limit(previewSampleSize).Find(&sample).Error; err != nil {
+		return 0, nil, err
+	}
+	o.afterFind(ctx, sample)
+
+	return count, sample, nil
+}
@@ -0,0 +1,27 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPreviewWhere(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Document, uint](db)
+	)
+
+	count, sample, err := repo.PreviewWhere(context.Background(), []Where{{Name: "title", IsLike: true, Value: "Draft"}})
+	if err != nil {
+		t.Fatalf("Failed to run PreviewWhere: %v", err)
+	}
+	if count != 0 || sample != nil {
+		t.Errorf("Expected an empty preview in dry run, got %d, %v", count, sample)
+	}
+
+	want := "SELECT count(*) FROM `documents` WHERE title LIKE ? AND `documents`.`deleted_at` IS NULL"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
@@ -0,0 +1,20 @@
+package base
+
+import "context"
+
+// ListInto finds one page of the rows of repo matching wheres like List, scanned into
+// D, e.g. a read model holding a subset of the fields of T, of which only the
+// columns are selected :
+//
+//	summaries, paginator, err := base.ListInto[UserSummary](ctx, userRepo, page, 20, orders, wheres)
+func ListInto[D any, T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](ctx context.Context, repo *BaseGorm[T, PkType], page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]D, *Paginator, error) {
+	var e T
+
+	// Model keeps the soft delete scope of T, gorm selecting the columns of D
+	db := repo.conn(ctx).Model(&e).Table(e.TableName())
+	if !newQueryOptions(opts).unpublished {
+		db = repo.visible(db)
+	}
+
+	return paginateInto[D](ctx, repo, db, page, pageSize, orders, wheres, opts)
+}
@@ -0,0 +1,28 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+type documentTitle struct {
+	ID    uint
+	Title string
+}
+
+func TestListInto(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Document, uint](db)
+	)
+
+	if _, _, err := ListInto[documentTitle](ctx, repo, 2, 10, []OrderBy{{Field: "title", Direction: "asc"}}, nil, WithoutTotal()); err != nil {
+		t.Fatalf("Failed to run ListInto: %v", err)
+	}
+	want := "SELECT `documents`.`id`,`documents`.`title` FROM `documents` WHERE `documents`.`deleted_at` IS NULL ORDER BY title asc LIMIT ? OFFSET ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
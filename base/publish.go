@@ -0,0 +1,100 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Publishable is implemented by models only visible between the times of their
+// publishAt and expireAt columns, a NULL value leaving that side open. Models with
+// publish_at and expire_at columns are publishable without implementing it.
+type Publishable interface {
+	PublishWindow() (publishAt string, expireAt string)
+}
+
+// ErrNotPublishable is returned by the publish window methods when T has no publish window.
+var ErrNotPublishable = errors.New("model has no publish window")
+
+// WithUnpublished makes List include the rows outside their publish window.
+func WithUnpublished() QueryOption {
+	return func(o *queryOptions) {
+		o.unpublished = true
+	}
+}
+
+// ListUpcoming finds one page of the rows matching wheres to be published later.
+func (o *BaseGorm[T, PkType]) ListUpcoming(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error) {
+	return o.listPublishState(ctx, page, pageSize, orders, wheres, opts, func(db *gorm.DB, publishAt, expireAt string, now time.Time) *gorm.DB {
+		return db.Where(fmt.Sprintf("%s > ?", publishAt), now)
+	})
+}
+
+// ListExpired finds one page of the rows matching wheres whose publication expired.
+func (o *BaseGorm[T, PkType]) ListExpired(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error) {
+	return o.listPublishState(ctx, page, pageSize, orders, wheres, opts, func(db *gorm.DB, publishAt, expireAt string, now time.Time) *gorm.DB {
+		return db.Where(fmt.Sprintf("%s <= ?", expireAt), now)
+	})
+}
+
+func (o *BaseGorm[T, PkType]) listPublishState(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts []QueryOption, state func(db *gorm.DB, publishAt, expireAt string, now time.Time) *gorm.DB) ([]T, *Paginator, error) {
+	var e T
+
+	publishAt, expireAt, ok := o.publishWindow()
+	if !ok {
+		err := fmt.Errorf("%w: %s", ErrNotPublishable, e.TableName())
+		o.logError(ctx, err)
+		return nil, nil, err
+	}
+
+	db := state(o.conn(ctx).Model(&e).Table(e.TableName()), publishAt, expireAt, o.publishNow())
+
+	return o.paginate(ctx, db, page, pageSize, orders, wheres, opts)
+}
+
+// visible restricts db to the rows inside their publish window, when T has one.
+func (o *BaseGorm[T, PkType]) visible(db *gorm.DB) *gorm.DB {
+	publishAt, expireAt, ok := o.publishWindow()
+	if !ok {
+		return db
+	}
+
+	now := o.publishNow()
+
+	return db.Where(fmt.Sprintf("(%s IS NULL OR %s <= ?) AND (%s IS NULL OR %s > ?)", publishAt, publishAt, expireAt, expireAt), now, now)
+}
+
+// publishWindow returns the publish window columns of T.
+func (o *BaseGorm[T, PkType]) publishWindow() (publishAt string, expireAt string, ok bool) {
+	var e T
+
+	if publishable, ok := interface{}(e).(Publishable); ok {
+		publishAt, expireAt = publishable.PublishWindow()
+		return publishAt, expireAt, true
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return "", "", false
+	}
+	if _, ok := sch.FieldsByDBName["publish_at"]; !ok {
+		return "", "", false
+	}
+	if _, ok := sch.FieldsByDBName["expire_at"]; !ok {
+		return "", "", false
+	}
+
+	return "publish_at", "expire_at", true
+}
+
+// publishNow is the time publish windows are compared with.
+func (o *BaseGorm[T, PkType]) publishNow() time.Time {
+	if o.opts.timestamps != nil {
+		return o.opts.timestamps.now()
+	}
+
+	return time.Now()
+}
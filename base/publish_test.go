@@ -0,0 +1,112 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type Announcement struct {
+	ID        uint       `gorm:"column:id;primaryKey"`
+	Title     string     `gorm:"column:title"`
+	PublishAt *time.Time `gorm:"column:publish_at"`
+	ExpireAt  *time.Time `gorm:"column:expire_at"`
+}
+
+func (Announcement) TableName() string {
+	return "announcements"
+}
+
+func (Announcement) PrimaryKey() string {
+	return "id"
+}
+
+type Campaign struct {
+	ID       uint       `gorm:"column:id;primaryKey"`
+	StartsAt *time.Time `gorm:"column:starts_at"`
+	EndsAt   *time.Time `gorm:"column:ends_at"`
+}
+
+func (Campaign) TableName() string {
+	return "campaigns"
+}
+
+func (Campaign) PrimaryKey() string {
+	return "id"
+}
+
+func (Campaign) PublishWindow() (string, string) {
+	return "starts_at", "ends_at"
+}
+
+func TestPublishWindow(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Announcement, uint](db)
+	)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{
+			name: "list skips rows outside their window",
+			run: func() error {
+				_, _, err := repo.List(ctx, 1, 10, nil, nil, WithoutTotal())
+				return err
+			},
+			want: "SELECT * FROM `announcements` WHERE (publish_at IS NULL OR publish_at <= ?) AND (expire_at IS NULL OR expire_at > ?) LIMIT ?",
+		},
+		{
+			name: "list with unpublished rows",
+			run: func() error {
+				_, _, err := repo.List(ctx, 1, 10, nil, nil, WithoutTotal(), WithUnpublished())
+				return err
+			},
+			want: "SELECT * FROM `announcements` LIMIT ?",
+		},
+		{
+			name: "upcoming",
+			run: func() error {
+				_, _, err := repo.ListUpcoming(ctx, 1, 10, nil, nil, WithoutTotal())
+				return err
+			},
+			want: "SELECT * FROM `announcements` WHERE publish_at > ? LIMIT ?",
+		},
+		{
+			name: "expired",
+			run: func() error {
+				_, _, err := repo.ListExpired(ctx, 1, 10, nil, nil, WithoutTotal())
+				return err
+			},
+			want: "SELECT * FROM `announcements` WHERE expire_at <= ? LIMIT ?",
+		},
+		{
+			name: "custom window columns",
+			run: func() error {
+				_, _, err := NewBaseGorm[Campaign, uint](db).List(ctx, 1, 10, nil, nil, WithoutTotal())
+				return err
+			},
+			want: "SELECT * FROM `campaigns` WHERE (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?) LIMIT ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatalf("Failed to list: %v", err)
+			}
+			if *sql != tt.want {
+				t.Errorf("Expected SQL\n%s\ngot\n%s", tt.want, *sql)
+			}
+		})
+	}
+
+	if _, _, err := NewBaseGorm[User, uint](db).ListUpcoming(ctx, 1, 10, nil, nil); !errors.Is(err, ErrNotPublishable) {
+		t.Errorf("Expected ErrNotPublishable, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryOption adjusts a read of Detail, DetailMultiple, Wheres, WheresList, the List
+// methods, Each and Iterate. Count, Exists and Pluck take the options changing which
+// rows match : WithJoins, WithUnscoped and WithIndexHint.
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	withoutTotal bool
+	unpublished  bool
+	joins        []join
+	unscoped     bool
+	indexHints   []string
+	preloads     []preload
+	locking      *clause.Locking
+	selects      []string
+	distinct     bool
+	distinctOn   []string
+	rows         interface{} // []T, see WithRows
+	countDB      *gorm.DB    // the COUNT of ListCustomWithCount
+}
+
+// join is a JOIN clause, or an association joined by name, with its arguments.
+type join struct {
+	query string
+	args  []interface{}
+}
+
+// preload is an association eager loaded with its conditions.
+type preload struct {
+	association string
+	conds       []interface{}
+}
+
+func newQueryOptions(opts []QueryOption) queryOptions {
+	var o queryOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	return o
+}
+
+// apply adds the options changing the query itself to db, whose table is set.
+func (o queryOptions) apply(db *gorm.DB) *gorm.DB {
+	return o.load(o.scope(db))
+}
+
+// scope adds the options changing which rows match to db, whose table is set. They
+// apply to the COUNT of the List methods too.
+func (o queryOptions) scope(db *gorm.DB) *gorm.DB {
+	if len(o.indexHints) > 0 {
+		if db.Statement.Table == "" && db.Statement.Model != nil {
+			_ = db.Statement.Parse(db.Statement.Model)
+		}
+		// the names are written in the SQL as is
+		for _, index := range o.indexHints {
+			if !indexName.MatchString(index) {
+				_ = db.AddError(fmt.Errorf("%w %q", ErrInvalidIndexHint, index))
+				return db
+			}
+		}
+		// the table name is kept by gorm for the soft delete clause
+		db = db.Table(fmt.Sprintf("%s USE INDEX (%s)", db.Statement.Quote(db.Statement.Table), strings.Join(o.indexHints, ", ")))
+	}
+	if o.unscoped {
+		db = db.Unscoped()
+	}
+	for _, j := range o.joins {
+		db = db.Joins(j.query, j.args...)
+	}
+
+	return db
+}
+
+// load adds the options changing how the matching rows are loaded to db.
+func (o queryOptions) load(db *gorm.DB) *gorm.DB {
+	for _, p := range o.preloads {
+		db = db.Preload(p.association, p.conds...)
+	}
+	if o.locking != nil {
+		db = db.Clauses(*o.locking)
+	}
+	if len(o.selects) > 0 {
+		db = db.Select(o.selects)
+	}
+	if o.distinct {
+		switch {
+		case len(o.distinctOn) > 0:
+			db = db.Distinct(o.distinctOn)
+		case len(o.selects) > 0:
+			db = db.Distinct()
+		default:
+			if db.Statement.Table == "" && db.Statement.Model != nil {
+				_ = db.Statement.Parse(db.Statement.Model)
+			}
+			db = db.Distinct(db.Statement.Table + ".*")
+		}
+	}
+
+	return db
+}
+
+// WithoutTotal skips the COUNT query of a List, e.g. for infinite scrolling. One
+// more row than the page size is fetched to set Paginator.HasNext, and Total,
+// TotalPages and LastPage are -1.
+func WithoutTotal() QueryOption {
+	return func(o *queryOptions) {
+		o.withoutTotal = true
+	}
+}
+
+// WithJoins joins query, a JOIN clause with its args, e.g. "JOIN posts ON
+// posts.user_id = users.id AND posts.status = ?", "published", or the name of a
+// has one or belongs to association of T, whose fields are then loaded too. Combine
+// it with WithDistinct when the join matches several rows per row of T.
+func WithJoins(query string, args ...interface{}) QueryOption {
+	return func(o *queryOptions) {
+		o.joins = append(o.joins, join{query: query, args: args})
+	}
+}
+
+// WithUnscoped includes the soft deleted rows, see ListTrashed for those only.
+func WithUnscoped() QueryOption {
+	return func(o *queryOptions) {
+		o.unscoped = true
+	}
+}
+
+// ErrInvalidIndexHint is returned by the reads given a WithIndexHint index name which
+// is not a plain identifier.
+var ErrInvalidIndexHint = errors.New("invalid index hint")
+
+var indexName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// WithIndexHint makes MySQL use one of indexes, by name, for the table of T with a
+// USE INDEX hint, when its optimizer picks a worse one. Other databases reject it.
+// The names are made of letters, digits and underscores, else the read fails with
+// ErrInvalidIndexHint.
+func WithIndexHint(indexes ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.indexHints = append(o.indexHints, indexes...)
+	}
+}
+
+// WithPreload eager loads association, the struct field name (e.g. "Posts", or
+// "Posts.Comments" for nested ones), with the found rows. conds are the conditions
+// of gorm's Preload, e.g. "published = ?", true.
+func WithPreload(association string, conds ...interface{}) QueryOption {
+	return func(o *queryOptions) {
+		o.preloads = append(o.preloads, preload{association: association, conds: conds})
+	}
+}
+
+// WithLock locks the found rows until the end of the transaction, e.g.
+// clause.Locking{Strength: clause.LockingStrengthUpdate} for SELECT ... FOR UPDATE, with
+// Options clause.LockingOptionsSkipLocked to consume rows as a queue or
+// clause.LockingOptionsNoWait to fail at once on locked rows. Outside a transaction,
+// see Transaction, the locks are released as soon as the query returns.
+func WithLock(locking clause.Locking) QueryOption {
+	return func(o *queryOptions) {
+		o.locking = &locking
+	}
+}
+
+// WithSelect only loads columns of the found rows, leaving the other fields zero,
+// e.g. to list the ids and names of users without their other columns. The COUNT of
+// the List methods is not affected.
+func WithSelect(columns ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.selects = append(o.selects, columns...)
+	}
+}
+
+// WithDistinct collapses the duplicate rows found by WheresList and the List methods,
+// e.g. those a ListCustom callback joining a has many association returns once per
+// child : SELECT DISTINCT on columns, the WithSelect ones or every column of T by
+// default. The COUNT of the List methods counts the distinct values of the same
+// columns, of T's primary key by default.
+func WithDistinct(columns ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.distinct = true
+		o.distinctOn = append(o.distinctOn, columns...)
+	}
+}
+
+// countDistinct is the COUNT expression of a List given WithDistinct, pk being the
+// qualified primary key of T.
+func (o queryOptions) countDistinct(pk string) string {
+	columns := []string{pk}
+	switch {
+	case len(o.distinctOn) > 0:
+		columns = o.distinctOn
+	case len(o.selects) > 0:
+		columns = o.selects
+	}
+
+	return fmt.Sprintf("COUNT(DISTINCT %s)", strings.Join(columns, ", "))
+}
+
+// WithRows makes WheresList and the List methods scan into rows, a []T emptied first,
+// reusing its capacity instead of allocating a new slice, e.g. one from a RowPool. The
+// returned rows share its backing array, so rows must not be used by the caller while
+// they are.
+func WithRows[T any](rows []T) QueryOption {
+	return func(o *queryOptions) {
+		o.rows = rows
+	}
+}
+
+// rowsOf returns the slice given by WithRows, emptied, nil without one.
+func rowsOf[T any](o queryOptions) ([]T, error) {
+	if o.rows == nil {
+		return nil, nil
+	}
+
+	rows, ok := o.rows.([]T)
+	if !ok {
+		var e T
+		return nil, fmt.Errorf("WithRows of %T cannot hold rows of %T", o.rows, e)
+	}
+
+	return rows[:0], nil
+}
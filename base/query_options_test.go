@@ -0,0 +1,188 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestQueryOptionsApplyPreloads(t *testing.T) {
+	db := newQueryOptions([]QueryOption{
+		WithPreload("Profile"),
+		WithPreload("Posts", "title = ?", "Published"),
+	}).apply(setupDryRunDB(t).Table(User{}.TableName()))
+
+	want := map[string][]interface{}{
+		"Profile": nil,
+		"Posts":   {"title = ?", "Published"},
+	}
+	if !reflect.DeepEqual(db.Statement.Preloads, want) {
+		t.Errorf("Expected preloads %v, got %v", want, db.Statement.Preloads)
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	if _, err := repo.Detail(ctx, 1, WithLock(clause.Locking{Strength: clause.LockingStrengthUpdate})); err != nil {
+		t.Fatalf("Failed to run Detail: %v", err)
+	}
+	if !strings.HasSuffix(*sql, "FOR UPDATE") {
+		t.Errorf("Expected Detail to lock the row, got: %s", *sql)
+	}
+
+	if _, err := repo.WheresList(ctx, nil, []Where{{Name: "name", Value: "Alice"}}, WithLock(clause.Locking{
+		Strength: clause.LockingStrengthUpdate,
+		Options:  clause.LockingOptionsSkipLocked,
+	})); err != nil {
+		t.Fatalf("Failed to run WheresList: %v", err)
+	}
+	if !strings.HasSuffix(*sql, "FOR UPDATE SKIP LOCKED") {
+		t.Errorf("Expected WheresList to skip locked rows, got: %s", *sql)
+	}
+}
+
+func TestWithRowsOfAnotherModel(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t))
+
+	if _, err := repo.WheresList(context.Background(), nil, nil, WithRows(make([]Post, 0, 10))); err == nil {
+		t.Error("Expected WheresList to refuse a slice of posts")
+	}
+}
+
+func TestWithSelect(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	if _, err := repo.WheresList(ctx, nil, []Where{{Name: "name", Value: "Alice"}}, WithSelect("id", "name")); err != nil {
+		t.Fatalf("Failed to run WheresList: %v", err)
+	}
+	want := "SELECT `id`,`name` FROM `dummy_users` WHERE name = ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, _, err := repo.List(ctx, 1, 10, nil, nil, WithSelect("id"), WithoutTotal()); err != nil {
+		t.Fatalf("Failed to run List: %v", err)
+	}
+	want = "SELECT `id` FROM `dummy_users` LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
+
+func TestWithDistinct(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		db      = setupDryRunDB(t)
+		sql     = captureSQL(t, db)
+		repo    = NewBaseGorm[User, uint](db)
+		queries []string
+		join    = func(db *gorm.DB) *gorm.DB {
+			return db.Model(&User{}).Joins("JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id").Where("dummy_posts.title LIKE ?", "%go%")
+		}
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:capture_queries", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	// the dry run counts 0 rows, skipping the find
+	if _, _, err := repo.ListCustom(ctx, 1, 10, nil, nil, join, WithDistinct()); err != nil {
+		t.Fatalf("Failed to run ListCustom: %v", err)
+	}
+	want := "SELECT COUNT(DISTINCT dummy_users.id) FROM `dummy_users` JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id WHERE dummy_posts.title LIKE ?"
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%v", want, queries)
+	}
+
+	if _, _, err := repo.ListCustom(ctx, 1, 10, nil, nil, join, WithDistinct(), WithoutTotal()); err != nil {
+		t.Fatalf("Failed to run ListCustom: %v", err)
+	}
+	want = "SELECT DISTINCT dummy_users.* FROM `dummy_users` JOIN dummy_posts ON dummy_posts.user_id = dummy_users.id WHERE dummy_posts.title LIKE ? LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, err := repo.WheresList(ctx, nil, nil, WithDistinct("name")); err != nil {
+		t.Fatalf("Failed to run WheresList: %v", err)
+	}
+	want = "SELECT DISTINCT `name` FROM `dummy_users`"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
+
+func TestScopeOptions(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		db      = setupDryRunDB(t)
+		sql     = captureSQL(t, db)
+		repo    = NewBaseGorm[Document, uint](db)
+		queries []string
+		opts    = []QueryOption{
+			WithIndexHint("idx_documents_title"),
+			WithJoins("JOIN authors ON authors.id = documents.author_id AND authors.active = ?", true),
+		}
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:capture_queries", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	// the dry run counts 0 rows, skipping the find
+	if _, _, err := repo.List(ctx, 1, 10, nil, []Where{{Name: "title", Value: "Go"}}, opts...); err != nil {
+		t.Fatalf("Failed to run List: %v", err)
+	}
+	want := "SELECT count(*) FROM `documents` USE INDEX (idx_documents_title) JOIN authors ON authors.id = documents.author_id AND authors.active = ? WHERE title = ? AND `documents`.`deleted_at` IS NULL"
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%v", want, queries)
+	}
+
+	if _, err := repo.Count(ctx, nil, WithUnscoped()); err != nil {
+		t.Fatalf("Failed to run Count: %v", err)
+	}
+	want = "SELECT count(*) FROM `documents`"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, err := repo.Exists(ctx, nil, WithUnscoped()); err != nil {
+		t.Fatalf("Failed to run Exists: %v", err)
+	}
+	want = "SELECT 1 FROM `documents` LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, err := repo.Detail(ctx, 1, WithIndexHint("PRIMARY")); err != nil {
+		t.Fatalf("Failed to run Detail: %v", err)
+	}
+	want = "SELECT * FROM `documents` USE INDEX (PRIMARY) WHERE id = ? AND `documents`.`deleted_at` IS NULL ORDER BY `documents`.`id` LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, err := repo.Count(ctx, nil, WithIndexHint("PRIMARY) WHERE 1 = 1 --")); !errors.Is(err, ErrInvalidIndexHint) {
+		t.Errorf("Expected ErrInvalidIndexHint, got %v", err)
+	}
+	if _, err := repo.Detail(ctx, 1, WithIndexHint("")); !errors.Is(err, ErrInvalidIndexHint) {
+		t.Errorf("Expected ErrInvalidIndexHint, got %v", err)
+	}
+}
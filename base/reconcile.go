@@ -0,0 +1,227 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ReconcileOptions configures Reconcile.
+type ReconcileOptions struct {
+	Scope     []Where // restricts the stored rows compared with the snapshot, e.g. to one source system
+	BatchSize int     // rows written per transaction, 500 when not positive
+	DryRun    bool    // only compute the report
+}
+
+// ReconcileUpdate is a stored row changed by Reconcile, with its changed columns.
+type ReconcileUpdate[T any] struct {
+	Row     *T
+	Changes map[string]Change
+}
+
+// ReconcileReport lists what Reconcile did, or would do in dry run mode.
+type ReconcileReport[T any] struct {
+	Inserts   []*T                 // snapshot rows without stored row
+	Updates   []ReconcileUpdate[T] // stored rows whose columns differ, restored too when they were soft deleted
+	Deletes   []*T                 // stored rows missing from the snapshot, soft deleted
+	Unchanged int
+}
+
+// Reconcile makes the stored rows in scope match snapshot, the full list of rows of
+// an external system, matched by the natural key keyColumns : missing rows are
+// inserted, changed ones updated, and stored rows missing from the snapshot soft
+// deleted (T needs a gorm.DeletedAt field). Soft deleted rows coming back are
+// restored. Writes are applied by transactions of BatchSize rows.
+func (o *BaseGorm[T, PkType]) Reconcile(ctx context.Context, snapshot []*T, keyColumns []string, opts ReconcileOptions) (*ReconcileReport[T], error) {
+	var (
+		report = &ReconcileReport[T]{}
+		err    error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	o.forgetIdentities(ctx)
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	deletedAt := deletedAtField(sch)
+	if deletedAt == nil {
+		err = fmt.Errorf("%w: %s", ErrSoftDeleteUnsupported, sch.Table)
+		return nil, err
+	}
+
+	keyFields := make([]*schema.Field, len(keyColumns))
+	for i, column := range keyColumns {
+		if keyFields[i] = sch.LookUpField(column); keyFields[i] == nil || keyFields[i].DBName == "" {
+			err = fmt.Errorf("key column %s not found in %s", column, sch.Table)
+			return nil, err
+		}
+	}
+	if len(keyFields) == 0 {
+		err = fmt.Errorf("reconcile of %s requires key columns", sch.Table)
+		return nil, err
+	}
+
+	keyOf := func(row *T) string {
+		values := make([]interface{}, len(keyFields))
+		for i, field := range keyFields {
+			values[i], _ = field.ValueOf(ctx, reflect.ValueOf(row).Elem())
+		}
+		return naturalKey(values)
+	}
+
+	// soft deleted rows are loaded too, to be restored instead of inserted again
+	var stored []*T
+	if err = bulkWheres(o.conn(ctx).Unscoped().Table(sch.Table), opts.Scope).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	storedByKey := make(map[string]*T, len(stored))
+	for _, row := range stored {
+		storedByKey[keyOf(row)] = row
+	}
+
+	seen := make(map[string]bool, len(snapshot))
+	for _, row := range snapshot {
+		key := keyOf(row)
+		if seen[key] {
+			err = fmt.Errorf("snapshot of %s repeats the key %q", sch.Table, key)
+			return nil, err
+		}
+		seen[key] = true
+
+		current, ok := storedByKey[key]
+		if !ok {
+			report.Inserts = append(report.Inserts, row)
+			continue
+		}
+
+		changes := reconcileChanges(ctx, sch, current, row)
+		if len(changes) == 0 {
+			report.Unchanged++
+			continue
+		}
+
+		// the snapshot row becomes the stored one, keeping its primary key
+		pk, _ := sch.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(current).Elem())
+		if err = sch.PrioritizedPrimaryField.Set(ctx, reflect.ValueOf(row).Elem(), pk); err != nil {
+			return nil, err
+		}
+		report.Updates = append(report.Updates, ReconcileUpdate[T]{Row: row, Changes: changes})
+	}
+
+	for _, row := range stored {
+		if _, active := deletedAt.ValueOf(ctx, reflect.ValueOf(row).Elem()); active && !seen[keyOf(row)] {
+			report.Deletes = append(report.Deletes, row)
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	err = o.applyReconcile(ctx, sch, report, opts.BatchSize)
+
+	return report, err
+}
+
+// reconcileChanges compares the columns of stored and wanted, ignoring the primary
+// key and the columns managed by gorm. A soft deleted stored row is a change of its
+// deleted at column.
+func reconcileChanges[T any](ctx context.Context, sch *schema.Schema, stored, wanted *T) map[string]Change {
+	var (
+		storedValue = reflect.ValueOf(stored).Elem()
+		wantedValue = reflect.ValueOf(wanted).Elem()
+		changes     = map[string]Change{}
+	)
+
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.PrimaryKey || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 {
+			continue
+		}
+
+		before, _ := field.ValueOf(ctx, storedValue)
+		after, _ := field.ValueOf(ctx, wantedValue)
+		if deletedAt, ok := before.(gorm.DeletedAt); ok {
+			if deletedAt.Valid {
+				changes[field.DBName] = Change{Old: deletedAt, New: gorm.DeletedAt{}}
+			}
+			continue
+		}
+		if !valuesEqual(before, after) {
+			changes[field.DBName] = Change{Old: before, New: after}
+		}
+	}
+
+	return changes
+}
+
+// applyReconcile writes report by transactions of batchSize rows.
+func (o *BaseGorm[T, PkType]) applyReconcile(ctx context.Context, sch *schema.Schema, report *ReconcileReport[T], batchSize int) error {
+	var e T
+
+	inBatches := func(n int, write func(ctx context.Context, from, to int) error) error {
+		for from := 0; from < n; from += batchSize {
+			to := min(from+batchSize, n)
+			if err := o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+				return write(generic_gorm.ContextWithDB(ctx, tx), from, to)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := inBatches(len(report.Inserts), func(ctx context.Context, from, to int) error {
+		_, _, err := o.CreateMultiple(ctx, report.Inserts[from:to])
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := inBatches(len(report.Updates), func(ctx context.Context, from, to int) error {
+		for _, update := range report.Updates[from:to] {
+			columns := changedColumns(update.Changes)
+			o.normalizeTimes(ctx, update.Row)
+			if err := o.validate(ctx, update.Row, columns); err != nil {
+				return err
+			}
+			if err := o.conn(ctx).Unscoped().Model(update.Row).Select(columns).Updates(update.Row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return inBatches(len(report.Deletes), func(ctx context.Context, from, to int) error {
+		ids := make([]PkType, 0, to-from)
+		for _, row := range report.Deletes[from:to] {
+			id, err := o.primaryKeyOf(ctx, sch, row)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return o.conn(ctx).Where(fmt.Sprintf("%s IN ?", e.PrimaryKey()), ids).Delete(&e).Error
+	})
+}
@@ -0,0 +1,53 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestReconcileDryRun(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		repo = NewBaseGorm[Document, uint](db)
+	)
+
+	report, err := repo.Reconcile(ctx, []*Document{{Title: "a"}, {Title: "b"}}, []string{"title"}, ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to reconcile documents: %v", err)
+	}
+	if len(report.Inserts) != 2 || len(report.Updates) != 0 || len(report.Deletes) != 0 {
+		t.Errorf("Expected 2 inserts into an empty table, got %+v", report)
+	}
+
+	if _, err := repo.Reconcile(ctx, []*Document{{Title: "a"}, {Title: "a"}}, []string{"title"}, ReconcileOptions{DryRun: true}); err == nil {
+		t.Error("Expected error for a snapshot repeating a key")
+	}
+
+	users := NewBaseGorm[User, uint](db)
+	if _, err := users.Reconcile(ctx, nil, []string{"email"}, ReconcileOptions{DryRun: true}); !errors.Is(err, ErrSoftDeleteUnsupported) {
+		t.Errorf("Expected ErrSoftDeleteUnsupported, got %v", err)
+	}
+}
+
+func TestReconcileChanges(t *testing.T) {
+	repo := NewBaseGorm[Document, uint](setupDryRunDB(t))
+	sch, err := repo.schema()
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	trashed := &Document{ID: 1, Title: "a", DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+	changes := reconcileChanges(context.Background(), sch, trashed, &Document{Title: "a"})
+	if len(changes) != 1 || changes["deleted_at"].New != (gorm.DeletedAt{}) {
+		t.Errorf("Expected the trashed document to be restored, got %+v", changes)
+	}
+
+	if changes := reconcileChanges(context.Background(), sch, &Document{ID: 1, Title: "a"}, &Document{Title: "a"}); len(changes) != 0 {
+		t.Errorf("Expected no change ignoring the primary key, got %+v", changes)
+	}
+}
@@ -0,0 +1,97 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository is the interface of BaseGorm, so its consumers can depend on it and
+// swap in a mock, e.g. generated with mockery or gomock, in their tests. It leaves
+// out Transaction, WithTx and SQLOf, which hand a *BaseGorm to their caller : the
+// consumers of the interface run their transactions with generic_gorm.TxManager,
+// whose context every repository joins.
+type Repository[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] interface {
+	// reads
+	Detail(ctx context.Context, id PkType, opts ...QueryOption) (*T, error)
+	DetailMultiple(ctx context.Context, ids []PkType, opts ...QueryOption) ([]*T, error)
+	Wheres(ctx context.Context, wheres []Where, opts ...QueryOption) (*T, error)
+	WheresList(ctx context.Context, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, error)
+	List(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error)
+	ListCustom(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, customCallback ListCustomCallback, opts ...QueryOption) ([]T, *Paginator, error)
+	ListCustomWithCount(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, selectCallback ListCustomCallback, countCallback ListCustomCallback, opts ...QueryOption) ([]T, *Paginator, error)
+	ListUpcoming(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error)
+	ListExpired(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error)
+	ListTrashed(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error)
+	Each(ctx context.Context, wheres []Where, orders []OrderBy, batchSize int, fn func(row *T) error, opts ...QueryOption) error
+	Iterate(ctx context.Context, wheres []Where, orders []OrderBy, batchSize int, opts ...QueryOption) iter.Seq2[*T, error]
+	Exists(ctx context.Context, wheres []Where, opts ...QueryOption) (bool, error)
+	Count(ctx context.Context, wheres []Where, opts ...QueryOption) (int64, error)
+	Pluck(ctx context.Context, column string, wheres []Where, dest interface{}, opts ...QueryOption) error
+	MaxBy(ctx context.Context, column string, wheres []Where) (*T, error)
+	MinBy(ctx context.Context, column string, wheres []Where) (*T, error)
+	SumDecimal(ctx context.Context, column string, wheres []Where) (Decimal, error)
+	SumInt64(ctx context.Context, column string, wheres []Where) (int64, error)
+	Avg(ctx context.Context, column string, wheres []Where) (float64, error)
+	Min(ctx context.Context, column string, wheres []Where, dest interface{}) error
+	Max(ctx context.Context, column string, wheres []Where, dest interface{}) error
+	GroupCount(ctx context.Context, groupColumn string, wheres []Where) (map[string]int64, error)
+	Facets(ctx context.Context, wheres []Where, facetColumns []string) (map[string][]FacetValue, error)
+	PreviewWhere(ctx context.Context, wheres []Where) (int64, []T, error)
+	FindDuplicates(ctx context.Context, columns []string) ([][]T, error)
+	TableChecksum(ctx context.Context, wheres []Where, columns []string) (string, error)
+	MapJSONNames(wheres []Where, orders []OrderBy) ([]Where, []OrderBy, error)
+	CoerceWheres(wheres []Where) ([]Where, error)
+
+	// writes
+	Create(ctx context.Context, row *T) (*T, error)
+	CreateMultiple(ctx context.Context, rows []*T) ([]*T, int64, error)
+	CreateMultipleDedup(ctx context.Context, rows []*T, keyColumns []string) (inserted []*T, skipped []*T, err error)
+	CreateMultiplePartial(ctx context.Context, rows []*T) (*ImportReport[T], error)
+	FirstOrCreate(ctx context.Context, wheres []Where, defaults *T) (*T, bool, error)
+	FirstOrCreateAssign(ctx context.Context, wheres []Where, defaults *T, assign map[string]interface{}) (*T, bool, error)
+	Update(ctx context.Context, row *T, updatedColumns []string) (int64, error)
+	UpdateWhere(ctx context.Context, wheres []Where, values map[string]interface{}) (int64, error)
+	UpdateWithFieldMask(ctx context.Context, row *T, mask []string) (int64, error)
+	Patch(ctx context.Context, id PkType, patch json.RawMessage) (*T, error)
+	Increment(ctx context.Context, id PkType, column string, delta interface{}) (int64, error)
+	Upsert(ctx context.Context, row *T, onConflictUpdatedColumns []string) (int64, error)
+	UpsertWithOptions(ctx context.Context, row *T, opts UpsertOptions) (*T, error)
+	Merge(ctx context.Context, keepID PkType, mergeIDs []PkType, strategy MergeStrategy[T]) (int64, error)
+	Reconcile(ctx context.Context, snapshot []*T, keyColumns []string, opts ReconcileOptions) (*ReconcileReport[T], error)
+
+	// deletes
+	Delete(ctx context.Context, id PkType) (int64, error)
+	DeleteWhere(ctx context.Context, wheres []Where) (int64, error)
+	SoftDelete(ctx context.Context, id PkType) (int64, error)
+	ForceDelete(ctx context.Context, id PkType) (int64, error)
+	Restore(ctx context.Context, id PkType) (int64, error)
+	PurgeExpired(ctx context.Context, column string, maxAge time.Duration, batchSize int) (int64, error)
+	UndoOperation(ctx context.Context, operationID string) (int64, error)
+
+	// associations
+	Association(ctx context.Context, model *T, field string, opts ...AssociationOption) *gorm.Association
+	AppendAssociation(ctx context.Context, model *T, field string, values interface{}) error
+	ReplaceAssociation(ctx context.Context, model *T, field string, values interface{}) error
+	DeleteAssociation(ctx context.Context, model *T, field string, values interface{}) error
+	ClearAssociation(ctx context.Context, model *T, field string) error
+	CountAssociation(ctx context.Context, model *T, field string, opts ...AssociationOption) int64
+	CountAssociationWithError(ctx context.Context, model *T, field string, opts ...AssociationOption) (int64, error)
+	CountAssociations(ctx context.Context, models []*T, field string, opts ...AssociationOption) (map[PkType]int64, error)
+	FindAssociation(ctx context.Context, model *T, field string, dest interface{}, opts ...AssociationOption) error
+	ListAssociation(ctx context.Context, model *T, field string, dest interface{}, page int, pageSize int, opts ...AssociationOption) (*Paginator, error)
+	IterateAssociation(ctx context.Context, model *T, field string, dest interface{}, batchSize int, fn func() error, opts ...AssociationOption) error
+	SyncAssociation(ctx context.Context, model *T, field string, desired interface{}) (SyncResult, error)
+
+	// change tracking
+	Diff(oldRow, newRow *T) (map[string]Change, error)
+	SaveChanges(ctx context.Context, row *T) (int64, error)
+	Untrack(ctx context.Context, id PkType)
+
+	// raw access
+	DB(ctx context.Context) *gorm.DB
+	VerifyFieldTypes() error
+}
@@ -0,0 +1,4 @@
+package base
+
+// BaseGorm must keep implementing Repository.
+var _ Repository[User, uint] = (*BaseGorm[User, uint])(nil)
@@ -0,0 +1,68 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PurgeExpired hard deletes the rows whose time column is older than maxAge, by
+// batches of batchSize rows (1000 when not positive) so no long lived lock is held
+// on the table. It returns the number of deleted rows, also when ctx is cancelled
+// between two batches.
+func (o *BaseGorm[T, PkType]) PurgeExpired(ctx context.Context, column string, maxAge time.Duration, batchSize int) (int64, error) {
+	var (
+		e      T
+		cutoff = time.Now().Add(-maxAge)
+		purged int64
+		err    error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	ctx, end := o.startMethodSpan(ctx)
+	defer func() { end(err) }()
+
+	o.forgetIdentities(ctx)
+
+	// column is written in the SQL as is
+	if err = o.checkColumns([]Where{{Name: column}}, nil); err != nil {
+		return 0, err
+	}
+	field, err := o.resolveColumn(column)
+	if err != nil {
+		return 0, err
+	}
+
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if o.opts.timestamps != nil {
+		cutoff = o.opts.timestamps.normalizeTime(cutoff)
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		result := o.conn(ctx).
+			Table(e.TableName()).
+			Unscoped().
+			Where(fmt.Sprintf("%s < ?", field.DBName), cutoff).
+			Limit(batchSize).
+			Delete(&e)
+		if err = result.Error; err != nil {
+			return purged, err
+		}
+
+		purged += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
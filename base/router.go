@@ -0,0 +1,11 @@
+package base
+
+import (
+	generic_gorm "github.com/harryosmar/generic-gorm"
+)
+
+// NewRoutedBaseGorm returns a repository of T on the database the router sends T to.
+func NewRoutedBaseGorm[T TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](router *generic_gorm.Router, opts ...RepoOption) *BaseGorm[T, PkType] {
+	var e T
+	return NewBaseGorm[T, PkType](router.DB(e), opts...)
+}
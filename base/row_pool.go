@@ -0,0 +1,39 @@
+package base
+
+import "sync"
+
+// RowPool recycles the slices rows are scanned into, see WithRows, so the endpoints
+// paging through large results continuously stop allocating one slice per page.
+type RowPool[T any] struct {
+	pool     sync.Pool
+	capacity int
+}
+
+// NewRowPool returns a RowPool of slices of capacity rows, usually the page size.
+func NewRowPool[T any](capacity int) *RowPool[T] {
+	p := &RowPool[T]{capacity: capacity}
+	p.pool.New = func() interface{} {
+		rows := make([]T, 0, p.capacity)
+		return &rows
+	}
+
+	return p
+}
+
+// Get returns an empty slice of at least the capacity of the pool.
+func (p *RowPool[T]) Get() []T {
+	return (*p.pool.Get().(*[]T))[:0]
+}
+
+// Put gives rows back to the pool once the caller is done with them. Their elements
+// are zeroed first so the pool keeps no reference to what they pointed to.
+func (p *RowPool[T]) Put(rows []T) {
+	if cap(rows) < p.capacity {
+		return
+	}
+
+	rows = rows[:cap(rows)]
+	clear(rows)
+	rows = rows[:0]
+	p.pool.Put(&rows)
+}
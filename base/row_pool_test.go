@@ -0,0 +1,23 @@
+package base
+
+import "testing"
+
+func TestRowPool(t *testing.T) {
+	pool := NewRowPool[User](10)
+
+	rows := pool.Get()
+	if len(rows) != 0 || cap(rows) < 10 {
+		t.Fatalf("Expected an empty slice of capacity 10, got len %d cap %d", len(rows), cap(rows))
+	}
+
+	rows = append(rows, User{ID: 1, Name: "Alice"})
+	pool.Put(rows)
+
+	// sync.Pool may drop what it is given, only the zeroing can be checked
+	if rows[0].Name != "" {
+		t.Errorf("Expected the returned rows to be zeroed, got %+v", rows[0])
+	}
+	if rows = pool.Get(); len(rows) != 0 || cap(rows) < 10 {
+		t.Errorf("Expected an empty slice of capacity 10, got len %d cap %d", len(rows), cap(rows))
+	}
+}
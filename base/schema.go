@@ -0,0 +1,66 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// schema returns gorm's parsed schema of T, cached by gorm per *gorm.DB.
+func (o *BaseGorm[T, PkType]) schema() (*schema.Schema, error) {
+	var (
+		e    T
+		stmt = &gorm.Statement{DB: o.db}
+	)
+
+	if err := stmt.Parse(&e); err != nil {
+		return nil, err
+	}
+
+	return stmt.Schema, nil
+}
+
+// primaryKeyOf reads the value of T's primary key column from row.
+func (o *BaseGorm[T, PkType]) primaryKeyOf(ctx context.Context, sch *schema.Schema, row *T) (PkType, error) {
+	var (
+		e  T
+		pk PkType
+	)
+
+	field := sch.LookUpField(e.PrimaryKey())
+	if field == nil {
+		return pk, fmt.Errorf("primary key %s not found in %s", e.PrimaryKey(), e.TableName())
+	}
+
+	value, _ := field.ValueOf(ctx, reflect.ValueOf(row).Elem())
+	pk, ok := value.(PkType)
+	if !ok {
+		return pk, fmt.Errorf("primary key %s of %s is %T, not %T", e.PrimaryKey(), e.TableName(), value, pk)
+	}
+
+	return pk, nil
+}
+
+// PolymorphicID returns the primary key of model, a pointer to a stored row, as a
+// string, the way tables attached to rows of any model (tags, notes...) store it.
+func PolymorphicID(ctx context.Context, db *gorm.DB, model TablerWithPrimaryKey) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+
+	field := stmt.Schema.LookUpField(model.PrimaryKey())
+	if field == nil {
+		return "", fmt.Errorf("primary key %s not found in %s", model.PrimaryKey(), model.TableName())
+	}
+
+	value, zero := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(model)))
+	if zero {
+		return "", fmt.Errorf("row of %s without primary key", model.TableName())
+	}
+
+	return fmt.Sprint(value), nil
+}
@@ -0,0 +1,17 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPolymorphicID(t *testing.T) {
+	db := setupDryRunDB(t)
+
+	if _, err := PolymorphicID(context.Background(), db, &User{}); err == nil {
+		t.Error("Expected an error for a row without primary key")
+	}
+	if id, err := PolymorphicID(context.Background(), db, &User{ID: 42}); err != nil || id != "42" {
+		t.Errorf("Expected id 42, got %q, %v", id, err)
+	}
+}
@@ -0,0 +1,111 @@
+package base
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+var (
+	typeSerializers sync.Map // reflect.Type => serializer name
+
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// RegisterTypeSerializer maps the Go type of sample (money, encrypted string, JSON
+// document...) to a GORM serializer, registering the serializer under name with gorm.
+// It is meant to be called once at package init. Model fields of that type must then
+// use the `gorm:"serializer:<name>"` tag, which WithSerializableFields enforces.
+// A nil serializer maps the type to an already registered serializer such as "json".
+func RegisterTypeSerializer(sample interface{}, name string, serializer schema.SerializerInterface) {
+	if serializer != nil {
+		schema.RegisterSerializer(name, serializer)
+	}
+	typeSerializers.Store(indirectType(reflect.TypeOf(sample)), name)
+}
+
+// VerifyFieldTypes checks every column of T can be written and scanned : basic types,
+// time.Time, sql.Scanner/driver.Valuer implementations or fields with a serializer.
+// Fields of a type registered with RegisterTypeSerializer must use that serializer.
+func (o *BaseGorm[T, PkType]) VerifyFieldTypes() error {
+	var e T
+
+	if problems := verifyStructFields(reflect.TypeOf(e)); len(problems) > 0 {
+		return fmt.Errorf("%s: %s", e.TableName(), strings.Join(problems, ", "))
+	}
+
+	_, err := o.schema()
+
+	return err
+}
+
+// verifyStructFields lists the fields of structType whose type cannot be stored in a column.
+// Struct and slice of struct fields are left to gorm, which parses them as associations.
+func verifyStructFields(structType reflect.Type) []string {
+	var problems []string
+	for i := 0; i < structType.NumField(); i++ {
+		var (
+			structField = structType.Field(i)
+			fieldType   = indirectType(structField.Type)
+			tagSettings = schema.ParseTagSetting(structField.Tag.Get("gorm"), ";")
+		)
+
+		if _, ignored := tagSettings["-"]; ignored || !structField.IsExported() {
+			continue
+		}
+
+		if name, ok := typeSerializers.Load(fieldType); ok {
+			if !strings.EqualFold(tagSettings["SERIALIZER"], name.(string)) {
+				problems = append(problems, fmt.Sprintf("%s (%s) must use the `gorm:\"serializer:%s\"` tag", structField.Name, fieldType, name))
+			}
+			continue
+		}
+
+		switch {
+		case tagSettings["SERIALIZER"] != "" || isColumnType(fieldType):
+		case fieldType.Kind() == reflect.Struct && (structField.Anonymous || tagSettings["EMBEDDED"] != ""):
+			problems = append(problems, verifyStructFields(fieldType)...)
+		case fieldType.Kind() == reflect.Struct,
+			(fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array) && indirectType(fieldType.Elem()).Kind() == reflect.Struct:
+		default:
+			problems = append(problems, fmt.Sprintf("%s has unsupported type %s", structField.Name, fieldType))
+		}
+	}
+
+	return problems
+}
+
+// isColumnType reports whether values of t can be stored in a column without a serializer.
+func isColumnType(t reflect.Type) bool {
+	if t == timeType || reflect.PointerTo(t).Implements(scannerType) && (t.Implements(valuerType) || reflect.PointerTo(t).Implements(valuerType)) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
@@ -0,0 +1,49 @@
+package base
+
+import (
+	"strings"
+	"testing"
+)
+
+type Settings struct {
+	Theme string
+}
+
+type Account struct {
+	ID       uint `gorm:"primaryKey"`
+	Settings Settings
+	Labels   map[string]string
+}
+
+func (Account) TableName() string {
+	return "dummy_accounts"
+}
+
+func (Account) PrimaryKey() string {
+	return "id"
+}
+
+func TestVerifyFieldTypes(t *testing.T) {
+	db := setupDryRunDB(t)
+
+	if err := NewBaseGorm[User, uint](db).VerifyFieldTypes(); err != nil {
+		t.Errorf("Expected User field types to be supported, got %v", err)
+	}
+
+	RegisterTypeSerializer(Settings{}, "json", nil)
+
+	err := NewBaseGorm[Account, uint](db).VerifyFieldTypes()
+	if err == nil {
+		t.Fatal("Expected unsupported field types to be reported")
+	}
+	if !strings.Contains(err.Error(), "serializer:json") || !strings.Contains(err.Error(), "Labels has unsupported type") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewBaseGorm to panic with WithSerializableFields")
+		}
+	}()
+	NewBaseGorm[Account, uint](db, WithSerializableFields())
+}
@@ -0,0 +1,60 @@
+package base
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SlowQuery is a statement of a repository running longer than the threshold of
+// WithSlowQueryThreshold.
+type SlowQuery struct {
+	Table        string
+	Method       string // the BaseGorm method, e.g. List, "" when unknown
+	Operation    string // create, query, update, delete, row or raw
+	SQL          string // with placeholders, see Vars
+	Vars         []interface{}
+	Duration     time.Duration
+	RowsAffected int64
+	Err          error
+}
+
+// SlowQueryCallback receives the slow statements, with the context of the
+// repository call.
+type SlowQueryCallback = func(ctx context.Context, query SlowQuery)
+
+type slowQueries struct {
+	threshold time.Duration
+	callback  SlowQueryCallback
+}
+
+// WithSlowQueryThreshold calls callback, e.g. to alert, with every statement of the
+// repository running longer than threshold, failed ones included. The callback runs
+// on the goroutine of the statement, after it, so it should not block.
+func WithSlowQueryThreshold(threshold time.Duration, callback SlowQueryCallback) RepoOption {
+	return func(o *repoOptions) {
+		if callback != nil {
+			o.slowQueries = &slowQueries{threshold: threshold, callback: callback}
+		}
+	}
+}
+
+// report calls the callback when the statement of db, run by method, ran longer
+// than the threshold.
+func (s *slowQueries) report(db *gorm.DB, method string, operation string, elapsed time.Duration) {
+	if elapsed <= s.threshold {
+		return
+	}
+
+	s.callback(db.Statement.Context, SlowQuery{
+		Table:        db.Statement.Table,
+		Method:       method,
+		Operation:    operation,
+		SQL:          db.Statement.SQL.String(),
+		Vars:         append([]interface{}(nil), db.Statement.Vars...),
+		Duration:     elapsed,
+		RowsAffected: db.RowsAffected,
+		Err:          db.Error,
+	})
+}
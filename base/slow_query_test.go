@@ -0,0 +1,43 @@
+package base
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithSlowQueryThreshold(t *testing.T) {
+	var (
+		db      = setupDryRunDB(t)
+		ctx     = context.WithValue(context.Background(), parentKey{}, "handler")
+		queries []SlowQuery
+		record  = func(ctx context.Context, query SlowQuery) {
+			if ctx.Value(parentKey{}) != "handler" {
+				t.Error("Expected the context of the repository call")
+			}
+			queries = append(queries, query)
+		}
+	)
+
+	if _, err := NewBaseGorm[User, uint](db, WithSlowQueryThreshold(time.Hour, record)).Wheres(ctx, []Where{{Name: "name", Value: "alice"}}); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if len(queries) != 0 {
+		t.Fatalf("Expected no statement over an hour, got %+v", queries)
+	}
+
+	if _, err := NewBaseGorm[User, uint](db, WithSlowQueryThreshold(0, record)).Wheres(ctx, []Where{{Name: "name", Value: "alice"}}); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if len(queries) != 1 {
+		t.Fatalf("Expected the statement reported, got %+v", queries)
+	}
+
+	query := queries[0]
+	if query.Table != "dummy_users" || query.Method != "Wheres" || query.Operation != "query" || query.Duration <= 0 {
+		t.Errorf("Unexpected slow query %+v", query)
+	}
+	if want := "SELECT * FROM `dummy_users` WHERE name = ? ORDER BY `dummy_users`.`id` LIMIT ?"; query.SQL != want || len(query.Vars) != 2 || query.Vars[0] != "alice" {
+		t.Errorf("Expected SQL %q with its vars, got %q %v", want, query.SQL, query.Vars)
+	}
+}
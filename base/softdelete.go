@@ -0,0 +1,95 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrSoftDeleteUnsupported is returned by the soft delete methods when T has no gorm.DeletedAt field.
+var ErrSoftDeleteUnsupported = errors.New("model has no gorm.DeletedAt field")
+
+// deletedAtColumn returns the soft delete column of T.
+func (o *BaseGorm[T, PkType]) deletedAtColumn() (string, error) {
+	sch, err := o.schema()
+	if err != nil {
+		return "", err
+	}
+
+	field := deletedAtField(sch)
+	if field == nil {
+		return "", fmt.Errorf("%w: %s", ErrSoftDeleteUnsupported, sch.Table)
+	}
+
+	return field.DBName, nil
+}
+
+// SoftDelete moves the row with the given primary key to the trash, applying the
+// cascade rules of T. Detail, Wheres, WheresList and List skip trashed rows.
+func (o *BaseGorm[T, PkType]) SoftDelete(ctx context.Context, id PkType) (int64, error) {
+	if _, err := o.deletedAtColumn(); err != nil {
+		o.logError(ctx, err)
+		return 0, err
+	}
+
+	return o.deleteByID(ctx, id, false)
+}
+
+// Restore takes the row with the given primary key out of the trash. Children
+// trashed by cascade rules are not restored.
+func (o *BaseGorm[T, PkType]) Restore(ctx context.Context, id PkType) (int64, error) {
+	var (
+		e   T
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	o.forgetIdentities(ctx)
+
+	column, err := o.deletedAtColumn()
+	if err != nil {
+		return 0, err
+	}
+	if err = o.authorizeID(ctx, ActionUpdate, id, true); err != nil {
+		return 0, err
+	}
+
+	result := o.conn(ctx).
+		Unscoped().
+		Model(&e).
+		Where(fmt.Sprintf("%s = ? AND %s IS NOT NULL", e.PrimaryKey(), column), id).
+		Update(column, nil)
+	err = result.Error
+
+	return result.RowsAffected, err
+}
+
+// ListTrashed finds one page of the trashed rows matching wheres.
+func (o *BaseGorm[T, PkType]) ListTrashed(ctx context.Context, page int, pageSize int, orders []OrderBy, wheres []Where, opts ...QueryOption) ([]T, *Paginator, error) {
+	var e T
+
+	column, err := o.deletedAtColumn()
+	if err != nil {
+		o.logError(ctx, err)
+		return nil, nil, err
+	}
+
+	db := o.conn(ctx).
+		Unscoped().
+		Model(&e).
+		Table(e.TableName()).
+		Where(fmt.Sprintf("%s IS NOT NULL", column))
+
+	return o.paginate(ctx, db, page, pageSize, orders, wheres, opts)
+}
+
+// ForceDelete permanently deletes the row with the given primary key, trashed or
+// not, and applies the cascade rules of T as permanent deletes too.
+func (o *BaseGorm[T, PkType]) ForceDelete(ctx context.Context, id PkType) (int64, error) {
+	return o.deleteByID(ctx, id, true)
+}
@@ -0,0 +1,95 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type Document struct {
+	ID        uint           `gorm:"column:id;primaryKey"`
+	Title     string         `gorm:"column:title"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index"`
+}
+
+func (Document) TableName() string {
+	return "documents"
+}
+
+func (Document) PrimaryKey() string {
+	return "id"
+}
+
+func TestSoftDelete(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Document, uint](db)
+	)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{
+			name: "list skips trashed rows in the count",
+			run: func() error {
+				_, _, err := repo.List(ctx, 1, 10, nil, nil)
+				return err
+			},
+			want: "SELECT count(*) FROM `documents` WHERE `documents`.`deleted_at` IS NULL",
+		},
+		{
+			name: "soft delete",
+			run: func() error {
+				_, err := repo.SoftDelete(ctx, 1)
+				return err
+			},
+			want: "UPDATE `documents` SET `deleted_at`=? WHERE id = ? AND `documents`.`deleted_at` IS NULL",
+		},
+		{
+			name: "restore",
+			run: func() error {
+				_, err := repo.Restore(ctx, 1)
+				return err
+			},
+			want: "UPDATE `documents` SET `deleted_at`=? WHERE id = ? AND deleted_at IS NOT NULL",
+		},
+		{
+			name: "list trashed",
+			run: func() error {
+				_, _, err := repo.ListTrashed(ctx, 1, 10, nil, nil)
+				return err
+			},
+			want: "SELECT count(*) FROM `documents` WHERE deleted_at IS NOT NULL",
+		},
+		{
+			name: "force delete",
+			run: func() error {
+				_, err := repo.ForceDelete(ctx, 1)
+				return err
+			},
+			want: "DELETE FROM `documents` WHERE id = ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *sql != tt.want {
+				t.Errorf("Expected SQL\n%s\ngot\n%s", tt.want, *sql)
+			}
+		})
+	}
+
+	users := NewBaseGorm[User, uint](db)
+	if _, err := users.SoftDelete(ctx, 1); !errors.Is(err, ErrSoftDeleteUnsupported) {
+		t.Errorf("Expected ErrSoftDeleteUnsupported for a model without DeletedAt, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package base
+
+import "gorm.io/gorm"
+
+// WithStatementTemplates builds the SQL of the most frequent statements once, in
+// NewBaseGorm, instead of at every call : Detail, WheresList without wheres nor orders,
+// and Exists without wheres. The calls given query options are built as usual.
+func WithStatementTemplates() RepoOption {
+	return func(o *repoOptions) {
+		o.statementTemplates = true
+	}
+}
+
+// templateParam marks the vars of a statementTemplate bound at every call.
+type templateParam int
+
+// statementTemplate is the SQL and vars of a statement built in dry run.
+type statementTemplate struct {
+	sql  string
+	vars []interface{}
+}
+
+// statementTemplates are the statements of a repository built by NewBaseGorm.
+type statementTemplates struct {
+	detail *statementTemplate // by primary key
+	list   *statementTemplate // every row
+	exists *statementTemplate // any row
+}
+
+// newStatementTemplate builds the statement of query on db without running it, nil
+// when it fails.
+func newStatementTemplate(db *gorm.DB, query func(tx *gorm.DB) *gorm.DB) *statementTemplate {
+	tx := query(db.Session(&gorm.Session{DryRun: true}))
+	if tx.Error != nil || tx.Statement.SQL.Len() == 0 {
+		return nil
+	}
+
+	return &statementTemplate{sql: tx.Statement.SQL.String(), vars: tx.Statement.Vars}
+}
+
+// bind returns db running the template, its templateParam vars replaced by params in
+// order.
+func (t *statementTemplate) bind(db *gorm.DB, params ...interface{}) *gorm.DB {
+	vars := append([]interface{}(nil), t.vars...)
+	for i, v := range vars {
+		if p, ok := v.(templateParam); ok {
+			vars[i] = params[p]
+		}
+	}
+
+	// a raw statement skips the clauses building of the query callbacks
+	tx := db.Raw("")
+	tx.Statement.SQL.WriteString(t.sql)
+	tx.Statement.Vars = vars
+
+	return tx
+}
+
+// buildTemplates builds the statement templates of the repository.
+func (o *BaseGorm[T, PkType]) buildTemplates() *statementTemplates {
+	var (
+		e  T
+		db = withTimeout(o.db, o.opts.statementTimeouts.Read)
+	)
+
+	return &statementTemplates{
+		detail: newStatementTemplate(db, func(tx *gorm.DB) *gorm.DB {
+			var row T
+			return tx.Table(o.table).Where(o.pkCondition, templateParam(0)).First(&row)
+		}),
+		list: newStatementTemplate(db, func(tx *gorm.DB) *gorm.DB {
+			var rows []T
+			return tx.Table(o.table).Find(&rows)
+		}),
+		exists: newStatementTemplate(db, func(tx *gorm.DB) *gorm.DB {
+			var found []int
+			return tx.Model(&e).Table(o.table).Select("1").Limit(1).Find(&found)
+		}),
+	}
+}
@@ -0,0 +1,85 @@
+package base
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// captureStatement records the SQL and vars of the last query run on db.
+func captureStatement(t *testing.T, db *gorm.DB) (*string, *[]interface{}) {
+	t.Helper()
+
+	var (
+		sql  string
+		vars []interface{}
+	)
+	err := db.Callback().Query().After("gorm:query").Register("test:capture_statement", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+		vars = tx.Statement.Vars
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	return &sql, &vars
+}
+
+func TestStatementTemplates(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		db        = setupDryRunDB(t)
+		sql, vars = captureStatement(t, db)
+		timeouts  = WithStatementTimeouts(StatementTimeouts{Read: time.Second})
+		built     = NewBaseGorm[Document, uint](db, timeouts)
+		templated = NewBaseGorm[Document, uint](db, timeouts, WithStatementTemplates())
+	)
+
+	for _, tt := range []struct {
+		name string
+		run  func(repo *BaseGorm[Document, uint]) error
+	}{
+		{"Detail", func(repo *BaseGorm[Document, uint]) error {
+			_, err := repo.Detail(ctx, 7)
+			return err
+		}},
+		{"WheresList", func(repo *BaseGorm[Document, uint]) error {
+			_, err := repo.WheresList(ctx, nil, nil)
+			return err
+		}},
+		{"Exists", func(repo *BaseGorm[Document, uint]) error {
+			_, err := repo.Exists(ctx, nil)
+			return err
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(built); err != nil {
+				t.Fatalf("Failed to run the built statement: %v", err)
+			}
+			wantSQL, wantVars := *sql, *vars
+
+			if err := tt.run(templated); err != nil {
+				t.Fatalf("Failed to run the template: %v", err)
+			}
+			if *sql != wantSQL {
+				t.Errorf("Expected SQL\n%s\ngot\n%s", wantSQL, *sql)
+			}
+			if !reflect.DeepEqual(*vars, wantVars) {
+				t.Errorf("Expected vars %v, got %v", wantVars, *vars)
+			}
+		})
+	}
+
+	// the query options are applied by building the statement
+	if _, err := templated.Detail(ctx, 7, WithLock(clause.Locking{Strength: "UPDATE"})); err != nil {
+		t.Fatalf("Failed to run Detail: %v", err)
+	}
+	want := "SELECT /*+ MAX_EXECUTION_TIME(1000) */ * FROM `documents` WHERE id = ? AND `documents`.`deleted_at` IS NULL ORDER BY `documents`.`id` LIMIT ? FOR UPDATE"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
@@ -0,0 +1,166 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/internal/callbacks"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StatementTimeouts are the longest times the database runs the SELECT statements
+// of a repository before aborting them, so a runaway query stops even when the
+// context of the caller has no deadline. Zero means no limit. MySQL gets a
+// MAX_EXECUTION_TIME optimizer hint. Postgres runs each query in a transaction,
+// or in the transaction of the caller, starting with SET LOCAL statement_timeout,
+// which is back to the one of the session after the query; the reads scanning
+// their rows after the statement, Row, Rows and the aggregates, get no timeout.
+// The writes get no timeout : MySQL cannot abort them, and their lock waits are
+// bounded by innodb_lock_wait_timeout or lock_timeout. NewBaseGorm panics with
+// ErrStatementTimeoutsUnsupported on another database rather than silently running
+// the reads without limit.
+type StatementTimeouts struct {
+	Read time.Duration // Detail, Wheres, WheresList, Exists, Count and the other reads
+	List time.Duration // the List methods, their COUNT included, Read when zero
+}
+
+// ErrStatementTimeoutsUnsupported is the panic of NewBaseGorm given
+// WithStatementTimeouts on a database other than MySQL and Postgres.
+var ErrStatementTimeoutsUnsupported = errors.New("statement timeouts require MySQL or Postgres")
+
+// statementTimeoutKey is the setting holding the statement_timeout of the Postgres
+// queries of a statement, and the instance setting holding the connection pool the
+// query ran on before its transaction.
+const statementTimeoutKey = "base:statement_timeout"
+
+// WithStatementTimeouts sets the statement timeouts of the repository.
+func WithStatementTimeouts(timeouts StatementTimeouts) RepoOption {
+	return func(o *repoOptions) {
+		if timeouts.List == 0 {
+			timeouts.List = timeouts.Read
+		}
+		o.statementTimeouts = timeouts
+	}
+}
+
+// maxExecutionTime is the MAX_EXECUTION_TIME hint of the SELECT of a statement.
+type maxExecutionTime time.Duration
+
+func (maxExecutionTime) Name() string {
+	return "MAX_EXECUTION_TIME"
+}
+
+func (m maxExecutionTime) Build(builder clause.Builder) {
+	builder.WriteString(fmt.Sprintf("/*+ MAX_EXECUTION_TIME(%d) */", time.Duration(m).Milliseconds()))
+}
+
+func (maxExecutionTime) MergeClause(*clause.Clause) {
+}
+
+// ModifyStatement places the hint right after the SELECT keyword.
+func (m maxExecutionTime) ModifyStatement(stmt *gorm.Statement) {
+	c := stmt.Clauses["SELECT"]
+	c.AfterNameExpression = m
+	stmt.Clauses["SELECT"] = c
+}
+
+// withTimeout limits the SELECT statements of db to timeout, when set.
+func withTimeout(db *gorm.DB, timeout time.Duration) *gorm.DB {
+	if timeout <= 0 {
+		return db
+	}
+
+	// a new session, so the queries chained from it do not share their conditions
+	if db.Dialector.Name() == DialectPostgres {
+		return db.Set(statementTimeoutKey, timeout).Session(&gorm.Session{})
+	}
+	return db.Clauses(maxExecutionTime(timeout)).Session(&gorm.Session{})
+}
+
+// checkStatementTimeouts fails when timeouts are set on a database other than MySQL
+// and Postgres.
+func checkStatementTimeouts(db *gorm.DB, timeouts StatementTimeouts) error {
+	if timeouts == (StatementTimeouts{}) || db.Dialector.Name() == DialectMySQL || db.Dialector.Name() == DialectPostgres {
+		return nil
+	}
+
+	return fmt.Errorf("%w, not %s", ErrStatementTimeoutsUnsupported, db.Dialector.Name())
+}
+
+// registerStatementTimeouts registers the callbacks setting the statement_timeout of
+// the Postgres queries, once for all the repositories of the database.
+func registerStatementTimeouts(db *gorm.DB) error {
+	return callbacks.Register(db, "base:statement_timeout", beginStatementTimeout, endStatementTimeout)
+}
+
+// beginStatementTimeout opens the transaction of a query, unless it runs in one, and
+// sets its statement_timeout. The other operations scan their rows once the
+// callbacks are done, so they cannot be given a transaction.
+func beginStatementTimeout(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		timeout, ok := db.Get(statementTimeoutKey)
+		if operation != "query" || !ok || db.Error != nil || db.DryRun {
+			return
+		}
+
+		var (
+			ctx  = db.Statement.Context
+			pool = db.Statement.ConnPool
+			tx   gorm.ConnPool
+			err  error
+		)
+		switch beginner := pool.(type) {
+		case gorm.TxCommitter:
+			pool = nil
+		case gorm.TxBeginner:
+			tx, err = beginner.BeginTx(ctx, nil)
+		case gorm.ConnPoolBeginner:
+			tx, err = beginner.BeginTx(ctx, nil)
+		default:
+			return
+		}
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+		if tx != nil {
+			db.Statement.ConnPool = tx
+		}
+		db.InstanceSet(statementTimeoutKey, pool)
+
+		if _, err = db.Statement.ConnPool.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.(time.Duration).Milliseconds())); err != nil {
+			_ = db.AddError(err)
+		}
+	}
+}
+
+// endStatementTimeout ends the transaction opened by beginStatementTimeout, or gives
+// the transaction of the caller the statement_timeout of the session back.
+func endStatementTimeout(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		pool, ok := db.InstanceGet(statementTimeoutKey)
+		if !ok {
+			return
+		}
+
+		if pool == nil {
+			// a failed statement aborts the transaction of the caller anyway
+			if db.Error == nil {
+				if _, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, "SET LOCAL statement_timeout TO DEFAULT"); err != nil {
+					_ = db.AddError(err)
+				}
+			}
+			return
+		}
+
+		tx := db.Statement.ConnPool.(gorm.TxCommitter)
+		if db.Error != nil {
+			_ = tx.Rollback()
+		} else if err := tx.Commit(); err != nil {
+			_ = db.AddError(err)
+		}
+		db.Statement.ConnPool = pool.(gorm.ConnPool)
+	}
+}
@@ -0,0 +1,196 @@
+package base
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestStatementTimeouts(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[Document, uint](db, WithStatementTimeouts(StatementTimeouts{Read: time.Second, List: 5 * time.Second}))
+	)
+
+	if _, err := repo.Detail(ctx, 1); err != nil {
+		t.Fatalf("Failed to run Detail: %v", err)
+	}
+	want := "SELECT /*+ MAX_EXECUTION_TIME(1000) */ * FROM `documents` WHERE id = ? AND `documents`.`deleted_at` IS NULL ORDER BY `documents`.`id` LIMIT ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, _, err := repo.List(ctx, 1, 10, nil, nil); err != nil {
+		t.Fatalf("Failed to run List: %v", err)
+	}
+	want = "SELECT /*+ MAX_EXECUTION_TIME(5000) */ count(*) FROM `documents` WHERE `documents`.`deleted_at` IS NULL"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+
+	if _, err := repo.UpdateWhere(ctx, []Where{{Name: "id", Value: 1}}, map[string]interface{}{"title": "Renamed"}); err != nil {
+		t.Fatalf("Failed to run UpdateWhere: %v", err)
+	}
+	want = "UPDATE `documents` SET `title`=? WHERE id = ?"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
+
+type otherDialector struct {
+	gorm.Dialector
+}
+
+func (otherDialector) Name() string {
+	return "sqlite"
+}
+
+func TestStatementTimeoutsUnsupported(t *testing.T) {
+	db, err := gorm.Open(otherDialector{mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/dry_run",
+		SkipInitializeWithVersion: true,
+	})}, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrStatementTimeoutsUnsupported) {
+			t.Errorf("Expected a panic with ErrStatementTimeoutsUnsupported, got %v", err)
+		}
+	}()
+
+	NewBaseGorm[Document, uint](db, WithStatementTimeouts(StatementTimeouts{Read: time.Second}))
+}
+
+// recordingConnector opens connections recording their statements, whose queries
+// return no row.
+type recordingConnector struct {
+	statements []string
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return recordingConn{c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	*recordingConnector
+}
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c recordingConn) Close() error {
+	return nil
+}
+
+func (c recordingConn) Begin() (driver.Tx, error) {
+	c.statements = append(c.statements, "BEGIN")
+	return c, nil
+}
+
+func (c recordingConn) Commit() error {
+	c.statements = append(c.statements, "COMMIT")
+	return nil
+}
+
+func (c recordingConn) Rollback() error {
+	c.statements = append(c.statements, "ROLLBACK")
+	return nil
+}
+
+func (c recordingConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.statements = append(c.statements, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c recordingConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.statements = append(c.statements, query)
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string {
+	return []string{"count"}
+}
+
+func (noRows) Close() error {
+	return nil
+}
+
+func (noRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+func TestStatementTimeoutsPostgres(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		connector = &recordingConnector{}
+	)
+	db, err := gorm.Open(postgresDialector{mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(connector),
+		SkipInitializeWithVersion: true,
+	})}, &gorm.Config{SkipDefaultTransaction: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	repo := NewBaseGorm[Document, uint](db, WithStatementTimeouts(StatementTimeouts{Read: time.Second, List: 5 * time.Second}))
+
+	if _, err := repo.Count(ctx, nil); err != nil {
+		t.Fatalf("Failed to run Count: %v", err)
+	}
+	want := []string{
+		"BEGIN",
+		"SET LOCAL statement_timeout = 1000",
+		"SELECT count(*) FROM `documents` WHERE `documents`.`deleted_at` IS NULL",
+		"COMMIT",
+	}
+	if !reflect.DeepEqual(connector.statements, want) {
+		t.Errorf("Expected statements\n%q\ngot\n%q", want, connector.statements)
+	}
+
+	connector.statements = nil
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, _, err := repo.List(generic_gorm.ContextWithDB(ctx, tx), 1, 10, nil, nil)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to run List: %v", err)
+	}
+	want = []string{
+		"BEGIN",
+		"SET LOCAL statement_timeout = 5000",
+		"SELECT count(*) FROM `documents` WHERE `documents`.`deleted_at` IS NULL",
+		"SET LOCAL statement_timeout TO DEFAULT",
+		"COMMIT",
+	}
+	if !reflect.DeepEqual(connector.statements, want) {
+		t.Errorf("Expected statements\n%q\ngot\n%q", want, connector.statements)
+	}
+
+	connector.statements = nil
+	if _, err := repo.UpdateWhere(ctx, []Where{{Name: "id", Value: 1}}, map[string]interface{}{"title": "Renamed"}); err != nil {
+		t.Fatalf("Failed to run UpdateWhere: %v", err)
+	}
+	want = []string{"UPDATE `documents` SET `title`=? WHERE id = ?"}
+	if !reflect.DeepEqual(connector.statements, want) {
+		t.Errorf("Expected statements\n%q\ngot\n%q", want, connector.statements)
+	}
+}
@@ -0,0 +1,133 @@
+package base
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"gorm.io/gorm"
+)
+
+// timestampOptions configures WithUTCTimestamps.
+type timestampOptions struct {
+	precision    time.Duration
+	readLocation *time.Location
+}
+
+// WithUTCTimestamps stores every time.Time field of T in UTC truncated to precision
+// (e.g. time.Microsecond for DATETIME(6), time.Second for DATETIME), including the
+// autoCreateTime/autoUpdateTime values, and converts loaded values to readLocation
+// (UTC when nil). The first write also warns when the database session time zone is
+// not UTC, which makes NOW() based defaults drift from the stored values.
+func WithUTCTimestamps(precision time.Duration, readLocation *time.Location) RepoOption {
+	return func(o *repoOptions) {
+		if readLocation == nil {
+			readLocation = time.UTC
+		}
+		o.timestamps = &timestampOptions{precision: precision, readLocation: readLocation}
+	}
+}
+
+// normalizeTime converts t to UTC with the configured precision.
+func (t *timestampOptions) normalizeTime(value time.Time) time.Time {
+	value = value.UTC()
+	if t.precision > 0 {
+		value = value.Truncate(t.precision)
+	}
+
+	return value
+}
+
+// now is used as gorm's NowFunc, for autoCreateTime and autoUpdateTime fields.
+func (t *timestampOptions) now() time.Time {
+	return t.normalizeTime(time.Now())
+}
+
+// normalizeTimes converts the time fields of row before it is written.
+func (o *BaseGorm[T, PkType]) normalizeTimes(ctx context.Context, row *T) {
+	if o.opts.timestamps == nil || row == nil {
+		return
+	}
+
+	o.checkSessionTimeZone(ctx)
+	o.mapTimes(ctx, row, o.opts.timestamps.normalizeTime)
+}
+
+// normalizeTimeValues converts the time values of an UpdateWhere values map.
+func (o *BaseGorm[T, PkType]) normalizeTimeValues(values map[string]interface{}) {
+	if o.opts.timestamps == nil {
+		return
+	}
+
+	for column, value := range values {
+		if t, ok := value.(time.Time); ok {
+			values[column] = o.opts.timestamps.normalizeTime(t)
+		}
+	}
+}
+
+// localizeTimes converts the time fields of a loaded row to the read location.
+func (o *BaseGorm[T, PkType]) localizeTimes(ctx context.Context, row *T) {
+	if o.opts.timestamps == nil {
+		return
+	}
+
+	location := o.opts.timestamps.readLocation
+	o.mapTimes(ctx, row, func(t time.Time) time.Time {
+		return t.In(location)
+	})
+}
+
+// mapTimes replaces every non zero time.Time and *time.Time column of row with fn(value).
+func (o *BaseGorm[T, PkType]) mapTimes(ctx context.Context, row *T, fn func(time.Time) time.Time) {
+	sch, err := o.schema()
+	if err != nil {
+		o.logError(ctx, err)
+		return
+	}
+
+	rowValue := reflect.ValueOf(row).Elem()
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.IndirectFieldType != timeType {
+			continue
+		}
+
+		fieldValue := field.ReflectValueOf(ctx, rowValue)
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+
+		if t := fieldValue.Interface().(time.Time); !t.IsZero() {
+			fieldValue.Set(reflect.ValueOf(fn(t)))
+		}
+	}
+}
+
+// checkSessionTimeZone warns once when the database session is not in UTC.
+func (o *BaseGorm[T, PkType]) checkSessionTimeZone(ctx context.Context) {
+	o.timeZoneCheck.Do(func() {
+		offset, err := SessionUTCOffset(ctx, o.db)
+		if err != nil {
+			generic_gorm.GetLoggerFromContext(ctx).Warnf("cannot check the database session time zone: %v", err)
+			return
+		}
+		if offset != 0 {
+			generic_gorm.GetLoggerFromContext(ctx).Warnf("database session time zone is UTC%+v, timestamps are stored in UTC", offset)
+		}
+	})
+}
+
+// SessionUTCOffset returns the offset of the MySQL session time zone from UTC.
+func SessionUTCOffset(ctx context.Context, db *gorm.DB) (time.Duration, error) {
+	var seconds int64
+	err := db.WithContext(ctx).
+		Raw("SELECT TIMESTAMPDIFF(SECOND, UTC_TIMESTAMP(), NOW())").
+		Scan(&seconds).
+		Error
+
+	return time.Duration(seconds) * time.Second, err
+}
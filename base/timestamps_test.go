@@ -0,0 +1,28 @@
+package base
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUTCTimestamps(t *testing.T) {
+	repo := NewBaseGorm[User, uint](setupDryRunDB(t), WithUTCTimestamps(time.Second, nil))
+	jakarta := time.FixedZone("WIB", 7*60*60)
+
+	user := &User{
+		Name:      "Time Zone User",
+		CreatedAt: time.Date(2024, 1, 2, 10, 0, 0, 123456789, jakarta),
+	}
+	if _, err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	want := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	if user.CreatedAt != want {
+		t.Errorf("Expected CreatedAt %v, got %v", want, user.CreatedAt)
+	}
+	if user.UpdatedAt.Location() != time.UTC || user.UpdatedAt.Nanosecond() != 0 {
+		t.Errorf("Expected autoUpdateTime in UTC with second precision, got %v", user.UpdatedAt)
+	}
+}
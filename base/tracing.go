@@ -0,0 +1,144 @@
+package base
+
+import (
+	"context"
+
+	"github.com/harryosmar/generic-gorm/internal/callbacks"
+	"gorm.io/gorm"
+)
+
+const (
+	spanKey = "base:span"
+	// the method of the span of a statement
+	spanMethodKey = "base:span_method"
+)
+
+// Tracer opens a span around every statement of a repository, see WithTracer. It
+// is an interface rather than an OpenTelemetry dependency : an adapter is a few
+// lines, see the readme.
+type Tracer interface {
+	// Start opens the span name, a child of the one of ctx, and returns the context
+	// carrying it, passed down to the driver and to the nested statements.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is ended once its statement ran.
+type Span interface {
+	End(attributes SpanAttributes)
+}
+
+// SpanAttributes describe the method or the statement of a Span.
+type SpanAttributes struct {
+	Table        string
+	Method       string // the repository method, e.g. List
+	Operation    string // create, query, update, delete, row or raw, empty for a method
+	RowsAffected int64
+	Err          error
+}
+
+// methodSpanKey is the context key of the methodSpan open, see startMethodSpan.
+type methodSpanKey struct{}
+
+// methodSpan is a repository method whose span is open.
+type methodSpan struct {
+	table  string
+	method string
+}
+
+// WithTracer opens a span named repo.<table>.<method> around every repository
+// method, e.g. repo.users.Create, nested in the span of the context. The methods
+// running several statements, e.g. the count and the find of List, nest one span
+// per statement in theirs, named repo.<table>.<method>.<operation>. The
+// repositories without it are not traced, on the same database too.
+func WithTracer(tracer Tracer) RepoOption {
+	return func(o *repoOptions) {
+		o.tracer = tracer
+	}
+}
+
+// statementContext returns ctx carrying how the statements run with it are traced,
+// timed for the error entries, logged with WithDebugLogging and reported by
+// WithSlowQueryThreshold. The method running them is resolved here, once, only when
+// they need it.
+func (o *BaseGorm[T, PkType]) statementContext(ctx context.Context) context.Context {
+	options := o.statements
+	if options.needsMethod() {
+		options = &statementOptions{tracer: options.tracer, slowQueries: options.slowQueries, debug: options.debug, values: options.values}
+		if span, ok := ctx.Value(methodSpanKey{}).(methodSpan); ok && span.table == o.table {
+			options.method = span.method
+		} else {
+			options.method = callerMethod()
+		}
+	}
+
+	return context.WithValue(ctx, statementsKey{}, options)
+}
+
+// startMethodSpan opens the span of the method calling it, so the spans of its
+// statements are nested in it, and returns the context carrying it with the func
+// ending it. The methods running a single statement do not call it, the span of
+// the statement stands for them.
+func (o *BaseGorm[T, PkType]) startMethodSpan(ctx context.Context) (context.Context, func(err error)) {
+	if o.opts.tracer == nil {
+		return ctx, func(error) {}
+	}
+
+	method := callerMethod()
+	ctx, span := o.opts.tracer.Start(ctx, "repo."+o.table+"."+method)
+	ctx = context.WithValue(ctx, methodSpanKey{}, methodSpan{table: o.table, method: method})
+
+	return ctx, func(err error) {
+		span.End(SpanAttributes{Table: o.table, Method: method, Err: err})
+	}
+}
+
+// registerTracing registers the callbacks opening and ending the spans on db, once
+// for all the repositories of the database.
+func registerTracing(db *gorm.DB) error {
+	return callbacks.Register(db, "base:tracing", startSpan, endSpan)
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		options, ok := statementOptionsOf(db)
+		if !ok || options.tracer == nil {
+			return
+		}
+
+		var (
+			ctx    = db.Statement.Context
+			method = options.method
+			name   = "repo." + db.Statement.Table + "." + method
+		)
+		// the statements of a method with its own span, or of none, are named after
+		// their operation
+		if parent, _ := ctx.Value(methodSpanKey{}).(methodSpan); method == "" {
+			name = "repo." + db.Statement.Table + "." + operation
+		} else if parent == (methodSpan{table: db.Statement.Table, method: method}) {
+			name += "." + operation
+		}
+
+		ctx, span := options.tracer.Start(ctx, name)
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+		db.InstanceSet(spanMethodKey, method)
+	}
+}
+
+func endSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		span, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+
+		method, _ := db.InstanceGet(spanMethodKey)
+		span.(Span).End(SpanAttributes{
+			Table:        db.Statement.Table,
+			Method:       method.(string),
+			Operation:    operation,
+			RowsAffected: db.RowsAffected,
+			Err:          db.Error,
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type parentKey struct{}
+
+type recordedSpan struct {
+	name       string
+	parent     interface{}
+	attributes SpanAttributes
+	ended      bool
+}
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordedSpan{name: name, parent: ctx.Value(parentKey{})}
+	r.spans = append(r.spans, span)
+
+	return context.WithValue(ctx, parentKey{}, name), span
+}
+
+func (s *recordedSpan) End(attributes SpanAttributes) {
+	s.attributes = attributes
+	s.ended = true
+}
+
+func TestWithTracer(t *testing.T) {
+	var (
+		db     = setupDryRunDB(t)
+		tracer = &recordingTracer{}
+		repo   = NewBaseGorm[User, uint](db, WithTracer(tracer))
+		ctx    = context.WithValue(context.Background(), parentKey{}, "handler")
+	)
+
+	if _, err := repo.Create(ctx, &User{Name: "alice"}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	if _, err := repo.UpdateWhere(ctx, []Where{{Name: "name", Value: "alice"}}, map[string]interface{}{"email": "alice@example.com"}); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+
+	want := []string{"repo.dummy_users.Create", "repo.dummy_users.UpdateWhere"}
+	if len(tracer.spans) != len(want) {
+		t.Fatalf("Expected %d spans, got %d", len(want), len(tracer.spans))
+	}
+	for i, name := range want {
+		span := tracer.spans[i]
+		if span.name != name || span.parent != "handler" || !span.ended {
+			t.Errorf("Expected the ended span %s in the span of the context, got %+v", name, span)
+		}
+		if span.attributes.Table != "dummy_users" || span.attributes.Method != name[len("repo.dummy_users."):] || span.attributes.Err != nil {
+			t.Errorf("Expected the attributes of %s, got %+v", name, span.attributes)
+		}
+	}
+
+	// the repositories without the option are not traced
+	if _, err := NewBaseGorm[User, uint](db).Create(ctx, &User{Name: "bob"}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	if len(tracer.spans) != len(want) {
+		t.Errorf("Expected no span of an untraced repository, got %d spans", len(tracer.spans))
+	}
+}
+
+func TestWithTracerNestsStatementsInMethodSpan(t *testing.T) {
+	var (
+		db     = setupDryRunDB(t)
+		tracer = &recordingTracer{}
+		repo   = NewBaseGorm[User, uint](db, WithTracer(tracer))
+		ctx    = context.WithValue(context.Background(), parentKey{}, "handler")
+	)
+
+	// a dry run counts no row, the find of the page would be skipped
+	if err := db.Callback().Query().After("gorm:query").Register("test:count", func(tx *gorm.DB) {
+		if dest, ok := tx.Statement.Dest.(*int64); ok {
+			*dest, tx.RowsAffected = 1, 1
+		}
+	}); err != nil {
+		t.Fatalf("Failed to register the callback: %v", err)
+	}
+
+	if _, _, err := repo.List(ctx, 1, 10, nil, nil); err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	want := []struct {
+		name   string
+		parent string
+	}{
+		{"repo.dummy_users.List", "handler"},
+		{"repo.dummy_users.List.query", "repo.dummy_users.List"},
+		{"repo.dummy_users.List.query", "repo.dummy_users.List"},
+	}
+	if len(tracer.spans) != len(want) {
+		t.Fatalf("Expected %d spans, got %d", len(want), len(tracer.spans))
+	}
+	for i, w := range want {
+		span := tracer.spans[i]
+		if span.name != w.name || span.parent != w.parent || !span.ended {
+			t.Errorf("Expected the ended span %s in %s, got %+v", w.name, w.parent, span)
+		}
+		if span.attributes.Method != "List" {
+			t.Errorf("Expected the method List, got %+v", span.attributes)
+		}
+	}
+	if tracer.spans[1].attributes.Operation != "query" {
+		t.Errorf("Expected the operation of the count, got %+v", tracer.spans[1].attributes)
+	}
+}
+
+func TestStatementContextResolvesMethodWhenNeeded(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		db     = setupDryRunDB(t)
+		plain  = NewBaseGorm[User, uint](db)
+		logged = NewBaseGorm[User, uint](db, WithDebugLogging())
+	)
+
+	// shared by the statements of every method, without walking the stack
+	if options, ok := statementOptionsOf(plain.DB(ctx)); !ok || options != plain.statements || options.method != "" {
+		t.Errorf("Expected the options of the repository without method, got %+v", options)
+	}
+
+	if options, ok := statementOptionsOf(logged.DB(ctx)); !ok || options.method != "DB" || !options.debug {
+		t.Errorf("Expected the options of the repository with the DB method, got %+v", options)
+	}
+}
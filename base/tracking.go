@@ -0,0 +1,149 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type trackingCtxKey struct{}
+
+// tracker keeps the originally loaded version of rows within a context, by table and
+// primary key.
+type tracker struct {
+	mu        sync.Mutex
+	snapshots map[string]map[interface{}]interface{}
+}
+
+// ContextWithTracking returns a context in which the repositories given WithTracking
+// remember the rows they load, so SaveChanges persists only their modified fields.
+// Create it per unit of work, e.g. per request, so the snapshots are dropped with it
+// and concurrent requests each diff against their own loads.
+func ContextWithTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trackingCtxKey{}, &tracker{snapshots: map[string]map[interface{}]interface{}{}})
+}
+
+func trackerOf(ctx context.Context) *tracker {
+	t, _ := ctx.Value(trackingCtxKey{}).(*tracker)
+	return t
+}
+
+func (t *tracker) load(table string, key interface{}) (interface{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot, ok := t.snapshots[table][key]
+	return snapshot, ok
+}
+
+func (t *tracker) store(table string, key interface{}, snapshot interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshots, ok := t.snapshots[table]
+	if !ok {
+		snapshots = map[interface{}]interface{}{}
+		t.snapshots[table] = snapshots
+	}
+	snapshots[key] = snapshot
+}
+
+func (t *tracker) delete(table string, key interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.snapshots[table], key)
+}
+
+// track remembers the loaded state of row in the tracking context of ctx, when
+// tracking is enabled.
+func (o *BaseGorm[T, PkType]) track(ctx context.Context, row *T) {
+	t := trackerOf(ctx)
+	if !o.opts.tracking || t == nil {
+		return
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		o.logError(ctx, err)
+		return
+	}
+
+	pk, err := o.primaryKeyOf(ctx, sch, row)
+	if err != nil {
+		o.logError(ctx, err)
+		return
+	}
+	t.store(o.table, pk, *row)
+}
+
+// Untrack forgets the loaded state of the row with the given primary key in the
+// tracking context of ctx.
+func (o *BaseGorm[T, PkType]) Untrack(ctx context.Context, id PkType) {
+	if t := trackerOf(ctx); t != nil {
+		t.delete(o.table, id)
+	}
+}
+
+// SaveChanges persists only the fields of row that changed since it was loaded via
+// Detail, Wheres, WheresList or List with ctx. It requires the WithTracking option
+// and a context created by ContextWithTracking.
+func (o *BaseGorm[T, PkType]) SaveChanges(ctx context.Context, row *T) (int64, error) {
+	var (
+		e   T
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	if !o.opts.tracking {
+		err = fmt.Errorf("tracking is not enabled for %s", e.TableName())
+		return 0, err
+	}
+	t := trackerOf(ctx)
+	if t == nil {
+		err = fmt.Errorf("no tracking context for %s, see ContextWithTracking", e.TableName())
+		return 0, err
+	}
+
+	sch, err := o.schema()
+	if err != nil {
+		return 0, err
+	}
+
+	pk, err := o.primaryKeyOf(ctx, sch, row)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshot, ok := t.load(o.table, pk)
+	if !ok {
+		err = fmt.Errorf("%s with %s %v is not tracked", e.TableName(), e.PrimaryKey(), pk)
+		return 0, err
+	}
+
+	original := snapshot.(T)
+	changes, err := o.Diff(&original, row)
+	if err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	rowsAffected, err := o.Update(ctx, row, changedColumns(changes))
+	if err != nil {
+		return rowsAffected, err
+	}
+
+	t.store(o.table, pk, *row)
+	if o.opts.changeListener != nil {
+		o.opts.changeListener(ctx, e.TableName(), changes)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,42 @@
+package base
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTrackingIsScopedToTheContext(t *testing.T) {
+	var (
+		repo       = NewBaseGorm[User, uint](setupDryRunDB(t), WithTracking())
+		first      = ContextWithTracking(context.Background())
+		second     = ContextWithTracking(context.Background())
+		firstLoad  = &User{ID: 1, Name: "first"}
+		secondLoad = &User{ID: 1, Name: "second"}
+	)
+
+	repo.track(first, firstLoad)
+	repo.track(second, secondLoad)
+
+	// unchanged since the load of its own context, whatever the other one loaded
+	if rowsAffected, err := repo.SaveChanges(second, &User{ID: 1, Name: "second"}); err != nil || rowsAffected != 0 {
+		t.Errorf("Expected no change against the load of the context, got %d, %v", rowsAffected, err)
+	}
+
+	if _, err := repo.SaveChanges(first, &User{ID: 1, Name: "renamed"}); err != nil {
+		t.Fatalf("Failed to save changes: %v", err)
+	}
+	if snapshot, _ := trackerOf(first).load(repo.table, uint(1)); snapshot.(User).Name != "renamed" {
+		t.Errorf("Expected the saved row to become the snapshot, got %+v", snapshot)
+	}
+	if snapshot, _ := trackerOf(second).load(repo.table, uint(1)); snapshot.(User).Name != "second" {
+		t.Errorf("Expected the snapshot of the other context kept, got %+v", snapshot)
+	}
+
+	repo.Untrack(first, 1)
+	if _, err := repo.SaveChanges(first, firstLoad); err == nil {
+		t.Error("Expected an error for an untracked row")
+	}
+	if _, err := repo.SaveChanges(context.Background(), firstLoad); err == nil {
+		t.Error("Expected an error without a tracking context")
+	}
+}
@@ -0,0 +1,28 @@
+package base
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// WithTx returns a copy of the repository running every method on tx, with the
+// same options.
+func (o *BaseGorm[T, PkType]) WithTx(tx *gorm.DB) *BaseGorm[T, PkType] {
+	// a new struct, the sync.Once of o must not be copied
+	repo := &BaseGorm[T, PkType]{db: tx, opts: o.opts, bound: true, table: o.table, pkCondition: o.pkCondition, templates: o.templates, statements: o.statements}
+	if repo.opts.timestamps != nil {
+		repo.db = tx.Session(&gorm.Session{NowFunc: repo.opts.timestamps.now})
+	}
+
+	return repo
+}
+
+// Transaction runs fn with a repository bound to a new transaction, committed when
+// fn returns nil and rolled back otherwise. Inside a transaction already held by
+// the repository or ctx, it runs in a savepoint.
+func (o *BaseGorm[T, PkType]) Transaction(ctx context.Context, fn func(repo *BaseGorm[T, PkType]) error) error {
+	return o.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(o.WithTx(tx))
+	})
+}
@@ -0,0 +1,21 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+)
+
+func TestWithTxIgnoresContextDB(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		tx   = db.Table("bound_tx") // stands for a transaction of the same database
+		repo = NewBaseGorm[User, uint](db).WithTx(tx)
+		ctx  = generic_gorm.ContextWithDB(context.Background(), db)
+	)
+
+	if table := repo.DB(ctx).Statement.Table; table != "bound_tx" {
+		t.Errorf("Expected the repository bound to tx to ignore the handle of the context, got table %q", table)
+	}
+}
@@ -0,0 +1,144 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+)
+
+// UpsertOptions configures UpsertWithOptions.
+type UpsertOptions struct {
+	// ConflictColumns is the conflict target, required on Postgres. MySQL ignores
+	// it and checks every unique key.
+	ConflictColumns []string
+	// UpdateColumns are updated on conflict.
+	UpdateColumns []string
+	// UpdateAll updates every column but the primary key on conflict.
+	UpdateAll bool
+	// DoNothing keeps the stored row on conflict.
+	DoNothing bool
+}
+
+// Upsert inserts row, or updates onConflictUpdatedColumns of the stored row it
+// conflicts with.
+func (o *BaseGorm[T, PkType]) Upsert(ctx context.Context, row *T, onConflictUpdatedColumns []string) (int64, error) {
+	return o.upsert(ctx, row, UpsertOptions{UpdateColumns: onConflictUpdatedColumns})
+}
+
+// UpsertWithOptions inserts row, or resolves the conflict as set by opts, and
+// returns the persisted row, reloaded so that it holds the stored values after a
+// DoNothing or a partial update. The row is reloaded by its ConflictColumns, or
+// else by its primary key when row had one: MySQL does not report the id of an
+// updated row, and row is returned as is.
+func (o *BaseGorm[T, PkType]) UpsertWithOptions(ctx context.Context, row *T, opts UpsertOptions) (*T, error) {
+	var (
+		e   T
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	sch, err := o.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	keyColumns := opts.ConflictColumns
+	if len(keyColumns) == 0 {
+		pk := sch.LookUpField(e.PrimaryKey())
+		if pk == nil {
+			err = fmt.Errorf("primary key %s not found in %s", e.PrimaryKey(), sch.Table)
+			return nil, err
+		}
+		if _, zero := pk.ValueOf(ctx, reflect.ValueOf(row).Elem()); zero {
+			if _, err = o.upsert(ctx, row, opts); err != nil {
+				return nil, err
+			}
+			return row, nil
+		}
+		keyColumns = []string{e.PrimaryKey()}
+	}
+
+	wheres := make([]Where, 0, len(keyColumns))
+	for _, column := range keyColumns {
+		field := sch.LookUpField(column)
+		if field == nil {
+			err = fmt.Errorf("conflict column %s not found in %s", column, sch.Table)
+			return nil, err
+		}
+		value, _ := field.ValueOf(ctx, reflect.ValueOf(row).Elem())
+		wheres = append(wheres, Where{Name: field.DBName, Value: value})
+	}
+
+	if _, err = o.upsert(ctx, row, opts); err != nil {
+		return nil, err
+	}
+
+	stored, err := found(o.Wheres(ctx, wheres))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return row, nil
+	}
+
+	return stored, nil
+}
+
+func (o *BaseGorm[T, PkType]) upsert(ctx context.Context, row *T, opts UpsertOptions) (int64, error) {
+	var (
+		e   T
+		db  = o.conn(ctx).Table(e.TableName())
+		err error
+	)
+
+	defer func() {
+		if err != nil {
+			o.logError(ctx, err)
+		}
+	}()
+
+	o.forgetIdentities(ctx)
+
+	if opts.DoNothing && (opts.UpdateAll || len(opts.UpdateColumns) > 0) {
+		err = errors.New("upsert: DoNothing cannot be combined with UpdateColumns or UpdateAll")
+		return 0, err
+	}
+	if opts, err = o.permittedUpsert(ctx, row, opts); err != nil {
+		return 0, err
+	}
+
+	o.normalizeTimes(ctx, row)
+	if err = o.validate(ctx, row, nil); err != nil {
+		return 0, err
+	}
+
+	result := db.Clauses(onConflict(opts)).Create(row)
+	err = result.Error
+
+	return result.RowsAffected, err
+}
+
+// onConflict returns the ON CONFLICT clause of opts.
+func onConflict(opts UpsertOptions) clause.OnConflict {
+	conflict := clause.OnConflict{
+		Columns:   make([]clause.Column, len(opts.ConflictColumns)),
+		DoNothing: opts.DoNothing,
+		UpdateAll: opts.UpdateAll,
+	}
+	for i, column := range opts.ConflictColumns {
+		conflict.Columns[i] = clause.Column{Name: column}
+	}
+	if !opts.DoNothing && !opts.UpdateAll {
+		conflict.DoUpdates = clause.AssignmentColumns(opts.UpdateColumns)
+	}
+
+	return conflict
+}
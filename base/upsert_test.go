@@ -0,0 +1,57 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestUpsertWithOptionsSQL(t *testing.T) {
+	var (
+		ctx = context.Background()
+		db  = setupDryRunDB(t)
+		sql string
+	)
+	if err := db.Callback().Create().After("gorm:create").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+	repo := NewBaseGorm[User, uint](db)
+
+	tests := []struct {
+		name string
+		opts UpsertOptions
+		want string
+	}{
+		{
+			name: "update columns",
+			opts: UpsertOptions{ConflictColumns: []string{"email"}, UpdateColumns: []string{"name"}},
+			want: "INSERT INTO `dummy_users` (`name`,`email`,`created_at`,`updated_at`) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)",
+		},
+		{
+			name: "do nothing",
+			opts: UpsertOptions{ConflictColumns: []string{"email"}, DoNothing: true},
+			want: "INSERT INTO `dummy_users` (`name`,`email`,`created_at`,`updated_at`) VALUES (?,?,?,?) ON DUPLICATE KEY UPDATE `id`=`id`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.UpsertWithOptions(ctx, &User{Name: "Alice", Email: "alice@example.com"}, tt.opts); err != nil {
+				t.Fatalf("Failed to upsert: %v", err)
+			}
+			if sql != tt.want {
+				t.Errorf("Expected SQL\n%s\ngot\n%s", tt.want, sql)
+			}
+		})
+	}
+
+	if _, err := repo.UpsertWithOptions(ctx, &User{}, UpsertOptions{DoNothing: true, UpdateAll: true}); err == nil {
+		t.Error("Expected an error for DoNothing with UpdateAll")
+	}
+	if _, err := repo.UpsertWithOptions(ctx, &User{}, UpsertOptions{ConflictColumns: []string{"missing"}}); err == nil {
+		t.Error("Expected an error for an unknown conflict column")
+	}
+}
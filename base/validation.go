@@ -0,0 +1,77 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationErrors maps the fields of a row failing struct validation to a message.
+type ValidationErrors map[string]string
+
+func (v ValidationErrors) Error() string {
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, fmt.Sprintf("%s %s", field, v[field]))
+	}
+
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+// validate runs the configured validator against row, restricted to the fields
+// backing columns when columns are given.
+func (o *BaseGorm[T, PkType]) validate(ctx context.Context, row *T, columns []string) error {
+	if o.opts.validator == nil {
+		return nil
+	}
+
+	var err error
+	if len(columns) == 0 || (len(columns) == 1 && columns[0] == "*") {
+		err = o.opts.validator.StructCtx(ctx, row)
+	} else {
+		sch, schemaErr := o.schema()
+		if schemaErr != nil {
+			return schemaErr
+		}
+
+		fields := make([]string, 0, len(columns))
+		for _, column := range columns {
+			if field := sch.LookUpField(column); field != nil {
+				fields = append(fields, strings.Join(field.BindNames, "."))
+			}
+		}
+		err = o.opts.validator.StructPartialCtx(ctx, row, fields...)
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	validationErrors := make(ValidationErrors, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		// strip the struct name from "User.Profile.Bio"
+		_, field, _ := strings.Cut(fieldError.Namespace(), ".")
+		validationErrors[field] = validationMessage(fieldError)
+	}
+
+	return validationErrors
+}
+
+func validationMessage(fieldError validator.FieldError) string {
+	if fieldError.Param() != "" {
+		return fmt.Sprintf("failed on the '%s=%s' rule", fieldError.Tag(), fieldError.Param())
+	}
+
+	return fmt.Sprintf("failed on the '%s' rule", fieldError.Tag())
+}
@@ -0,0 +1,42 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type Signup struct {
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `validate:"required"`
+	Email string `validate:"required,email"`
+}
+
+func (Signup) TableName() string {
+	return "dummy_signups"
+}
+
+func (Signup) PrimaryKey() string {
+	return "id"
+}
+
+func TestValidationBeforeWrites(t *testing.T) {
+	repo := NewBaseGorm[Signup, uint](setupDryRunDB(t), WithValidator(validator.New()))
+	ctx := context.Background()
+
+	_, err := repo.Create(ctx, &Signup{Email: "not-an-email"})
+	var validationErrors ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	if len(validationErrors) != 2 || validationErrors["Name"] == "" || validationErrors["Email"] == "" {
+		t.Errorf("Expected Name and Email validation errors, got %v", validationErrors)
+	}
+
+	// Only the updated columns are validated
+	if _, err = repo.Update(ctx, &Signup{ID: 1, Name: "Valid Name"}, []string{"name"}); err != nil {
+		t.Errorf("Expected partial update to pass validation, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package base
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// whereJSON is the JSON form of a Where, its value and group decoded by hand.
+type whereJSON struct {
+	Name             string
+	IsLike           bool
+	IsFullTextSearch bool
+	Operator         Operator
+	Value            json.RawMessage
+	Group            *whereGroupJSON
+}
+
+type whereGroupJSON struct {
+	Or     bool
+	Wheres []json.RawMessage
+}
+
+// UnmarshalJSON decodes a Where from typed clients as well as from strings : a
+// Value may be a string, a boolean, a number, an integer one decoded as an int64, or
+// an array, making an IN condition when no Operator is given. An explicit null Value
+// makes an IS NULL condition, IS NOT NULL with the != Operator. Unknown fields are
+// ignored, see DecodeWheresStrict.
+func (c *Where) UnmarshalJSON(data []byte) error {
+	return c.decode(data, false)
+}
+
+// DecodeWheresStrict decodes a JSON array of Where like json.Unmarshal does, but
+// fails on unknown fields, in groups too, e.g. a misspelled "Operater" which would
+// otherwise be dropped and widen the filter.
+func DecodeWheresStrict(data []byte) ([]Where, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, err
+	}
+
+	return decodeWheres(raws, true)
+}
+
+func decodeWheres(raws []json.RawMessage, strict bool) ([]Where, error) {
+	wheres := make([]Where, len(raws))
+	for i, raw := range raws {
+		if err := wheres[i].decode(raw, strict); err != nil {
+			return nil, err
+		}
+	}
+
+	return wheres, nil
+}
+
+func (c *Where) decode(data []byte, strict bool) error {
+	var (
+		decoded whereJSON
+		decoder = json.NewDecoder(bytes.NewReader(data))
+	)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&decoded); err != nil {
+		return fmt.Errorf("where: %w", err)
+	}
+
+	*c = Where{
+		Name:             decoded.Name,
+		IsLike:           decoded.IsLike,
+		IsFullTextSearch: decoded.IsFullTextSearch,
+		Operator:         decoded.Operator,
+	}
+
+	if decoded.Group != nil {
+		nested, err := decodeWheres(decoded.Group.Wheres, strict)
+		if err != nil {
+			return err
+		}
+		c.Group = &WhereGroup{Or: decoded.Group.Or, Wheres: nested}
+	}
+
+	if len(decoded.Value) == 0 {
+		return nil
+	}
+	if bytes.Equal(bytes.TrimSpace(decoded.Value), []byte("null")) {
+		switch c.Operator {
+		case "", OpEq:
+			c.Operator = OpIsNull
+		case OpNe:
+			c.Operator = OpIsNotNull
+		}
+		return nil
+	}
+
+	valueDecoder := json.NewDecoder(bytes.NewReader(decoded.Value))
+	valueDecoder.UseNumber()
+	var value interface{}
+	if err := valueDecoder.Decode(&value); err != nil {
+		return fmt.Errorf("where %s: %w", c.Name, err)
+	}
+
+	c.Value = jsonValue(value)
+	if _, ok := c.Value.([]interface{}); ok && c.Operator == "" && !c.IsLike && !c.IsFullTextSearch {
+		c.Operator = OpIn
+	}
+
+	return nil
+}
+
+// jsonValue converts the json.Number of a value decoded with UseNumber to an int64,
+// or a float64 when it is not an integer.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonValue(v[i])
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,150 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWhereOperators(t *testing.T) {
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		where    Where
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{Where{Name: "id", Value: 1}, "id = ?", []interface{}{1}},
+		{Where{Name: "id", Operator: OpNe, Value: 1}, "id != ?", []interface{}{1}},
+		{Where{Name: "age", Operator: OpGte, Value: 18}, "age >= ?", []interface{}{18}},
+		{Where{Name: "status", Operator: OpIn, Value: []string{"new", "paid"}}, "status IN ?", []interface{}{[]string{"new", "paid"}}},
+		{Where{Name: "created_at", Operator: OpBetween, Value: []time.Time{from, to}}, "created_at BETWEEN ? AND ?", []interface{}{from, to}},
+		{Where{Name: "deleted_at", Operator: OpIsNull, Value: "ignored"}, "deleted_at IS NULL", nil},
+		{Where{Name: "email", Operator: OpIsNotNull}, "email IS NOT NULL", nil},
+		{Where{Name: "name", Operator: "; DROP TABLE users", Value: "x"}, "name = ?", []interface{}{"x"}},
+		{Where{Name: "name", IsLike: true, Operator: OpGt, Value: "ware"}, "name LIKE ?", []interface{}{"ware"}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.where.String(); got != tt.wantSQL {
+			t.Errorf("Expected SQL %q, got %q", tt.wantSQL, got)
+		}
+		if got := tt.where.Args(); !reflect.DeepEqual(got, tt.wantArgs) {
+			t.Errorf("Expected args %v for %q, got %v", tt.wantArgs, tt.wantSQL, got)
+		}
+	}
+}
+
+func TestListWithOperators(t *testing.T) {
+	var (
+		db   = setupDryRunDB(t)
+		sql  = captureSQL(t, db)
+		repo = NewBaseGorm[User, uint](db)
+	)
+
+	_, _, err := repo.List(context.Background(), 1, 10, nil, []Where{
+		{Name: "id", Operator: OpIn, Value: []uint{1, 2, 3}},
+		{Name: "created_at", Operator: OpBetween, Value: []string{"2024-01-01", "2024-02-01"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+
+	want := "SELECT count(*) FROM `dummy_users` WHERE id IN (?,?,?) AND (created_at BETWEEN ? AND ?)"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
+
+func TestWhereGroupFromJSON(t *testing.T) {
+	var (
+		db      = setupDryRunDB(t)
+		sql     = captureSQL(t, db)
+		repo    = NewBaseGorm[User, uint](db)
+		filters []Where
+	)
+
+	payload := `[
+		{"Group": {"Or": true, "Wheres": [
+			{"Name": "name", "Value": "Alice"},
+			{"Group": {"Wheres": [
+				{"Name": "email", "IsLike": true, "Value": "@example.com"},
+				{"Name": "id", "Operator": ">", "Value": 10}
+			]}}
+		]}},
+		{"Name": "created_at", "Operator": "IS NOT NULL"}
+	]`
+	if err := json.Unmarshal([]byte(payload), &filters); err != nil {
+		t.Fatalf("Failed to decode filters: %v", err)
+	}
+
+	wantArgs := []interface{}{"Alice", "@example.com", int64(10)}
+	if args := filters[0].Args(); !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Expected args %v, got %v", wantArgs, args)
+	}
+
+	if _, _, err := repo.List(context.Background(), 1, 10, nil, filters); err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+
+	want := "SELECT count(*) FROM `dummy_users` WHERE ((name = ? OR (email LIKE ? AND id > ?))) AND created_at IS NOT NULL"
+	if *sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, *sql)
+	}
+}
+
+func TestWhereUnmarshalTypedValues(t *testing.T) {
+	payload := `[
+		{"Name": "active", "Value": true},
+		{"Name": "id", "Value": [1, 2, 9007199254740993]},
+		{"Name": "price", "Operator": ">", "Value": 9.5},
+		{"Name": "deleted_at", "Value": null},
+		{"Name": "email", "Operator": "!=", "Value": null},
+		{"Name": "created_at", "Operator": "BETWEEN", "Value": ["2024-01-01", "2024-12-31"]},
+		{"Name": "name", "Operator": ">"}
+	]`
+
+	var filters []Where
+	if err := json.Unmarshal([]byte(payload), &filters); err != nil {
+		t.Fatalf("Failed to decode filters: %v", err)
+	}
+
+	want := []Where{
+		{Name: "active", Value: true},
+		{Name: "id", Operator: OpIn, Value: []interface{}{int64(1), int64(2), int64(9007199254740993)}},
+		{Name: "price", Operator: OpGt, Value: 9.5},
+		{Name: "deleted_at", Operator: OpIsNull},
+		{Name: "email", Operator: OpIsNotNull},
+		{Name: "created_at", Operator: OpBetween, Value: []interface{}{"2024-01-01", "2024-12-31"}},
+		{Name: "name", Operator: OpGt},
+	}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("Expected filters\n%+v\ngot\n%+v", want, filters)
+	}
+}
+
+func TestDecodeWheresStrict(t *testing.T) {
+	filters, err := DecodeWheresStrict([]byte(`[{"Group": {"Or": true, "Wheres": [{"Name": "id", "Value": 1}, {"Name": "id", "Value": 2}]}}]`))
+	if err != nil {
+		t.Fatalf("Failed to decode filters: %v", err)
+	}
+	if len(filters) != 1 || len(filters[0].Group.Wheres) != 2 || filters[0].Group.Wheres[1].Value != int64(2) {
+		t.Errorf("Unexpected filters %+v", filters)
+	}
+
+	for _, payload := range []string{
+		`[{"Name": "total", "Operater": ">", "Value": 100}]`,
+		`[{"Group": {"Wheres": [{"Name": "id", "Value": 1, "Negate": true}]}}]`,
+	} {
+		if _, err := DecodeWheresStrict([]byte(payload)); err == nil {
+			t.Errorf("Expected %s to fail on its unknown field", payload)
+		}
+	}
+
+	var lenient []Where
+	if err := json.Unmarshal([]byte(`[{"Name": "total", "Operater": ">", "Value": 100}]`), &lenient); err != nil {
+		t.Errorf("Expected json.Unmarshal to ignore unknown fields, got %v", err)
+	}
+}
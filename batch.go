@@ -0,0 +1,111 @@
+package generic_gorm
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// batchRepository is the part of the repositories, e.g. a *base.BaseGorm or a
+// wrapper embedding it, an operation of a Batch needs to find their database.
+type batchRepository interface {
+	DB(ctx context.Context) *gorm.DB
+}
+
+type batchCreator[T any] interface {
+	batchRepository
+	Create(ctx context.Context, row *T) (*T, error)
+}
+
+type batchUpdater[T any] interface {
+	batchRepository
+	Update(ctx context.Context, row *T, updatedColumns []string) (int64, error)
+}
+
+type batchUpserter[T any] interface {
+	batchRepository
+	Upsert(ctx context.Context, row *T, onConflictUpdatedColumns []string) (int64, error)
+}
+
+type batchDeleter[PkType any] interface {
+	batchRepository
+	Delete(ctx context.Context, id PkType) (int64, error)
+}
+
+// Batch runs operations in order in one transaction of db, committed when they all
+// succeed, and returns the first error, e.g.
+//
+//	err := generic_gorm.Batch(ctx, db,
+//		generic_gorm.BatchCreate(userRepo, user),
+//		generic_gorm.BatchCreate(postRepo, post),
+//		generic_gorm.BatchUpdate(profileRepo, profile, []string{"bio"}),
+//	)
+//
+// The repositories join it through the context, see ContextWithDB, and a
+// transaction of ctx on the same database, in which it runs in a savepoint, see
+// TxManager.RunInTransaction.
+func Batch(ctx context.Context, db *gorm.DB, operations ...func(ctx context.Context) error) error {
+	if len(operations) == 0 {
+		return nil
+	}
+
+	return NewTxManager(db).RunInTransaction(ctx, func(ctx context.Context) error {
+		for i, operation := range operations {
+			if err := operation(ctx); err != nil {
+				return fmt.Errorf("batch operation %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+}
+
+// BatchCreate returns the Create of row by repo, an operation of Batch. The
+// overrides of the wrappers embedding a *base.BaseGorm, e.g. quota.Repo, apply.
+func BatchCreate[T any](repo batchCreator[T], row *T) func(ctx context.Context) error {
+	return batchOperation(repo, "Create", func(ctx context.Context) error {
+		_, err := repo.Create(ctx, row)
+		return err
+	})
+}
+
+// BatchUpdate returns the Update of the columns of row by repo, every column when
+// updatedColumns is empty, an operation of Batch.
+func BatchUpdate[T any](repo batchUpdater[T], row *T, updatedColumns []string) func(ctx context.Context) error {
+	return batchOperation(repo, "Update", func(ctx context.Context) error {
+		_, err := repo.Update(ctx, row, updatedColumns)
+		return err
+	})
+}
+
+// BatchUpsert returns the Upsert of row by repo, an operation of Batch.
+func BatchUpsert[T any](repo batchUpserter[T], row *T, onConflictUpdatedColumns []string) func(ctx context.Context) error {
+	return batchOperation(repo, "Upsert", func(ctx context.Context) error {
+		_, err := repo.Upsert(ctx, row, onConflictUpdatedColumns)
+		return err
+	})
+}
+
+// BatchDelete returns the Delete of the row of primary key id by repo, an
+// operation of Batch.
+func BatchDelete[PkType any](repo batchDeleter[PkType], id PkType) func(ctx context.Context) error {
+	return batchOperation(repo, "Delete", func(ctx context.Context) error {
+		_, err := repo.Delete(ctx, id)
+		return err
+	})
+}
+
+// batchOperation runs the method of repo, failing with ErrCrossDatabaseTransaction
+// when repo is not on the database of the transaction of ctx, which it would not
+// join.
+func batchOperation(repo batchRepository, method string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if tx, ok := DBFromContext(ctx); ok && !SameDatabase(repo.DB(ctx), tx) {
+			return fmt.Errorf("%s by %T: %w", method, repo, ErrCrossDatabaseTransaction)
+		}
+		if err := run(ctx); err != nil {
+			return fmt.Errorf("%s by %T: %w", method, repo, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package generic_gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type batchUser struct{ Name string }
+
+type batchPost struct{ Title string }
+
+// batchRepo records the calls of a repository of T, and whether they joined the
+// transaction of the context.
+type batchRepo[T any] struct {
+	db    *gorm.DB
+	calls *[]string
+	fail  error
+}
+
+// DB joins the transaction of ctx on its database only, like the repositories.
+func (r batchRepo[T]) DB(ctx context.Context) *gorm.DB {
+	if tx, ok := DBFromContext(ctx); ok && SameDatabase(tx, r.db) {
+		return tx
+	}
+	return r.db
+}
+
+func (r batchRepo[T]) record(ctx context.Context, call string) error {
+	if tx, ok := DBFromContext(ctx); !ok || tx.Statement.ConnPool == r.db.Statement.ConnPool {
+		call += " outside of the transaction"
+	}
+	*r.calls = append(*r.calls, call)
+	return r.fail
+}
+
+func (r batchRepo[T]) Create(ctx context.Context, row *T) (*T, error) {
+	return row, r.record(ctx, "create")
+}
+
+func (r batchRepo[T]) Update(ctx context.Context, row *T, columns []string) (int64, error) {
+	return 1, r.record(ctx, "update")
+}
+
+func (r batchRepo[T]) Delete(ctx context.Context, id uint) (int64, error) {
+	return 1, r.record(ctx, "delete")
+}
+
+func TestBatch(t *testing.T) {
+	pool := &fakePool{tx: &fakeTx{}}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: pool, SkipInitializeWithVersion: true}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	var (
+		calls []string
+		users = batchRepo[batchUser]{db: db, calls: &calls}
+		posts = batchRepo[batchPost]{db: db, calls: &calls}
+		ctx   = context.Background()
+	)
+
+	if err = Batch(ctx, db, BatchCreate(users, &batchUser{}), BatchUpdate(posts, &batchPost{}, nil), BatchDelete(posts, uint(1))); err != nil {
+		t.Fatalf("Failed to run batch: %v", err)
+	}
+	if len(calls) != 3 || calls[0] != "create" || calls[1] != "update" || calls[2] != "delete" {
+		t.Errorf("Expected the operations run in order in the transaction, got %q", calls)
+	}
+	if !pool.tx.committed {
+		t.Error("Expected a commit")
+	}
+
+	calls, pool.tx = nil, &fakeTx{}
+	failed := errors.New("failed")
+	err = Batch(ctx, db, BatchCreate(users, &batchUser{}), BatchCreate(batchRepo[batchPost]{db: db, calls: &calls, fail: failed}, &batchPost{}))
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected the error of the failed operation, got %v", err)
+	}
+	if !pool.tx.rolledBack || pool.tx.committed {
+		t.Errorf("Expected a rollback, got %+v", pool.tx)
+	}
+
+	// a repository of another database would not join the transaction
+	calls, pool.tx = nil, &fakeTx{}
+	err = Batch(ctx, db, BatchCreate(batchRepo[batchPost]{db: openDryRunDB(t, "posts"), calls: &calls}, &batchPost{}))
+	if !errors.Is(err, ErrCrossDatabaseTransaction) {
+		t.Errorf("Expected ErrCrossDatabaseTransaction, got %v", err)
+	}
+	if len(calls) != 0 || !pool.tx.rolledBack {
+		t.Errorf("Expected nothing run and a rollback, got %q and %+v", calls, pool.tx)
+	}
+}
@@ -0,0 +1,558 @@
+// Package cache is a second level cache of the rows read by Detail, DetailMultiple
+// and Wheres, kept in a Cache : in process by NewLRU, or shared by the instances by
+// an adapter of a Redis or Memcached client, see the readme. Every write through
+// the CachedRepo invalidates the rows of its table, by changing the version of the
+// table their keys carry. The pages of the List methods are not cached, see
+// WithCompression for the large rows.
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+)
+
+// Cache stores values by key for a TTL. The implementations are safe for concurrent use.
+type Cache interface {
+	// Get returns the value of key, found false when it is missing or expired.
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	// Set stores value under key for ttl, 0 keeping it until evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// GetMulti returns the values of the keys found, in one round trip.
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+	// SetMulti stores the values by key for ttl, in one round trip.
+	SetMulti(ctx context.Context, values map[string][]byte, ttl time.Duration) error
+	// Delete removes the keys, missing ones included.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Codec encodes the rows in the cache.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Option configures a CachedRepo.
+type Option func(*config)
+
+type config struct {
+	ttl           time.Duration
+	negativeTTL   *time.Duration // see WithNegativeTTL, ttl when nil
+	codec         Codec
+	prefix        string
+	compress      bool
+	compressAbove int        // bytes, see WithCompression
+	compressor    Compressor // see WithCompressor
+	stale         *stale     // see WithStaleWhileRevalidate
+	now           func() time.Time
+}
+
+// WithTTL sets how long the rows are cached, 5 minutes by default.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeTTL caches the not found rows of Detail, DetailMultiple and Wheres for
+// ttl instead of the TTL of WithTTL, e.g. a few seconds, so the lookups of missing
+// keys, e.g. by scrapers, are absorbed without keeping a created row hidden for long.
+// The writes invalidate them like the other rows. A negative ttl does not cache them.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.negativeTTL = &ttl
+	}
+}
+
+// WithCodec replaces the JSON encoding of the rows, e.g. for models hiding columns
+// from JSON with `json:"-"`, which JSON would not cache.
+func WithCodec(codec Codec) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// WithKeyPrefix prefixes the keys, generic_gorm: by default, e.g. to share a Redis
+// database between applications.
+func WithKeyPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// CachedRepo is a repository caching the rows read by Detail, DetailMultiple and
+// Wheres, the not found ones included. The reads given query options, or run within a
+// transaction of the context, see generic_gorm.ContextWithDB, bypass the cache.
+// The other methods are the ones of the wrapped repository, the writes invalidating
+// the cached rows of the table. The writes through Transaction, WithTx or DB do
+// not, and a read of another instance running between a write in a transaction and
+// its commit may cache the previous row until its TTL. The rows found in the cache go
+// through the policy of the repository, see base.WithPolicy, and its time zone,
+// tracking and identity map, like the rows read from the database.
+type CachedRepo[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] struct {
+	*base.BaseGorm[T, PkType]
+
+	cache  Cache
+	config config
+}
+
+// NewCachedRepo returns a CachedRepo caching the rows of repo in cache.
+func NewCachedRepo[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](repo *base.BaseGorm[T, PkType], cache Cache, opts ...Option) *CachedRepo[T, PkType] {
+	r := &CachedRepo[T, PkType]{
+		BaseGorm: repo,
+		cache:    cache,
+		config:   config{ttl: 5 * time.Minute, codec: jsonCodec{}, prefix: "generic_gorm:", compressor: deflate{}, now: time.Now},
+	}
+	for _, opt := range opts {
+		opt(&r.config)
+	}
+	if r.config.compress {
+		r.config.codec = compressingCodec{codec: r.config.codec, compressor: r.config.compressor, threshold: r.config.compressAbove}
+	}
+
+	return r
+}
+
+// Detail returns the row of primary key id, from the cache when it holds it, checked
+// against the policy of the repository like the rows read from the database.
+func (r *CachedRepo[T, PkType]) Detail(ctx context.Context, id PkType, opts ...base.QueryOption) (*T, error) {
+	if !r.cacheable(ctx, opts) {
+		return r.BaseGorm.Detail(ctx, id, opts...)
+	}
+
+	return r.cached(ctx, fmt.Sprintf("detail:%v", id), func(ctx context.Context) (*T, error) {
+		return r.BaseGorm.Detail(ctx, id)
+	}, func(ctx context.Context, row *T) (*T, error) {
+		return r.BaseGorm.LoadedByID(ctx, id, row)
+	})
+}
+
+// Wheres returns the first row matching wheres, from the cache when it holds it.
+func (r *CachedRepo[T, PkType]) Wheres(ctx context.Context, wheres []base.Where, opts ...base.QueryOption) (*T, error) {
+	if !r.cacheable(ctx, opts) {
+		return r.BaseGorm.Wheres(ctx, wheres, opts...)
+	}
+
+	encoded, err := json.Marshal(wheres)
+	if err != nil {
+		return r.BaseGorm.Wheres(ctx, wheres)
+	}
+	sum := sha256.Sum256(encoded)
+
+	return r.cached(ctx, "wheres:"+hex.EncodeToString(sum[:]), func(ctx context.Context) (*T, error) {
+		return r.BaseGorm.Wheres(ctx, wheres)
+	}, func(ctx context.Context, row *T) (*T, error) {
+		r.BaseGorm.Loaded(ctx, row)
+		return row, nil
+	})
+}
+
+// DetailMultiple returns the rows of primary keys ids in their order, a nil row
+// standing for a missing one. The cached rows are read in one round trip, and the
+// others in one IN query, then cached in one round trip.
+func (r *CachedRepo[T, PkType]) DetailMultiple(ctx context.Context, ids []PkType, opts ...base.QueryOption) ([]*T, error) {
+	if !r.cacheable(ctx, opts) || len(ids) == 0 {
+		return r.BaseGorm.DetailMultiple(ctx, ids, opts...)
+	}
+
+	version, err := r.version(ctx)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return r.BaseGorm.DetailMultiple(ctx, ids)
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.key(fmt.Sprintf("%s:detail:%v", version, id))
+	}
+
+	values, err := r.cache.GetMulti(ctx, keys)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+	}
+
+	var (
+		rows    = make([]*T, len(ids))
+		missing []PkType
+		loaded  = map[PkType]bool{}
+	)
+	for i, id := range ids {
+		if value, found := values[keys[i]]; found {
+			row, fresh, err := r.decode(value)
+			if err == nil && fresh {
+				if row != nil {
+					if row, err = r.BaseGorm.LoadedByID(ctx, id, row); err != nil {
+						return nil, err
+					}
+				}
+				rows[i] = row
+				continue
+			}
+			if err != nil {
+				generic_gorm.GetLoggerFromContext(ctx).WithField("key", keys[i]).Errorf("decode cached row: %v", err)
+			}
+		}
+		if !loaded[id] {
+			loaded[id] = true
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return rows, nil
+	}
+
+	found, err := r.BaseGorm.DetailMultiple(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		byID     = make(map[PkType]*T, len(missing))
+		backfill = map[time.Duration]map[string][]byte{} // by TTL, the not found rows having theirs
+	)
+	for i, id := range missing {
+		byID[id] = found[i]
+		// not found rows included, like Detail
+		ttl, cached := r.storedTTL(found[i])
+		if !cached {
+			continue
+		}
+		value, err := r.encode(found[i])
+		if err != nil {
+			generic_gorm.GetLoggerFromContext(ctx).Errorf("encode row %v: %v", id, err)
+			continue
+		}
+		if backfill[ttl] == nil {
+			backfill[ttl] = map[string][]byte{}
+		}
+		backfill[ttl][r.key(fmt.Sprintf("%s:detail:%v", version, id))] = value
+	}
+	for ttl, values := range backfill {
+		if err := r.cache.SetMulti(ctx, values, ttl); err != nil {
+			generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		}
+	}
+
+	for i, id := range ids {
+		if row, ok := byID[id]; ok {
+			rows[i] = row
+		}
+	}
+
+	return rows, nil
+}
+
+func (r *CachedRepo[T, PkType]) cacheable(ctx context.Context, opts []base.QueryOption) bool {
+	_, inTransaction := generic_gorm.DBFromContext(ctx)
+	return len(opts) == 0 && !inTransaction
+}
+
+// cached returns the row of key in the current version of the table, reading it with
+// load and caching it when missing. The rows found in the cache are passed to hit,
+// running on them what the repository runs on the rows it reads, e.g. its policy.
+// The failures of the cache fall back to load.
+func (r *CachedRepo[T, PkType]) cached(ctx context.Context, key string, load func(ctx context.Context) (*T, error), hit func(ctx context.Context, row *T) (*T, error)) (*T, error) {
+	version, err := r.version(ctx)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return load(ctx)
+	}
+	key = r.key(version + ":" + key)
+
+	value, found, err := r.cache.Get(ctx, key)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).WithField("key", key).Error(err)
+	}
+	if found {
+		row, fresh, err := r.decode(value)
+		if err == nil {
+			if !fresh {
+				r.refresh(ctx, key, load)
+			}
+			if row == nil {
+				return nil, nil
+			}
+			return hit(ctx, row)
+		}
+		generic_gorm.GetLoggerFromContext(ctx).WithField("key", key).Errorf("decode cached row: %v", err)
+	}
+
+	row, err := load(ctx)
+	if err != nil {
+		return row, err
+	}
+	r.store(ctx, key, row)
+
+	return row, nil
+}
+
+// store caches row under key, logging the failures.
+func (r *CachedRepo[T, PkType]) store(ctx context.Context, key string, row *T) {
+	ttl, cached := r.storedTTL(row)
+	if !cached {
+		return
+	}
+
+	value, err := r.encode(row)
+	if err == nil {
+		err = r.cache.Set(ctx, key, value, ttl)
+	}
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).WithField("key", key).Error(err)
+	}
+}
+
+// encode encodes row with the codec, preceded by the time it stays fresh until in
+// the stale while revalidate mode.
+func (r *CachedRepo[T, PkType]) encode(row *T) ([]byte, error) {
+	value, err := r.config.codec.Marshal(row)
+	if err != nil || r.config.stale == nil {
+		return value, err
+	}
+
+	freshUntil := int64(math.MaxInt64)
+	if ttl := r.ttlOf(row); ttl > 0 {
+		freshUntil = r.config.now().Add(ttl).UnixNano()
+	}
+	return append(binary.BigEndian.AppendUint64(nil, uint64(freshUntil)), value...), nil
+}
+
+// decode decodes the row of value, and reports whether it is still fresh.
+func (r *CachedRepo[T, PkType]) decode(value []byte) (*T, bool, error) {
+	fresh := true
+	if r.config.stale != nil {
+		if len(value) < 8 {
+			return nil, false, fmt.Errorf("cache entry of %d bytes without its freshness", len(value))
+		}
+		fresh = r.config.now().UnixNano() < int64(binary.BigEndian.Uint64(value))
+		value = value[8:]
+	}
+
+	var row *T
+	err := r.config.codec.Unmarshal(value, &row)
+	return row, fresh, err
+}
+
+// ttlOf is how long row stays fresh, row being nil for a not found one.
+func (r *CachedRepo[T, PkType]) ttlOf(row *T) time.Duration {
+	if row == nil && r.config.negativeTTL != nil {
+		return *r.config.negativeTTL
+	}
+
+	return r.config.ttl
+}
+
+// storedTTL is how long the cache keeps row, fresh then stale, and cached false when
+// it is not cached.
+func (r *CachedRepo[T, PkType]) storedTTL(row *T) (ttl time.Duration, cached bool) {
+	ttl = r.ttlOf(row)
+	if ttl < 0 {
+		return 0, false
+	}
+	if r.config.stale == nil || ttl == 0 {
+		return ttl, true
+	}
+
+	return ttl + r.config.stale.ttl, true
+}
+
+func (r *CachedRepo[T, PkType]) key(suffix string) string {
+	var e T
+	return r.config.prefix + e.TableName() + ":" + suffix
+}
+
+// version returns the current version of the table, setting a new one when the
+// cache lost it, so the rows cached under a previous version are never read again.
+func (r *CachedRepo[T, PkType]) version(ctx context.Context) (string, error) {
+	value, found, err := r.cache.Get(ctx, r.key("version"))
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return string(value), nil
+	}
+
+	return r.bump(ctx)
+}
+
+// bump sets a new version of the table.
+func (r *CachedRepo[T, PkType]) bump(ctx context.Context) (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	version := hex.EncodeToString(b[:])
+	// the version outlives the rows cached under it
+	return version, r.cache.Set(ctx, r.key("version"), []byte(version), 0)
+}
+
+// invalidate makes the cached rows of the table unreachable after a write, failed
+// ones included as they may have written some rows.
+func (r *CachedRepo[T, PkType]) invalidate(ctx context.Context) {
+	if _, err := r.bump(ctx); err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Errorf("invalidate cached rows: %v", err)
+	}
+}
+
+func (r *CachedRepo[T, PkType]) Create(ctx context.Context, row *T) (*T, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Create(ctx, row)
+}
+
+func (r *CachedRepo[T, PkType]) CreateMultiple(ctx context.Context, rows []*T) ([]*T, int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.CreateMultiple(ctx, rows)
+}
+
+func (r *CachedRepo[T, PkType]) CreateMultipleDedup(ctx context.Context, rows []*T, keyColumns []string) ([]*T, []*T, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.CreateMultipleDedup(ctx, rows, keyColumns)
+}
+
+func (r *CachedRepo[T, PkType]) CreateMultiplePartial(ctx context.Context, rows []*T) (*base.ImportReport[T], error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.CreateMultiplePartial(ctx, rows)
+}
+
+func (r *CachedRepo[T, PkType]) FirstOrCreate(ctx context.Context, wheres []base.Where, defaults *T) (*T, bool, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.FirstOrCreate(ctx, wheres, defaults)
+}
+
+func (r *CachedRepo[T, PkType]) FirstOrCreateAssign(ctx context.Context, wheres []base.Where, defaults *T, assign map[string]interface{}) (*T, bool, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.FirstOrCreateAssign(ctx, wheres, defaults, assign)
+}
+
+func (r *CachedRepo[T, PkType]) Update(ctx context.Context, row *T, updatedColumns []string) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Update(ctx, row, updatedColumns)
+}
+
+func (r *CachedRepo[T, PkType]) UpdateWhere(ctx context.Context, wheres []base.Where, values map[string]interface{}) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.UpdateWhere(ctx, wheres, values)
+}
+
+func (r *CachedRepo[T, PkType]) UpdateWithFieldMask(ctx context.Context, row *T, mask []string) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.UpdateWithFieldMask(ctx, row, mask)
+}
+
+func (r *CachedRepo[T, PkType]) Patch(ctx context.Context, id PkType, patch json.RawMessage) (*T, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Patch(ctx, id, patch)
+}
+
+func (r *CachedRepo[T, PkType]) Increment(ctx context.Context, id PkType, column string, delta interface{}) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Increment(ctx, id, column, delta)
+}
+
+func (r *CachedRepo[T, PkType]) Upsert(ctx context.Context, row *T, onConflictUpdatedColumns []string) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Upsert(ctx, row, onConflictUpdatedColumns)
+}
+
+func (r *CachedRepo[T, PkType]) UpsertWithOptions(ctx context.Context, row *T, opts base.UpsertOptions) (*T, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.UpsertWithOptions(ctx, row, opts)
+}
+
+func (r *CachedRepo[T, PkType]) Merge(ctx context.Context, keepID PkType, mergeIDs []PkType, strategy base.MergeStrategy[T]) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Merge(ctx, keepID, mergeIDs, strategy)
+}
+
+func (r *CachedRepo[T, PkType]) Reconcile(ctx context.Context, snapshot []*T, keyColumns []string, opts base.ReconcileOptions) (*base.ReconcileReport[T], error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Reconcile(ctx, snapshot, keyColumns, opts)
+}
+
+func (r *CachedRepo[T, PkType]) SaveChanges(ctx context.Context, row *T) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.SaveChanges(ctx, row)
+}
+
+func (r *CachedRepo[T, PkType]) Delete(ctx context.Context, id PkType) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Delete(ctx, id)
+}
+
+func (r *CachedRepo[T, PkType]) DeleteWhere(ctx context.Context, wheres []base.Where) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.DeleteWhere(ctx, wheres)
+}
+
+func (r *CachedRepo[T, PkType]) SoftDelete(ctx context.Context, id PkType) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.SoftDelete(ctx, id)
+}
+
+func (r *CachedRepo[T, PkType]) ForceDelete(ctx context.Context, id PkType) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.ForceDelete(ctx, id)
+}
+
+func (r *CachedRepo[T, PkType]) Restore(ctx context.Context, id PkType) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.Restore(ctx, id)
+}
+
+func (r *CachedRepo[T, PkType]) PurgeExpired(ctx context.Context, column string, maxAge time.Duration, batchSize int) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.PurgeExpired(ctx, column, maxAge, batchSize)
+}
+
+func (r *CachedRepo[T, PkType]) UndoOperation(ctx context.Context, operationID string) (int64, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.UndoOperation(ctx, operationID)
+}
+
+// the association writes may change the foreign keys of the rows, e.g. of belongs to
+// associations
+
+func (r *CachedRepo[T, PkType]) AppendAssociation(ctx context.Context, model *T, field string, values interface{}) error {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.AppendAssociation(ctx, model, field, values)
+}
+
+func (r *CachedRepo[T, PkType]) ReplaceAssociation(ctx context.Context, model *T, field string, values interface{}) error {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.ReplaceAssociation(ctx, model, field, values)
+}
+
+func (r *CachedRepo[T, PkType]) DeleteAssociation(ctx context.Context, model *T, field string, values interface{}) error {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.DeleteAssociation(ctx, model, field, values)
+}
+
+func (r *CachedRepo[T, PkType]) ClearAssociation(ctx context.Context, model *T, field string) error {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.ClearAssociation(ctx, model, field)
+}
+
+func (r *CachedRepo[T, PkType]) SyncAssociation(ctx context.Context, model *T, field string, desired interface{}) (base.SyncResult, error) {
+	defer r.invalidate(ctx)
+	return r.BaseGorm.SyncAssociation(ctx, model, field, desired)
+}
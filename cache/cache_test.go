@@ -0,0 +1,418 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Product struct {
+	ID   uint   `json:"id" gorm:"column:id;primaryKey"`
+	Name string `json:"name" gorm:"column:name"`
+}
+
+func (Product) TableName() string {
+	return "cache_products"
+}
+
+func (Product) PrimaryKey() string {
+	return "id"
+}
+
+// CachedRepo must keep implementing base.Repository.
+var _ base.Repository[Product, uint] = (*CachedRepo[Product, uint])(nil)
+
+func TestCachedRepo(t *testing.T) {
+	var (
+		db      = testdb.DryRun(t)
+		ctx     = context.Background()
+		queries int
+		lru     = NewLRU(100)
+		repo    = NewCachedRepo(base.NewBaseGorm[Product, uint](db), lru)
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		queries++
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	read := func() {
+		t.Helper()
+		if _, err := repo.Detail(ctx, 1); err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		if _, err := repo.Wheres(ctx, []base.Where{{Name: "name", Value: "pen"}}); err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+	}
+
+	read()
+	read()
+	if queries != 2 {
+		t.Fatalf("Expected the second reads served by the cache, got %d queries", queries)
+	}
+
+	if _, err := repo.Detail(ctx, 1, base.WithSelect("id")); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if queries != 3 {
+		t.Fatalf("Expected a read with query options to bypass the cache, got %d queries", queries)
+	}
+
+	if _, err := repo.UpdateWhere(ctx, []base.Where{{Name: "id", Value: 1}}, map[string]interface{}{"name": "pencil"}); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	read()
+	if queries != 5 {
+		t.Errorf("Expected the write to invalidate the cached rows, got %d queries", queries)
+	}
+
+	// the rows of the previous version are never read again
+	if err := lru.Delete(ctx, "generic_gorm:cache_products:version"); err != nil {
+		t.Fatalf("Failed to delete version: %v", err)
+	}
+	read()
+	if queries != 7 {
+		t.Errorf("Expected a lost version to invalidate the cached rows, got %d queries", queries)
+	}
+}
+
+func TestCachedDetailMultiple(t *testing.T) {
+	var (
+		db      = testdb.DryRun(t)
+		ctx     = context.Background()
+		queried [][]interface{}
+		repo    = NewCachedRepo(base.NewBaseGorm[Product, uint](db), NewLRU(100))
+	)
+	// the dry run returns no rows : the query returns the requested products but 3
+	err := db.Callback().Query().After("gorm:query").Register("test:stub_rows", func(tx *gorm.DB) {
+		queried = append(queried, tx.Statement.Vars)
+		if rows, ok := tx.Statement.Dest.(*[]Product); ok {
+			for _, id := range tx.Statement.Vars {
+				if id != uint(3) {
+					*rows = append(*rows, Product{ID: id.(uint), Name: fmt.Sprintf("product %v", id)})
+				}
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	check := func(rows []*Product) {
+		t.Helper()
+		if len(rows) != 4 || rows[0].ID != 2 || rows[1].ID != 1 || rows[2] != nil || rows[3].ID != 2 {
+			t.Fatalf("Expected the rows in the order of the ids, got %v", rows)
+		}
+	}
+
+	rows, err := repo.DetailMultiple(ctx, []uint{2, 1, 3, 2})
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	check(rows)
+	if len(queried) != 1 || fmt.Sprint(queried[0]) != "[2 1 3]" {
+		t.Fatalf("Expected one IN query of the distinct ids, got %v", queried)
+	}
+
+	if rows, err = repo.DetailMultiple(ctx, []uint{2, 1, 3, 2}); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	check(rows)
+	if row, err := repo.Detail(ctx, 1); err != nil || row.Name != "product 1" {
+		t.Fatalf("Expected the row cached by DetailMultiple, got %v, %v", row, err)
+	}
+	if len(queried) != 1 {
+		t.Errorf("Expected the next reads served by the cache, got %v", queried)
+	}
+
+	if rows, err = repo.DetailMultiple(ctx, []uint{4, 1}); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if rows[0].ID != 4 || rows[1].ID != 1 || len(queried) != 2 || fmt.Sprint(queried[1]) != "[4]" {
+		t.Errorf("Expected only the missing id queried, got %v and %v", rows, queried)
+	}
+}
+
+func TestCachedRowsAuthorized(t *testing.T) {
+	var (
+		db     = testdb.DryRun(t)
+		policy = func(ctx context.Context, actor *base.Actor, action base.Action, row interface{}) error {
+			if actor == nil || actor.ID != "alice" {
+				return base.ErrForbidden
+			}
+			return nil
+		}
+		repo  = NewCachedRepo(base.NewBaseGorm[Product, uint](db, base.WithPolicy(policy)), NewLRU(100))
+		alice = base.ContextWithActor(context.Background(), base.Actor{ID: "alice"})
+		bob   = base.ContextWithActor(context.Background(), base.Actor{ID: "bob"})
+	)
+	// the dry run returns no rows
+	err := db.Callback().Query().After("gorm:query").Register("test:stub_rows", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *Product:
+			*dest, tx.RowsAffected = Product{ID: 1, Name: "pen"}, 1
+		case *[]Product:
+			*dest = append(*dest, Product{ID: 1, Name: "pen"})
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	if row, err := repo.Detail(alice, 1); err != nil || row == nil {
+		t.Fatalf("Expected alice to read the row, got %v, %v", row, err)
+	}
+
+	// the row cached for alice is not served to bob
+	if _, err := repo.Detail(bob, 1); !errors.Is(err, base.ErrForbidden) {
+		t.Errorf("Expected the cached row denied to bob, got %v", err)
+	}
+	if _, err := repo.DetailMultiple(bob, []uint{1}); !errors.Is(err, base.ErrForbidden) {
+		t.Errorf("Expected the cached rows denied to bob, got %v", err)
+	}
+
+	// the cached rows join the identity map like the ones read from the database
+	ctx := base.ContextWithIdentityMap(alice)
+	first, err := repo.Detail(ctx, 1)
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if second, err := repo.Detail(ctx, 1); err != nil || second != first {
+		t.Errorf("Expected the same row from the identity map, got %p and %p, %v", first, second, err)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var (
+		db      = testdb.DryRun(t)
+		ctx     = context.Background()
+		now     = time.Now()
+		mu      sync.Mutex
+		queries int
+		gate    chan struct{}
+		repo    = NewCachedRepo(base.NewBaseGorm[Product, uint](db), NewLRU(100), WithTTL(time.Minute), WithStaleWhileRevalidate(time.Hour, 1))
+	)
+	repo.config.now = func() time.Time { return now }
+
+	// the dry run returns no rows : the reads return the product named after their number
+	err := db.Callback().Query().After("gorm:query").Register("test:stub_rows", func(tx *gorm.DB) {
+		mu.Lock()
+		queries++
+		name, wait := fmt.Sprintf("read %d", queries), gate
+		mu.Unlock()
+
+		if wait != nil {
+			<-wait
+		}
+		if row, ok := tx.Statement.Dest.(*Product); ok {
+			row.Name = name
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	detail := func(id uint) string {
+		t.Helper()
+		row, err := repo.Detail(ctx, id)
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		return row.Name
+	}
+	queried := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return queries
+	}
+
+	if detail(1) != "read 1" || detail(2) != "read 2" {
+		t.Fatal("Expected the rows read from the database")
+	}
+
+	now = now.Add(2 * time.Minute)
+	mu.Lock()
+	gate = make(chan struct{})
+	mu.Unlock()
+
+	if name := detail(1); name != "read 1" {
+		t.Errorf("Expected the stale row served at once, got %s", name)
+	}
+	detail(1) // already refreshing
+	detail(2) // no refresh slot left
+	close(gate)
+
+	// the slot is released once the row is stored
+	for deadline := time.Now().Add(time.Second); len(repo.config.stale.refreshes) > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the refresh slot released")
+		}
+	}
+
+	if queried() != 3 {
+		t.Errorf("Expected a single bounded refresh, got %d queries", queried())
+	}
+	if name := detail(1); name != "read 3" || queried() != 3 {
+		t.Errorf("Expected the refreshed row from the cache, got %s after %d queries", name, queried())
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	tests := []struct {
+		name        string
+		negativeTTL time.Duration
+		want        []int // the queries after each step
+	}{
+		{name: "short", negativeTTL: time.Second, want: []int{1, 1, 2, 3, 3}},
+		{name: "disabled", negativeTTL: -1, want: []int{1, 2, 3, 4, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				db      = testdb.DryRun(t)
+				ctx     = context.Background()
+				now     = time.Now()
+				queries int
+				lru     = NewLRU(100)
+				repo    = NewCachedRepo(base.NewBaseGorm[Product, uint](db), lru, WithTTL(time.Hour), WithNegativeTTL(tt.negativeTTL))
+			)
+			lru.now = func() time.Time { return now }
+
+			// the dry run finds no row without error : the product 404 is not found
+			err := db.Callback().Query().After("gorm:query").Register("test:not_found", func(tx *gorm.DB) {
+				queries++
+				if len(tx.Statement.Vars) > 0 && tx.Statement.Vars[0] == uint(404) {
+					tx.AddError(gorm.ErrRecordNotFound)
+				}
+			})
+			if err != nil {
+				t.Fatalf("Failed to register callback: %v", err)
+			}
+
+			steps := []func(){
+				func() {
+					if row, err := repo.Detail(ctx, 404); err != nil || row != nil {
+						t.Fatalf("Expected no row, got %v, %v", row, err)
+					}
+				},
+				func() { repo.Detail(ctx, 404) },
+				func() { repo.Detail(ctx, 1) },
+				func() {
+					now = now.Add(2 * time.Second)
+					repo.Detail(ctx, 404)
+				},
+				func() { repo.Detail(ctx, 1) },
+			}
+			for i, step := range steps {
+				step()
+				if queries != tt.want[i] {
+					t.Fatalf("Step %d: expected %d queries, got %d", i, tt.want[i], queries)
+				}
+			}
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		lru   = NewLRU(100)
+		repo  = NewCachedRepo(base.NewBaseGorm[Product, uint](testdb.DryRun(t)), lru, WithCompression(64))
+		large = &Product{ID: 1, Name: strings.Repeat("fountain pen ", 100)}
+	)
+
+	encoded, err := repo.config.codec.Marshal(large)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if encoded[0] != entryCompressed || len(encoded) > 200 {
+		t.Errorf("Expected a compressed entry, got %d bytes starting with %d", len(encoded), encoded[0])
+	}
+	var decoded *Product
+	if err := repo.config.codec.Unmarshal(encoded, &decoded); err != nil || *decoded != *large {
+		t.Errorf("Expected the large row back, got %+v, %v", decoded, err)
+	}
+
+	// the dry run reads a zero row, under the threshold
+	if _, err := repo.Detail(ctx, 1); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	version, _, _ := lru.Get(ctx, "generic_gorm:cache_products:version")
+	value, found, _ := lru.Get(ctx, "generic_gorm:cache_products:"+string(version)+":detail:1")
+	if !found || value[0] != entryRaw || string(value[1:]) != `{"id":0,"name":""}` {
+		t.Errorf("Expected the small row stored as is, got %q", value)
+	}
+	if _, err := repo.Detail(ctx, 1); err != nil {
+		t.Errorf("Failed to read the cached row: %v", err)
+	}
+}
+
+// reversing is a Compressor standing for an adapter of snappy or zstd.
+type reversing struct{}
+
+func (reversing) Compress(data []byte) ([]byte, error) {
+	reversed := append([]byte(nil), data...)
+	slices.Reverse(reversed)
+	return reversed, nil
+}
+
+func (r reversing) Decompress(data []byte) ([]byte, error) {
+	return r.Compress(data)
+}
+
+func TestWithCompressor(t *testing.T) {
+	var (
+		repo  = NewCachedRepo(base.NewBaseGorm[Product, uint](testdb.DryRun(t)), NewLRU(100), WithCompressor(reversing{}), WithCompression(8))
+		large = &Product{ID: 1, Name: "fountain pen"}
+	)
+
+	encoded, err := repo.config.codec.Marshal(large)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if encoded[0] != entryCompressed || string(encoded[1:]) != `}"nep niatnuof":"eman",1:"di"{` {
+		t.Errorf("Expected an entry compressed by the compressor, got %q", encoded)
+	}
+	var decoded *Product
+	if err := repo.config.codec.Unmarshal(encoded, &decoded); err != nil || *decoded != *large {
+		t.Errorf("Expected the row back, got %+v, %v", decoded, err)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	var (
+		ctx = context.Background()
+		now = time.Now()
+		lru = NewLRU(2)
+	)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(ctx, "a", []byte("1"), 0)
+	lru.Set(ctx, "b", []byte("2"), time.Minute)
+	lru.Get(ctx, "a")
+	lru.Set(ctx, "c", []byte("3"), 0)
+
+	if _, found, _ := lru.Get(ctx, "b"); found {
+		t.Error("Expected the least recently used key to be evicted")
+	}
+	if value, found, _ := lru.Get(ctx, "a"); !found || string(value) != "1" {
+		t.Errorf("Expected a kept, got %q, %v", value, found)
+	}
+
+	lru.Set(ctx, "c", []byte("3"), time.Minute)
+	now = now.Add(time.Minute)
+	if _, found, _ := lru.Get(ctx, "c"); found || lru.Len() != 1 {
+		t.Errorf("Expected c to expire, got %v with %d keys", found, lru.Len())
+	}
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// the first byte of the entries of a compressing codec
+const (
+	entryRaw        byte = 0
+	entryCompressed byte = 1
+)
+
+// Compressor compresses the large entries of WithCompression. DEFLATE of the
+// standard library is the default : snappy or zstd, faster for the same ratio,
+// would make every user of the package depend on them, so they are plugged in
+// with WithCompressor through an adapter of a few lines, see the readme.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// WithCompression compresses the encoded rows larger than threshold bytes, so the
+// large rows do not fill the memory of the cache. The smaller ones are stored as
+// is, compressing them would save little for the CPU spent. It wraps the codec of
+// WithCodec whatever the order of the options. The entries cached without it, or
+// with another Compressor, fail to decode and are read again from the database.
+// The CachedRepo caches rows, not the pages of the List methods, which any write
+// would invalidate at once : a large row is what it compresses.
+func WithCompression(threshold int) Option {
+	return func(c *config) {
+		c.compress = true
+		c.compressAbove = threshold
+	}
+}
+
+// WithCompressor compresses the entries of WithCompression with compressor instead
+// of DEFLATE.
+func WithCompressor(compressor Compressor) Option {
+	return func(c *config) {
+		c.compressor = compressor
+	}
+}
+
+// compressingCodec compresses the values of codec above threshold bytes, marking
+// each entry by its first byte.
+type compressingCodec struct {
+	codec      Codec
+	compressor Compressor
+	threshold  int
+}
+
+func (c compressingCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= c.threshold {
+		return append([]byte{entryRaw}, data...), nil
+	}
+
+	compressed, err := c.compressor.Compress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{entryCompressed}, compressed...), nil
+}
+
+func (c compressingCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty cache entry")
+	}
+
+	switch data[0] {
+	case entryRaw:
+		return c.codec.Unmarshal(data[1:], v)
+	case entryCompressed:
+		decompressed, err := c.compressor.Decompress(data[1:])
+		if err != nil {
+			return err
+		}
+		return c.codec.Unmarshal(decompressed, v)
+	default:
+		return fmt.Errorf("cache entry of unknown encoding %d", data[0])
+	}
+}
+
+// deflate is the default Compressor, favoring speed over ratio.
+type deflate struct{}
+
+func (deflate) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (deflate) Decompress(data []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+}
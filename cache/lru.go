@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in process Cache of a bounded number of keys, evicting the least
+// recently used one.
+type LRU struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // of *lruEntry, the most recently used first
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // zero when it does not expire
+}
+
+// NewLRU returns an LRU holding at most capacity keys.
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+// Get implements Cache.
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, found := c.get(key)
+	return value, found, nil
+}
+
+// GetMulti implements Cache.
+func (c *LRU) GetMulti(_ context.Context, keys []string) (map[string][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if value, found := c.get(key); found {
+			values[key] = value
+		}
+	}
+
+	return values, nil
+}
+
+// Set implements Cache.
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	expiresAt := c.expiresAt(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, expiresAt)
+	return nil
+}
+
+// SetMulti implements Cache.
+func (c *LRU) SetMulti(_ context.Context, values map[string][]byte, ttl time.Duration) error {
+	expiresAt := c.expiresAt(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, value := range values {
+		c.set(key, value, expiresAt)
+	}
+	return nil
+}
+
+// Delete implements Cache.
+func (c *LRU) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of keys held, expired ones included until read or evicted.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU) expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return c.now().Add(ttl)
+}
+
+func (c *LRU) get(key string) ([]byte, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (c *LRU) set(key string, value []byte, expiresAt time.Time) {
+	if element, ok := c.entries[key]; ok {
+		element.Value = &lruEntry{key: key, value: value, expiresAt: expiresAt}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
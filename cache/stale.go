@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+)
+
+type stale struct {
+	ttl        time.Duration
+	refreshes  chan struct{} // a slot per running refresh
+	refreshing sync.Map      // the keys being refreshed
+}
+
+// WithStaleWhileRevalidate keeps serving the rows of Detail and Wheres for ttl once
+// the TTL of WithTTL expired, reading them again in the background, so the reads of
+// popular rows do not wait for the database when they expire. At most maxRefreshes
+// reads run in the background, a stale row read while they are all busy is refreshed
+// by a later read. DetailMultiple reads the stale rows again with the missing ones.
+func WithStaleWhileRevalidate(ttl time.Duration, maxRefreshes int) Option {
+	return func(c *config) {
+		if maxRefreshes <= 0 {
+			maxRefreshes = 1
+		}
+		c.stale = &stale{ttl: ttl, refreshes: make(chan struct{}, maxRefreshes)}
+	}
+}
+
+// refresh reads the row of key again with load in the background, unless it is
+// already being refreshed or every refresh slot is busy.
+func (r *CachedRepo[T, PkType]) refresh(ctx context.Context, key string, load func(ctx context.Context) (*T, error)) {
+	s := r.config.stale
+	if _, refreshing := s.refreshing.LoadOrStore(key, true); refreshing {
+		return
+	}
+
+	select {
+	case s.refreshes <- struct{}{}:
+	default:
+		s.refreshing.Delete(key)
+		return
+	}
+
+	// the refresh outlives the request
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() {
+			<-s.refreshes
+			s.refreshing.Delete(key)
+		}()
+
+		row, err := load(ctx)
+		if err != nil {
+			generic_gorm.GetLoggerFromContext(ctx).WithField("key", key).Errorf("refresh cached row: %v", err)
+			return
+		}
+		r.store(ctx, key, row)
+	}()
+}
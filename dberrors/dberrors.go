@@ -0,0 +1,149 @@
+// Package dberrors classifies the errors of the MySQL, Postgres and SQLite drivers,
+// so callers can check for a duplicate key or a deadlock with errors.Is rather than
+// matching error messages.
+package dberrors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDuplicateKey is a violation of a primary key or unique index.
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrForeignKeyViolation is a missing parent row, or a parent row still referenced.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrNotNullViolation is a NULL written to a NOT NULL column.
+	ErrNotNullViolation = errors.New("not null violation")
+	// ErrCheckViolation is a row failing a CHECK constraint.
+	ErrCheckViolation = errors.New("check constraint violation")
+	// ErrDeadlock is a transaction rolled back to break a deadlock, which can be retried.
+	ErrDeadlock = errors.New("deadlock")
+	// ErrLockTimeout is a lock not acquired in time, or at once with NOWAIT.
+	ErrLockTimeout = errors.New("lock timeout")
+)
+
+// mysqlErrors maps the MySQL error numbers to their class.
+var mysqlErrors = map[uint16]error{
+	1022: ErrDuplicateKey,
+	1062: ErrDuplicateKey,
+	1586: ErrDuplicateKey,
+	1216: ErrForeignKeyViolation,
+	1217: ErrForeignKeyViolation,
+	1451: ErrForeignKeyViolation,
+	1452: ErrForeignKeyViolation,
+	1048: ErrNotNullViolation,
+	1364: ErrNotNullViolation,
+	3819: ErrCheckViolation,
+	1213: ErrDeadlock,
+	1205: ErrLockTimeout,
+	3572: ErrLockTimeout,
+}
+
+// postgresErrors maps the Postgres SQLSTATE codes to their class.
+var postgresErrors = map[string]error{
+	"23505": ErrDuplicateKey,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"23514": ErrCheckViolation,
+	"40P01": ErrDeadlock,
+	"55P03": ErrLockTimeout,
+}
+
+// sqliteErrors maps the messages of the SQLite drivers, which expose no portable
+// error code, to their class.
+var sqliteErrors = map[string]error{
+	"UNIQUE constraint failed":      ErrDuplicateKey,
+	"PRIMARY KEY constraint failed": ErrDuplicateKey,
+	"FOREIGN KEY constraint failed": ErrForeignKeyViolation,
+	"NOT NULL constraint failed":    ErrNotNullViolation,
+	"CHECK constraint failed":       ErrCheckViolation,
+	"database is locked":            ErrLockTimeout,
+}
+
+// sqlStater is implemented by the errors of the Postgres drivers, e.g. *pgconn.PgError.
+type sqlStater interface {
+	SQLState() string
+}
+
+// Classify returns the class of err, one of the errors of this package, or nil when
+// err is not a recognized driver error. The errors gorm translates with its
+// TranslateError config are recognized too.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErrors[mysqlErr.Number]
+	}
+
+	var pgErr sqlStater
+	if errors.As(err, &pgErr) {
+		return postgresErrors[pgErr.SQLState()]
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicateKey
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrForeignKeyViolation
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return ErrCheckViolation
+	}
+
+	message := err.Error()
+	for prefix, class := range sqliteErrors {
+		if strings.Contains(message, prefix) {
+			return class
+		}
+	}
+
+	return nil
+}
+
+// Translate wraps err with its class, see Classify, so both errors.Is(err, class) and
+// errors.As(err, &driverError) hold. Unrecognized errors are returned as is.
+func Translate(err error) error {
+	class := Classify(err)
+	if class == nil || errors.Is(err, class) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", class, err)
+}
+
+// IsDuplicateKey reports whether err violates a primary key or unique index.
+func IsDuplicateKey(err error) bool {
+	return errors.Is(Translate(err), ErrDuplicateKey)
+}
+
+// IsForeignKeyViolation reports whether err violates a foreign key.
+func IsForeignKeyViolation(err error) bool {
+	return errors.Is(Translate(err), ErrForeignKeyViolation)
+}
+
+// IsNotNullViolation reports whether err writes NULL to a NOT NULL column.
+func IsNotNullViolation(err error) bool {
+	return errors.Is(Translate(err), ErrNotNullViolation)
+}
+
+// IsCheckViolation reports whether err fails a CHECK constraint.
+func IsCheckViolation(err error) bool {
+	return errors.Is(Translate(err), ErrCheckViolation)
+}
+
+// IsDeadlock reports whether err rolled back a transaction to break a deadlock.
+func IsDeadlock(err error) bool {
+	return errors.Is(Translate(err), ErrDeadlock)
+}
+
+// IsLockTimeout reports whether err failed to acquire a lock in time.
+func IsLockTimeout(err error) bool {
+	return errors.Is(Translate(err), ErrLockTimeout)
+}
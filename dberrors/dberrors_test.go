@@ -0,0 +1,75 @@
+package dberrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// pgError mimics *pgconn.PgError.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string {
+	return "ERROR: postgres error (SQLSTATE " + e.code + ")"
+}
+
+func (e *pgError) SQLState() string {
+	return e.code
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "nil", err: nil, want: nil},
+		{name: "unrecognized", err: errors.New("connection refused"), want: nil},
+		{name: "mysql duplicate entry", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'email'"}, want: ErrDuplicateKey},
+		{name: "mysql child row", err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}, want: ErrForeignKeyViolation},
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, want: ErrDeadlock},
+		{name: "mysql lock wait timeout", err: &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, want: ErrLockTimeout},
+		{name: "mysql other", err: &mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}, want: nil},
+		{name: "wrapped mysql error", err: fmt.Errorf("create user: %w", &mysql.MySQLError{Number: 1062}), want: ErrDuplicateKey},
+		{name: "postgres unique violation", err: &pgError{code: "23505"}, want: ErrDuplicateKey},
+		{name: "postgres deadlock", err: &pgError{code: "40P01"}, want: ErrDeadlock},
+		{name: "sqlite unique constraint", err: errors.New("UNIQUE constraint failed: users.email"), want: ErrDuplicateKey},
+		{name: "sqlite foreign key", err: errors.New("FOREIGN KEY constraint failed"), want: ErrForeignKeyViolation},
+		{name: "gorm translated error", err: gorm.ErrDuplicatedKey, want: ErrDuplicateKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	driverErr := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'email'"}
+
+	err := Translate(driverErr)
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("Expected the translated error to be ErrDuplicateKey, got %v", err)
+	}
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1062 {
+		t.Errorf("Expected the driver error to be kept, got %v", err)
+	}
+
+	if !IsDuplicateKey(driverErr) || IsDeadlock(driverErr) {
+		t.Error("Expected the driver error to be a duplicate key only")
+	}
+
+	unknown := errors.New("connection refused")
+	if Translate(unknown) != unknown {
+		t.Error("Expected an unrecognized error to be returned as is")
+	}
+}
@@ -0,0 +1,216 @@
+// Package dblock implements coarse grained distributed locks on top of a database
+// table, for environments without Redis or etcd. Locks expire after their TTL unless
+// renewed, so a crashed holder never blocks others forever. Holders' clocks are
+// expected to be synchronized, as expiries are computed in Go.
+package dblock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotAcquired is returned by Hold when the lock is held by another owner.
+var ErrNotAcquired = errors.New("lock held by another owner")
+
+// ErrLockLost is the cause of the context cancellation when a held lock cannot be renewed.
+var ErrLockLost = errors.New("lock lost")
+
+// Lock is one row of the locks table.
+type Lock struct {
+	Name      string    `json:"name" gorm:"column:name;primaryKey;size:191"`
+	Owner     string    `json:"owner" gorm:"column:owner;size:191"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Lock) TableName() string {
+	return "locks"
+}
+
+func (Lock) PrimaryKey() string {
+	return "name"
+}
+
+// Locker acquires locks on behalf of one owner, e.g. a hostname and process id.
+type Locker struct {
+	repo     *base.BaseGorm[Lock, string]
+	owner    string
+	now      func() time.Time
+	leaseTTL time.Duration
+}
+
+// Option configures a Locker.
+type Option func(*Locker)
+
+// WithLeaseTTL sets the TTL of the leadership lock taken by RunWhenLeader, 15s by default.
+// A crashed leader is replaced after at most this duration.
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(l *Locker) {
+		l.leaseTTL = ttl
+	}
+}
+
+// NewLocker returns a Locker for owner storing locks in db, see Migrate.
+func NewLocker(db *gorm.DB, owner string, opts ...Option) *Locker {
+	l := &Locker{
+		repo:     base.NewBaseGorm[Lock, string](db),
+		owner:    owner,
+		now:      func() time.Time { return time.Now().UTC() },
+		leaseTTL: 15 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Owner returns the owner the locks are acquired for.
+func (l *Locker) Owner() string {
+	return l.owner
+}
+
+// Migrate creates the locks table.
+func (l *Locker) Migrate(ctx context.Context) error {
+	return l.repo.DB(ctx).AutoMigrate(&Lock{})
+}
+
+// Acquire takes the lock name for ttl when it is free, expired or already held by
+// this owner (which extends it), and reports whether the lock is now held.
+func (l *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx)
+		now      = l.now()
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.WithField("lock", name).Error(err)
+		}
+	}()
+
+	// atomic insert when nobody ever took the lock
+	result := l.repo.DB(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Lock{Name: name, Owner: l.owner, ExpiresAt: now.Add(ttl)})
+	if err = result.Error; err != nil {
+		return false, err
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// atomic take over of an expired lock, or extension of our own
+	result = l.repo.DB(ctx).
+		Model(&Lock{}).
+		Where("name = ? AND (expires_at < ? OR owner = ?)", name, now, l.owner).
+		Updates(map[string]interface{}{"owner": l.owner, "expires_at": now.Add(ttl)})
+	if err = result.Error; err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// Renew extends a lock held by this owner for another ttl, and reports false when
+// the lock expired or was taken over in the meantime.
+func (l *Locker) Renew(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx)
+		now      = l.now()
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.WithField("lock", name).Error(err)
+		}
+	}()
+
+	result := l.repo.DB(ctx).
+		Model(&Lock{}).
+		Where("name = ? AND owner = ? AND expires_at >= ?", name, l.owner, now).
+		Update("expires_at", now.Add(ttl))
+	if err = result.Error; err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// Release frees a lock held by this owner, it is a no-op for locks held by others.
+func (l *Locker) Release(ctx context.Context, name string) error {
+	_, err := l.repo.DeleteWhere(ctx, []base.Where{
+		{Name: "name", Value: name},
+		{Name: "owner", Value: l.owner},
+	})
+
+	return err
+}
+
+// Hold acquires the lock name and runs fn while renewing the lock every ttl/3. The
+// context passed to fn is cancelled with ErrLockLost as cause when a renewal fails,
+// and the returned error then wraps ErrLockLost. The lock is released when fn returns.
+// ErrNotAcquired is returned when the lock is held by another owner.
+func (l *Locker) Hold(ctx context.Context, name string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	acquired, err := l.Acquire(ctx, name, ttl)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return fmt.Errorf("%w: %s", ErrNotAcquired, name)
+	}
+
+	heldCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.heartbeat(heldCtx, name, ttl, cancel)
+	}()
+
+	err = fn(heldCtx)
+	if cause := context.Cause(heldCtx); errors.Is(cause, ErrLockLost) {
+		err = errors.Join(err, cause)
+	}
+	cancel(nil)
+	<-done
+
+	// release even when ctx is already done
+	if releaseErr := l.Release(context.WithoutCancel(ctx), name); releaseErr != nil {
+		return errors.Join(err, releaseErr)
+	}
+
+	return err
+}
+
+// heartbeat renews the lock name until ctx is done, cancelling it when the lock is lost.
+func (l *Locker) heartbeat(ctx context.Context, name string, ttl time.Duration, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := l.Renew(ctx, name, ttl)
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			if err != nil || !renewed {
+				cancel(fmt.Errorf("%w: %s", ErrLockLost, name))
+				return
+			}
+		}
+	}
+}
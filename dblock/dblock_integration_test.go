@@ -0,0 +1,52 @@
+package dblock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+func TestLocker(t *testing.T) {
+	db := testdb.MySQL(t)
+	ctx := context.Background()
+
+	first, second := NewLocker(db, "first"), NewLocker(db, "second")
+	if err := first.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate locks: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Where("name = ?", "test-lock").Delete(&Lock{})
+	})
+
+	acquired, err := first.Acquire(ctx, "test-lock", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("Expected first owner to acquire the lock, got %v (%v)", acquired, err)
+	}
+
+	acquired, err = second.Acquire(ctx, "test-lock", time.Minute)
+	if err != nil || acquired {
+		t.Fatalf("Expected second owner not to acquire a held lock, got %v (%v)", acquired, err)
+	}
+
+	err = second.Hold(ctx, "test-lock", time.Minute, func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrNotAcquired) {
+		t.Errorf("Expected ErrNotAcquired, got %v", err)
+	}
+
+	if err = first.Release(ctx, "test-lock"); err != nil {
+		t.Fatalf("Failed to release the lock: %v", err)
+	}
+
+	ran := false
+	err = second.Hold(ctx, "test-lock", time.Minute, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Errorf("Expected second owner to hold the released lock, got %v", err)
+	}
+}
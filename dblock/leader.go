@@ -0,0 +1,56 @@
+package dblock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+)
+
+// RunWhenLeader campaigns for the leadership name and runs fn only while this owner
+// is the leader, so jobs such as an outbox relay run once per cluster. Followers retry
+// every third of the lease TTL. When the leadership is lost, fn's context is cancelled
+// and the campaign starts again, as it does after a database error. RunWhenLeader
+// returns fn's result once fn returns on its own, or ctx.Err() after stepping down
+// when ctx is cancelled.
+func (l *Locker) RunWhenLeader(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	logEntry := generic_gorm.GetLoggerFromContext(ctx).WithFields(map[string]interface{}{
+		"leadership": name,
+		"owner":      l.owner,
+	})
+
+	retry := time.NewTicker(l.leaseTTL / 3)
+	defer retry.Stop()
+
+	for {
+		elected := false
+		err := l.Hold(ctx, name, l.leaseTTL, func(ctx context.Context) error {
+			elected = true
+			logEntry.Info("elected leader")
+			return fn(ctx)
+		})
+
+		switch {
+		case ctx.Err() != nil:
+			if elected {
+				logEntry.Info("stepped down")
+			}
+			return ctx.Err()
+		case errors.Is(err, ErrLockLost):
+			logEntry.Warn("leadership lost")
+		case errors.Is(err, ErrNotAcquired):
+		case !elected:
+			// the campaign itself failed, e.g. the database is unreachable
+			logEntry.Warnf("campaign failed: %v", err)
+		default:
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-retry.C:
+		}
+	}
+}
@@ -0,0 +1,395 @@
+// Package denorm maintains denormalized columns, copies of a column of a source table
+// in the rows of a target table referencing it, e.g. users.name in posts.author_name.
+// The writes of the source column through a Repo queue the keys of the changed rows in
+// a denorm_pending table, in the transaction of the write like an outbox. The
+// Maintainer then copies the current values to the target rows by batches, see
+// Start, and Rebuild copies every value again, e.g. once a rule is added.
+package denorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+)
+
+// Rule copies SourceTable.SourceColumn into TargetTable.TargetColumn, for the target
+// rows whose TargetForeignKey references the SourceKey of the source row.
+type Rule struct {
+	Name             string // unique name of the rule, e.g. "posts.author_name"
+	SourceTable      string // e.g. "users"
+	SourceKey        string // e.g. "id"
+	SourceColumn     string // e.g. "name"
+	TargetTable      string // e.g. "posts"
+	TargetKey        string // primary key of the target rows, batches are ranges of it, e.g. "id"
+	TargetForeignKey string // e.g. "author_id"
+	TargetColumn     string // e.g. "author_name"
+}
+
+// Pending is a source row whose column changed, queued in the denorm_pending table
+// until copied to its target rows.
+type Pending struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Rule      string    `json:"rule" gorm:"column:rule;size:191"`
+	SourceKey string    `json:"source_key" gorm:"column:source_key;size:191"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (Pending) TableName() string {
+	return "denorm_pending"
+}
+
+func (Pending) PrimaryKey() string {
+	return "id"
+}
+
+// Maintainer copies the values of the source columns of its rules to their targets.
+type Maintainer struct {
+	pending      *base.BaseGorm[Pending, uint]
+	rules        map[string]Rule
+	batchSize    int
+	pollInterval time.Duration
+}
+
+// Option configures a Maintainer.
+type Option func(*Maintainer)
+
+// WithBatchSize sets how many target rows an UPDATE writes, and how many pending rows
+// RunPending handles, 500 by default.
+func WithBatchSize(size int) Option {
+	return func(m *Maintainer) {
+		m.batchSize = size
+	}
+}
+
+// WithPollInterval sets how often Start looks for pending rows, 5s by default.
+func WithPollInterval(interval time.Duration) Option {
+	return func(m *Maintainer) {
+		m.pollInterval = interval
+	}
+}
+
+// NewMaintainer returns the Maintainer of rules, queuing the pending rows in db, see
+// Migrate.
+func NewMaintainer(db *gorm.DB, rules []Rule, opts ...Option) *Maintainer {
+	m := &Maintainer{
+		pending:      base.NewBaseGorm[Pending, uint](db),
+		rules:        make(map[string]Rule, len(rules)),
+		batchSize:    500,
+		pollInterval: 5 * time.Second,
+	}
+	for _, rule := range rules {
+		m.rules[rule.Name] = rule
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Migrate creates the denorm_pending table.
+func (m *Maintainer) Migrate(ctx context.Context) error {
+	return m.pending.DB(ctx).AutoMigrate(&Pending{})
+}
+
+// Rebuild copies the source column of the rule name to all its target rows, and
+// returns the number of target rows changed.
+func (m *Maintainer) Rebuild(ctx context.Context, name string) (int64, error) {
+	return m.copyValues(ctx, name, "")
+}
+
+// Propagate copies the source column of the row sourceKey to its target rows, and
+// returns the number of target rows changed.
+func (m *Maintainer) Propagate(ctx context.Context, name string, sourceKey string) (int64, error) {
+	return m.copyValues(ctx, name, sourceKey)
+}
+
+// copyValues copies the source column of the rule name to the target rows
+// referencing sourceKey, all of them without sourceKey, by batches of target keys.
+func (m *Maintainer) copyValues(ctx context.Context, name string, sourceKey string) (int64, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx).WithField("rule", name)
+		db       = m.pending.DB(ctx)
+		copied   int64
+		last     string
+	)
+
+	rule, ok := m.rules[name]
+	if !ok {
+		err := fmt.Errorf("unknown denormalization rule %s", name)
+		logEntry.Error(err)
+		return 0, err
+	}
+
+	update := updateStatement(db.Dialector.Name(), rule)
+	for {
+		var keys []string
+		query := db.Table(rule.TargetTable).Order(rule.TargetKey).Limit(m.batchSize)
+		if sourceKey != "" {
+			query = query.Where(rule.TargetForeignKey+" = ?", sourceKey)
+		}
+		if last != "" {
+			query = query.Where(rule.TargetKey+" > ?", last)
+		}
+		if err := query.Pluck(rule.TargetKey, &keys).Error; err != nil {
+			logEntry.Error(err)
+			return copied, err
+		}
+		if len(keys) == 0 {
+			return copied, nil
+		}
+
+		result := db.Exec(update, keys)
+		if result.Error != nil {
+			logEntry.Error(result.Error)
+			return copied, result.Error
+		}
+		copied += result.RowsAffected
+
+		if len(keys) < m.batchSize {
+			return copied, nil
+		}
+		last = keys[len(keys)-1]
+	}
+}
+
+// updateStatement returns the UPDATE copying the source column of rule to the target
+// rows of the keys given as argument.
+func updateStatement(dialect string, rule Rule) string {
+	if dialect == base.DialectPostgres {
+		return fmt.Sprintf("UPDATE %s SET %s = %s.%s FROM %s WHERE %s.%s = %s.%s AND %s.%s IN ?",
+			rule.TargetTable, rule.TargetColumn, rule.SourceTable, rule.SourceColumn, rule.SourceTable,
+			rule.SourceTable, rule.SourceKey, rule.TargetTable, rule.TargetForeignKey,
+			rule.TargetTable, rule.TargetKey)
+	}
+
+	return fmt.Sprintf("UPDATE %s JOIN %s ON %s.%s = %s.%s SET %s.%s = %s.%s WHERE %s.%s IN ?",
+		rule.TargetTable, rule.SourceTable, rule.SourceTable, rule.SourceKey, rule.TargetTable, rule.TargetForeignKey,
+		rule.TargetTable, rule.TargetColumn, rule.SourceTable, rule.SourceColumn,
+		rule.TargetTable, rule.TargetKey)
+}
+
+// RunPending copies the values of up to the batch size of pending rows, the oldest
+// first, and removes them from the queue. The rows failing stay queued for the next
+// run. It returns the number of pending rows handled.
+func (m *Maintainer) RunPending(ctx context.Context) (int, error) {
+	pending, _, err := m.pending.List(ctx, 1, m.batchSize, []base.OrderBy{{Field: "id", Direction: "asc"}}, nil, base.WithoutTotal())
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	var (
+		copied  = map[[2]string]bool{} // rule and source key
+		handled []uint
+		failed  error
+	)
+	for _, p := range pending {
+		key := [2]string{p.Rule, p.SourceKey}
+		if !copied[key] {
+			// the errors are logged by Propagate
+			if _, err := m.Propagate(ctx, p.Rule, p.SourceKey); err != nil {
+				failed = err
+				continue
+			}
+			copied[key] = true
+		}
+		handled = append(handled, p.ID)
+	}
+
+	if len(handled) > 0 {
+		if _, err := m.pending.DeleteWhere(ctx, []base.Where{{Name: "id", Operator: base.OpIn, Value: handled}}); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(handled), failed
+}
+
+// Start runs the pending rows until ctx is cancelled, and returns ctx's error. Run it
+// on one instance, e.g. with dblock.Locker.RunWhenLeader : the copies are idempotent
+// but would be made twice.
+func (m *Maintainer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// errors are logged, the next poll retries
+		for {
+			handled, err := m.RunPending(ctx)
+			if err != nil || handled < m.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rulesOf returns the rules whose source is table, and writes one of columns, all
+// columns being written when columns is empty or "*".
+func (m *Maintainer) rulesOf(table string, columns []string) []Rule {
+	var rules []Rule
+	for _, rule := range m.rules {
+		if rule.SourceTable != table {
+			continue
+		}
+		if len(columns) == 0 || columns[0] == "*" {
+			rules = append(rules, rule)
+			continue
+		}
+		for _, column := range columns {
+			if column == rule.SourceColumn {
+				rules = append(rules, rule)
+				break
+			}
+		}
+	}
+
+	return rules
+}
+
+// enqueue queues the sourceKeys of rules on db, the transaction of the write.
+func enqueue(db *gorm.DB, rules []Rule, sourceKeys map[string][]string) error {
+	var pending []Pending
+	for _, rule := range rules {
+		for _, key := range sourceKeys[rule.SourceKey] {
+			pending = append(pending, Pending{Rule: rule.Name, SourceKey: key})
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	return db.Create(&pending).Error
+}
+
+// Repo is a repository queuing the changes of the source columns it writes, see
+// Maintainer. Update, UpdateWhere and SaveChanges then run in a transaction, or in a
+// savepoint of the one of the context. The other methods, Patch and
+// UpdateWithFieldMask included, are the ones of the wrapped repository.
+type Repo[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] struct {
+	*base.BaseGorm[T, PkType]
+
+	maintainer *Maintainer
+}
+
+// NewRepo returns a Repo queuing in m the changes of the source columns of repo.
+func NewRepo[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](repo *base.BaseGorm[T, PkType], m *Maintainer) *Repo[T, PkType] {
+	return &Repo[T, PkType]{BaseGorm: repo, maintainer: m}
+}
+
+// Update updates row like base.BaseGorm.Update, and queues it when a source column
+// is written.
+func (r *Repo[T, PkType]) Update(ctx context.Context, row *T, updatedColumns []string) (int64, error) {
+	var e T
+	rules := r.maintainer.rulesOf(e.TableName(), updatedColumns)
+	if len(rules) == 0 {
+		return r.BaseGorm.Update(ctx, row, updatedColumns)
+	}
+
+	var rowsAffected int64
+	err := r.Transaction(ctx, func(repo *base.BaseGorm[T, PkType]) error {
+		var err error
+		if rowsAffected, err = repo.Update(ctx, row, updatedColumns); err != nil || rowsAffected == 0 {
+			return err
+		}
+
+		return r.enqueueRow(ctx, repo, rules, row)
+	})
+
+	return rowsAffected, err
+}
+
+// SaveChanges saves the changes of row like base.BaseGorm.SaveChanges, and queues it
+// when it changed.
+func (r *Repo[T, PkType]) SaveChanges(ctx context.Context, row *T) (int64, error) {
+	var e T
+	rules := r.maintainer.rulesOf(e.TableName(), nil)
+	if len(rules) == 0 {
+		return r.BaseGorm.SaveChanges(ctx, row)
+	}
+
+	var rowsAffected int64
+	err := r.Transaction(ctx, func(repo *base.BaseGorm[T, PkType]) error {
+		var err error
+		if rowsAffected, err = repo.SaveChanges(ctx, row); err != nil || rowsAffected == 0 {
+			return err
+		}
+
+		return r.enqueueRow(ctx, repo, rules, row)
+	})
+
+	return rowsAffected, err
+}
+
+// UpdateWhere updates the rows matching wheres like base.BaseGorm.UpdateWhere, and
+// queues them when a source column is written.
+func (r *Repo[T, PkType]) UpdateWhere(ctx context.Context, wheres []base.Where, values map[string]interface{}) (int64, error) {
+	var (
+		e       T
+		columns = make([]string, 0, len(values))
+	)
+	for column := range values {
+		columns = append(columns, column)
+	}
+
+	rules := r.maintainer.rulesOf(e.TableName(), columns)
+	if len(rules) == 0 {
+		return r.BaseGorm.UpdateWhere(ctx, wheres, values)
+	}
+
+	var rowsAffected int64
+	err := r.Transaction(ctx, func(repo *base.BaseGorm[T, PkType]) error {
+		// the keys are read first, the update may change the columns of wheres
+		keys := map[string][]string{}
+		for _, rule := range rules {
+			if _, ok := keys[rule.SourceKey]; ok {
+				continue
+			}
+			var values []string
+			if err := repo.Pluck(ctx, rule.SourceKey, wheres, &values); err != nil {
+				return err
+			}
+			keys[rule.SourceKey] = values
+		}
+
+		var err error
+		if rowsAffected, err = repo.UpdateWhere(ctx, wheres, values); err != nil || rowsAffected == 0 {
+			return err
+		}
+
+		return enqueue(repo.DB(ctx), rules, keys)
+	})
+
+	return rowsAffected, err
+}
+
+func (r *Repo[T, PkType]) enqueueRow(ctx context.Context, repo *base.BaseGorm[T, PkType], rules []Rule, row *T) error {
+	db := repo.DB(ctx)
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(row); err != nil {
+		return err
+	}
+
+	keys := map[string][]string{}
+	for _, rule := range rules {
+		field := stmt.Schema.LookUpField(rule.SourceKey)
+		if field == nil {
+			return fmt.Errorf("source key %s of rule %s not found in %s", rule.SourceKey, rule.Name, stmt.Schema.Table)
+		}
+		value, _ := field.ValueOf(ctx, reflect.ValueOf(row).Elem())
+		keys[rule.SourceKey] = []string{fmt.Sprint(value)}
+	}
+
+	return enqueue(db, rules, keys)
+}
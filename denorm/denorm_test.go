@@ -0,0 +1,159 @@
+package denorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Author struct {
+	ID   uint   `gorm:"column:id;primaryKey"`
+	Name string `gorm:"column:name;size:191"`
+}
+
+func (Author) TableName() string {
+	return "denorm_authors"
+}
+
+func (Author) PrimaryKey() string {
+	return "id"
+}
+
+type Article struct {
+	ID         uint   `gorm:"column:id;primaryKey"`
+	AuthorID   uint   `gorm:"column:author_id;index"`
+	AuthorName string `gorm:"column:author_name;size:191"`
+}
+
+func (Article) TableName() string {
+	return "denorm_articles"
+}
+
+func (Article) PrimaryKey() string {
+	return "id"
+}
+
+var authorName = Rule{
+	Name:             "articles.author_name",
+	SourceTable:      "denorm_authors",
+	SourceKey:        "id",
+	SourceColumn:     "name",
+	TargetTable:      "denorm_articles",
+	TargetKey:        "id",
+	TargetForeignKey: "author_id",
+	TargetColumn:     "author_name",
+}
+
+func TestUpdateStatement(t *testing.T) {
+	if got, want := updateStatement(base.DialectMySQL, authorName),
+		"UPDATE denorm_articles JOIN denorm_authors ON denorm_authors.id = denorm_articles.author_id SET denorm_articles.author_name = denorm_authors.name WHERE denorm_articles.id IN ?"; got != want {
+		t.Errorf("Expected MySQL statement\n%s\ngot\n%s", want, got)
+	}
+	if got, want := updateStatement(base.DialectPostgres, authorName),
+		"UPDATE denorm_articles SET author_name = denorm_authors.name FROM denorm_authors WHERE denorm_authors.id = denorm_articles.author_id AND denorm_articles.id IN ?"; got != want {
+		t.Errorf("Expected Postgres statement\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRulesOf(t *testing.T) {
+	m := NewMaintainer(testdb.DryRun(t), []Rule{authorName})
+
+	for _, tt := range []struct {
+		table   string
+		columns []string
+		want    int
+	}{
+		{"denorm_authors", []string{"name"}, 1},
+		{"denorm_authors", nil, 1},
+		{"denorm_authors", []string{"*"}, 1},
+		{"denorm_authors", []string{"email"}, 0},
+		{"denorm_articles", []string{"name"}, 0},
+	} {
+		if got := m.rulesOf(tt.table, tt.columns); len(got) != tt.want {
+			t.Errorf("rulesOf(%s, %v): expected %d rules, got %d", tt.table, tt.columns, tt.want, len(got))
+		}
+	}
+}
+
+func TestPropagateBatchesTargetKeys(t *testing.T) {
+	var (
+		db  = testdb.DryRun(t)
+		sql string
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	if _, err := NewMaintainer(db, []Rule{authorName}, WithBatchSize(100)).Propagate(context.Background(), authorName.Name, "7"); err != nil {
+		t.Fatalf("Failed to propagate: %v", err)
+	}
+
+	// the dry run finds no target row, so no UPDATE follows
+	want := "SELECT `id` FROM `denorm_articles` WHERE author_id = ? ORDER BY id LIMIT ?"
+	if sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, sql)
+	}
+
+	if _, err := NewMaintainer(db, nil).Rebuild(context.Background(), "unknown"); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+}
+
+func TestMaintainer(t *testing.T) {
+	var (
+		db      = testdb.MySQL(t)
+		ctx     = context.Background()
+		m       = NewMaintainer(db, []Rule{authorName}, WithBatchSize(2))
+		authors = NewRepo(base.NewBaseGorm[Author, uint](db), m)
+	)
+	if err := db.AutoMigrate(&Author{}, &Article{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := m.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DROP TABLE IF EXISTS denorm_authors, denorm_articles")
+		db.Exec("DELETE FROM denorm_pending WHERE rule = ?", authorName.Name)
+	})
+
+	author := &Author{Name: "Ada"}
+	if _, err := authors.Create(ctx, author); err != nil {
+		t.Fatalf("Failed to create author: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := db.Create(&Article{AuthorID: author.ID}).Error; err != nil {
+			t.Fatalf("Failed to create article: %v", err)
+		}
+	}
+
+	if copied, err := m.Rebuild(ctx, authorName.Name); err != nil || copied != 3 {
+		t.Fatalf("Expected 3 articles rebuilt over 2 batches, got %d, %v", copied, err)
+	}
+
+	author.Name = "Ada Lovelace"
+	if _, err := authors.Update(ctx, author, []string{"name"}); err != nil {
+		t.Fatalf("Failed to update author: %v", err)
+	}
+	if _, err := authors.UpdateWhere(ctx, []base.Where{{Name: "id", Value: author.ID}}, map[string]interface{}{"name": "Countess Lovelace"}); err != nil {
+		t.Fatalf("Failed to update author: %v", err)
+	}
+
+	if handled, err := m.RunPending(ctx); err != nil || handled != 2 {
+		t.Fatalf("Expected the 2 queued changes handled, got %d, %v", handled, err)
+	}
+
+	var names []string
+	db.Model(&Article{}).Distinct().Pluck("author_name", &names)
+	if len(names) != 1 || names[0] != "Countess Lovelace" {
+		t.Errorf("Expected every article to carry the last name, got %v", names)
+	}
+	if handled, err := m.RunPending(ctx); err != nil || handled != 0 {
+		t.Errorf("Expected an empty queue, got %d, %v", handled, err)
+	}
+}
@@ -0,0 +1,248 @@
+// Package featureflag stores feature flags with percentage rollouts and per tenant
+// overrides in the feature_flags and feature_flag_overrides tables. Both tables are
+// small, so they are read whole and cached for a short time.
+package featureflag
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+)
+
+// Flag is one flag of the feature_flags table.
+type Flag struct {
+	Name        string    `json:"name" gorm:"column:name;primaryKey;size:191"`
+	Enabled     bool      `json:"enabled" gorm:"column:enabled"` // false turns the flag off for every tenant without an override
+	Rollout     int       `json:"rollout" gorm:"column:rollout"` // percentage of the tenants the enabled flag is on for, 0 to 100
+	Description string    `json:"description" gorm:"column:description;type:text"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Flag) TableName() string {
+	return "feature_flags"
+}
+
+func (Flag) PrimaryKey() string {
+	return "name"
+}
+
+// Override forces a flag on or off for one tenant, whatever its rollout.
+type Override struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	FlagName  string    `json:"flag_name" gorm:"column:flag_name;size:191;uniqueIndex:idx_feature_flag_overrides_flag_tenant"`
+	TenantID  string    `json:"tenant_id" gorm:"column:tenant_id;size:191;uniqueIndex:idx_feature_flag_overrides_flag_tenant"`
+	Enabled   bool      `json:"enabled" gorm:"column:enabled"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Override) TableName() string {
+	return "feature_flag_overrides"
+}
+
+func (Override) PrimaryKey() string {
+	return "id"
+}
+
+const tenantCtxName = "x-feature-flag-tenant-ctx"
+
+// ContextWithTenant sets the tenant IsEnabled evaluates flags for.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantCtxName, tenantID)
+}
+
+// TenantFromContext returns the tenant set by ContextWithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantCtxName).(string)
+	return tenantID, ok
+}
+
+// snapshot is the content of both tables at loadedAt.
+type snapshot struct {
+	flags     map[string]Flag
+	overrides map[string]map[string]bool // flag name => tenant id => enabled
+	loadedAt  time.Time
+}
+
+// Store evaluates and manages feature flags.
+type Store struct {
+	flags     *base.BaseGorm[Flag, string]
+	overrides *base.BaseGorm[Override, uint]
+	now       func() time.Time
+	cacheTTL  time.Duration
+
+	mu     sync.Mutex
+	cached *snapshot
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithCacheTTL sets how long flags are served from the cache, 10s by default.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.cacheTTL = ttl
+	}
+}
+
+// NewStore returns a Store of the feature flag tables of db, see Migrate.
+func NewStore(db *gorm.DB, opts ...Option) *Store {
+	s := &Store{
+		flags:     base.NewBaseGorm[Flag, string](db),
+		overrides: base.NewBaseGorm[Override, uint](db),
+		now:       time.Now,
+		cacheTTL:  10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Migrate creates the feature_flags and feature_flag_overrides tables.
+func (s *Store) Migrate(ctx context.Context) error {
+	return s.flags.DB(ctx).AutoMigrate(&Flag{}, &Override{})
+}
+
+// IsEnabled reports whether flag is on for the tenant of ctx. An override of the
+// tenant wins, then a disabled or unknown flag is off, and an enabled one is on for
+// its rollout percentage of the tenants, always the same ones for a given flag.
+// Without tenant in ctx, only a full rollout is on. When the flags cannot be
+// loaded, the last loaded ones are used, and every flag is off before the first load.
+func (s *Store) IsEnabled(ctx context.Context, flag string) bool {
+	snap := s.snapshot(ctx)
+	tenantID, hasTenant := TenantFromContext(ctx)
+
+	if hasTenant {
+		if enabled, ok := snap.overrides[flag][tenantID]; ok {
+			return enabled
+		}
+	}
+
+	stored, ok := snap.flags[flag]
+	if !ok || !stored.Enabled {
+		return false
+	}
+	if stored.Rollout >= 100 {
+		return true
+	}
+	if !hasTenant {
+		return false
+	}
+
+	return bucket(flag, tenantID) < stored.Rollout
+}
+
+// SetFlag creates or updates flag.
+func (s *Store) SetFlag(ctx context.Context, flag Flag) error {
+	flag.UpdatedAt = s.now()
+	if _, err := s.flags.Upsert(ctx, &flag, []string{"enabled", "rollout", "description", "updated_at"}); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	return nil
+}
+
+// SetOverride forces flag on or off for tenantID.
+func (s *Store) SetOverride(ctx context.Context, flag string, tenantID string, enabled bool) error {
+	override := &Override{FlagName: flag, TenantID: tenantID, Enabled: enabled, UpdatedAt: s.now()}
+	if _, err := s.overrides.Upsert(ctx, override, []string{"enabled", "updated_at"}); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	return nil
+}
+
+// RemoveOverride makes flag follow its rollout again for tenantID.
+func (s *Store) RemoveOverride(ctx context.Context, flag string, tenantID string) error {
+	if _, err := s.overrides.DeleteWhere(ctx, []base.Where{
+		{Name: "flag_name", Value: flag},
+		{Name: "tenant_id", Value: tenantID},
+	}); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	return nil
+}
+
+// invalidate makes the next IsEnabled reload the flags, so the changes made by
+// this process apply at once. Other processes see them when their cache expires.
+func (s *Store) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil {
+		s.cached.loadedAt = time.Time{}
+	}
+}
+
+// snapshot returns the cached flags, reloading them once expired.
+func (s *Store) snapshot(ctx context.Context) *snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && s.now().Sub(s.cached.loadedAt) < s.cacheTTL {
+		return s.cached
+	}
+
+	loaded, err := s.load(ctx)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Warnf("cannot reload feature flags, using the last loaded ones: %v", err)
+		if s.cached == nil {
+			return &snapshot{}
+		}
+		// retry at the next expiry rather than on every call
+		s.cached.loadedAt = s.now()
+		return s.cached
+	}
+	s.cached = loaded
+
+	return loaded
+}
+
+func (s *Store) load(ctx context.Context) (*snapshot, error) {
+	flags, err := s.flags.WheresList(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := s.overrides.WheresList(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &snapshot{
+		flags:     make(map[string]Flag, len(flags)),
+		overrides: map[string]map[string]bool{},
+		loadedAt:  s.now(),
+	}
+	for _, flag := range flags {
+		snap.flags[flag.Name] = flag
+	}
+	for _, override := range overrides {
+		if snap.overrides[override.FlagName] == nil {
+			snap.overrides[override.FlagName] = map[string]bool{}
+		}
+		snap.overrides[override.FlagName][override.TenantID] = override.Enabled
+	}
+
+	return snap, nil
+}
+
+// bucket places tenantID in one of 100 buckets, independently for each flag so that
+// the same tenants are not always the first to get new features.
+func bucket(flag string, tenantID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(flag))
+	h.Write([]byte{0})
+	h.Write([]byte(tenantID))
+
+	return int(h.Sum32() % 100)
+}
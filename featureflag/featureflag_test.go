@@ -0,0 +1,92 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+func TestIsEnabled(t *testing.T) {
+	store := NewStore(testdb.DryRun(t))
+	store.cached = &snapshot{
+		flags: map[string]Flag{
+			"new-checkout": {Name: "new-checkout", Enabled: true, Rollout: 50},
+			"dark-mode":    {Name: "dark-mode", Enabled: true, Rollout: 100},
+			"killed":       {Name: "killed", Enabled: false, Rollout: 100},
+		},
+		overrides: map[string]map[string]bool{
+			"killed": {"acme": true},
+		},
+		loadedAt: time.Now(),
+	}
+
+	var (
+		ctx     = context.Background()
+		enabled int
+	)
+	for i := 0; i < 1000; i++ {
+		if store.IsEnabled(ContextWithTenant(ctx, fmt.Sprintf("tenant-%d", i)), "new-checkout") {
+			enabled++
+		}
+	}
+	if enabled < 400 || enabled > 600 {
+		t.Errorf("Expected about half of the tenants in a 50%% rollout, got %d of 1000", enabled)
+	}
+
+	acme := ContextWithTenant(ctx, "acme")
+	if store.IsEnabled(acme, "new-checkout") != store.IsEnabled(acme, "new-checkout") {
+		t.Error("Expected a tenant to stay in its rollout bucket")
+	}
+	if !store.IsEnabled(ctx, "dark-mode") {
+		t.Error("Expected a full rollout to be on without tenant")
+	}
+	if store.IsEnabled(ctx, "new-checkout") {
+		t.Error("Expected a partial rollout to be off without tenant")
+	}
+	if !store.IsEnabled(acme, "killed") || store.IsEnabled(ContextWithTenant(ctx, "globex"), "killed") {
+		t.Error("Expected the override to win over the disabled flag for its tenant only")
+	}
+	if store.IsEnabled(acme, "unknown") {
+		t.Error("Expected an unknown flag to be off")
+	}
+}
+
+func TestStore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		db    = testdb.MySQL(t)
+		store = NewStore(db)
+		acme  = ContextWithTenant(ctx, "acme")
+	)
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate feature flags: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("flag_name = ?", "beta").Delete(&Override{})
+		db.Where("name = ?", "beta").Delete(&Flag{})
+	})
+
+	if err := store.SetFlag(ctx, Flag{Name: "beta", Enabled: true, Rollout: 0}); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+	if store.IsEnabled(acme, "beta") {
+		t.Error("Expected a 0% rollout to be off")
+	}
+
+	if err := store.SetOverride(ctx, "beta", "acme", true); err != nil {
+		t.Fatalf("Failed to set override: %v", err)
+	}
+	if !store.IsEnabled(acme, "beta") {
+		t.Error("Expected the override to turn the flag on")
+	}
+
+	if err := store.RemoveOverride(ctx, "beta", "acme"); err != nil {
+		t.Fatalf("Failed to remove override: %v", err)
+	}
+	if store.IsEnabled(acme, "beta") {
+		t.Error("Expected the flag to follow its rollout again")
+	}
+}
@@ -3,15 +3,22 @@ module github.com/harryosmar/generic-gorm
 go 1.23
 
 require (
+	github.com/go-playground/validator/v10 v10.22.1
+	github.com/go-sql-driver/mysql v1.7.0
 	github.com/sirupsen/logrus v1.9.3
 	gorm.io/driver/mysql v1.5.2
 	gorm.io/gorm v1.25.12
 )
 
 require (
-	github.com/go-sql-driver/mysql v1.7.0 // indirect
+	github.com/gabriel-vasile/mimetype v1.4.3 // indirect
+	github.com/go-playground/locales v0.14.1 // indirect
+	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
-	golang.org/x/sys v0.5.0 // indirect
+	github.com/leodido/go-urn v1.4.0 // indirect
+	golang.org/x/crypto v0.19.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
 )
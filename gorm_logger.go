@@ -0,0 +1,131 @@
+package generic_gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// GormLogger is a gorm logger.Interface writing to the logger of the context, see
+// ContextWithLogger, so the SQL logs carry the fields of the request, e.g. its id or
+// tenant. Set it with gorm.Config{Logger: NewGormLogger()}.
+type GormLogger struct {
+	level                     logger.LogLevel
+	slowThreshold             time.Duration
+	ignoreRecordNotFoundError bool
+}
+
+// GormLoggerOption configures a GormLogger.
+type GormLoggerOption func(*GormLogger)
+
+// WithGormLogLevel sets the gorm level, logger.Warn by default. logger.Info logs
+// every statement, at the debug level of logrus.
+func WithGormLogLevel(level logger.LogLevel) GormLoggerOption {
+	return func(l *GormLogger) {
+		l.level = level
+	}
+}
+
+// WithSlowThreshold logs the statements running longer as warnings, 200ms by default,
+// 0 disabling it.
+func WithSlowThreshold(threshold time.Duration) GormLoggerOption {
+	return func(l *GormLogger) {
+		l.slowThreshold = threshold
+	}
+}
+
+// WithRecordNotFoundErrors logs gorm.ErrRecordNotFound as an error, ignored by default.
+func WithRecordNotFoundErrors() GormLoggerOption {
+	return func(l *GormLogger) {
+		l.ignoreRecordNotFoundError = false
+	}
+}
+
+// NewGormLogger returns a GormLogger.
+func NewGormLogger(opts ...GormLoggerOption) *GormLogger {
+	l := &GormLogger{
+		level:                     logger.Warn,
+		slowThreshold:             200 * time.Millisecond,
+		ignoreRecordNotFoundError: true,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// LogMode implements logger.Interface, returning a copy logging at level.
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+
+	return &copied
+}
+
+// Info implements logger.Interface.
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		GetLoggerFromContext(ctx).WithField("source", utils.FileWithLineNum()).Infof(msg, data...)
+	}
+}
+
+// Warn implements logger.Interface.
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		GetLoggerFromContext(ctx).WithField("source", utils.FileWithLineNum()).Warnf(msg, data...)
+	}
+}
+
+// Error implements logger.Interface.
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		GetLoggerFromContext(ctx).WithField("source", utils.FileWithLineNum()).Errorf(msg, data...)
+	}
+}
+
+// Trace implements logger.Interface, logging a statement with the fields sql,
+// duration, rows and source : as an error when it failed, a warning when slow, and
+// at the debug level otherwise, with logger.Info.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	var (
+		elapsed   = time.Since(begin)
+		failed    = err != nil && l.level >= logger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.ignoreRecordNotFoundError)
+		slow      = l.slowThreshold != 0 && elapsed > l.slowThreshold && l.level >= logger.Warn
+		logEntry  = GetLoggerFromContext(ctx)
+		traceable = l.level >= logger.Info && logEntry.Logger.IsLevelEnabled(log.DebugLevel)
+	)
+	if !failed && !slow && !traceable {
+		return
+	}
+
+	sql, rows := fc()
+	logEntry = logEntry.WithFields(log.Fields{
+		"sql":      sql,
+		"duration": elapsed,
+		"source":   utils.FileWithLineNum(),
+	})
+	// rows is -1 for the statements not reporting them
+	if rows != -1 {
+		logEntry = logEntry.WithField("rows", rows)
+	}
+
+	switch {
+	case failed:
+		logEntry.Error(err)
+	case slow:
+		logEntry.Warn(fmt.Sprintf("slow statement over %v", l.slowThreshold))
+	default:
+		logEntry.Debug("statement")
+	}
+}
@@ -0,0 +1,56 @@
+package generic_gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestGormLogger(t *testing.T) {
+	var (
+		base, hook = test.NewNullLogger()
+		ctx        = ContextWithLogger(context.Background(), log.NewEntry(base).WithField("request_id", "r1"))
+		fc         = func() (string, int64) { return "SELECT * FROM `users`", 3 }
+	)
+	base.SetLevel(log.DebugLevel)
+
+	l := NewGormLogger(WithSlowThreshold(time.Second))
+	l.Trace(ctx, time.Now(), fc, nil)
+	l.Trace(ctx, time.Now(), fc, gorm.ErrRecordNotFound)
+	if len(hook.AllEntries()) != 0 {
+		t.Fatalf("Expected the fast statements and the not found error not logged at the warn level, got %d entries", len(hook.AllEntries()))
+	}
+
+	l.Trace(ctx, time.Now(), fc, errors.New("deadlock found"))
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != log.ErrorLevel || entry.Data["request_id"] != "r1" || entry.Data["sql"] != "SELECT * FROM `users`" || entry.Data["rows"] != int64(3) {
+		t.Fatalf("Expected the failed statement logged with the fields of the context, got %+v", entry)
+	}
+
+	l.Trace(ctx, time.Now().Add(-2*time.Second), fc, nil)
+	if entry := hook.LastEntry(); entry.Level != log.WarnLevel {
+		t.Errorf("Expected the slow statement logged as a warning, got %v", entry.Level)
+	}
+
+	hook.Reset()
+	l.LogMode(logger.Info).Trace(ctx, time.Now(), func() (string, int64) { return "SET @a = 1", -1 }, nil)
+	entry = hook.LastEntry()
+	if entry == nil || entry.Level != log.DebugLevel {
+		t.Fatalf("Expected the statement logged at the debug level with logger.Info, got %+v", entry)
+	}
+	if _, ok := entry.Data["rows"]; ok {
+		t.Error("Expected no rows field for a statement not reporting them")
+	}
+
+	hook.Reset()
+	l.LogMode(logger.Silent).Trace(ctx, time.Now(), fc, errors.New("deadlock found"))
+	if len(hook.AllEntries()) != 0 {
+		t.Error("Expected nothing logged with logger.Silent")
+	}
+}
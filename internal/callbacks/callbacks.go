@@ -0,0 +1,54 @@
+// Package callbacks registers callbacks around the statements of every gorm
+// operation, for the plugins and the repositories timing or tracing them.
+package callbacks
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// mu serializes the registrations, gorm's check and register being two steps.
+var mu sync.Mutex
+
+// registerer is a callback positioned before or after a gorm one.
+type registerer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// Register registers the callbacks returned by before and after, given the
+// operation, e.g. query, around the statements of create, query, update, delete,
+// row and raw of db, named <name>:before_<operation> and <name>:after_<operation>.
+// It is a no-op when they are already registered, e.g. by another repository of
+// the database. It is safe to call concurrently, e.g. from repositories built in
+// several goroutines.
+func Register(db *gorm.DB, name string, before, after func(operation string) func(*gorm.DB)) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	callbacks := db.Callback()
+	if callbacks.Query().Get(name+":after_query") != nil {
+		return nil
+	}
+
+	for _, operation := range []struct {
+		name          string
+		before, after registerer
+	}{
+		{"create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{"query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{"update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{"delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{"row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{"raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	} {
+		if err := operation.before.Register(name+":before_"+operation.name, before(operation.name)); err != nil {
+			return err
+		}
+		if err := operation.after.Register(name+":after_"+operation.name, after(operation.name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
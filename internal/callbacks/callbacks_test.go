@@ -0,0 +1,43 @@
+package callbacks
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestRegisterConcurrently(t *testing.T) {
+	var (
+		db     = testdb.DryRun(t)
+		before atomic.Int64
+		wg     sync.WaitGroup
+	)
+	count := func(string) func(*gorm.DB) {
+		return func(*gorm.DB) { before.Add(1) }
+	}
+	noop := func(string) func(*gorm.DB) {
+		return func(*gorm.DB) {}
+	}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := Register(db, "test", count, noop); err != nil {
+				t.Errorf("Failed to register the callbacks: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var rows []map[string]interface{}
+	if err := db.Table("orders").Find(&rows).Error; err != nil {
+		t.Fatalf("Failed to find: %v", err)
+	}
+	if before.Load() != 1 {
+		t.Errorf("Expected the callbacks to be registered once, ran %d times", before.Load())
+	}
+}
@@ -0,0 +1,68 @@
+// Package testdb opens the databases used by the tests of the subpackages.
+package testdb
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// MySQL connects to the MySQL database configured by the MYSQL_* environment
+// variables, and skips the test when MYSQL_PASSWORD is not set.
+func MySQL(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	password := os.Getenv("MYSQL_PASSWORD")
+	if password == "" {
+		t.Skip("MYSQL_PASSWORD environment variable not set")
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		getenv("MYSQL_USERNAME", "root"),
+		password,
+		getenv("MYSQL_HOST", "localhost"),
+		getenv("MYSQL_PORT", "3306"),
+		getenv("MYSQL_DATABASE", "demo"),
+	)
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	return db
+}
+
+// DryRun returns a MySQL *gorm.DB that never opens a connection, for tests that
+// only need schema metadata or generated SQL.
+func DryRun(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/dry_run",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	return db
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
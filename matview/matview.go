@@ -0,0 +1,136 @@
+// Package matview keeps the result of an expensive query, e.g. an aggregate listing,
+// in a materialized view read through a read-only repository, and refreshes it on a
+// schedule through the scheduler package. On Postgres it is a MATERIALIZED VIEW
+// refreshed CONCURRENTLY, on MySQL a table rebuilt aside and swapped atomically.
+package matview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/scheduler"
+	"gorm.io/gorm"
+)
+
+// JobPrefix prefixes the scheduler job names of the views.
+const JobPrefix = "matview:"
+
+// Reader is the read-only repository of a view.
+type Reader[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] interface {
+	Detail(ctx context.Context, id PkType, opts ...base.QueryOption) (*T, error)
+	DetailMultiple(ctx context.Context, ids []PkType, opts ...base.QueryOption) ([]*T, error)
+	Wheres(ctx context.Context, wheres []base.Where, opts ...base.QueryOption) (*T, error)
+	WheresList(ctx context.Context, orders []base.OrderBy, wheres []base.Where, opts ...base.QueryOption) ([]T, error)
+	List(ctx context.Context, page int, pageSize int, orders []base.OrderBy, wheres []base.Where, opts ...base.QueryOption) ([]T, *base.Paginator, error)
+	Each(ctx context.Context, wheres []base.Where, orders []base.OrderBy, batchSize int, fn func(row *T) error, opts ...base.QueryOption) error
+	Iterate(ctx context.Context, wheres []base.Where, orders []base.OrderBy, batchSize int, opts ...base.QueryOption) iter.Seq2[*T, error]
+	Exists(ctx context.Context, wheres []base.Where, opts ...base.QueryOption) (bool, error)
+	Count(ctx context.Context, wheres []base.Where, opts ...base.QueryOption) (int64, error)
+	Pluck(ctx context.Context, column string, wheres []base.Where, dest interface{}, opts ...base.QueryOption) error
+}
+
+// View is the materialized view of T, its rows, named by the TableName of T and
+// keyed by its PrimaryKey, holding the result of a query.
+type View[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] struct {
+	db    *gorm.DB
+	query string
+	repo  *base.BaseGorm[T, PkType]
+}
+
+// New returns the View of T holding the result of query, a SELECT of the columns of
+// T, see Create.
+func New[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](db *gorm.DB, query string, opts ...base.RepoOption) *View[T, PkType] {
+	return &View[T, PkType]{db: db, query: query, repo: base.NewBaseGorm[T, PkType](db, opts...)}
+}
+
+// Repo returns the read-only repository of the view.
+func (v *View[T, PkType]) Repo() Reader[T, PkType] {
+	return v.repo
+}
+
+// Create creates the view, filled with the result of the query, unless it exists.
+func (v *View[T, PkType]) Create(ctx context.Context) error {
+	var e T
+	return v.exec(ctx, createStatements(v.db.Dialector.Name(), e.TableName(), e.PrimaryKey(), v.query))
+}
+
+// Refresh replaces the rows of the view with the result of the query. The reads
+// running meanwhile see the previous rows.
+func (v *View[T, PkType]) Refresh(ctx context.Context) error {
+	var e T
+	return v.exec(ctx, refreshStatements(v.db.Dialector.Name(), e.TableName(), v.query))
+}
+
+func (v *View[T, PkType]) exec(ctx context.Context, statements []string) error {
+	db := v.repo.DB(ctx)
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			generic_gorm.GetLoggerFromContext(ctx).WithField("sql", statement).Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createStatements returns the statements creating the view table. Postgres needs a
+// unique index to refresh a view CONCURRENTLY.
+func createStatements(dialect string, table string, primaryKey string, query string) []string {
+	if dialect == base.DialectPostgres {
+		return []string{
+			fmt.Sprintf("CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS %s", table, query),
+			fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s_%s_key ON %s (%s)", table, primaryKey, table, primaryKey),
+		}
+	}
+
+	return []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (PRIMARY KEY (%s)) AS %s", table, primaryKey, query),
+	}
+}
+
+// refreshStatements returns the statements refreshing the view table. On MySQL the
+// new rows are written to a table swapped with the view by a RENAME, atomic.
+func refreshStatements(dialect string, table string, query string) []string {
+	if dialect == base.DialectPostgres {
+		return []string{fmt.Sprintf("REFRESH MATERIALIZED VIEW CONCURRENTLY %s", table)}
+	}
+
+	var (
+		fresh = table + "_refresh"
+		stale = table + "_stale"
+	)
+	return []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s, %s", fresh, stale),
+		fmt.Sprintf("CREATE TABLE %s LIKE %s", fresh, table),
+		fmt.Sprintf("INSERT INTO %s %s", fresh, query),
+		fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s", table, stale, fresh, table),
+		fmt.Sprintf("DROP TABLE %s", stale),
+	}
+}
+
+// Schedule refreshes the view on the spec schedule (see scheduler.ParseSchedule)
+// through s, recording the duration_seconds metric of each run. The refreshes only
+// run while s is started.
+func (v *View[T, PkType]) Schedule(ctx context.Context, s *scheduler.Scheduler, spec string) error {
+	var (
+		e    T
+		name = JobPrefix + e.TableName()
+	)
+	s.Register(name, v.handler())
+	return s.Schedule(ctx, name, spec, nil)
+}
+
+func (v *View[T, PkType]) handler() scheduler.Handler {
+	return func(ctx context.Context, _ json.RawMessage) error {
+		started := time.Now()
+		err := v.Refresh(ctx)
+		scheduler.SetMetric(ctx, "duration_seconds", time.Since(started).Seconds())
+
+		return err
+	}
+}
@@ -0,0 +1,124 @@
+package matview
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Sale struct {
+	ID         uint    `gorm:"column:id;primaryKey"`
+	CustomerID uint    `gorm:"column:customer_id"`
+	Amount     float64 `gorm:"column:amount"`
+}
+
+func (Sale) TableName() string {
+	return "matview_sales"
+}
+
+func (Sale) PrimaryKey() string {
+	return "id"
+}
+
+type CustomerRevenue struct {
+	CustomerID uint    `gorm:"column:customer_id;primaryKey"`
+	Revenue    float64 `gorm:"column:revenue"`
+	Sales      int64   `gorm:"column:sales"`
+}
+
+func (CustomerRevenue) TableName() string {
+	return "matview_customer_revenues"
+}
+
+func (CustomerRevenue) PrimaryKey() string {
+	return "customer_id"
+}
+
+const revenueQuery = "SELECT customer_id, SUM(amount) AS revenue, COUNT(*) AS sales FROM matview_sales GROUP BY customer_id"
+
+func TestStatements(t *testing.T) {
+	if got := createStatements(base.DialectPostgres, "revenues", "customer_id", "SELECT 1"); !reflect.DeepEqual(got, []string{
+		"CREATE MATERIALIZED VIEW IF NOT EXISTS revenues AS SELECT 1",
+		"CREATE UNIQUE INDEX IF NOT EXISTS revenues_customer_id_key ON revenues (customer_id)",
+	}) {
+		t.Errorf("Unexpected Postgres create statements %q", got)
+	}
+	if got := refreshStatements(base.DialectPostgres, "revenues", "SELECT 1"); !reflect.DeepEqual(got, []string{
+		"REFRESH MATERIALIZED VIEW CONCURRENTLY revenues",
+	}) {
+		t.Errorf("Unexpected Postgres refresh statements %q", got)
+	}
+}
+
+func TestRefreshSwapsTableOnMySQL(t *testing.T) {
+	var (
+		db         = testdb.DryRun(t)
+		statements []string
+	)
+	if err := db.Callback().Raw().After("gorm:raw").Register("test:capture_sql", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	view := New[CustomerRevenue, uint](db, revenueQuery)
+	if err := view.handler()(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+
+	want := []string{
+		"DROP TABLE IF EXISTS matview_customer_revenues_refresh, matview_customer_revenues_stale",
+		"CREATE TABLE matview_customer_revenues_refresh LIKE matview_customer_revenues",
+		"INSERT INTO matview_customer_revenues_refresh " + revenueQuery,
+		"RENAME TABLE matview_customer_revenues TO matview_customer_revenues_stale, matview_customer_revenues_refresh TO matview_customer_revenues",
+		"DROP TABLE matview_customer_revenues_stale",
+	}
+	if !reflect.DeepEqual(statements, want) {
+		t.Errorf("Expected statements\n%q\ngot\n%q", want, statements)
+	}
+}
+
+func TestView(t *testing.T) {
+	var (
+		db   = testdb.MySQL(t)
+		ctx  = context.Background()
+		view = New[CustomerRevenue, uint](db, revenueQuery)
+	)
+	if err := db.AutoMigrate(&Sale{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DROP TABLE IF EXISTS matview_sales, matview_customer_revenues")
+	})
+
+	for _, sale := range []Sale{{CustomerID: 1, Amount: 10}, {CustomerID: 1, Amount: 5}, {CustomerID: 2, Amount: 7}} {
+		if err := db.Create(&sale).Error; err != nil {
+			t.Fatalf("Failed to create sale: %v", err)
+		}
+	}
+	if err := view.Create(ctx); err != nil {
+		t.Fatalf("Failed to create view: %v", err)
+	}
+
+	revenue, err := view.Repo().Detail(ctx, 1)
+	if err != nil || revenue == nil || revenue.Revenue != 15 || revenue.Sales != 2 {
+		t.Fatalf("Expected 15 over 2 sales, got %+v, %v", revenue, err)
+	}
+
+	if err := db.Create(&Sale{CustomerID: 3, Amount: 1}).Error; err != nil {
+		t.Fatalf("Failed to create sale: %v", err)
+	}
+	if count, _ := view.Repo().Count(ctx, nil); count != 2 {
+		t.Errorf("Expected the view to keep 2 rows until refreshed, got %d", count)
+	}
+	if err := view.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to refresh view: %v", err)
+	}
+	if count, _ := view.Repo().Count(ctx, nil); count != 3 {
+		t.Errorf("Expected 3 rows once refreshed, got %d", count)
+	}
+}
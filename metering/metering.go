@@ -0,0 +1,175 @@
+// Package metering reports the statements run by a *gorm.DB per tenant and table,
+// with the rows they created or deleted, to a Meter, e.g. to bill tenants on their
+// API usage and storage without instrumenting every repository.
+package metering
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Operation is the kind of a metered statement.
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationQuery  Operation = "query"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Event is one statement of a tenant.
+type Event struct {
+	TenantID  string
+	Table     string
+	Operation Operation
+	Rows      int64 // rows affected, or found by a query
+	RowDelta  int64 // rows created, minus rows deleted, soft deletes included
+}
+
+// Meter receives the events of the statements, synchronously, so it must not block.
+type Meter interface {
+	Record(ctx context.Context, event Event)
+}
+
+// TenantFunc returns the tenant a statement runs for, from its context. The
+// statements without tenant are not metered.
+type TenantFunc func(ctx context.Context) (string, bool)
+
+// Plugin is a gorm plugin sending the events of the statements of a *gorm.DB to a
+// Meter, see NewPlugin.
+type Plugin struct {
+	meter    Meter
+	tenantOf TenantFunc
+}
+
+// NewPlugin returns a Plugin to register with db.Use. Row counts are the ones
+// reported by the driver, an upsert updating a row counting as created with MySQL.
+// Raw SQL is not metered.
+func NewPlugin(meter Meter, tenantOf TenantFunc) *Plugin {
+	return &Plugin{meter: meter, tenantOf: tenantOf}
+}
+
+// Name implements gorm.Plugin.
+func (p *Plugin) Name() string {
+	return "metering"
+}
+
+// Initialize implements gorm.Plugin.
+func (p *Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().After("gorm:create").Register("metering:after_create", p.after(OperationCreate)),
+		callbacks.Query().After("gorm:query").Register("metering:after_query", p.after(OperationQuery)),
+		callbacks.Update().After("gorm:update").Register("metering:after_update", p.after(OperationUpdate)),
+		callbacks.Delete().After("gorm:delete").Register("metering:after_delete", p.after(OperationDelete)),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Plugin) after(operation Operation) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.Statement.SQL.Len() == 0 {
+			return
+		}
+
+		ctx := db.Statement.Context
+		tenantID, ok := p.tenantOf(ctx)
+		if !ok {
+			return
+		}
+
+		event := Event{TenantID: tenantID, Table: db.Statement.Table, Operation: operation, Rows: db.RowsAffected}
+		switch operation {
+		case OperationCreate:
+			event.RowDelta = db.RowsAffected
+		case OperationDelete:
+			event.RowDelta = -db.RowsAffected
+		}
+
+		p.meter.Record(ctx, event)
+	}
+}
+
+// Usage sums the events of a tenant and table.
+type Usage struct {
+	TenantID string `json:"tenant_id"`
+	Table    string `json:"table"`
+	Creates  int64  `json:"creates"`
+	Queries  int64  `json:"queries"`
+	Updates  int64  `json:"updates"`
+	Deletes  int64  `json:"deletes"`
+	RowDelta int64  `json:"row_delta"`
+}
+
+type usageKey struct {
+	tenantID string
+	table    string
+}
+
+// Aggregator is a Meter summing the events in memory, to be drained periodically to
+// the billing system instead of sending it every statement.
+type Aggregator struct {
+	mu    sync.Mutex
+	usage map[usageKey]*Usage
+}
+
+// NewAggregator returns an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{usage: map[usageKey]*Usage{}}
+}
+
+// Record implements Meter.
+func (a *Aggregator) Record(ctx context.Context, event Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := usageKey{tenantID: event.TenantID, table: event.Table}
+	usage, ok := a.usage[key]
+	if !ok {
+		usage = &Usage{TenantID: event.TenantID, Table: event.Table}
+		a.usage[key] = usage
+	}
+
+	switch event.Operation {
+	case OperationCreate:
+		usage.Creates++
+	case OperationQuery:
+		usage.Queries++
+	case OperationUpdate:
+		usage.Updates++
+	case OperationDelete:
+		usage.Deletes++
+	}
+	usage.RowDelta += event.RowDelta
+}
+
+// Drain returns the usage summed since the previous Drain, by tenant and table, and
+// starts over.
+func (a *Aggregator) Drain() []Usage {
+	a.mu.Lock()
+	usage := a.usage
+	a.usage = map[usageKey]*Usage{}
+	a.mu.Unlock()
+
+	drained := make([]Usage, 0, len(usage))
+	for _, u := range usage {
+		drained = append(drained, *u)
+	}
+	sort.Slice(drained, func(i, j int) bool {
+		if drained[i].TenantID != drained[j].TenantID {
+			return drained[i].TenantID < drained[j].TenantID
+		}
+		return drained[i].Table < drained[j].Table
+	})
+
+	return drained
+}
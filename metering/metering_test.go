@@ -0,0 +1,87 @@
+package metering
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Contact struct {
+	ID   uint   `gorm:"column:id;primaryKey"`
+	Name string `gorm:"column:name"`
+}
+
+func (Contact) TableName() string {
+	return "contacts"
+}
+
+type tenantCtxKey struct{}
+
+func tenantOf(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantCtxKey{}).(string)
+	return tenantID, ok
+}
+
+func TestPluginMetersTenantStatements(t *testing.T) {
+	var (
+		db         = testdb.DryRun(t)
+		aggregator = NewAggregator()
+		ctx        = context.WithValue(context.Background(), tenantCtxKey{}, "acme")
+	)
+	if err := db.Use(NewPlugin(aggregator, tenantOf)); err != nil {
+		t.Fatalf("Failed to register the plugin: %v", err)
+	}
+	// the dry run affects no row
+	if err := db.Callback().Create().Before("metering:after_create").Register("test:rows_affected", func(tx *gorm.DB) {
+		tx.RowsAffected = int64(reflect.Indirect(reflect.ValueOf(tx.Statement.Dest)).Len())
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	if err := db.WithContext(ctx).Create(&[]Contact{{Name: "Ada"}, {Name: "Grace"}}).Error; err != nil {
+		t.Fatalf("Failed to create contacts: %v", err)
+	}
+	var contacts []Contact
+	if err := db.WithContext(ctx).Find(&contacts).Error; err != nil {
+		t.Fatalf("Failed to find contacts: %v", err)
+	}
+	if err := db.WithContext(ctx).Model(&Contact{}).Where("id = ?", 1).Update("name", "Ada L.").Error; err != nil {
+		t.Fatalf("Failed to update contact: %v", err)
+	}
+	// without tenant
+	if err := db.Find(&contacts).Error; err != nil {
+		t.Fatalf("Failed to find contacts: %v", err)
+	}
+
+	want := []Usage{{TenantID: "acme", Table: "contacts", Creates: 1, Queries: 1, Updates: 1, RowDelta: 2}}
+	if got := aggregator.Drain(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected usage %+v, got %+v", want, got)
+	}
+	if got := aggregator.Drain(); len(got) != 0 {
+		t.Errorf("Expected the aggregator to be drained, got %+v", got)
+	}
+}
+
+func TestAggregatorSumsByTenantAndTable(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		aggregator = NewAggregator()
+	)
+
+	aggregator.Record(ctx, Event{TenantID: "b", Table: "contacts", Operation: OperationDelete, Rows: 3, RowDelta: -3})
+	aggregator.Record(ctx, Event{TenantID: "a", Table: "invoices", Operation: OperationCreate, Rows: 1, RowDelta: 1})
+	aggregator.Record(ctx, Event{TenantID: "a", Table: "contacts", Operation: OperationCreate, Rows: 5, RowDelta: 5})
+	aggregator.Record(ctx, Event{TenantID: "b", Table: "contacts", Operation: OperationCreate, Rows: 1, RowDelta: 1})
+
+	want := []Usage{
+		{TenantID: "a", Table: "contacts", Creates: 1, RowDelta: 5},
+		{TenantID: "a", Table: "invoices", Creates: 1, RowDelta: 1},
+		{TenantID: "b", Table: "contacts", Creates: 1, Deletes: 1, RowDelta: -2},
+	}
+	if got := aggregator.Drain(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected usage %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,218 @@
+// Package metrics counts the statements run by a *gorm.DB and their latency, by
+// operation, table and status, and serves them in the Prometheus text format. The
+// module does not depend on the Prometheus client : Snapshot feeds a collector of
+// the registry of the application, see the readme.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/internal/callbacks"
+	"gorm.io/gorm"
+)
+
+const startedAtKey = "metrics:started_at"
+
+// DefaultBuckets are the upper bounds of the latency histogram, in seconds, the ones
+// of the Prometheus client.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Status labels the outcome of a statement. gorm.ErrRecordNotFound is a success.
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailure Status = "failure"
+)
+
+// Series are the counts of the statements of one operation, table and status.
+type Series struct {
+	Operation string // create, query, update, delete, row or raw
+	Table     string
+	Status    Status
+	Count     uint64
+	Sum       float64  // total latency, in seconds
+	Buckets   []uint64 // cumulative counts of the statements under each bound of Bounds
+}
+
+type seriesKey struct {
+	operation string
+	table     string
+	status    Status
+}
+
+// Metrics is a gorm plugin recording the statements of a *gorm.DB, see New.
+type Metrics struct {
+	namespace string
+	bounds    []float64
+
+	mu     sync.Mutex
+	series map[seriesKey]*Series
+}
+
+// Option configures Metrics.
+type Option func(*Metrics)
+
+// WithNamespace prefixes the metric names, generic_gorm by default.
+func WithNamespace(namespace string) Option {
+	return func(m *Metrics) {
+		m.namespace = namespace
+	}
+}
+
+// WithBuckets replaces DefaultBuckets, bounds in seconds sorted increasingly.
+func WithBuckets(bounds ...float64) Option {
+	return func(m *Metrics) {
+		m.bounds = bounds
+	}
+}
+
+// New returns Metrics to register with db.Use.
+func New(opts ...Option) *Metrics {
+	m := &Metrics{
+		namespace: "generic_gorm",
+		bounds:    DefaultBuckets,
+		series:    map[seriesKey]*Series{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Name implements gorm.Plugin.
+func (m *Metrics) Name() string {
+	return "metrics"
+}
+
+// Initialize implements gorm.Plugin, timing the statements of every operation.
+func (m *Metrics) Initialize(db *gorm.DB) error {
+	return callbacks.Register(db, "metrics", func(string) func(*gorm.DB) { return m.before }, m.after)
+}
+
+func (m *Metrics) before(db *gorm.DB) {
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+func (m *Metrics) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startedAtKey)
+		if !ok {
+			return
+		}
+		startedAt, ok := value.(time.Time)
+		// a statement failing before its SQL is built, e.g. by a hook, still fails
+		if !ok || (db.Statement.SQL.Len() == 0 && db.Error == nil) {
+			return
+		}
+
+		status := StatusSuccess
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = StatusFailure
+		}
+		m.record(seriesKey{operation: operation, table: db.Statement.Table, status: status}, time.Since(startedAt))
+	}
+}
+
+func (m *Metrics) record(key seriesKey, elapsed time.Duration) {
+	seconds := elapsed.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series, ok := m.series[key]
+	if !ok {
+		series = &Series{Operation: key.operation, Table: key.table, Status: key.status, Buckets: make([]uint64, len(m.bounds))}
+		m.series[key] = series
+	}
+
+	series.Count++
+	series.Sum += seconds
+	for i, bound := range m.bounds {
+		if seconds <= bound {
+			series.Buckets[i]++
+		}
+	}
+}
+
+// Bounds returns the upper bounds of the buckets of the Series, in seconds.
+func (m *Metrics) Bounds() []float64 {
+	return append([]float64(nil), m.bounds...)
+}
+
+// Snapshot returns a copy of the Series, by table, operation and status.
+func (m *Metrics) Snapshot() []Series {
+	m.mu.Lock()
+	snapshot := make([]Series, 0, len(m.series))
+	for _, series := range m.series {
+		copied := *series
+		copied.Buckets = append([]uint64(nil), series.Buckets...)
+		snapshot = append(snapshot, copied)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		a, b := snapshot[i], snapshot[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		if a.Operation != b.Operation {
+			return a.Operation < b.Operation
+		}
+		return a.Status < b.Status
+	})
+
+	return snapshot
+}
+
+// Reset forgets the recorded statements.
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.series = map[seriesKey]*Series{}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ServeHTTP writes the Snapshot in the Prometheus text format : the counter
+// <namespace>_repository_operations_total and the histogram
+// <namespace>_repository_operation_duration_seconds.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	var (
+		snapshot = m.Snapshot()
+		total    = m.namespace + "_repository_operations_total"
+		duration = m.namespace + "_repository_operation_duration_seconds"
+		b        strings.Builder
+	)
+
+	labels := func(s Series) string {
+		return fmt.Sprintf(`operation="%s",table="%s",status="%s"`, labelEscaper.Replace(s.Operation), labelEscaper.Replace(s.Table), s.Status)
+	}
+
+	fmt.Fprintf(&b, "# HELP %s Statements run, by operation, table and status.\n# TYPE %s counter\n", total, total)
+	for _, s := range snapshot {
+		fmt.Fprintf(&b, "%s{%s} %d\n", total, labels(s), s.Count)
+	}
+
+	fmt.Fprintf(&b, "# HELP %s Latency of the statements, by operation, table and status.\n# TYPE %s histogram\n", duration, duration)
+	for _, s := range snapshot {
+		for i, bound := range m.bounds {
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", duration, labels(s), strconv.FormatFloat(bound, 'g', -1, 64), s.Buckets[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", duration, labels(s), s.Count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", duration, labels(s), strconv.FormatFloat(s.Sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", duration, labels(s), s.Count)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(b.String()))
+}
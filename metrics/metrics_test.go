@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Order struct {
+	ID     uint   `gorm:"column:id;primaryKey"`
+	Status string `gorm:"column:status"`
+}
+
+func (Order) TableName() string {
+	return "orders"
+}
+
+func TestMetrics(t *testing.T) {
+	var (
+		db      = testdb.DryRun(t)
+		metrics = New(WithNamespace("shop"), WithBuckets(0.1, 1))
+	)
+	if err := db.Use(metrics); err != nil {
+		t.Fatalf("Failed to register the metrics: %v", err)
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("test:fail", func(tx *gorm.DB) {
+		_ = tx.AddError(errors.New("lock wait timeout"))
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var orders []Order
+		db.Where("status = ?", "paid").Find(&orders)
+	}
+	db.Model(&Order{}).Where("id = ?", 1).Update("status", "paid")
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Expected 2 series, got %+v", snapshot)
+	}
+	if s := snapshot[0]; s.Operation != "query" || s.Table != "orders" || s.Status != StatusSuccess || s.Count != 2 || s.Buckets[0] != 2 {
+		t.Errorf("Expected 2 successful queries under 100ms, got %+v", s)
+	}
+	if s := snapshot[1]; s.Operation != "update" || s.Status != StatusFailure || s.Count != 1 {
+		t.Errorf("Expected 1 failed update, got %+v", s)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE shop_repository_operations_total counter",
+		`shop_repository_operations_total{operation="query",table="orders",status="success"} 2`,
+		`shop_repository_operations_total{operation="update",table="orders",status="failure"} 1`,
+		"# TYPE shop_repository_operation_duration_seconds histogram",
+		`shop_repository_operation_duration_seconds_bucket{operation="query",table="orders",status="success",le="0.1"} 2`,
+		`shop_repository_operation_duration_seconds_bucket{operation="query",table="orders",status="success",le="+Inf"} 2`,
+		`shop_repository_operation_duration_seconds_count{operation="update",table="orders",status="failure"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected the line %q in\n%s", line, body)
+		}
+	}
+
+	metrics.Reset()
+	if len(metrics.Snapshot()) != 0 {
+		t.Error("Expected no series after Reset")
+	}
+}
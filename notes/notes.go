@@ -0,0 +1,111 @@
+// Package notes attaches notes written by the author of the context to rows of any
+// model, identified by their table name and primary key, in a notes table.
+package notes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+)
+
+// Note is one note of the notes table.
+type Note struct {
+	ID          uint           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	NotableType string         `json:"notable_type" gorm:"column:notable_type;size:64;index:idx_notes_notable"` // table name of the annotated row
+	NotableID   string         `json:"notable_id" gorm:"column:notable_id;size:191;index:idx_notes_notable"`    // primary key of the annotated row
+	AuthorID    string         `json:"author_id" gorm:"column:author_id;size:191;index"`
+	Body        string         `json:"body" gorm:"column:body;type:text"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"column:deleted_at;index"`
+}
+
+func (Note) TableName() string {
+	return "notes"
+}
+
+func (Note) PrimaryKey() string {
+	return "id"
+}
+
+// ErrNoAuthor is returned when a note is written without author in the context.
+var ErrNoAuthor = errors.New("no note author in context")
+
+const authorCtxName = "x-note-author-ctx"
+
+// ContextWithAuthor sets the author of the notes written with the returned context.
+func ContextWithAuthor(ctx context.Context, authorID string) context.Context {
+	return context.WithValue(ctx, authorCtxName, authorID)
+}
+
+// AuthorFromContext returns the author set by ContextWithAuthor.
+func AuthorFromContext(ctx context.Context) (string, bool) {
+	authorID, ok := ctx.Value(authorCtxName).(string)
+	return authorID, ok && authorID != ""
+}
+
+// Notes reads and writes notes.
+type Notes struct {
+	repo *base.BaseGorm[Note, uint]
+}
+
+// NewNotes returns the Notes of the notes table of db, see Migrate.
+func NewNotes(db *gorm.DB) *Notes {
+	return &Notes{repo: base.NewBaseGorm[Note, uint](db)}
+}
+
+// Migrate creates the notes table.
+func (n *Notes) Migrate(ctx context.Context) error {
+	return n.repo.DB(ctx).AutoMigrate(&Note{})
+}
+
+// Add writes a note on model, a pointer to a stored row, by the author of ctx.
+func (n *Notes) Add(ctx context.Context, model base.TablerWithPrimaryKey, body string) (*Note, error) {
+	authorID, ok := AuthorFromContext(ctx)
+	if !ok {
+		generic_gorm.GetLoggerFromContext(ctx).Error(ErrNoAuthor)
+		return nil, ErrNoAuthor
+	}
+
+	notableID, err := base.PolymorphicID(ctx, n.repo.DB(ctx), model)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	return n.repo.Create(ctx, &Note{NotableType: model.TableName(), NotableID: notableID, AuthorID: authorID, Body: body})
+}
+
+// List finds one page of the notes of model, the newest first. Deleted notes are skipped.
+func (n *Notes) List(ctx context.Context, model base.TablerWithPrimaryKey, page int, pageSize int, opts ...base.QueryOption) ([]Note, *base.Paginator, error) {
+	notableID, err := base.PolymorphicID(ctx, n.repo.DB(ctx), model)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return nil, nil, err
+	}
+
+	return n.repo.List(ctx, page, pageSize,
+		[]base.OrderBy{{Field: "created_at", Direction: "desc"}, {Field: "id", Direction: "desc"}},
+		[]base.Where{{Name: "notable_type", Value: model.TableName()}, {Name: "notable_id", Value: notableID}},
+		opts...,
+	)
+}
+
+// Edit replaces the body of the note id.
+func (n *Notes) Edit(ctx context.Context, id uint, body string) (int64, error) {
+	return n.repo.UpdateWhere(ctx, []base.Where{{Name: "id", Value: id}}, map[string]interface{}{"body": body})
+}
+
+// Delete moves the note id to the trash.
+func (n *Notes) Delete(ctx context.Context, id uint) (int64, error) {
+	return n.repo.SoftDelete(ctx, id)
+}
+
+// Restore takes the note id out of the trash.
+func (n *Notes) Restore(ctx context.Context, id uint) (int64, error) {
+	return n.repo.Restore(ctx, id)
+}
@@ -0,0 +1,84 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Ticket struct {
+	ID uint `gorm:"column:id;primaryKey"`
+}
+
+func (Ticket) TableName() string {
+	return "tickets"
+}
+
+func (Ticket) PrimaryKey() string {
+	return "id"
+}
+
+func TestAddRequiresAuthor(t *testing.T) {
+	n := NewNotes(testdb.DryRun(t))
+
+	if _, err := n.Add(context.Background(), &Ticket{ID: 1}, "Called back"); !errors.Is(err, ErrNoAuthor) {
+		t.Errorf("Expected ErrNoAuthor, got %v", err)
+	}
+}
+
+func TestListSQL(t *testing.T) {
+	var (
+		db  = testdb.DryRun(t)
+		sql string
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	if _, _, err := NewNotes(db).List(context.Background(), &Ticket{ID: 7}, 1, 20); err != nil {
+		t.Fatalf("Failed to list notes: %v", err)
+	}
+
+	// the dry run counts no note, so the last query is the count
+	want := "SELECT count(*) FROM `notes` WHERE notable_type = ? AND notable_id = ? AND `notes`.`deleted_at` IS NULL"
+	if sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, sql)
+	}
+}
+
+func TestNotes(t *testing.T) {
+	var (
+		db     = testdb.MySQL(t)
+		n      = NewNotes(db)
+		ctx    = ContextWithAuthor(context.Background(), "agent-7")
+		ticket = &Ticket{ID: 42}
+	)
+	if err := n.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate notes: %v", err)
+	}
+	t.Cleanup(func() { db.Unscoped().Where("notable_type = ?", Ticket{}.TableName()).Delete(&Note{}) })
+
+	first, err := n.Add(ctx, ticket, "Customer called")
+	if err != nil {
+		t.Fatalf("Failed to add note: %v", err)
+	}
+	if _, err := n.Add(ctx, ticket, "Refund issued"); err != nil {
+		t.Fatalf("Failed to add note: %v", err)
+	}
+	if _, err := n.Delete(ctx, first.ID); err != nil {
+		t.Fatalf("Failed to delete note: %v", err)
+	}
+
+	notes, paginator, err := n.List(ctx, ticket, 1, 10)
+	if err != nil {
+		t.Fatalf("Failed to list notes: %v", err)
+	}
+	if paginator.Total != 1 || notes[0].Body != "Refund issued" || notes[0].AuthorID != "agent-7" {
+		t.Errorf("Expected the remaining note of agent-7, got %+v", notes)
+	}
+}
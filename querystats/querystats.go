@@ -0,0 +1,200 @@
+// Package querystats aggregates the count and latency of the statements run by a
+// *gorm.DB per fingerprint, the SQL with its literals replaced by placeholders, to
+// find the most expensive queries without enabling the slow query log.
+package querystats
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/internal/callbacks"
+	"gorm.io/gorm"
+)
+
+const startedAtKey = "querystats:started_at"
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	numberLiteral  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholders   = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	repeatedTuples = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint normalizes sql so the statements differing only by their values share
+// it : literals become ?, lists of placeholders (?) and repeated VALUES tuples are
+// collapsed, and whitespace is squeezed.
+func Fingerprint(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = numberLiteral.ReplaceAllString(sql, "?")
+	sql = placeholders.ReplaceAllString(sql, "(?)")
+	sql = repeatedTuples.ReplaceAllString(sql, "(?)")
+	sql = whitespace.ReplaceAllString(sql, " ")
+
+	return strings.TrimSpace(sql)
+}
+
+// QueryStat aggregates the statements of one fingerprint.
+type QueryStat struct {
+	Fingerprint string        `json:"fingerprint"`
+	Count       int64         `json:"count"`
+	Errors      int64         `json:"errors"`
+	TotalTime   time.Duration `json:"total_time"`
+	MaxTime     time.Duration `json:"max_time"`
+}
+
+// MeanTime is the average latency of the statements.
+func (s QueryStat) MeanTime() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+
+	return s.TotalTime / time.Duration(s.Count)
+}
+
+// Collector is a gorm plugin aggregating the statements of a *gorm.DB, see Use.
+type Collector struct {
+	maxFingerprints int
+
+	mu       sync.Mutex
+	stats    map[string]*QueryStat
+	overflow int64
+}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithMaxFingerprints bounds the memory of the Collector to max fingerprints, 1000
+// by default. The statements of new fingerprints are then only counted by Overflow.
+func WithMaxFingerprints(max int) Option {
+	return func(c *Collector) {
+		c.maxFingerprints = max
+	}
+}
+
+// NewCollector returns a Collector to register with db.Use.
+func NewCollector(opts ...Option) *Collector {
+	c := &Collector{
+		maxFingerprints: 1000,
+		stats:           map[string]*QueryStat{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Name implements gorm.Plugin.
+func (c *Collector) Name() string {
+	return "querystats"
+}
+
+// Initialize implements gorm.Plugin, timing the statements of every operation.
+func (c *Collector) Initialize(db *gorm.DB) error {
+	return callbacks.Register(db, "querystats", func(string) func(*gorm.DB) { return c.before }, func(string) func(*gorm.DB) { return c.after })
+}
+
+func (c *Collector) before(db *gorm.DB) {
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+func (c *Collector) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(startedAtKey)
+	if !ok {
+		return
+	}
+	startedAt, ok := value.(time.Time)
+	if !ok || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	c.record(Fingerprint(db.Statement.SQL.String()), time.Since(startedAt), db.Error != nil)
+}
+
+func (c *Collector) record(fingerprint string, elapsed time.Duration, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stat, ok := c.stats[fingerprint]
+	if !ok {
+		if len(c.stats) >= c.maxFingerprints {
+			c.overflow++
+			return
+		}
+		stat = &QueryStat{Fingerprint: fingerprint}
+		c.stats[fingerprint] = stat
+	}
+
+	stat.Count++
+	stat.TotalTime += elapsed
+	if elapsed > stat.MaxTime {
+		stat.MaxTime = elapsed
+	}
+	if failed {
+		stat.Errors++
+	}
+}
+
+// TopQueries returns the n fingerprints with the longest total time, all when n is
+// not positive.
+func (c *Collector) TopQueries(n int) []QueryStat {
+	c.mu.Lock()
+	stats := make([]QueryStat, 0, len(c.stats))
+	for _, stat := range c.stats {
+		stats = append(stats, *stat)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalTime != stats[j].TotalTime {
+			return stats[i].TotalTime > stats[j].TotalTime
+		}
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+
+	return stats
+}
+
+// Overflow returns the number of statements not aggregated as the Collector held
+// its maximum number of fingerprints.
+func (c *Collector) Overflow() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.overflow
+}
+
+// Reset forgets the aggregated statements.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats = map[string]*QueryStat{}
+	c.overflow = 0
+}
+
+// ServeHTTP reports the TopQueries as JSON, 20 unless set by the limit parameter.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c.TopQueries(limit))
+}
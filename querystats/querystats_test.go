@@ -0,0 +1,93 @@
+package querystats
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+type Order struct {
+	ID     uint   `gorm:"column:id;primaryKey"`
+	Status string `gorm:"column:status"`
+}
+
+func (Order) TableName() string {
+	return "orders"
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			sql:  "SELECT * FROM `orders` WHERE status = 'paid' AND total > 10.5",
+			want: "SELECT * FROM `orders` WHERE status = ? AND total > ?",
+		},
+		{
+			sql:  "SELECT * FROM `orders` WHERE id IN (?,?,?)",
+			want: "SELECT * FROM `orders` WHERE id IN (?)",
+		},
+		{
+			sql:  "INSERT INTO `orders` (`status`) VALUES (?),(?),\n(?)",
+			want: "INSERT INTO `orders` (`status`) VALUES (?)",
+		},
+		{
+			sql:  "SELECT * FROM `t1` WHERE name = 'it''s'   LIMIT 10",
+			want: "SELECT * FROM `t1` WHERE name = ? LIMIT ?",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Fingerprint(tt.sql); got != tt.want {
+			t.Errorf("Fingerprint(%q)\nExpected %q\ngot      %q", tt.sql, tt.want, got)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var (
+		db        = testdb.DryRun(t)
+		collector = NewCollector(WithMaxFingerprints(2))
+	)
+	if err := db.Use(collector); err != nil {
+		t.Fatalf("Failed to register the collector: %v", err)
+	}
+
+	for _, ids := range [][]uint{{1}, {1, 2}, {1, 2, 3}} {
+		var orders []Order
+		db.Where("id IN ?", ids).Find(&orders)
+	}
+	db.Model(&Order{}).Where("id = ?", 1).Update("status", "paid")
+	db.Delete(&Order{}, 1)
+
+	top := collector.TopQueries(0)
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 fingerprints, got %+v", top)
+	}
+
+	counts := map[string]int64{}
+	for _, stat := range top {
+		counts[stat.Fingerprint] = stat.Count
+	}
+	if counts["SELECT * FROM `orders` WHERE id IN (?)"] != 3 {
+		t.Errorf("Expected the 3 finds to share a fingerprint, got %+v", top)
+	}
+	if collector.Overflow() != 1 {
+		t.Errorf("Expected the delete to overflow, got %d", collector.Overflow())
+	}
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/queries?limit=1", nil))
+	var report []QueryStat
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil || len(report) != 1 {
+		t.Errorf("Expected a report of 1 query, got %s (%v)", recorder.Body.String(), err)
+	}
+
+	collector.Reset()
+	if len(collector.TopQueries(0)) != 0 {
+		t.Error("Expected Reset to forget the statements")
+	}
+}
@@ -0,0 +1,238 @@
+// Package quota limits the rows a tenant may hold in a model, e.g. 1,000 contacts on
+// the free tier. The limits are soft : the counts are cached per tenant and only
+// follow the creations of the repository, so rows deleted elsewhere, or created in a
+// transaction rolled back later, are only seen once the count is refreshed.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/tenant"
+	"gorm.io/gorm/schema"
+)
+
+const defaultCountTTL = 5 * time.Minute
+
+// Model is a tenant scoped model, see tenant.Scoped.
+type Model interface {
+	base.TablerWithPrimaryKey
+	tenant.Scoped
+}
+
+// ErrQuotaExceeded is returned when a creation would take a tenant above its limit.
+type ErrQuotaExceeded struct {
+	Table    string
+	TenantID string
+	Limit    int64
+	Count    int64 // rows of the tenant before the creation
+	Adding   int64 // rows the creation adds
+}
+
+func (e *ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("quota exceeded: tenant %s has %d of %d %s, cannot add %d", e.TenantID, e.Count, e.Limit, e.Table, e.Adding)
+}
+
+// Limit returns the maximum number of rows of tenantID, e.g. depending on its plan. A
+// negative limit means unlimited.
+type Limit func(ctx context.Context, tenantID string) (int64, error)
+
+// Max limits every tenant to n rows.
+func Max(n int64) Limit {
+	return func(ctx context.Context, tenantID string) (int64, error) {
+		return n, nil
+	}
+}
+
+type config struct {
+	countTTL time.Duration
+}
+
+// Option configures a Repo.
+type Option func(*config)
+
+// WithCountTTL sets how long the count of a tenant is trusted before being counted
+// again in the database, 5 minutes by default.
+func WithCountTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.countTTL = ttl
+	}
+}
+
+type count struct {
+	rows     int64
+	loadedAt time.Time
+}
+
+// Repo is a repository whose Create and CreateMultiple enforce the limit of the
+// tenant of the rows. The other methods are the ones of the wrapped repository.
+type Repo[T Model, PkType string | int64 | int32 | int | uint] struct {
+	*base.BaseGorm[T, PkType]
+
+	limit  Limit
+	config config
+	now    func() time.Time
+
+	mu     sync.Mutex
+	counts map[string]*count
+}
+
+// NewRepo returns a Repo enforcing limit on the rows created through repo.
+func NewRepo[T Model, PkType string | int64 | int32 | int | uint](repo *base.BaseGorm[T, PkType], limit Limit, opts ...Option) *Repo[T, PkType] {
+	r := &Repo[T, PkType]{
+		BaseGorm: repo,
+		limit:    limit,
+		config:   config{countTTL: defaultCountTTL},
+		now:      time.Now,
+		counts:   map[string]*count{},
+	}
+	for _, opt := range opts {
+		opt(&r.config)
+	}
+
+	return r
+}
+
+// Create creates row unless its tenant has reached its limit, returning an
+// *ErrQuotaExceeded then.
+func (r *Repo[T, PkType]) Create(ctx context.Context, row *T) (*T, error) {
+	tenantID, err := r.tenantOf(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.reserve(ctx, map[string]int64{tenantID: 1}); err != nil {
+		return nil, err
+	}
+
+	created, err := r.BaseGorm.Create(ctx, row)
+	if err != nil {
+		r.Invalidate(tenantID)
+	}
+
+	return created, err
+}
+
+// CreateMultiple creates rows unless one of their tenants would exceed its limit,
+// returning an *ErrQuotaExceeded then and creating none of them.
+func (r *Repo[T, PkType]) CreateMultiple(ctx context.Context, rows []*T) ([]*T, int64, error) {
+	adding := map[string]int64{}
+	for _, row := range rows {
+		tenantID, err := r.tenantOf(ctx, row)
+		if err != nil {
+			return rows, 0, err
+		}
+		adding[tenantID]++
+	}
+
+	if err := r.reserve(ctx, adding); err != nil {
+		return rows, 0, err
+	}
+
+	created, rowsAffected, err := r.BaseGorm.CreateMultiple(ctx, rows)
+	if err != nil {
+		for tenantID := range adding {
+			r.Invalidate(tenantID)
+		}
+	}
+
+	return created, rowsAffected, err
+}
+
+// Usage returns the number of rows of tenantID, as counted by the repository, and its
+// limit.
+func (r *Repo[T, PkType]) Usage(ctx context.Context, tenantID string) (used int64, limit int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, err := r.count(ctx, tenantID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	limit, err = r.limit(ctx, tenantID)
+	return c.rows, limit, err
+}
+
+// Invalidate drops the cached count of tenantID, e.g. after deleting its rows, so the
+// next creation counts them again.
+func (r *Repo[T, PkType]) Invalidate(tenantID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.counts, tenantID)
+}
+
+// reserve checks that every tenant of adding can take its rows, and adds them to the
+// cached counts. The lock is held across the tenants so concurrent creations do not
+// both take the last row.
+func (r *Repo[T, PkType]) reserve(ctx context.Context, adding map[string]int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]*count, len(adding))
+	for tenantID, n := range adding {
+		c, err := r.count(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+
+		limit, err := r.limit(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+		if limit >= 0 && c.rows+n > limit {
+			var e T
+			return &ErrQuotaExceeded{Table: e.TableName(), TenantID: tenantID, Limit: limit, Count: c.rows, Adding: n}
+		}
+		counts[tenantID] = c
+	}
+
+	for tenantID, c := range counts {
+		c.rows += adding[tenantID]
+	}
+
+	return nil
+}
+
+// count returns the cached count of tenantID, counting its rows when it is missing or
+// older than the count TTL. r.mu must be held.
+func (r *Repo[T, PkType]) count(ctx context.Context, tenantID string) (*count, error) {
+	if c, ok := r.counts[tenantID]; ok && r.now().Sub(c.loadedAt) < r.config.countTTL {
+		return c, nil
+	}
+
+	var e T
+	rows, err := r.BaseGorm.Count(ctx, []base.Where{{Name: e.TenantColumn(), Value: tenantID}})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &count{rows: rows, loadedAt: r.now()}
+	r.counts[tenantID] = c
+
+	return c, nil
+}
+
+// schemas caches the schemas tenantOf parses.
+var schemas sync.Map
+
+// tenantOf returns the value of the tenant column of row.
+func (r *Repo[T, PkType]) tenantOf(ctx context.Context, row *T) (string, error) {
+	sch, err := schema.Parse(row, &schemas, r.DB(ctx).NamingStrategy)
+	if err != nil {
+		return "", err
+	}
+
+	field := sch.LookUpField((*row).TenantColumn())
+	if field == nil {
+		return "", fmt.Errorf("tenant column %s not found in %s", (*row).TenantColumn(), sch.Table)
+	}
+
+	value, _ := field.ValueOf(ctx, reflect.ValueOf(row).Elem())
+	return fmt.Sprint(value), nil
+}
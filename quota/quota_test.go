@@ -0,0 +1,84 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+type Contact struct {
+	ID       uint `gorm:"column:id;primaryKey"`
+	TenantID uint `gorm:"column:tenant_id"`
+	Name     string
+}
+
+func (Contact) TableName() string {
+	return "contacts"
+}
+
+func (Contact) PrimaryKey() string {
+	return "id"
+}
+
+func (Contact) TenantColumn() string {
+	return "tenant_id"
+}
+
+func TestRepoEnforcesLimit(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		repo = NewRepo(base.NewBaseGorm[Contact, uint](testdb.DryRun(t)), Max(2))
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.Create(ctx, &Contact{TenantID: 1, Name: "Ada"}); err != nil {
+			t.Fatalf("Failed to create contact: %v", err)
+		}
+	}
+
+	var exceeded *ErrQuotaExceeded
+	if _, err := repo.Create(ctx, &Contact{TenantID: 1, Name: "Grace"}); !errors.As(err, &exceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if exceeded.TenantID != "1" || exceeded.Count != 2 || exceeded.Limit != 2 || exceeded.Table != "contacts" {
+		t.Errorf("Unexpected error %+v", exceeded)
+	}
+
+	if _, _, err := repo.CreateMultiple(ctx, []*Contact{{TenantID: 2}, {TenantID: 2}, {TenantID: 2}}); !errors.As(err, &exceeded) {
+		t.Errorf("Expected ErrQuotaExceeded for 3 contacts, got %v", err)
+	}
+	if _, _, err := repo.CreateMultiple(ctx, []*Contact{{TenantID: 2}, {TenantID: 3}}); err != nil {
+		t.Errorf("Failed to create contacts of other tenants: %v", err)
+	}
+
+	used, limit, err := repo.Usage(ctx, "2")
+	if err != nil || used != 1 || limit != 2 {
+		t.Errorf("Expected 1 of 2 contacts used, got %d of %d, %v", used, limit, err)
+	}
+}
+
+func TestRepoRecountsAfterTTL(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		repo = NewRepo(base.NewBaseGorm[Contact, uint](testdb.DryRun(t)), Max(1), WithCountTTL(time.Minute))
+		now  = time.Now()
+	)
+	repo.now = func() time.Time { return now }
+
+	if _, err := repo.Create(ctx, &Contact{TenantID: 1}); err != nil {
+		t.Fatalf("Failed to create contact: %v", err)
+	}
+	if _, err := repo.Create(ctx, &Contact{TenantID: 1}); err == nil {
+		t.Fatal("Expected the second contact to exceed the quota")
+	}
+
+	// the dry run database counts no row
+	now = now.Add(time.Minute)
+	if _, err := repo.Create(ctx, &Contact{TenantID: 1}); err != nil {
+		t.Errorf("Expected the count to be refreshed, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package generic_gorm
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// RawList runs the raw query sql with args and scans its rows into T, for the
+// queries the repositories cannot express. Like them, it runs on the connection set
+// by ContextWithDB when it belongs to the database of db, e.g. the transaction of
+// the caller, and logs its error with the logger of ctx.
+func RawList[T any](ctx context.Context, db *gorm.DB, sql string, args ...any) ([]T, error) {
+	var rows []T
+
+	err := rawConn(ctx, db).Raw(sql, args...).Scan(&rows).Error
+	if err != nil {
+		GetLoggerFromContext(ctx).WithField("sql", sql).Error(err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// RawOne is RawList for a query returning a single row, failing with
+// gorm.ErrRecordNotFound, which is not logged, when it returns none.
+func RawOne[T any](ctx context.Context, db *gorm.DB, sql string, args ...any) (*T, error) {
+	var row T
+
+	tx := rawConn(ctx, db).Raw(sql, args...).Scan(&row)
+	if tx.Error != nil {
+		GetLoggerFromContext(ctx).WithField("sql", sql).Error(tx.Error)
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &row, nil
+}
+
+// rawConn returns the connection of ctx when it belongs to the database of db, else db.
+func rawConn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if conn, ok := DBFromContext(ctx); ok && SameDatabase(conn, db) {
+		return conn.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,50 @@
+package generic_gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestRawListRunsOnContextDB(t *testing.T) {
+	var (
+		db      = openDryRunDB(t, "users")
+		other   = openDryRunDB(t, "events")
+		sql     string
+		inTx    bool
+		query   = "SELECT id, COUNT(*) AS total FROM users GROUP BY id HAVING total > ?"
+		capture = func(tx *gorm.DB) {
+			sql = tx.Statement.SQL.String()
+			_, inTx = tx.Get("test:tx")
+		}
+		register = func(db *gorm.DB) {
+			if err := db.Callback().Row().After("gorm:row").Register("test:capture_sql", capture); err != nil {
+				t.Fatalf("Failed to register callback: %v", err)
+			}
+		}
+	)
+	register(db)
+	register(other)
+
+	type total struct {
+		ID    uint
+		Total int64
+	}
+
+	ctx := ContextWithDB(context.Background(), db.Set("test:tx", true))
+	if _, err := RawList[total](ctx, db, query, 1); !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("Expected the dry run to build the query only, got %v", err)
+	}
+	if sql != query || !inTx {
+		t.Errorf("Expected %q on the connection of the context, got %q, %v", query, sql, inTx)
+	}
+
+	if _, err := RawOne[total](ctx, other, query, 1); !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("Expected the dry run to build the query only, got %v", err)
+	}
+	if inTx {
+		t.Error("Expected the connection of another database to be ignored")
+	}
+}
@@ -0,0 +1,95 @@
+// Package retention purges expired rows of several models, each on its own cron,
+// through the scheduler package.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/scheduler"
+)
+
+// JobPrefix prefixes the scheduler job names of the retention policies.
+const JobPrefix = "retention:"
+
+// Purger deletes expired rows, it is implemented by base.BaseGorm.
+type Purger interface {
+	PurgeExpired(ctx context.Context, column string, maxAge time.Duration, batchSize int) (int64, error)
+}
+
+// Policy purges the rows of Repo whose Column is older than MaxAge, on the Spec
+// schedule (see scheduler.ParseSchedule).
+type Policy struct {
+	Name      string // unique name of the policy, e.g. the table name
+	Spec      string
+	Repo      Purger
+	Column    string
+	MaxAge    time.Duration
+	BatchSize int // see base.BaseGorm.PurgeExpired
+}
+
+// Executor schedules the retention policies.
+type Executor struct {
+	scheduler *scheduler.Scheduler
+	policies  map[string]Policy
+}
+
+// NewExecutor returns an Executor running the policies through s. The jobs only run
+// while s is started.
+func NewExecutor(s *scheduler.Scheduler) *Executor {
+	return &Executor{scheduler: s, policies: map[string]Policy{}}
+}
+
+// Add registers and schedules the policies. Each run records the purged_rows and
+// duration_seconds metrics in the scheduler's run history.
+func (e *Executor) Add(ctx context.Context, policies ...Policy) error {
+	for _, policy := range policies {
+		if policy.Name == "" || policy.Repo == nil || policy.Column == "" || policy.MaxAge <= 0 {
+			return errors.New("retention policy requires a name, a repository, a column and a positive max age")
+		}
+
+		e.scheduler.Register(JobPrefix+policy.Name, e.handler(policy))
+		if err := e.scheduler.Schedule(ctx, JobPrefix+policy.Name, policy.Spec, nil); err != nil {
+			return err
+		}
+		e.policies[policy.Name] = policy
+	}
+
+	return nil
+}
+
+func (e *Executor) handler(policy Policy) scheduler.Handler {
+	return func(ctx context.Context, _ json.RawMessage) error {
+		started := time.Now()
+		purged, err := policy.Repo.PurgeExpired(ctx, policy.Column, policy.MaxAge, policy.BatchSize)
+
+		scheduler.SetMetric(ctx, "purged_rows", float64(purged))
+		scheduler.SetMetric(ctx, "duration_seconds", time.Since(started).Seconds())
+		generic_gorm.GetLoggerFromContext(ctx).
+			WithField("policy", policy.Name).
+			WithField("purged_rows", purged).
+			Info("retention policy run")
+
+		return err
+	}
+}
+
+// SetEnabled is the kill switch of the policy name : a disabled policy stops running
+// on every instance until enabled again.
+func (e *Executor) SetEnabled(ctx context.Context, name string, enabled bool) error {
+	return e.scheduler.SetEnabled(ctx, JobPrefix+name, enabled)
+}
+
+// SetAllEnabled flips the kill switch of every policy added to e.
+func (e *Executor) SetAllEnabled(ctx context.Context, enabled bool) error {
+	for name := range e.policies {
+		if err := e.SetEnabled(ctx, name, enabled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
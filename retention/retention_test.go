@@ -0,0 +1,39 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"github.com/harryosmar/generic-gorm/scheduler"
+)
+
+type fakePurger struct {
+	column string
+	maxAge time.Duration
+}
+
+func (f *fakePurger) PurgeExpired(ctx context.Context, column string, maxAge time.Duration, batchSize int) (int64, error) {
+	f.column, f.maxAge = column, maxAge
+	return 7, nil
+}
+
+func TestHandlerPurgesPolicy(t *testing.T) {
+	var (
+		purger   = &fakePurger{}
+		executor = NewExecutor(scheduler.NewScheduler(testdb.DryRun(t)))
+		policy   = Policy{Name: "sessions", Spec: "@daily", Repo: purger, Column: "created_at", MaxAge: time.Hour}
+	)
+
+	if err := executor.handler(policy)(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to run policy: %v", err)
+	}
+	if purger.column != "created_at" || purger.maxAge != time.Hour {
+		t.Errorf("Expected purge of created_at older than 1h, got %s older than %v", purger.column, purger.maxAge)
+	}
+
+	if err := executor.Add(context.Background(), Policy{Name: "invalid", Spec: "@daily", Repo: purger}); err == nil {
+		t.Error("Expected error when adding a policy without column")
+	}
+}
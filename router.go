@@ -0,0 +1,99 @@
+package generic_gorm
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Tabler is a model with a table name, as used by the Router registry.
+type Tabler interface {
+	TableName() string
+}
+
+// Router maps models to the physical database they live on (users DB, analytics DB...),
+// so repositories of every model can be built from one registry.
+type Router struct {
+	mu          sync.RWMutex
+	defaultName string
+	databases   map[string]*gorm.DB
+	routes      map[string]string // table name => database name
+}
+
+// NewRouter returns a Router sending every model to defaultDB unless routed elsewhere.
+func NewRouter(defaultName string, defaultDB *gorm.DB) *Router {
+	return &Router{
+		defaultName: defaultName,
+		databases:   map[string]*gorm.DB{defaultName: defaultDB},
+		routes:      map[string]string{},
+	}
+}
+
+// AddDatabase registers another physical database under name.
+func (r *Router) AddDatabase(name string, db *gorm.DB) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.databases[name] = db
+
+	return r
+}
+
+// Route sends models to the database registered under name.
+func (r *Router) Route(name string, models ...Tabler) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.databases[name]; !ok {
+		panic(fmt.Sprintf("generic_gorm: route to unknown database %q", name))
+	}
+	for _, model := range models {
+		r.routes[model.TableName()] = name
+	}
+
+	return r
+}
+
+// DatabaseName returns the name of the database model lives on.
+func (r *Router) DatabaseName(model Tabler) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name, ok := r.routes[model.TableName()]; ok {
+		return name
+	}
+
+	return r.defaultName
+}
+
+// DB returns the database model lives on.
+func (r *Router) DB(model Tabler) *gorm.DB {
+	name := r.DatabaseName(model)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.databases[name]
+}
+
+// NameOf returns the name under which db, or a session/transaction derived from it,
+// was registered, and false for an unknown database.
+func (r *Router) NameOf(db *gorm.DB) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for name, registered := range r.databases {
+		if SameDatabase(registered, db) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// SameDatabase reports whether a and b, or the sessions/transactions they derive
+// from, share the same connection pool.
+func SameDatabase(a, b *gorm.DB) bool {
+	return a != nil && b != nil && a.Config.ConnPool == b.Config.ConnPool
+}
@@ -0,0 +1,52 @@
+package generic_gorm
+
+import (
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type routedUser struct{}
+
+func (routedUser) TableName() string { return "users" }
+
+type routedEvent struct{}
+
+func (routedEvent) TableName() string { return "events" }
+
+func openDryRunDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/" + name,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+
+	return db
+}
+
+func TestRouter(t *testing.T) {
+	usersDB, analyticsDB := openDryRunDB(t, "users"), openDryRunDB(t, "analytics")
+
+	router := NewRouter("users", usersDB).
+		AddDatabase("analytics", analyticsDB).
+		Route("analytics", routedEvent{})
+
+	if !SameDatabase(router.DB(routedUser{}), usersDB) {
+		t.Error("Expected users to be routed to the default database")
+	}
+	if !SameDatabase(router.DB(routedEvent{}), analyticsDB) {
+		t.Error("Expected events to be routed to the analytics database")
+	}
+	if SameDatabase(usersDB, analyticsDB) {
+		t.Error("Expected different databases not to be the same")
+	}
+
+	if name, ok := router.NameOf(analyticsDB.Session(&gorm.Session{})); !ok || name != "analytics" {
+		t.Errorf("Expected a session of the analytics database to be named analytics, got %q", name)
+	}
+}
@@ -0,0 +1,156 @@
+// Package saga runs workflows spanning several databases as ordered steps with
+// compensation actions, persisting their progress in a table since no distributed
+// transaction is available.
+package saga
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+)
+
+// Saga statuses.
+const (
+	StatusRunning      = "running"
+	StatusCompleted    = "completed"
+	StatusCompensating = "compensating"
+	StatusCompensated  = "compensated"
+	StatusFailed       = "failed" // a compensation failed, manual intervention is needed
+)
+
+// State is the persisted progress of one saga execution.
+type State struct {
+	ID             string    `json:"id" gorm:"column:id;primaryKey;size:64"`
+	Name           string    `json:"name" gorm:"column:name;size:191;index"`
+	Status         string    `json:"status" gorm:"column:status;size:32;index"`
+	CompletedSteps int       `json:"completed_steps" gorm:"column:completed_steps"`
+	FailedStep     string    `json:"failed_step" gorm:"column:failed_step;size:191"`
+	Error          string    `json:"error" gorm:"column:error;type:text"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (State) TableName() string {
+	return "sagas"
+}
+
+func (State) PrimaryKey() string {
+	return "id"
+}
+
+// Step is one action of a saga, with the action undoing it when a later step fails.
+// Compensate may be nil for steps without side effects.
+type Step struct {
+	Name       string
+	Action     func(ctx context.Context) error
+	Compensate func(ctx context.Context) error
+}
+
+// Runner executes sagas and stores their State through a BaseGorm repository.
+type Runner struct {
+	repo *base.BaseGorm[State, string]
+}
+
+// NewRunner returns a Runner persisting saga states in db, see Migrate.
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{repo: base.NewBaseGorm[State, string](db)}
+}
+
+// Migrate creates the sagas table.
+func (r *Runner) Migrate(ctx context.Context) error {
+	return r.repo.DB(ctx).AutoMigrate(&State{})
+}
+
+// Run executes steps in order. When a step fails, the compensations of the already
+// completed steps run in reverse order and the step error is returned. An empty id
+// generates one. The final state is returned even when the saga failed.
+func (r *Runner) Run(ctx context.Context, id string, name string, steps ...Step) (*State, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx).WithField("saga", name)
+		err      error
+	)
+
+	if id == "" {
+		if id, err = newID(); err != nil {
+			return nil, err
+		}
+	}
+
+	state, err := r.repo.Create(ctx, &State{ID: id, Name: name, Status: StatusRunning})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range steps {
+		if stepErr := step.Action(ctx); stepErr != nil {
+			logEntry.WithField("step", step.Name).Error(stepErr)
+			state.FailedStep = step.Name
+			state.Error = stepErr.Error()
+
+			return state, errors.Join(stepErr, r.compensate(ctx, state, steps[:state.CompletedSteps]))
+		}
+
+		state.CompletedSteps++
+		if err = r.save(ctx, state, "completed_steps"); err != nil {
+			return state, err
+		}
+	}
+
+	state.Status = StatusCompleted
+	if err = r.save(ctx, state, "status"); err != nil {
+		return state, err
+	}
+
+	return state, nil
+}
+
+// compensate undoes completed in reverse order, stopping at the first failing compensation.
+func (r *Runner) compensate(ctx context.Context, state *State, completed []Step) error {
+	state.Status = StatusCompensating
+	if err := r.save(ctx, state, "status", "failed_step", "error"); err != nil {
+		return err
+	}
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		if completed[i].Compensate == nil {
+			continue
+		}
+
+		if err := completed[i].Compensate(ctx); err != nil {
+			state.Status = StatusFailed
+			state.Error = fmt.Sprintf("%s; compensation of %s: %v", state.Error, completed[i].Name, err)
+
+			return errors.Join(fmt.Errorf("compensation of %s: %w", completed[i].Name, err), r.save(ctx, state, "status", "error"))
+		}
+	}
+
+	state.Status = StatusCompensated
+
+	return r.save(ctx, state, "status")
+}
+
+func (r *Runner) save(ctx context.Context, state *State, columns ...string) error {
+	_, err := r.repo.Update(ctx, state, columns)
+	return err
+}
+
+// Get returns the state of a saga, nil when it does not exist.
+func (r *Runner) Get(ctx context.Context, id string) (*State, error) {
+	return r.repo.Detail(ctx, id)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
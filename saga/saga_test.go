@@ -0,0 +1,49 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+func TestRunCompensatesInReverseOrder(t *testing.T) {
+	var (
+		runner = NewRunner(testdb.DryRun(t))
+		calls  []string
+		failed = errors.New("payment declined")
+		step   = func(name string, err error) Step {
+			return Step{
+				Name: name,
+				Action: func(ctx context.Context) error {
+					calls = append(calls, name)
+					return err
+				},
+				Compensate: func(ctx context.Context) error {
+					calls = append(calls, "undo "+name)
+					return nil
+				},
+			}
+		}
+	)
+
+	state, err := runner.Run(context.Background(), "order-1", "checkout",
+		step("reserve stock", nil),
+		step("create invoice", nil),
+		step("charge card", failed),
+		step("ship", nil),
+	)
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected the step error, got %v", err)
+	}
+
+	want := []string{"reserve stock", "create invoice", "charge card", "undo create invoice", "undo reserve stock"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected calls %v, got %v", want, calls)
+	}
+	if state.Status != StatusCompensated || state.FailedStep != "charge card" || state.CompletedSteps != 2 {
+		t.Errorf("Unexpected final state %+v", state)
+	}
+}
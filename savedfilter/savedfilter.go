@@ -0,0 +1,172 @@
+// Package savedfilter persists named filters of a model, the JSON of their Where and
+// OrderBy, in a saved_filters table, e.g. the shareable views of a list screen. A
+// filter is validated against the model when saved, and again when run in case the
+// model changed since.
+package savedfilter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+)
+
+// Filter is one saved filter of the saved_filters table.
+type Filter struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Table     string    `json:"table" gorm:"column:table_name;size:64;index:idx_saved_filters_owner"` // table of the filtered model
+	OwnerID   string    `json:"owner_id" gorm:"column:owner_id;size:191;index:idx_saved_filters_owner"`
+	Name      string    `json:"name" gorm:"column:name;size:191"`
+	Wheres    string    `json:"wheres" gorm:"column:wheres;type:text"` // JSON array of base.Where, with the JSON names of the model
+	Orders    string    `json:"orders" gorm:"column:orders;type:text"` // JSON array of base.OrderBy, with the JSON names of the model
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Filter) TableName() string {
+	return "saved_filters"
+}
+
+func (Filter) PrimaryKey() string {
+	return "id"
+}
+
+// ErrInvalidFilter is wrapped by the errors of a filter which is not valid JSON, has
+// unknown fields, an invalid operator or direction, or belongs to another model.
+// The unknown names and values of the model fail with base.ErrUnknownField and
+// base.ErrInvalidValue.
+var ErrInvalidFilter = errors.New("invalid saved filter")
+
+// Filters saves and runs the filters of the model T.
+type Filters[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] struct {
+	filters *base.BaseGorm[Filter, uint]
+	repo    *base.BaseGorm[T, PkType]
+}
+
+// NewFilters returns the Filters of repo, stored in the saved_filters table of db,
+// see Migrate.
+func NewFilters[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](db *gorm.DB, repo *base.BaseGorm[T, PkType]) *Filters[T, PkType] {
+	return &Filters[T, PkType]{filters: base.NewBaseGorm[Filter, uint](db), repo: repo}
+}
+
+// Migrate creates the saved_filters table.
+func (f *Filters[T, PkType]) Migrate(ctx context.Context) error {
+	return f.filters.DB(ctx).AutoMigrate(&Filter{})
+}
+
+// Validate decodes wheres and orders, JSON arrays of base.Where and base.OrderBy
+// with the JSON names of T, and returns them with the columns of T. A null or empty
+// orders is no order.
+func (f *Filters[T, PkType]) Validate(wheres json.RawMessage, orders json.RawMessage) ([]base.Where, []base.OrderBy, error) {
+	decodedWheres, err := base.DecodeWheresStrict(wheres)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if err = checkOperators(decodedWheres); err != nil {
+		return nil, nil, err
+	}
+
+	var decodedOrders []base.OrderBy
+	if trimmed := bytes.TrimSpace(orders); len(trimmed) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&decodedOrders); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+	}
+	for _, order := range decodedOrders {
+		if order.String() == "" {
+			return nil, nil, fmt.Errorf("%w: direction %q of %s, asc or desc", ErrInvalidFilter, order.Direction, order.Field)
+		}
+	}
+
+	return f.repo.MapJSONNames(decodedWheres, decodedOrders)
+}
+
+// checkOperators rejects the operators base.Where would silently turn into equality.
+func checkOperators(wheres []base.Where) error {
+	for _, v := range wheres {
+		if v.Group != nil {
+			if err := checkOperators(v.Group.Wheres); err != nil {
+				return err
+			}
+			continue
+		}
+		if v.Operator != "" && !v.Operator.IsValid() {
+			return fmt.Errorf("%w: operator %q of %s", ErrInvalidFilter, v.Operator, v.Name)
+		}
+	}
+
+	return nil
+}
+
+// Save validates and stores the filter name of ownerID, see Validate.
+func (f *Filters[T, PkType]) Save(ctx context.Context, ownerID string, name string, wheres json.RawMessage, orders json.RawMessage) (*Filter, error) {
+	if _, _, err := f.Validate(wheres, orders); err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return nil, err
+	}
+
+	var e T
+	return f.filters.Create(ctx, &Filter{
+		Table:   e.TableName(),
+		OwnerID: ownerID,
+		Name:    name,
+		Wheres:  compact(wheres),
+		Orders:  compact(orders),
+	})
+}
+
+// compact returns the compacted JSON array data, validated, [] when there is none.
+func compact(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil || buf.String() == "null" {
+		return "[]"
+	}
+
+	return buf.String()
+}
+
+// List finds one page of the filters of T saved by ownerID, by name.
+func (f *Filters[T, PkType]) List(ctx context.Context, ownerID string, page int, pageSize int) ([]Filter, *base.Paginator, error) {
+	var e T
+	return f.filters.List(ctx, page, pageSize,
+		[]base.OrderBy{{Field: "name", Direction: "asc"}, {Field: "id", Direction: "asc"}},
+		[]base.Where{{Name: "table_name", Value: e.TableName()}, {Name: "owner_id", Value: ownerID}},
+	)
+}
+
+// Delete deletes the filter id.
+func (f *Filters[T, PkType]) Delete(ctx context.Context, id uint) (int64, error) {
+	return f.filters.Delete(ctx, id)
+}
+
+// RunSavedFilter finds one page of the rows of T matching the filter filterID,
+// validated again, nil rows when there is no such filter.
+func (f *Filters[T, PkType]) RunSavedFilter(ctx context.Context, filterID uint, page int, pageSize int, opts ...base.QueryOption) ([]T, *base.Paginator, error) {
+	filter, err := f.filters.Detail(ctx, filterID)
+	if err != nil || filter == nil {
+		return nil, nil, err
+	}
+
+	var e T
+	if filter.Table != e.TableName() {
+		err = fmt.Errorf("%w: filter %d is one of %s", ErrInvalidFilter, filterID, filter.Table)
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return nil, nil, err
+	}
+
+	wheres, orders, err := f.Validate(json.RawMessage(filter.Wheres), json.RawMessage(filter.Orders))
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return nil, nil, err
+	}
+
+	return f.repo.List(ctx, page, pageSize, orders, wheres, opts...)
+}
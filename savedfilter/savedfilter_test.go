@@ -0,0 +1,127 @@
+package savedfilter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+type Invoice struct {
+	ID       uint    `gorm:"column:id;primaryKey" json:"id"`
+	Customer string  `gorm:"column:customer;size:191" json:"customer"`
+	Amount   float64 `gorm:"column:amount" json:"amount"`
+	Status   string  `gorm:"column:status;size:32" json:"status"`
+}
+
+func (Invoice) TableName() string {
+	return "saved_filter_invoices"
+}
+
+func (Invoice) PrimaryKey() string {
+	return "id"
+}
+
+func TestValidate(t *testing.T) {
+	db := testdb.DryRun(t)
+	f := NewFilters(db, base.NewBaseGorm[Invoice, uint](db))
+
+	wheres, orders, err := f.Validate(
+		json.RawMessage(`[{"Name":"amount","Operator":">=","Value":"100"},{"Name":"status","Value":["open","late"]}]`),
+		json.RawMessage(`[{"Field":"amount","Direction":"desc"}]`),
+	)
+	if err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+	if wheres[0].Value != 100.0 || wheres[1].Operator != base.OpIn || orders[0].Field != "amount" {
+		t.Errorf("Expected the coerced wheres and orders, got %+v %+v", wheres, orders)
+	}
+
+	for name, test := range map[string]struct {
+		wheres, orders string
+		err            error
+	}{
+		"malformed":       {wheres: `{`, err: ErrInvalidFilter},
+		"unknown field":   {wheres: `[{"Name":"status","Operater":"!="}]`, err: ErrInvalidFilter},
+		"operator":        {wheres: `[{"Name":"status","Operator":"; DROP","Value":"x"}]`, err: ErrInvalidFilter},
+		"grouped":         {wheres: `[{"Group":{"Wheres":[{"Name":"status","Operator":"~","Value":"x"}]}}]`, err: ErrInvalidFilter},
+		"direction":       {wheres: `[]`, orders: `[{"Field":"amount","Direction":"up"}]`, err: ErrInvalidFilter},
+		"unknown column":  {wheres: `[{"Name":"password","Value":"x"}]`, err: base.ErrUnknownField},
+		"unknown order":   {wheres: `[]`, orders: `[{"Field":"password","Direction":"asc"}]`, err: base.ErrUnknownField},
+		"uncoerced value": {wheres: `[{"Name":"amount","Value":"much"}]`, err: base.ErrInvalidValue},
+	} {
+		if _, _, err := f.Validate(json.RawMessage(test.wheres), json.RawMessage(test.orders)); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", name, test.err, err)
+		}
+	}
+}
+
+func TestSaveRejectsInvalidFilter(t *testing.T) {
+	db := testdb.DryRun(t)
+	f := NewFilters(db, base.NewBaseGorm[Invoice, uint](db))
+
+	if _, err := f.Save(context.Background(), "user-1", "Big", json.RawMessage(`[{"Name":"secret","Value":1}]`), nil); !errors.Is(err, base.ErrUnknownField) {
+		t.Errorf("Expected ErrUnknownField, got %v", err)
+	}
+}
+
+func TestRunSavedFilter(t *testing.T) {
+	var (
+		db       = testdb.MySQL(t)
+		ctx      = context.Background()
+		invoices = base.NewBaseGorm[Invoice, uint](db)
+		f        = NewFilters(db, invoices)
+	)
+	if err := db.AutoMigrate(&Invoice{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := f.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DROP TABLE saved_filter_invoices")
+		db.Exec("DELETE FROM saved_filters WHERE owner_id = ?", "user-1")
+	})
+
+	for _, invoice := range []*Invoice{
+		{Customer: "acme", Amount: 50, Status: "open"},
+		{Customer: "globex", Amount: 150, Status: "open"},
+		{Customer: "initech", Amount: 300, Status: "paid"},
+		{Customer: "umbrella", Amount: 200, Status: "late"},
+	} {
+		if _, err := invoices.Create(ctx, invoice); err != nil {
+			t.Fatalf("Failed to create invoice: %v", err)
+		}
+	}
+
+	filter, err := f.Save(ctx, "user-1", "Big unpaid",
+		json.RawMessage(`[{"Name":"amount","Operator":">","Value":100}, {"Name":"status","Value":["open","late"]}]`),
+		json.RawMessage(`[{"Field":"amount","Direction":"desc"}]`),
+	)
+	if err != nil {
+		t.Fatalf("Failed to save filter: %v", err)
+	}
+	if filter.Wheres != `[{"Name":"amount","Operator":">","Value":100},{"Name":"status","Value":["open","late"]}]` {
+		t.Errorf("Expected the compacted wheres, got %s", filter.Wheres)
+	}
+
+	rows, paginator, err := f.RunSavedFilter(ctx, filter.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("Failed to run filter: %v", err)
+	}
+	if paginator.Total != 2 || rows[0].Customer != "umbrella" || rows[1].Customer != "globex" {
+		t.Errorf("Expected umbrella and globex, got %+v", rows)
+	}
+
+	saved, _, err := f.List(ctx, "user-1", 1, 10)
+	if err != nil || len(saved) != 1 || saved[0].Name != "Big unpaid" {
+		t.Errorf("Expected the saved filter, got %+v, %v", saved, err)
+	}
+
+	if rows, _, err := f.RunSavedFilter(ctx, filter.ID+1000, 1, 10); err != nil || rows != nil {
+		t.Errorf("Expected no rows for a missing filter, got %+v, %v", rows, err)
+	}
+}
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes the next activation time of a job.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// ParseSchedule parses a standard 5 field cron expression ("*/5 * * * *", fields are
+// minute, hour, day of month, month and day of week with *, lists, ranges and steps),
+// one of the @hourly, @daily, @weekly, @monthly, @yearly shortcuts, or "@every <duration>".
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: bad interval", spec)
+		}
+		return everySchedule(interval), nil
+	}
+
+	if expanded, ok := map[string]string{
+		"@hourly":  "0 * * * *",
+		"@daily":   "0 0 * * *",
+		"@weekly":  "0 0 * * 0",
+		"@monthly": "0 0 1 * *",
+		"@yearly":  "0 0 1 1 *",
+	}[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", spec)
+	}
+
+	var (
+		schedule cronSchedule
+		err      error
+	)
+	for i, bounds := range [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}} {
+		if schedule.fields[i], err = parseCronField(fields[i], bounds[0], bounds[1]); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	// 7 is an alias of sunday
+	if schedule.fields[4]&(1<<7) != 0 {
+		schedule.fields[4] |= 1
+	}
+	schedule.domStar, schedule.dowStar = fields[2] == "*", fields[4] == "*"
+
+	return &schedule, nil
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// cronSchedule holds one bit set per field : minute, hour, day of month, month, day of week.
+type cronSchedule struct {
+	fields           [5]uint64
+	domStar, dowStar bool
+}
+
+func (c *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	// a matching time exists within 5 years for any valid expression (Feb 29th)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case !c.has(3, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.has(1, t.Hour()):
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !c.has(0, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron : when both day fields are restricted, either may match.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := c.has(2, t.Day()), c.has(4, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+func (c *cronSchedule) has(field, value int) bool {
+	return c.fields[field]&(1<<uint(value)) != 0
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if start, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("bad range in %q", part)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q out of range [%d-%d]", part, min, max)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	return bits, nil
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 42, 0, time.UTC) // a wednesday
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, time.February, 1, 3, 0, 0, 0, time.UTC)},
+		{"30 9-17 * * 1-5", time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.February, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * 1", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("ParseSchedule(%q).Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every -1m"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected ParseSchedule(%q) to fail", spec)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+// Package scheduler runs recurring jobs from a table shared by all instances of a
+// service. Due jobs are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so every
+// activation runs on a single instance, failed runs are retried with an exponential
+// backoff, and every attempt is recorded in a run history table.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Run statuses.
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// Job is one recurring job of the scheduled_jobs table.
+type Job struct {
+	Name      string    `json:"name" gorm:"column:name;primaryKey;size:191"`
+	Spec      string    `json:"spec" gorm:"column:spec;size:191"`
+	Payload   string    `json:"payload" gorm:"column:payload;type:text"`
+	Enabled   bool      `json:"enabled" gorm:"column:enabled;default:true"`
+	NextRunAt time.Time `json:"next_run_at" gorm:"column:next_run_at;index"`
+	Attempt   int       `json:"attempt" gorm:"column:attempt"` // failed attempts of the current activation
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Job) TableName() string {
+	return "scheduled_jobs"
+}
+
+func (Job) PrimaryKey() string {
+	return "name"
+}
+
+// JobRun is one attempt to run a job, stored in the scheduled_job_runs table.
+type JobRun struct {
+	ID         uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	JobName    string    `json:"job_name" gorm:"column:job_name;size:191;index"`
+	Attempt    int       `json:"attempt" gorm:"column:attempt"`
+	Status     string    `json:"status" gorm:"column:status;size:32"`
+	Error      string    `json:"error" gorm:"column:error;type:text"`
+	Metrics    string    `json:"metrics" gorm:"column:metrics;type:text"` // JSON object of the values given to SetMetric
+	StartedAt  time.Time `json:"started_at" gorm:"column:started_at"`
+	FinishedAt time.Time `json:"finished_at" gorm:"column:finished_at"`
+}
+
+func (JobRun) TableName() string {
+	return "scheduled_job_runs"
+}
+
+func (JobRun) PrimaryKey() string {
+	return "id"
+}
+
+// Handler runs one activation of a job with the payload given to Schedule.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// Scheduler claims due jobs and dispatches them to their registered Handler.
+type Scheduler struct {
+	jobs *base.BaseGorm[Job, string]
+	runs *base.BaseGorm[JobRun, uint]
+	now  func() time.Time
+
+	pollInterval time.Duration
+	batchSize    int
+	runTimeout   time.Duration
+	maxRetries   int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithPollInterval sets how often Start looks for due jobs, 10s by default.
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		s.pollInterval = interval
+	}
+}
+
+// WithBatchSize sets how many due jobs are claimed at once, 10 by default.
+func WithBatchSize(size int) Option {
+	return func(s *Scheduler) {
+		s.batchSize = size
+	}
+}
+
+// WithRunTimeout bounds the duration of a run, 5m by default. A claimed job is not
+// claimed again before this timeout, so a job whose instance crashed reruns after it.
+func WithRunTimeout(timeout time.Duration) Option {
+	return func(s *Scheduler) {
+		s.runTimeout = timeout
+	}
+}
+
+// WithRetry sets how many times a failed run is retried before waiting for the next
+// activation, and the backoff doubling from initial up to max between retries.
+// Defaults to 3 retries, from 10s up to 10m.
+func WithRetry(maxRetries int, initial, max time.Duration) Option {
+	return func(s *Scheduler) {
+		s.maxRetries = maxRetries
+		s.backoff = initial
+		s.maxBackoff = max
+	}
+}
+
+// NewScheduler returns a Scheduler storing jobs and their runs in db, see Migrate.
+func NewScheduler(db *gorm.DB, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		jobs:         base.NewBaseGorm[Job, string](db),
+		runs:         base.NewBaseGorm[JobRun, uint](db),
+		now:          func() time.Time { return time.Now().UTC() },
+		pollInterval: 10 * time.Second,
+		batchSize:    10,
+		runTimeout:   5 * time.Minute,
+		maxRetries:   3,
+		backoff:      10 * time.Second,
+		maxBackoff:   10 * time.Minute,
+		handlers:     map[string]Handler{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Migrate creates the scheduled_jobs and scheduled_job_runs tables.
+func (s *Scheduler) Migrate(ctx context.Context) error {
+	return s.jobs.DB(ctx).AutoMigrate(&Job{}, &JobRun{})
+}
+
+// Register sets the handler of the job name. Only jobs having a handler are claimed
+// by this instance, so instances running an older version leave new jobs alone.
+func (s *Scheduler) Register(name string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[name] = handler
+}
+
+// Schedule creates or updates the job name, running on spec (see ParseSchedule)
+// with payload marshalled to JSON. Its next activation is kept when spec did not
+// change, so calling Schedule at every start up does not delay it.
+func (s *Scheduler) Schedule(ctx context.Context, name string, spec string, payload interface{}) error {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx).WithField("job", name)
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	job := &Job{Name: name, Spec: spec, Payload: string(encoded), Enabled: true, NextRunAt: schedule.Next(s.now())}
+	result := s.jobs.DB(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if err = result.Error; err != nil || result.RowsAffected == 1 {
+		return err
+	}
+
+	stored, err := s.jobs.Detail(ctx, name)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		err = fmt.Errorf("job %s vanished while being scheduled", name)
+		return err
+	}
+
+	columns := []string{"payload"}
+	if stored.Spec != spec {
+		columns = append(columns, "spec", "next_run_at", "attempt")
+	}
+	_, err = s.jobs.Update(ctx, job, columns)
+
+	return err
+}
+
+// SetEnabled pauses or resumes the job name on every instance.
+func (s *Scheduler) SetEnabled(ctx context.Context, name string, enabled bool) error {
+	_, err := s.jobs.UpdateWhere(ctx, []base.Where{{Name: "name", Value: name}}, map[string]interface{}{"enabled": enabled})
+	return err
+}
+
+// Start polls for due jobs until ctx is cancelled, and returns ctx's error.
+func (s *Scheduler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// errors are logged, the next poll retries
+		_, _ = s.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue claims up to the batch size of due jobs, runs them concurrently and waits
+// for them. It returns the number of jobs run.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	jobs, err := s.claim(ctx)
+	if err != nil || len(jobs) == 0 {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range jobs {
+		wg.Add(1)
+		go func(job *Job) {
+			defer wg.Done()
+			s.run(ctx, job)
+		}(&jobs[i])
+	}
+	wg.Wait()
+
+	return len(jobs), nil
+}
+
+// claim locks the due jobs having a handler, skipping the ones locked by other
+// instances, and pushes their next_run_at past the run timeout before committing.
+func (s *Scheduler) claim(ctx context.Context) ([]Job, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx)
+		now      = s.now()
+		jobs     []Job
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	s.mu.RLock()
+	names := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	err = s.jobs.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("enabled = ? AND next_run_at <= ? AND name IN ?", true, now, names).
+			Order("next_run_at").
+			Limit(s.batchSize).
+			Find(&jobs).Error; err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		claimed := make([]string, len(jobs))
+		for i, job := range jobs {
+			claimed[i] = job.Name
+		}
+
+		return tx.Model(&Job{}).
+			Where("name IN ?", claimed).
+			Update("next_run_at", now.Add(s.runTimeout)).Error
+	})
+
+	return jobs, err
+}
+
+// run executes one claimed job, records the attempt and schedules the next one.
+func (s *Scheduler) run(ctx context.Context, job *Job) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx).WithField("job", job.Name)
+		attempt  = job.Attempt + 1
+		run      = &JobRun{JobName: job.Name, Attempt: attempt, Status: RunSucceeded, StartedAt: s.now()}
+	)
+
+	s.mu.RLock()
+	handler := s.handlers[job.Name]
+	s.mu.RUnlock()
+
+	metrics := &runMetrics{values: map[string]float64{}}
+	runErr := s.call(context.WithValue(ctx, runMetricsCtxKey{}, metrics), handler, job)
+	run.FinishedAt = s.now()
+	run.Metrics = metrics.encode()
+
+	next := map[string]interface{}{"attempt": 0}
+	if runErr != nil {
+		logEntry.WithField("attempt", attempt).Error(runErr)
+		run.Status, run.Error = RunFailed, runErr.Error()
+	}
+
+	switch {
+	case runErr != nil && attempt <= s.maxRetries:
+		next["attempt"] = attempt
+		next["next_run_at"] = run.FinishedAt.Add(s.backoffFor(attempt))
+	default:
+		schedule, err := ParseSchedule(job.Spec)
+		if err != nil {
+			logEntry.Error(err)
+			next["enabled"] = false
+			break
+		}
+		next["next_run_at"] = schedule.Next(run.FinishedAt)
+	}
+
+	// the history and the next activation must be written even when ctx was cancelled
+	ctx = context.WithoutCancel(ctx)
+	_, _ = s.runs.Create(ctx, run)
+	_, _ = s.jobs.UpdateWhere(ctx, []base.Where{{Name: "name", Value: job.Name}}, next)
+}
+
+type runMetricsCtxKey struct{}
+
+type runMetrics struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (m *runMetrics) encode() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.values) == 0 {
+		return ""
+	}
+	encoded, _ := json.Marshal(m.values)
+
+	return string(encoded)
+}
+
+// SetMetric records a value, e.g. a number of processed rows, in the run history of
+// the job being run by ctx. It is a no-op outside of a Handler.
+func SetMetric(ctx context.Context, name string, value float64) {
+	metrics, ok := ctx.Value(runMetricsCtxKey{}).(*runMetrics)
+	if !ok {
+		return
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	metrics.values[name] = value
+}
+
+// call runs handler with the run timeout, turning a panic into an error.
+func (s *Scheduler) call(ctx context.Context, handler Handler, job *Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.runTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job %s panicked: %v", job.Name, r)
+		}
+	}()
+
+	if handler == nil {
+		return errors.New("no handler registered")
+	}
+
+	return handler(ctx, json.RawMessage(job.Payload))
+}
+
+// backoffFor returns the delay before retry attempt, doubling from the initial backoff.
+func (s *Scheduler) backoffFor(attempt int) time.Duration {
+	delay := s.backoff
+	for i := 1; i < attempt && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, s.maxBackoff)
+}
+
+// Runs returns the latest runs of the job name, most recent first.
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]JobRun, error) {
+	runs, _, err := s.runs.List(ctx, 1, limit,
+		[]base.OrderBy{{Field: "id", Direction: "desc"}},
+		[]base.Where{{Name: "job_name", Value: name}},
+	)
+
+	return runs, err
+}
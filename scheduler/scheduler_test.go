@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+func TestBackoff(t *testing.T) {
+	s := NewScheduler(testdb.DryRun(t), WithRetry(5, time.Second, 5*time.Second))
+
+	for attempt, want := range []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if attempt == 0 {
+			continue
+		}
+		if got := s.backoffFor(attempt); got != want {
+			t.Errorf("backoffFor(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestSetMetric(t *testing.T) {
+	metrics := &runMetrics{values: map[string]float64{}}
+	ctx := context.WithValue(context.Background(), runMetricsCtxKey{}, metrics)
+
+	SetMetric(ctx, "rows", 42)
+	SetMetric(context.Background(), "ignored", 1)
+
+	if got := metrics.encode(); got != `{"rows":42}` {
+		t.Errorf("Expected metrics {\"rows\":42}, got %s", got)
+	}
+}
@@ -0,0 +1,109 @@
+// Package sequence generates gapless numbers, e.g. invoice or order numbers, from
+// counters of a sequences table. A number is taken in the transaction of the caller
+// when ctx holds one, so rolling it back gives the number back.
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sequence is the counter of one sequence and scope in the sequences table.
+type Sequence struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"column:name;size:191;uniqueIndex:idx_sequences_name_scope"`
+	Scope     string    `json:"scope" gorm:"column:scope;size:191;uniqueIndex:idx_sequences_name_scope"` // e.g. a tenant id, empty when unscoped
+	Value     int64     `json:"value" gorm:"column:value"`                                               // last number taken
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Sequence) TableName() string {
+	return "sequences"
+}
+
+func (Sequence) PrimaryKey() string {
+	return "id"
+}
+
+// Generator takes numbers from the sequences table.
+type Generator struct {
+	repo *base.BaseGorm[Sequence, uint]
+}
+
+// NewGenerator returns a Generator of the sequences table of db, see Migrate.
+func NewGenerator(db *gorm.DB) *Generator {
+	return &Generator{repo: base.NewBaseGorm[Sequence, uint](db)}
+}
+
+// Migrate creates the sequences table.
+func (g *Generator) Migrate(ctx context.Context) error {
+	return g.repo.DB(ctx).AutoMigrate(&Sequence{})
+}
+
+// NextNumber takes the next number of the unscoped sequence name, starting at 1.
+func (g *Generator) NextNumber(ctx context.Context, name string) (int64, error) {
+	return g.NextScopedNumber(ctx, name, "")
+}
+
+// NextScopedNumber takes the next number of sequence name in scope, e.g. a tenant id
+// or a year for numbers restarting every year, starting at 1. The counter row stays
+// locked until the transaction ends, so concurrent callers wait instead of leaving
+// gaps or taking the same number.
+func (g *Generator) NextScopedNumber(ctx context.Context, name string, scope string) (int64, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx).WithField("sequence", name)
+		number   int64
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	err = g.repo.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Sequence{Name: name, Scope: scope}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&Sequence{}).Where("name = ? AND scope = ?", name, scope).Update("value", gorm.Expr("value + 1")).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&Sequence{}).Where("name = ? AND scope = ?", name, scope).Select("value").Scan(&number).Error
+	})
+
+	return number, err
+}
+
+var placeholder = regexp.MustCompile(`\{(year|month|day|number)(?::(\d+))?\}`)
+
+// Format renders number in layout, replacing {year}, {month} and {day} with the date
+// of at, and {number} with number, zero padded to N digits with {number:N}. For
+// example "INV-{year}-{number:6}" gives INV-2024-000123.
+func Format(layout string, number int64, at time.Time) string {
+	return placeholder.ReplaceAllStringFunc(layout, func(match string) string {
+		parts := placeholder.FindStringSubmatch(match)
+		width, _ := strconv.Atoi(parts[2])
+
+		switch parts[1] {
+		case "year":
+			return fmt.Sprintf("%04d", at.Year())
+		case "month":
+			return fmt.Sprintf("%02d", int(at.Month()))
+		case "day":
+			return fmt.Sprintf("%02d", at.Day())
+		}
+
+		return fmt.Sprintf("%0*d", width, number)
+	})
+}
@@ -0,0 +1,66 @@
+package sequence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+func TestFormat(t *testing.T) {
+	at := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{"INV-{year}-{number:6}", "INV-2024-000123"},
+		{"{year}{month}{day}/{number}", "20240307/123"},
+		{"ORD-{number:2}", "ORD-123"},
+		{"{unknown}-{number}", "{unknown}-123"},
+	}
+
+	for _, tt := range tests {
+		if got := Format(tt.layout, 123, at); got != tt.want {
+			t.Errorf("Format(%q): expected %s, got %s", tt.layout, tt.want, got)
+		}
+	}
+}
+
+func TestNextNumber(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		db        = testdb.MySQL(t)
+		generator = NewGenerator(db)
+	)
+	if err := generator.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate sequences: %v", err)
+	}
+	t.Cleanup(func() { db.Where("name = ?", "test_invoices").Delete(&Sequence{}) })
+
+	for want := int64(1); want <= 2; want++ {
+		if got, err := generator.NextNumber(ctx, "test_invoices"); err != nil || got != want {
+			t.Fatalf("Expected number %d, got %d, %v", want, got, err)
+		}
+	}
+	if got, err := generator.NextScopedNumber(ctx, "test_invoices", "acme"); err != nil || got != 1 {
+		t.Errorf("Expected scoped numbering to start at 1, got %d, %v", got, err)
+	}
+
+	rollback := errors.New("rollback")
+	err := generic_gorm.NewTxManager(db).RunInTransaction(ctx, func(ctx context.Context) error {
+		if _, err := generator.NextNumber(ctx, "test_invoices"); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+	if got, err := generator.NextNumber(ctx, "test_invoices"); err != nil || got != 3 {
+		t.Errorf("Expected the rolled back number 3 to be taken again, got %d, %v", got, err)
+	}
+}
@@ -0,0 +1,132 @@
+package generic_gorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	sessionCtxName = "x-session-db-ctx"
+)
+
+var sessionIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// SessionVar is a statement changing the state of a database session, and the
+// statement restoring it.
+type SessionVar struct {
+	name  string // variable name, validated as it cannot be a placeholder
+	set   string
+	args  []interface{}
+	reset string
+}
+
+// SystemVariable sets the MySQL session variable name, e.g. lc_time_names, and
+// restores its default afterwards.
+func SystemVariable(name string, value interface{}) SessionVar {
+	return SessionVar{
+		name:  name,
+		set:   fmt.Sprintf("SET SESSION %s = ?", name),
+		args:  []interface{}{value},
+		reset: fmt.Sprintf("SET SESSION %s = DEFAULT", name),
+	}
+}
+
+// UserVariable sets the user variable @name, e.g. the id of the current user read
+// by triggers, and sets it back to NULL afterwards.
+func UserVariable(name string, value interface{}) SessionVar {
+	return SessionVar{
+		name:  name,
+		set:   fmt.Sprintf("SET @%s = ?", name),
+		args:  []interface{}{value},
+		reset: fmt.Sprintf("SET @%s = NULL", name),
+	}
+}
+
+// Role activates a role granted to the connecting user, and the default roles
+// afterwards. SET ROLE takes no placeholder, the name is validated and quoted.
+func Role(name string) SessionVar {
+	return SessionVar{
+		name:  name,
+		set:   fmt.Sprintf("SET ROLE `%s`", name),
+		reset: "SET ROLE DEFAULT",
+	}
+}
+
+// StatementTimeout aborts the SELECT statements running longer than timeout.
+func StatementTimeout(timeout time.Duration) SessionVar {
+	return SystemVariable("max_execution_time", timeout.Milliseconds())
+}
+
+// SessionVariables returns the session variables of the operations run with ctx,
+// e.g. from the role or locale of the authenticated user.
+type SessionVariables func(ctx context.Context) []SessionVar
+
+// WithSession runs fn on a single connection of db, on which the variables returned
+// by variables(ctx) are set before and reset after fn, also when fn fails or panics.
+// A connection that cannot be reset is closed instead of going back to the pool.
+// Repositories of db called with the context given to fn use that connection.
+func WithSession(ctx context.Context, db *gorm.DB, variables SessionVariables, fn func(ctx context.Context) error) error {
+	vars := variables(ctx)
+	for _, v := range vars {
+		if !sessionIdentifier.MatchString(v.name) {
+			return fmt.Errorf("invalid session variable name %q", v.name)
+		}
+	}
+
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) (err error) {
+		var applied []SessionVar
+
+		// a new session, so chained calls do not share conn's statement
+		conn = conn.Session(&gorm.Session{})
+
+		defer func() {
+			// the session must be reset even when ctx is cancelled
+			resetCtx := context.WithoutCancel(ctx)
+			for i := len(applied) - 1; i >= 0; i-- {
+				if resetErr := conn.WithContext(resetCtx).Exec(applied[i].reset).Error; resetErr != nil {
+					GetLoggerFromContext(ctx).Error(resetErr)
+					discardConn(conn)
+					err = errors.Join(err, resetErr)
+					return
+				}
+			}
+		}()
+
+		for _, v := range vars {
+			if err = conn.Exec(v.set, v.args...).Error; err != nil {
+				return err
+			}
+			applied = append(applied, v)
+		}
+
+		return fn(ContextWithDB(ctx, conn))
+	})
+}
+
+// discardConn closes the pinned connection of conn, instead of releasing it to the pool.
+func discardConn(conn *gorm.DB) {
+	if sqlConn, ok := conn.Statement.ConnPool.(*sql.Conn); ok {
+		_ = sqlConn.Raw(func(interface{}) error {
+			return driver.ErrBadConn
+		})
+	}
+}
+
+// ContextWithDB makes the repositories of the same database as db run their
+// operations on db, e.g. a pinned connection, when called with the returned context.
+func ContextWithDB(ctx context.Context, db *gorm.DB) context.Context {
+	return context.WithValue(ctx, sessionCtxName, db)
+}
+
+// DBFromContext returns the *gorm.DB stored by ContextWithDB.
+func DBFromContext(ctx context.Context) (*gorm.DB, bool) {
+	db, ok := ctx.Value(sessionCtxName).(*gorm.DB)
+	return db, ok
+}
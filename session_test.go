@@ -0,0 +1,61 @@
+package generic_gorm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithSessionRejectsInvalidNames(t *testing.T) {
+	var (
+		db     = openDryRunDB(t, "users")
+		called bool
+	)
+
+	err := WithSession(context.Background(), db, func(ctx context.Context) []SessionVar {
+		return []SessionVar{UserVariable("user_id = 1; DROP TABLE users; --", 1)}
+	}, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected error for an invalid session variable name")
+	}
+	if called {
+		t.Error("Expected fn not to be called")
+	}
+}
+
+func TestContextWithDB(t *testing.T) {
+	db := openDryRunDB(t, "users")
+
+	if _, ok := DBFromContext(context.Background()); ok {
+		t.Error("Expected no db in an empty context")
+	}
+
+	stored, ok := DBFromContext(ContextWithDB(context.Background(), db))
+	if !ok || stored != db {
+		t.Error("Expected the db stored in the context")
+	}
+}
+
+func TestRole(t *testing.T) {
+	role := Role("reporting")
+	if role.set != "SET ROLE `reporting`" || len(role.args) != 0 {
+		t.Errorf("Expected the quoted role name without placeholder, got %q %v", role.set, role.args)
+	}
+
+	var (
+		db     = openDryRunDB(t, "users")
+		called bool
+	)
+
+	err := WithSession(context.Background(), db, func(ctx context.Context) []SessionVar {
+		return []SessionVar{Role("reporting`; DROP TABLE users; --")}
+	}, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("Expected an invalid role name to be rejected before fn, got %v", err)
+	}
+}
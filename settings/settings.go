@@ -0,0 +1,246 @@
+// Package settings stores typed runtime settings as JSON values of a settings table.
+// Values are cached in process, and Start polls the table so that watchers of every
+// instance are notified of the changes made by any of them.
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+)
+
+// Setting is one setting of the settings table. Its key is stored in setting_key,
+// KEY being reserved in MySQL.
+type Setting struct {
+	Key       string    `json:"key" gorm:"column:setting_key;primaryKey;size:191"`
+	Value     string    `json:"value" gorm:"column:value;type:text"` // JSON
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
+
+func (Setting) PrimaryKey() string {
+	return "setting_key"
+}
+
+// entry is the cached value of a key, found false when the key is not stored.
+type entry struct {
+	value    string
+	found    bool
+	loadedAt time.Time
+}
+
+// watcher decodes a changed value and passes it to the function given to Watch.
+type watcher struct {
+	id     int
+	notify func(ctx context.Context, value string)
+}
+
+// Store reads and writes settings through a cache.
+type Store struct {
+	repo *base.BaseGorm[Setting, string]
+	now  func() time.Time
+
+	cacheTTL     time.Duration
+	pollInterval time.Duration
+
+	mu       sync.RWMutex
+	cache    map[string]entry
+	complete bool // the cache holds every stored key, see Refresh
+	watchers map[string][]watcher
+	nextID   int
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithCacheTTL sets how long a value read by Get is served from the cache, 30s by
+// default. Refresh reloads every value whatever their age.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.cacheTTL = ttl
+	}
+}
+
+// WithPollInterval sets how often Start refreshes the cache, 10s by default.
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Store) {
+		s.pollInterval = interval
+	}
+}
+
+// NewStore returns a Store of the settings table of db, see Migrate.
+func NewStore(db *gorm.DB, opts ...Option) *Store {
+	s := &Store{
+		repo:         base.NewBaseGorm[Setting, string](db),
+		now:          time.Now,
+		cacheTTL:     30 * time.Second,
+		pollInterval: 10 * time.Second,
+		cache:        map[string]entry{},
+		watchers:     map[string][]watcher{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Migrate creates the settings table.
+func (s *Store) Migrate(ctx context.Context) error {
+	return s.repo.DB(ctx).AutoMigrate(&Setting{})
+}
+
+// Get decodes the value of key into a V, and returns fallback when key is not stored.
+func Get[V any](ctx context.Context, s *Store, key string, fallback V) (V, error) {
+	stored, found, err := s.load(ctx, key)
+	if err != nil || !found {
+		return fallback, err
+	}
+
+	var value V
+	if err = json.Unmarshal([]byte(stored), &value); err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).WithField("setting", key).Error(err)
+		return fallback, err
+	}
+
+	return value, nil
+}
+
+// Set stores value as the JSON value of key, and notifies the watchers of key in
+// this process. Other processes notice the change at their next Refresh.
+func Set[V any](ctx context.Context, s *Store, key string, value V) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).WithField("setting", key).Error(err)
+		return err
+	}
+
+	setting := &Setting{Key: key, Value: string(encoded), UpdatedAt: s.now()}
+	if _, err = s.repo.Upsert(ctx, setting, []string{"value", "updated_at"}); err != nil {
+		return err
+	}
+
+	s.store(ctx, key, entry{value: setting.Value, found: true, loadedAt: s.now()}, true)
+
+	return nil
+}
+
+// Watch calls fn with the new value of key each time it changes, until the returned
+// function is called. Values which cannot be decoded into a V are logged and skipped.
+func Watch[V any](s *Store, key string, fn func(value V)) (cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	id := s.nextID
+	s.watchers[key] = append(s.watchers[key], watcher{id: id, notify: func(ctx context.Context, stored string) {
+		var value V
+		if err := json.Unmarshal([]byte(stored), &value); err != nil {
+			generic_gorm.GetLoggerFromContext(ctx).WithField("setting", key).Error(err)
+			return
+		}
+		fn(value)
+	}})
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		watchers := s.watchers[key]
+		for i, w := range watchers {
+			if w.id == id {
+				s.watchers[key] = append(watchers[:i:i], watchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Start refreshes the cache every poll interval until ctx is cancelled, and returns
+// ctx's error.
+func (s *Store) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// errors are logged, the next poll retries
+		_ = s.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh reloads every setting, settings tables being small, and notifies the
+// watchers of the values which changed since they were cached.
+func (s *Store) Refresh(ctx context.Context) error {
+	settings, err := s.repo.WheresList(ctx, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	loadedAt := s.now()
+	for _, setting := range settings {
+		s.store(ctx, setting.Key, entry{value: setting.Value, found: true, loadedAt: loadedAt}, false)
+	}
+
+	s.mu.Lock()
+	s.complete = true
+	s.mu.Unlock()
+
+	return nil
+}
+
+// load returns the value of key, from the cache while it is fresh.
+func (s *Store) load(ctx context.Context, key string) (string, bool, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[key]
+	s.mu.RUnlock()
+
+	if ok && s.now().Sub(cached.loadedAt) < s.cacheTTL {
+		return cached.value, cached.found, nil
+	}
+
+	setting, err := s.repo.Detail(ctx, key)
+	if err != nil {
+		return "", false, err
+	}
+
+	loaded := entry{found: setting != nil, loadedAt: s.now()}
+	if setting != nil {
+		loaded.value = setting.Value
+	}
+	s.store(ctx, key, loaded, false)
+
+	return loaded.value, loaded.found, nil
+}
+
+// store caches the value of key and notifies its watchers when it changed. Before
+// the first Refresh, a key seen for the first time has nothing to be compared with,
+// unless known is set because the value was just written.
+func (s *Store) store(ctx context.Context, key string, loaded entry, known bool) {
+	s.mu.Lock()
+	previous, ok := s.cache[key]
+	s.cache[key] = loaded
+
+	var watchers []watcher
+	if (known || ok || s.complete) && loaded.found && (!previous.found || previous.value != loaded.value) {
+		watchers = append(watchers, s.watchers[key]...)
+	}
+	s.mu.Unlock()
+
+	for _, w := range watchers {
+		w.notify(ctx, loaded.value)
+	}
+}
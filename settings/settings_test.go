@@ -0,0 +1,98 @@
+package settings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+func TestSetGetWatch(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewStore(testdb.DryRun(t))
+		seen  []int
+	)
+
+	cancel := Watch(store, "max_upload_mb", func(value int) {
+		seen = append(seen, value)
+	})
+
+	for _, value := range []int{25, 25, 50} {
+		if err := Set(ctx, store, "max_upload_mb", value); err != nil {
+			t.Fatalf("Failed to set setting: %v", err)
+		}
+	}
+	if value, err := Get(ctx, store, "max_upload_mb", 10); err != nil || value != 50 {
+		t.Errorf("Expected the cached value 50, got %v, %v", value, err)
+	}
+	if len(seen) != 2 || seen[0] != 25 || seen[1] != 50 {
+		t.Errorf("Expected to be notified of 25 then 50, got %v", seen)
+	}
+
+	cancel()
+	if err := Set(ctx, store, "max_upload_mb", 100); err != nil {
+		t.Fatalf("Failed to set setting: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected no notification after cancel, got %v", seen)
+	}
+}
+
+func TestStoreNotifiesRemoteChanges(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewStore(testdb.DryRun(t))
+		seen  []string
+	)
+	Watch(store, "banner", func(value string) {
+		seen = append(seen, value)
+	})
+
+	// first sight before a refresh, nothing to compare with
+	store.store(ctx, "banner", entry{value: `"hello"`, found: true}, false)
+	// changed by another instance
+	store.store(ctx, "banner", entry{value: `"maintenance"`, found: true}, false)
+
+	if len(seen) != 1 || seen[0] != "maintenance" {
+		t.Errorf("Expected to be notified of the remote change only, got %v", seen)
+	}
+}
+
+func TestStore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		db    = testdb.MySQL(t)
+		store = NewStore(db)
+		other = NewStore(db)
+		seen  []bool
+	)
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate settings: %v", err)
+	}
+	t.Cleanup(func() { db.Where("setting_key = ?", "maintenance").Delete(&Setting{}) })
+
+	if value, err := Get(ctx, store, "maintenance", false); err != nil || value {
+		t.Fatalf("Expected the fallback of a missing setting, got %v, %v", value, err)
+	}
+
+	if err := other.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to refresh settings: %v", err)
+	}
+	Watch(other, "maintenance", func(value bool) {
+		seen = append(seen, value)
+	})
+
+	if err := Set(ctx, store, "maintenance", true); err != nil {
+		t.Fatalf("Failed to set setting: %v", err)
+	}
+	if err := other.Refresh(ctx); err != nil {
+		t.Fatalf("Failed to refresh settings: %v", err)
+	}
+	if len(seen) != 1 || !seen[0] {
+		t.Errorf("Expected the other store to be notified once, got %v", seen)
+	}
+	if value, err := Get(ctx, other, "maintenance", false); err != nil || !value {
+		t.Errorf("Expected the refreshed value, got %v, %v", value, err)
+	}
+}
@@ -0,0 +1,233 @@
+// Package tagging tags rows of any model, keeping the tags in a tags table and the
+// tagged rows, identified by their table name and primary key, in a taggings table.
+package tagging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tag is one tag of the tags table.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"column:name;size:191;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+func (Tag) PrimaryKey() string {
+	return "id"
+}
+
+// Tagging links a tag to a tagged row of the taggings table.
+type Tagging struct {
+	ID           uint      `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	TagID        uint      `json:"tag_id" gorm:"column:tag_id;uniqueIndex:idx_taggings_tag_taggable"`
+	TaggableType string    `json:"taggable_type" gorm:"column:taggable_type;size:64;uniqueIndex:idx_taggings_tag_taggable;index:idx_taggings_taggable"` // table name of the tagged row
+	TaggableID   string    `json:"taggable_id" gorm:"column:taggable_id;size:191;uniqueIndex:idx_taggings_tag_taggable;index:idx_taggings_taggable"`    // primary key of the tagged row
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (Tagging) TableName() string {
+	return "taggings"
+}
+
+func (Tagging) PrimaryKey() string {
+	return "id"
+}
+
+// TagCount is the number of rows tagged with Name.
+type TagCount struct {
+	Name  string `json:"name" gorm:"column:name"`
+	Count int64  `json:"count" gorm:"column:count"`
+}
+
+// Tagger tags and untags rows.
+type Tagger struct {
+	tags     *base.BaseGorm[Tag, uint]
+	taggings *base.BaseGorm[Tagging, uint]
+}
+
+// NewTagger returns a Tagger of the tags and taggings tables of db, see Migrate.
+func NewTagger(db *gorm.DB) *Tagger {
+	return &Tagger{
+		tags:     base.NewBaseGorm[Tag, uint](db),
+		taggings: base.NewBaseGorm[Tagging, uint](db),
+	}
+}
+
+// Migrate creates the tags and taggings tables.
+func (t *Tagger) Migrate(ctx context.Context) error {
+	return t.tags.DB(ctx).AutoMigrate(&Tag{}, &Tagging{})
+}
+
+// Tag adds the tags names to model, a pointer to a stored row, creating the missing
+// tags. Tags model already has are left alone.
+func (t *Tagger) Tag(ctx context.Context, model base.TablerWithPrimaryKey, names ...string) error {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx)
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	names = normalize(names)
+	if len(names) == 0 {
+		return nil
+	}
+
+	taggableID, err := base.PolymorphicID(ctx, t.tags.DB(ctx), model)
+	if err != nil {
+		return err
+	}
+
+	err = t.tags.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		tags := make([]Tag, len(names))
+		for i, name := range names {
+			tags[i] = Tag{Name: name}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+			return err
+		}
+
+		var ids []uint
+		if err := tx.Model(&Tag{}).Where("name IN ?", names).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		taggings := make([]Tagging, len(ids))
+		for i, id := range ids {
+			taggings[i] = Tagging{TagID: id, TaggableType: model.TableName(), TaggableID: taggableID}
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&taggings).Error
+	})
+
+	return err
+}
+
+// Untag removes the tags names from model. The tags themselves are kept.
+func (t *Tagger) Untag(ctx context.Context, model base.TablerWithPrimaryKey, names ...string) error {
+	names = normalize(names)
+	if len(names) == 0 {
+		return nil
+	}
+
+	taggableID, err := base.PolymorphicID(ctx, t.tags.DB(ctx), model)
+	if err != nil {
+		generic_gorm.GetLoggerFromContext(ctx).Error(err)
+		return err
+	}
+
+	tagIDs := t.tags.DB(ctx).Model(&Tag{}).Select("id").Where("name IN ?", names)
+	_, err = t.taggings.DeleteWhere(ctx, []base.Where{
+		{Name: "taggable_type", Value: model.TableName()},
+		{Name: "taggable_id", Value: taggableID},
+		{Name: "tag_id", Operator: base.OpIn, Value: tagIDs},
+	})
+
+	return err
+}
+
+// TagsOf returns the names of the tags of model, sorted.
+func (t *Tagger) TagsOf(ctx context.Context, model base.TablerWithPrimaryKey) ([]string, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx)
+		names    []string
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	taggableID, err := base.PolymorphicID(ctx, t.tags.DB(ctx), model)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.tags.DB(ctx).
+		Model(&Tag{}).
+		Joins("JOIN taggings ON taggings.tag_id = tags.id").
+		Where("taggings.taggable_type = ? AND taggings.taggable_id = ?", model.TableName(), taggableID).
+		Order("tags.name").
+		Pluck("tags.name", &names).Error
+
+	return names, err
+}
+
+// TagCounts returns how many rows of the table taggableType, every table when empty,
+// have each tag, the most used tags first.
+func (t *Tagger) TagCounts(ctx context.Context, taggableType string) ([]TagCount, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx)
+		counts   []TagCount
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	db := t.tags.DB(ctx).
+		Model(&Tag{}).
+		Select("tags.name AS name, COUNT(*) AS count").
+		Joins("JOIN taggings ON taggings.tag_id = tags.id")
+	if taggableType != "" {
+		db = db.Where("taggings.taggable_type = ?", taggableType)
+	}
+
+	err = db.Group("tags.name").Order("count DESC, tags.name").Find(&counts).Error
+
+	return counts, err
+}
+
+// ListByTag finds one page of the rows of repo having the tag name.
+func ListByTag[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](ctx context.Context, repo *base.BaseGorm[T, PkType], name string, page int, pageSize int, orders []base.OrderBy, opts ...base.QueryOption) ([]T, *base.Paginator, error) {
+	var e T
+
+	return repo.ListCustom(ctx, page, pageSize, orders, nil, func(db *gorm.DB) *gorm.DB {
+		tagged := db.Session(&gorm.Session{NewDB: true}).
+			Table(Tagging{}.TableName()).
+			Select("taggings.taggable_id").
+			Joins("JOIN tags ON tags.id = taggings.tag_id").
+			Where("tags.name = ? AND taggings.taggable_type = ?", strings.TrimSpace(name), e.TableName())
+
+		return db.Model(&e).Table(e.TableName()).Where(fmt.Sprintf("%s.%s IN (?)", e.TableName(), e.PrimaryKey()), tagged)
+	}, opts...)
+}
+
+// normalize trims names and drops the empty and repeated ones.
+func normalize(names []string) []string {
+	var (
+		normalized = make([]string, 0, len(names))
+		seen       = make(map[string]bool, len(names))
+	)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+
+	return normalized
+}
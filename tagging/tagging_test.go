@@ -0,0 +1,106 @@
+package tagging
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Article struct {
+	ID    uint   `gorm:"column:id;primaryKey"`
+	Title string `gorm:"column:title"`
+}
+
+func (Article) TableName() string {
+	return "tagging_articles"
+}
+
+func (Article) PrimaryKey() string {
+	return "id"
+}
+
+func TestNormalize(t *testing.T) {
+	got := normalize([]string{" go ", "", "gorm", "go"})
+	if want := []string{"go", "gorm"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestListByTagSQL(t *testing.T) {
+	var (
+		db  = testdb.DryRun(t)
+		sql string
+	)
+	if err := db.Callback().Query().After("gorm:query").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	repo := base.NewBaseGorm[Article, uint](db)
+	if _, _, err := ListByTag(context.Background(), repo, "go", 1, 10, nil, base.WithoutTotal()); err != nil {
+		t.Fatalf("Failed to list by tag: %v", err)
+	}
+
+	want := "SELECT * FROM `tagging_articles` WHERE tagging_articles.id IN (SELECT taggings.taggable_id FROM `taggings` JOIN tags ON tags.id = taggings.tag_id WHERE tags.name = ? AND taggings.taggable_type = ?) LIMIT ?"
+	if sql != want {
+		t.Errorf("Expected SQL\n%s\ngot\n%s", want, sql)
+	}
+}
+
+func TestTagger(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		db     = testdb.MySQL(t)
+		tagger = NewTagger(db)
+		repo   = base.NewBaseGorm[Article, uint](db)
+	)
+	if err := tagger.Migrate(ctx); err != nil {
+		t.Fatalf("Failed to migrate tags: %v", err)
+	}
+	if err := db.AutoMigrate(&Article{}); err != nil {
+		t.Fatalf("Failed to migrate articles: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("taggable_type = ?", Article{}.TableName()).Delete(&Tagging{})
+		db.Migrator().DropTable(&Article{})
+	})
+
+	first, err := repo.Create(ctx, &Article{Title: "Generics"})
+	if err != nil {
+		t.Fatalf("Failed to create article: %v", err)
+	}
+	second, err := repo.Create(ctx, &Article{Title: "Transactions"})
+	if err != nil {
+		t.Fatalf("Failed to create article: %v", err)
+	}
+
+	if err := tagger.Tag(ctx, first, "go", "gorm"); err != nil {
+		t.Fatalf("Failed to tag article: %v", err)
+	}
+	if err := tagger.Tag(ctx, second, "gorm", "gorm"); err != nil {
+		t.Fatalf("Failed to tag article: %v", err)
+	}
+	if err := tagger.Untag(ctx, first, "go"); err != nil {
+		t.Fatalf("Failed to untag article: %v", err)
+	}
+
+	names, err := tagger.TagsOf(ctx, first)
+	if err != nil || !reflect.DeepEqual(names, []string{"gorm"}) {
+		t.Errorf("Expected the first article to keep gorm only, got %v, %v", names, err)
+	}
+
+	articles, paginator, err := ListByTag(ctx, repo, "gorm", 1, 10, nil)
+	if err != nil || paginator.Total != 2 || len(articles) != 2 {
+		t.Errorf("Expected 2 articles tagged gorm, got %d, %v", len(articles), err)
+	}
+
+	counts, err := tagger.TagCounts(ctx, Article{}.TableName())
+	if err != nil || !reflect.DeepEqual(counts, []TagCount{{Name: "gorm", Count: 2}}) {
+		t.Errorf("Expected gorm used twice, got %+v, %v", counts, err)
+	}
+}
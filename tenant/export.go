@@ -0,0 +1,155 @@
+package tenant
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const exportBatchSize = 500
+
+// Manifest describes an export, it is stored as manifest.json in the archive.
+type Manifest struct {
+	TenantID   interface{}     `json:"tenant_id"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Tables     []TableManifest `json:"tables"` // in import order, referenced tables first
+}
+
+// TableManifest describes the export file of one table.
+type TableManifest struct {
+	Table  string `json:"table"`
+	File   string `json:"file"`
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// ExportTenant writes to w a zip archive holding, for each registered model, the
+// rows of tenantID as one JSON object per line in tables/<table>.ndjson, followed by
+// manifest.json. Tables are ordered so that a table comes after the tables it
+// references through a foreign key, and can be imported in the manifest order.
+// Many to many join tables are not exported, as they have no tenant column.
+func (r *Registry) ExportTenant(ctx context.Context, tenantID interface{}, w io.Writer) (*Manifest, error) {
+	var (
+		logEntry = generic_gorm.GetLoggerFromContext(ctx).WithField("tenant_id", tenantID)
+		manifest = &Manifest{TenantID: tenantID, ExportedAt: time.Now().UTC()}
+		archive  = zip.NewWriter(w)
+		err      error
+	)
+
+	defer func() {
+		if err != nil {
+			logEntry.Error(err)
+		}
+	}()
+
+	tables, err := r.importOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		var entry *TableManifest
+		if entry, err = r.exportTable(ctx, archive, table, tenantID); err != nil {
+			return nil, err
+		}
+		manifest.Tables = append(manifest.Tables, *entry)
+	}
+
+	file, err := archive.Create("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+
+	if err = archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+type exportedTable struct {
+	schema *schema.Schema
+	model  Scoped
+}
+
+// importOrder returns the registered models, referenced tables first.
+func (r *Registry) importOrder() ([]exportedTable, error) {
+	var (
+		models  = r.Models()
+		byType  = make(map[reflect.Type]Scoped, len(models))
+		parsers = make([]interface{}, len(models))
+	)
+	for i, model := range models {
+		byType[reflect.TypeOf(model).Elem()] = model
+		parsers[i] = model
+	}
+
+	// DependencyOrder lists referencing tables first, which is the deletion order
+	ordered, err := base.DependencyOrder(r.db, parsers...)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]exportedTable, 0, len(models))
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if model, ok := byType[ordered[i].ModelType]; ok {
+			tables = append(tables, exportedTable{schema: ordered[i], model: model})
+		}
+	}
+
+	return tables, nil
+}
+
+// exportTable streams the rows of one table, FindInBatches walks them by primary key.
+func (r *Registry) exportTable(ctx context.Context, archive *zip.Writer, table exportedTable, tenantID interface{}) (*TableManifest, error) {
+	entry := &TableManifest{Table: table.schema.Table, File: fmt.Sprintf("tables/%s.ndjson", table.schema.Table)}
+
+	file, err := archive.Create(entry.File)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		hash    = sha256.New()
+		encoder = json.NewEncoder(io.MultiWriter(file, hash))
+		rows    = reflect.New(reflect.SliceOf(table.schema.ModelType))
+	)
+
+	result := r.db.WithContext(ctx).
+		Model(table.model).
+		Where(fmt.Sprintf("%s = ?", table.model.TenantColumn()), tenantID).
+		FindInBatches(rows.Interface(), exportBatchSize, func(tx *gorm.DB, batch int) error {
+			slice := rows.Elem()
+			for i := 0; i < slice.Len(); i++ {
+				if err := encoder.Encode(slice.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			entry.Rows += int64(slice.Len())
+
+			return nil
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("export %s: %w", entry.Table, result.Error)
+	}
+
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	return entry, nil
+}
@@ -0,0 +1,76 @@
+package tenant
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+)
+
+type Invoice struct {
+	ID         uint `gorm:"primaryKey"`
+	TenantID   uint
+	CustomerID uint
+	Customer   Customer
+}
+
+func (Invoice) TenantColumn() string {
+	return "tenant_id"
+}
+
+type Customer struct {
+	ID       uint `gorm:"primaryKey"`
+	TenantID uint
+	Name     string
+}
+
+func (Customer) TenantColumn() string {
+	return "tenant_id"
+}
+
+func TestExportTenant(t *testing.T) {
+	registry := NewRegistry(testdb.DryRun(t))
+	registry.Register(&Invoice{}, &Customer{})
+
+	var buf bytes.Buffer
+	manifest, err := registry.ExportTenant(context.Background(), 42, &buf)
+	if err != nil {
+		t.Fatalf("Failed to export tenant: %v", err)
+	}
+
+	if len(manifest.Tables) != 2 || manifest.Tables[0].Table != "customers" || manifest.Tables[1].Table != "invoices" {
+		t.Fatalf("Expected customers before invoices, got %+v", manifest.Tables)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	for _, name := range []string{"tables/customers.ndjson", "tables/invoices.ndjson", "manifest.json"} {
+		if files[name] == nil {
+			t.Errorf("Expected %s in the archive", name)
+		}
+	}
+
+	reader, err := files["manifest.json"].Open()
+	if err != nil {
+		t.Fatalf("Failed to open manifest: %v", err)
+	}
+	defer reader.Close()
+
+	var stored Manifest
+	if err := json.NewDecoder(reader).Decode(&stored); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+	if stored.TenantID != float64(42) {
+		t.Errorf("Expected tenant 42 in the manifest, got %v", stored.TenantID)
+	}
+}
@@ -0,0 +1,50 @@
+// Package tenant keeps the list of tenant scoped models of a service, the ones whose
+// rows all belong to a single tenant, for tools operating on a whole tenant.
+package tenant
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Scoped is implemented by models holding a tenant id column.
+type Scoped interface {
+	TenantColumn() string
+}
+
+// Registry lists the tenant scoped models stored in one database.
+type Registry struct {
+	db *gorm.DB
+
+	mu     sync.RWMutex
+	models []Scoped
+}
+
+// NewRegistry returns an empty Registry of models stored in db.
+func NewRegistry(db *gorm.DB) *Registry {
+	return &Registry{db: db}
+}
+
+// Register adds models, given as pointers to zero values, to r.
+func (r *Registry) Register(models ...Scoped) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, model := range models {
+		if reflect.TypeOf(model).Kind() != reflect.Pointer {
+			panic(fmt.Sprintf("tenant: register %T as a pointer", model))
+		}
+		r.models = append(r.models, model)
+	}
+}
+
+// Models returns the registered models, in registration order.
+func (r *Registry) Models() []Scoped {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]Scoped(nil), r.models...)
+}
@@ -0,0 +1,42 @@
+package generic_gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrCrossDatabaseTransaction is returned by RunInTransaction called within a
+// transaction of another database, which cannot be joined nor committed atomically.
+var ErrCrossDatabaseTransaction = errors.New("transaction across databases")
+
+// TxManager runs units of work spanning several repositories in one transaction.
+type TxManager struct {
+	db *gorm.DB
+}
+
+// NewTxManager returns a TxManager opening its transactions on db.
+func NewTxManager(db *gorm.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// RunInTransaction runs fn in a transaction, committed when fn returns nil and
+// rolled back otherwise. The context given to fn holds the transaction, so the
+// repositories of the same database called with it share the transaction. Called
+// within another transaction of the same database, it runs in a savepoint of it,
+// and fails with ErrCrossDatabaseTransaction within one of another database.
+func (m *TxManager) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	db := m.db
+	if outer, ok := DBFromContext(ctx); ok {
+		if !SameDatabase(outer, m.db) {
+			return ErrCrossDatabaseTransaction
+		}
+		db = outer
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ContextWithDB(ctx, tx))
+	}, opts...)
+}
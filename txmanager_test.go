@@ -0,0 +1,90 @@
+package generic_gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// fakePool begins fakeTx transactions without a server.
+type fakePool struct {
+	gorm.ConnPool
+	tx *fakeTx
+}
+
+func (p *fakePool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return p.tx, nil
+}
+
+type fakeTx struct {
+	gorm.ConnPool
+	committed, rolledBack bool
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.rolledBack = true
+	return nil
+}
+
+func TestRunInTransaction(t *testing.T) {
+	pool := &fakePool{tx: &fakeTx{}}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: pool, SkipInitializeWithVersion: true}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	var (
+		manager = NewTxManager(db)
+		failed  = errors.New("failed")
+	)
+
+	err = manager.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		tx, ok := DBFromContext(ctx)
+		if !ok || tx.Statement.ConnPool != pool.tx {
+			t.Error("Expected the context to hold the transaction")
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected the error of fn, got %v", err)
+	}
+	if !pool.tx.rolledBack || pool.tx.committed {
+		t.Errorf("Expected a rollback, got %+v", pool.tx)
+	}
+
+	pool.tx = &fakeTx{}
+	if err = manager.RunInTransaction(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("Failed to run transaction: %v", err)
+	}
+	if !pool.tx.committed {
+		t.Error("Expected a commit")
+	}
+}
+
+func TestRunInTransactionAcrossDatabases(t *testing.T) {
+	var (
+		usersDB, analyticsDB = openDryRunDB(t, "users"), openDryRunDB(t, "analytics")
+		ctx                  = ContextWithDB(context.Background(), usersDB)
+		called               bool
+	)
+
+	err := NewTxManager(analyticsDB).RunInTransaction(ctx, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrCrossDatabaseTransaction) {
+		t.Errorf("Expected ErrCrossDatabaseTransaction, got %v", err)
+	}
+	if called {
+		t.Error("Expected fn not to be called")
+	}
+}
@@ -0,0 +1,235 @@
+// Package writebehind buffers the rows of append-only models, e.g. events or audit
+// rows, in memory and inserts them by batches in the background, trading the
+// durability of the buffered rows for far fewer INSERT round trips.
+package writebehind
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	generic_gorm "github.com/harryosmar/generic-gorm"
+	"github.com/harryosmar/generic-gorm/base"
+)
+
+// ErrFull is returned by Create when the buffer holds its capacity, see WithBlockWhenFull.
+var ErrFull = errors.New("write-behind buffer full")
+
+// ErrClosed is returned by Create once Start returned.
+var ErrClosed = errors.New("write-behind buffer closed")
+
+type config struct {
+	batchSize     int
+	flushInterval time.Duration
+	capacity      int
+	blockWhenFull bool
+	drainTimeout  time.Duration
+}
+
+// Option configures a Buffer.
+type Option func(*config)
+
+// WithBatchSize sets how many rows are inserted at once, 500 by default.
+func WithBatchSize(size int) Option {
+	return func(c *config) {
+		c.batchSize = size
+	}
+}
+
+// WithFlushInterval sets how long rows wait for their batch to fill before being
+// inserted anyway, 1s by default.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.flushInterval = interval
+	}
+}
+
+// WithCapacity sets how many rows the buffer holds, 10000 by default.
+func WithCapacity(capacity int) Option {
+	return func(c *config) {
+		c.capacity = capacity
+	}
+}
+
+// WithBlockWhenFull makes Create wait for room in a full buffer, until its context
+// is done, instead of failing with ErrFull.
+func WithBlockWhenFull() Option {
+	return func(c *config) {
+		c.blockWhenFull = true
+	}
+}
+
+// WithDrainTimeout sets how long Start keeps inserting the buffered rows once its
+// context is cancelled, 5s by default. The rows still buffered afterwards are lost,
+// at once with 0.
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.drainTimeout = timeout
+	}
+}
+
+// Stats counts the rows given to a Buffer.
+type Stats struct {
+	Written int64 `json:"written"`
+	Failed  int64 `json:"failed"`  // lost to failed inserts
+	Dropped int64 `json:"dropped"` // refused with ErrFull
+	Lost    int64 `json:"lost"`    // still buffered when the drain timed out
+	Pending int   `json:"pending"`
+}
+
+// Buffer inserts the rows given to Create by batches, see Start.
+type Buffer[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint] struct {
+	repo *base.BaseGorm[T, PkType]
+	config
+
+	rows     chan *T
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+
+	written atomic.Int64
+	failed  atomic.Int64
+	dropped atomic.Int64
+	lost    atomic.Int64
+}
+
+// NewBuffer returns a Buffer inserting rows with repo.CreateMultiple.
+func NewBuffer[T base.TablerWithPrimaryKey, PkType string | int64 | int32 | int | uint](repo *base.BaseGorm[T, PkType], opts ...Option) *Buffer[T, PkType] {
+	c := config{
+		batchSize:     500,
+		flushInterval: time.Second,
+		capacity:      10000,
+		drainTimeout:  5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &Buffer[T, PkType]{
+		repo:    repo,
+		config:  c,
+		rows:    make(chan *T, c.capacity),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Create buffers row, inserted by Start within the flush interval. Insert errors
+// are only logged and counted in Stats.
+func (b *Buffer[T, PkType]) Create(ctx context.Context, row *T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	if !b.blockWhenFull {
+		select {
+		case b.rows <- row:
+			return nil
+		default:
+			b.dropped.Add(1)
+			return ErrFull
+		}
+	}
+
+	select {
+	case b.rows <- row:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.stopped:
+		return ErrClosed
+	}
+}
+
+// Start inserts the buffered rows until ctx is cancelled, then stops accepting rows,
+// drains the buffer within the drain timeout, and returns ctx's error.
+func (b *Buffer[T, PkType]) Start(ctx context.Context) error {
+	var (
+		ticker = time.NewTicker(b.flushInterval)
+		batch  = make([]*T, 0, b.batchSize)
+	)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.close()
+			b.drain(ctx, batch)
+			return ctx.Err()
+		case row := <-b.rows:
+			if batch = append(batch, row); len(batch) >= b.batchSize {
+				b.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				b.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// Stats returns the counters of the buffer.
+func (b *Buffer[T, PkType]) Stats() Stats {
+	return Stats{
+		Written: b.written.Load(),
+		Failed:  b.failed.Load(),
+		Dropped: b.dropped.Load(),
+		Lost:    b.lost.Load(),
+		Pending: len(b.rows),
+	}
+}
+
+// close makes Create fail with ErrClosed, once the calls in progress returned.
+func (b *Buffer[T, PkType]) close() {
+	b.stopOnce.Do(func() {
+		close(b.stopped)
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+}
+
+// drain inserts batch and the buffered rows until the drain timeout.
+func (b *Buffer[T, PkType]) drain(ctx context.Context, batch []*T) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.drainTimeout)
+	defer cancel()
+
+	for {
+		for len(batch) < b.batchSize && len(b.rows) > 0 {
+			batch = append(batch, <-b.rows)
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		if ctx.Err() != nil {
+			lost := len(batch) + len(b.rows)
+			b.lost.Add(int64(lost))
+			generic_gorm.GetLoggerFromContext(ctx).Errorf("write-behind: %d rows lost, not inserted within the drain timeout", lost)
+			return
+		}
+
+		b.flush(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// flush inserts batch, CreateMultiple logging its failure.
+func (b *Buffer[T, PkType]) flush(ctx context.Context, batch []*T) {
+	if _, _, err := b.repo.CreateMultiple(ctx, batch); err != nil {
+		b.failed.Add(int64(len(batch)))
+		return
+	}
+
+	b.written.Add(int64(len(batch)))
+}
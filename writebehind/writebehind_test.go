@@ -0,0 +1,151 @@
+package writebehind
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harryosmar/generic-gorm/base"
+	"github.com/harryosmar/generic-gorm/internal/testdb"
+	"gorm.io/gorm"
+)
+
+type Event struct {
+	ID   uint   `gorm:"column:id;primaryKey"`
+	Name string `gorm:"column:name"`
+}
+
+func (Event) TableName() string {
+	return "events"
+}
+
+func (Event) PrimaryKey() string {
+	return "id"
+}
+
+// countInserts counts the INSERT statements run on db.
+func countInserts(t *testing.T, db *gorm.DB) *atomic.Int64 {
+	t.Helper()
+
+	var inserts atomic.Int64
+	if err := db.Callback().Create().After("gorm:create").Register("test:count_inserts", func(tx *gorm.DB) {
+		inserts.Add(1)
+	}); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	return &inserts
+}
+
+func TestBufferDrainsOnStop(t *testing.T) {
+	var (
+		db      = testdb.DryRun(t)
+		inserts = countInserts(t, db)
+		buffer  = NewBuffer(base.NewBaseGorm[Event, uint](db), WithBatchSize(2), WithFlushInterval(time.Hour))
+	)
+
+	for i := 0; i < 5; i++ {
+		if err := buffer.Create(context.Background(), &Event{Name: "clicked"}); err != nil {
+			t.Fatalf("Failed to buffer event: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := buffer.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Start to return the context error, got %v", err)
+	}
+
+	if got := inserts.Load(); got != 3 {
+		t.Errorf("Expected 5 events to be inserted in 3 batches, got %d INSERTs", got)
+	}
+	if stats := buffer.Stats(); stats.Written != 5 || stats.Pending != 0 {
+		t.Errorf("Expected 5 written events, got %+v", stats)
+	}
+	if err := buffer.Create(context.Background(), &Event{Name: "clicked"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Start returned, got %v", err)
+	}
+}
+
+func TestBufferFlushesByBatch(t *testing.T) {
+	var (
+		db      = testdb.DryRun(t)
+		inserts = countInserts(t, db)
+		buffer  = NewBuffer(base.NewBaseGorm[Event, uint](db), WithBatchSize(2), WithFlushInterval(time.Hour))
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- buffer.Start(ctx)
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := buffer.Create(ctx, &Event{Name: "clicked"}); err != nil {
+			t.Fatalf("Failed to buffer event: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for inserts.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := inserts.Load(); got != 1 {
+		t.Errorf("Expected the full batch to be inserted before the flush interval, got %d INSERTs", got)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestBufferFull(t *testing.T) {
+	buffer := NewBuffer(base.NewBaseGorm[Event, uint](testdb.DryRun(t)), WithCapacity(1))
+
+	if err := buffer.Create(context.Background(), &Event{Name: "clicked"}); err != nil {
+		t.Fatalf("Failed to buffer event: %v", err)
+	}
+	if err := buffer.Create(context.Background(), &Event{Name: "clicked"}); !errors.Is(err, ErrFull) {
+		t.Errorf("Expected ErrFull, got %v", err)
+	}
+
+	blocking := NewBuffer(base.NewBaseGorm[Event, uint](testdb.DryRun(t)), WithCapacity(1), WithBlockWhenFull())
+	if err := blocking.Create(context.Background(), &Event{Name: "clicked"}); err != nil {
+		t.Fatalf("Failed to buffer event: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := blocking.Create(ctx, &Event{Name: "clicked"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Create to wait until its deadline, got %v", err)
+	}
+
+	if stats := buffer.Stats(); stats.Dropped != 1 || stats.Pending != 1 {
+		t.Errorf("Expected 1 dropped and 1 pending event, got %+v", stats)
+	}
+}
+
+func TestBufferLosesRowsWithoutDrain(t *testing.T) {
+	var (
+		db      = testdb.DryRun(t)
+		inserts = countInserts(t, db)
+		buffer  = NewBuffer(base.NewBaseGorm[Event, uint](db), WithDrainTimeout(0))
+	)
+
+	for i := 0; i < 3; i++ {
+		if err := buffer.Create(context.Background(), &Event{Name: "clicked"}); err != nil {
+			t.Fatalf("Failed to buffer event: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = buffer.Start(ctx)
+
+	if got := inserts.Load(); got != 0 {
+		t.Errorf("Expected no INSERT, got %d", got)
+	}
+	if stats := buffer.Stats(); stats.Lost != 3 {
+		t.Errorf("Expected 3 lost events, got %+v", stats)
+	}
+}